@@ -1,21 +1,147 @@
-package main
-
-import "github.com/vrischmann/envconfig"
-
-var config struct {
-	HTTP struct {
-		Port int `envconfig:"HTTP_PORT,default=8080" json:"Port"`
-	}
-	Debug struct {
-		PrintConfig    bool `envconfig:"DEBUG_PRINTCONFIG,default=false" json:"PrintConfig"`
-		PrintRootCause bool `envconfig:"DEBUG_PRINTROOTCAUSE,default=false" json:"PrintRootCause"`
-	}
-	CommandDatabase struct {
-		URL string `envconfig:"COMMAND_DATABASE_URL" json:"CommandDatabaseURL"`
-	}
-}
-
-/// initialize configuration.
-func initConfig() error {
-	return envconfig.Init(&config)
-}
+package main
+
+import (
+	"time"
+
+	"github.com/vrischmann/envconfig"
+)
+
+var config struct {
+	HTTP struct {
+		Port int `envconfig:"HTTP_PORT,default=8080" json:"Port"`
+	}
+	Debug struct {
+		PrintConfig    bool `envconfig:"DEBUG_PRINTCONFIG,default=false" json:"PrintConfig"`
+		PrintRootCause bool `envconfig:"DEBUG_PRINTROOTCAUSE,default=false" json:"PrintRootCause"`
+	}
+	Auth struct {
+		JWTSecret  string        `envconfig:"AUTH_JWT_SECRET" json:"-"`
+		SessionTTL time.Duration `envconfig:"AUTH_SESSION_TTL,default=8h" json:"SessionTTL"`
+		//Admins are the login names allowed to run administrative operations
+		Admins []string `envconfig:"AUTH_ADMINS,optional" json:"Admins"`
+	}
+	OIDC struct {
+		IssuerURL       string   `envconfig:"OIDC_ISSUER_URL,optional" json:"IssuerURL"`
+		ClientID        string   `envconfig:"OIDC_CLIENT_ID,optional" json:"ClientID"`
+		ClientSecret    string   `envconfig:"OIDC_CLIENT_SECRET,optional" json:"-"`
+		RedirectURL     string   `envconfig:"OIDC_REDIRECT_URL,optional" json:"RedirectURL"`
+		Scopes          []string `envconfig:"OIDC_SCOPES,optional" json:"Scopes"`
+		UsernameClaim   string   `envconfig:"OIDC_USERNAME_CLAIM,default=preferred_username" json:"UsernameClaim"`
+		DepartmentClaim string   `envconfig:"OIDC_DEPARTMENT_CLAIM,optional" json:"DepartmentClaim"`
+		JobTitleClaim   string   `envconfig:"OIDC_JOBTITLE_CLAIM,optional" json:"JobTitleClaim"`
+		AutoProvision   bool     `envconfig:"OIDC_AUTO_PROVISION,default=false" json:"AutoProvision"`
+		//TrustUsernameClaim links a first login to the account named by UsernameClaim, only for providers that keep
+		//that claim unique and immutable. Otherwise a first login links by verified email or provisions an account.
+		TrustUsernameClaim bool `envconfig:"OIDC_TRUST_USERNAME_CLAIM,default=false" json:"TrustUsernameClaim"`
+		//PostLoginURL is where the browser is sent once the session cookie is set
+		PostLoginURL string `envconfig:"OIDC_POST_LOGIN_URL,default=/" json:"PostLoginURL"`
+		//Organization receives the users that log in through this provider
+		Organization string `envconfig:"OIDC_ORGANIZATION,default=default" json:"Organization"`
+	}
+	SCIM struct {
+		//BearerToken is the shared secret configured in the HR provisioning client. SCIM is off when empty.
+		BearerToken  string `envconfig:"SCIM_BEARER_TOKEN,optional" json:"-"`
+		Organization string `envconfig:"SCIM_ORGANIZATION,default=default" json:"Organization"`
+	}
+	LDAP struct {
+		URL            string        `envconfig:"LDAP_URL,optional" json:"URL"`
+		BindDN         string        `envconfig:"LDAP_BIND_DN,optional" json:"BindDN"`
+		BindPassword   string        `envconfig:"LDAP_BIND_PASSWORD,optional" json:"-"`
+		BaseDN         string        `envconfig:"LDAP_BASE_DN,optional" json:"BaseDN"`
+		UserFilter     string        `envconfig:"LDAP_USER_FILTER,default=(objectClass=person)" json:"UserFilter"`
+		DisabledFilter string        `envconfig:"LDAP_DISABLED_FILTER,optional" json:"DisabledFilter"`
+		LoginAttr      string        `envconfig:"LDAP_LOGIN_ATTR,default=uid" json:"LoginAttr"`
+		GivenNameAttr  string        `envconfig:"LDAP_GIVENNAME_ATTR,default=givenName" json:"GivenNameAttr"`
+		FamilyNameAttr string        `envconfig:"LDAP_FAMILYNAME_ATTR,default=sn" json:"FamilyNameAttr"`
+		EmailAttr      string        `envconfig:"LDAP_EMAIL_ATTR,default=mail" json:"EmailAttr"`
+		DepartmentAttr string        `envconfig:"LDAP_DEPARTMENT_ATTR,default=departmentNumber" json:"DepartmentAttr"`
+		TitleAttr      string        `envconfig:"LDAP_TITLE_ATTR,default=title" json:"TitleAttr"`
+		ManagerAttr    string        `envconfig:"LDAP_MANAGER_ATTR,default=manager" json:"ManagerAttr"`
+		SyncInterval   time.Duration `envconfig:"LDAP_SYNC_INTERVAL,default=0s" json:"SyncInterval"`
+		Organization   string        `envconfig:"LDAP_ORGANIZATION,default=default" json:"Organization"`
+		//MaxDisableShare is the largest share of the active directory users one sync may disable, more aborts the sync
+		MaxDisableShare float64 `envconfig:"LDAP_MAX_DISABLE_SHARE,default=0.1" json:"MaxDisableShare"`
+	}
+	Leave struct {
+		//AccrualInterval is how often leave accruals are brought up to date, 0 disables the job
+		AccrualInterval time.Duration `envconfig:"LEAVE_ACCRUAL_INTERVAL,default=6h" json:"AccrualInterval"`
+	}
+	Reports struct {
+		//MissingTimesheetsInterval is how often the previous month is checked for missing timesheets, 0 disables the job
+		MissingTimesheetsInterval time.Duration `envconfig:"MISSING_TIMESHEETS_INTERVAL,default=24h" json:"MissingTimesheetsInterval"`
+		//MissingPunchesInterval is how often the previous day is checked for missing punches, 0 disables the job
+		MissingPunchesInterval time.Duration `envconfig:"MISSING_PUNCHES_INTERVAL,default=24h" json:"MissingPunchesInterval"`
+	}
+	SMTP struct {
+		//Host is the mail server, mails are only logged when it is empty
+		Host     string `envconfig:"SMTP_HOST,optional" json:"Host"`
+		Port     int    `envconfig:"SMTP_PORT,default=25" json:"Port"`
+		Username string `envconfig:"SMTP_USERNAME,optional" json:"Username"`
+		Password string `envconfig:"SMTP_PASSWORD,optional" json:"-"`
+		From     string `envconfig:"SMTP_FROM,default=timesheets@localhost" json:"From"`
+		//Timeout bounds the delivery of one mail
+		Timeout time.Duration `envconfig:"SMTP_TIMEOUT,default=30s" json:"Timeout"`
+	}
+	Reminders struct {
+		//Interval is how often due reminders are sent, 0 disables the job
+		Interval           time.Duration `envconfig:"REMINDER_INTERVAL,default=1h" json:"Interval"`
+		DaysBeforeDeadline int           `envconfig:"REMINDER_DAYS_BEFORE_DEADLINE,default=3" json:"DaysBeforeDeadline"`
+	}
+	Webhooks struct {
+		//DeliveryInterval is how often due deliveries are sent, 0 disables the job
+		DeliveryInterval time.Duration `envconfig:"WEBHOOK_DELIVERY_INTERVAL,default=10s" json:"DeliveryInterval"`
+		Timeout          time.Duration `envconfig:"WEBHOOK_TIMEOUT,default=10s" json:"Timeout"`
+		RetryBase        time.Duration `envconfig:"WEBHOOK_RETRY_BASE,default=30s" json:"RetryBase"`
+		MaxAttempts      int           `envconfig:"WEBHOOK_MAX_ATTEMPTS,default=8" json:"MaxAttempts"`
+	}
+	Outbox struct {
+		//RelayInterval is how often pending events are published, 0 disables the job
+		RelayInterval time.Duration `envconfig:"OUTBOX_RELAY_INTERVAL,default=2s" json:"RelayInterval"`
+		RetryBase     time.Duration `envconfig:"OUTBOX_RETRY_BASE,default=10s" json:"RetryBase"`
+		Retention     time.Duration `envconfig:"OUTBOX_RETENTION,default=168h" json:"Retention"`
+		//Sinks are published to in order: webhooks, log
+		Sinks []string `envconfig:"OUTBOX_SINKS,default=webhooks" json:"Sinks"`
+	}
+	Timers struct {
+		//MaxDuration is how long a timer may run before it is considered forgotten and cut to it
+		MaxDuration time.Duration `envconfig:"TIMER_MAX_DURATION,default=12h" json:"MaxDuration"`
+		//CheckInterval is how often forgotten timers are stopped and unbooked time entries booked, 0 disables the job
+		CheckInterval time.Duration `envconfig:"TIMER_CHECK_INTERVAL,default=15m" json:"CheckInterval"`
+	}
+	Storage struct {
+		//Backend is local, files kept below LocalDir, or s3 for an S3-compatible bucket such as MinIO
+		Backend     string        `envconfig:"STORAGE_BACKEND,default=local" json:"Backend"`
+		LocalDir    string        `envconfig:"STORAGE_LOCAL_DIR,default=./attachments" json:"LocalDir"`
+		S3Endpoint  string        `envconfig:"STORAGE_S3_ENDPOINT,optional" json:"S3Endpoint"`
+		S3Region    string        `envconfig:"STORAGE_S3_REGION,default=us-east-1" json:"S3Region"`
+		S3Bucket    string        `envconfig:"STORAGE_S3_BUCKET,optional" json:"S3Bucket"`
+		S3AccessKey string        `envconfig:"STORAGE_S3_ACCESS_KEY,optional" json:"S3AccessKey"`
+		S3SecretKey string        `envconfig:"STORAGE_S3_SECRET_KEY,optional" json:"-"`
+		S3Timeout   time.Duration `envconfig:"STORAGE_S3_TIMEOUT,default=60s" json:"S3Timeout"`
+	}
+	Attachments struct {
+		//MaxSize is the largest file accepted, in bytes
+		MaxSize int64 `envconfig:"ATTACHMENT_MAX_SIZE,default=10485760" json:"MaxSize"`
+		//AllowedTypes are the MIME types accepted as sniffed from the content
+		AllowedTypes []string `envconfig:"ATTACHMENT_ALLOWED_TYPES,default=application/pdf;image/png;image/jpeg" json:"AllowedTypes"`
+	}
+	Compliance struct {
+		//A limit of 0 turns its check off, a severity is either warning or blocking
+		MaxDailyHours           float64 `envconfig:"COMPLIANCE_MAX_DAILY_HOURS,default=10" json:"MaxDailyHours"`
+		MaxDailySeverity        string  `envconfig:"COMPLIANCE_MAX_DAILY_SEVERITY,default=warning" json:"MaxDailySeverity"`
+		MaxAverageWeeklyHours   float64 `envconfig:"COMPLIANCE_MAX_AVERAGE_WEEKLY_HOURS,default=48" json:"MaxAverageWeeklyHours"`
+		AverageWeeklySeverity   string  `envconfig:"COMPLIANCE_AVERAGE_WEEKLY_SEVERITY,default=warning" json:"AverageWeeklySeverity"`
+		MinRestHours            float64 `envconfig:"COMPLIANCE_MIN_REST_HOURS,default=11" json:"MinRestHours"`
+		RestSeverity            string  `envconfig:"COMPLIANCE_REST_SEVERITY,default=warning" json:"RestSeverity"`
+		MaxConsecutiveDays      int     `envconfig:"COMPLIANCE_MAX_CONSECUTIVE_DAYS,default=6" json:"MaxConsecutiveDays"`
+		ConsecutiveDaysSeverity string  `envconfig:"COMPLIANCE_CONSECUTIVE_DAYS_SEVERITY,default=warning" json:"ConsecutiveDaysSeverity"`
+	}
+	CommandDatabase struct {
+		URL string `envconfig:"COMMAND_DATABASE_URL" json:"CommandDatabaseURL"`
+	}
+}
+
+/// initialize configuration.
+func initConfig() error {
+	return envconfig.Init(&config)
+}
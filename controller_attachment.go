@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//uploadAttachment takes the file from the "file" field of a multipart form
+func uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	//The form around the file is small, larger requests are cut off and fail to parse
+	r.Body = http.MaxBytesReader(w, r.Body, config.Attachments.MaxSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to read the uploaded file")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+	defer file.Close()
+
+	a, err := attachmentService.Upload(r.Context(), principal, chi.URLParam(r, "loginName"), chi.URLParam(r, "date"),
+		header.Filename, file)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, a)
+}
+
+func getAttachments(w http.ResponseWriter, r *http.Request) {
+	attachments, err := attachmentService.ListAttachments(r.Context(), auth.FromContext(r.Context()),
+		chi.URLParam(r, "loginName"), chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, attachments)
+}
+
+func downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	a, content, err := attachmentService.Download(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "attachmentID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Checksum-Sha256", a.Checksum)
+	if _, err = io.Copy(w, content); err != nil {
+		log.Error().Err(err).Str("attachmentID", a.ID.String()).Msg("Error while sending the attachment")
+	}
+}
+
+func deleteAttachment(w http.ResponseWriter, r *http.Request) {
+	if err := attachmentService.DeleteAttachment(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "attachmentID")); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, nil)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func setBillingRate(w http.ResponseWriter, r *http.Request) {
+	rate := &timesheets.BillingRate{}
+	if err := json.NewDecoder(r.Body).Decode(rate); err != nil {
+		log.Error().Err(err).Msg("Unable to parse billing rate json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	rate, err := billingRateService.SetRate(r.Context(), rate)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rate)
+}
+
+func getBillingRates(w http.ResponseWriter, r *http.Request) {
+	withDeleted := r.URL.Query().Get("deleted") == "true"
+	rates, err := billingRateService.ListRates(r.Context(), r.URL.Query().Get("project"), withDeleted)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rates)
+}
+
+func deleteBillingRate(w http.ResponseWriter, r *http.Request) {
+	rateID := chi.URLParam(r, "rateID")
+
+	if err := billingRateService.DeleteRate(r.Context(), auth.FromContext(r.Context()), rateID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rateID)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func getComments(w http.ResponseWriter, r *http.Request) {
+	comments, err := commentService.ListComments(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, comments)
+}
+
+func addComment(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	c := &timesheets.TimesheetComment{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse comment json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := commentService.AddComment(r.Context(), principal, chi.URLParam(r, "loginName"), chi.URLParam(r, "date"), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func editComment(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	c := &timesheets.TimesheetComment{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse comment json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := commentService.EditComment(r.Context(), principal, chi.URLParam(r, "loginName"), chi.URLParam(r, "commentID"), c.Body)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func deleteComment(w http.ResponseWriter, r *http.Request) {
+	if err := commentService.DeleteComment(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "commentID")); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, nil)
+}
+
+func getCommentHistory(w http.ResponseWriter, r *http.Request) {
+	revisions, err := commentService.CommentHistory(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "commentID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, revisions)
+}
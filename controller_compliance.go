@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"timesheet/commons/res"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func getComplianceReport(w http.ResponseWriter, r *http.Request) {
+	month, err := strconv.Atoi(chi.URLParam(r, "month"))
+	if err != nil {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	findings, err := complianceService.Report(r.Context(), r.URL.Query().Get("loginName"), month, year)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, findings)
+}
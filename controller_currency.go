@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//maxExchangeRateCSVSize bounds an uploaded file of exchange rates, years of daily rates of a few pairs fit easily
+const maxExchangeRateCSVSize = 4 << 20
+
+func setExchangeRate(w http.ResponseWriter, r *http.Request) {
+	rate := &timesheets.ExchangeRate{}
+	if err := json.NewDecoder(r.Body).Decode(rate); err != nil {
+		log.Error().Err(err).Msg("Unable to parse exchange rate json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	rate, err := exchangeRateService.SetRate(r.Context(), rate)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rate)
+}
+
+func getExchangeRates(w http.ResponseWriter, r *http.Request) {
+	withDeleted := r.URL.Query().Get("deleted") == "true"
+	rates, err := exchangeRateService.ListRates(r.Context(), r.URL.Query().Get("currency"), withDeleted)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rates)
+}
+
+func deleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	rateID := chi.URLParam(r, "rateID")
+
+	if err := exchangeRateService.DeleteRate(r.Context(), auth.FromContext(r.Context()), rateID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rateID)
+}
+
+//importExchangeRates takes the raw text/csv file as the request body, ?source= names where the rates came from
+func importExchangeRates(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxExchangeRateCSVSize)
+
+	report, err := exchangeRateService.ImportCSV(r.Context(), r.URL.Query().Get("source"), body)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, report)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"timesheet/commons/res"
+
+	"github.com/pkg/errors"
+)
+
+//syncDirectory runs the LDAP sync on demand. Pass dryRun=true to only get the report of pending changes.
+func syncDirectory(w http.ResponseWriter, r *http.Request) {
+	if directorySyncService == nil {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.RecordNotFound,
+			Cause: errors.New("LDAP_URL is not configured")}, config.Debug.PrintRootCause)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	ctx, err := withOrganization(r.Context(), config.LDAP.Organization)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+
+	report, err := directorySyncService.Sync(ctx, dryRun)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, report)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"timesheet/commons/res"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func getExpectedHours(w http.ResponseWriter, r *http.Request) {
+	month, _ := strconv.Atoi(chi.URLParam(r, "month"))
+	year, _ := strconv.Atoi(chi.URLParam(r, "year"))
+
+	eh, err := expectedHoursService.GetExpectedHours(r.Context(), chi.URLParam(r, "loginName"), month, year)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, eh)
+}
+
+func getMissingTimesheets(w http.ResponseWriter, r *http.Request) {
+	month, _ := strconv.Atoi(chi.URLParam(r, "month"))
+	year, _ := strconv.Atoi(chi.URLParam(r, "year"))
+
+	report, err := expectedHoursService.MissingTimesheets(r.Context(), month, year)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, report)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func createExpenseClaim(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	c := &timesheets.ExpenseClaim{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse expense claim json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := expenseService.CreateClaim(r.Context(), principal, c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func getExpenseClaims(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	claims, err := expenseService.ListClaims(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		query.Get("from"), query.Get("to"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, claims)
+}
+
+func getExpenseClaim(w http.ResponseWriter, r *http.Request) {
+	c, err := expenseService.GetClaim(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "claimID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func updateExpenseClaim(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	c := &timesheets.ExpenseClaim{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse expense claim json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := expenseService.UpdateClaim(r.Context(), principal, chi.URLParam(r, "loginName"), chi.URLParam(r, "claimID"), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func deleteExpenseClaim(w http.ResponseWriter, r *http.Request) {
+	if err := expenseService.DeleteClaim(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "claimID")); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, nil)
+}
+
+func approveExpenseClaim(w http.ResponseWriter, r *http.Request) {
+	reviewExpenseClaim(w, r, true)
+}
+
+func rejectExpenseClaim(w http.ResponseWriter, r *http.Request) {
+	reviewExpenseClaim(w, r, false)
+}
+
+//reviewExpenseClaim takes an optional {"Note": "..."} body
+func reviewExpenseClaim(w http.ResponseWriter, r *http.Request, approve bool) {
+	review := &timesheets.ExpenseReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil && err != io.EOF {
+		log.Error().Err(err).Msg("Unable to parse expense review json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := expenseService.ReviewClaim(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "claimID"), approve, review)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
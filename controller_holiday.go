@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//maxICSSize bounds an uploaded iCalendar file, a country's holidays for decades fit easily
+const maxICSSize = 1 << 20
+
+func createHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	c := &timesheets.HolidayCalendar{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Msg("Unable to parse holiday calendar json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := holidayService.CreateCalendar(r.Context(), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func getHolidayCalendars(w http.ResponseWriter, r *http.Request) {
+	calendars, err := holidayService.ListCalendars(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, calendars)
+}
+
+//getHolidayCalendar returns the calendar with the holidays of ?year=, the current year by default
+func getHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+
+	c, err := holidayService.GetCalendar(r.Context(), chi.URLParam(r, "calendarID"), year)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func updateHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	c := &timesheets.HolidayCalendar{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Msg("Unable to parse holiday calendar json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := holidayService.UpdateCalendar(r.Context(), chi.URLParam(r, "calendarID"), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func deleteHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	calendarID := chi.URLParam(r, "calendarID")
+
+	if err := holidayService.DeleteCalendar(r.Context(), calendarID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, calendarID)
+}
+
+func addHoliday(w http.ResponseWriter, r *http.Request) {
+	h := &timesheets.Holiday{}
+	if err := json.NewDecoder(r.Body).Decode(h); err != nil {
+		log.Error().Err(err).Msg("Unable to parse holiday json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	h, err := holidayService.AddHoliday(r.Context(), chi.URLParam(r, "calendarID"), h)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, h)
+}
+
+func deleteHoliday(w http.ResponseWriter, r *http.Request) {
+	holidayID := chi.URLParam(r, "holidayID")
+
+	if err := holidayService.DeleteHoliday(r.Context(), chi.URLParam(r, "calendarID"), holidayID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, holidayID)
+}
+
+//importHolidays takes the raw text/calendar file as the request body
+func importHolidays(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxICSSize)
+
+	report, err := holidayService.ImportICS(r.Context(), chi.URLParam(r, "calendarID"), body)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, report)
+}
+
+func getCalendarAssignments(w http.ResponseWriter, r *http.Request) {
+	assignments, err := holidayService.ListAssignments(r.Context(), chi.URLParam(r, "calendarID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, assignments)
+}
+
+func assignHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	changeCalendarAssignment(w, r, holidayService.AssignCalendar)
+}
+
+func unassignHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	changeCalendarAssignment(w, r, holidayService.UnassignCalendar)
+}
+
+func changeCalendarAssignment(w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, calendarID string, a *timesheets.CalendarAssignment) error) {
+	a := &timesheets.CalendarAssignment{}
+	if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+		log.Error().Err(err).Msg("Unable to parse calendar assignment json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	if err := change(r.Context(), chi.URLParam(r, "calendarID"), a); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, a)
+}
+
+func getWorkingDays(w http.ResponseWriter, r *http.Request) {
+	loginName := chi.URLParam(r, "loginName")
+	month, _ := strconv.Atoi(chi.URLParam(r, "month"))
+	year, _ := strconv.Atoi(chi.URLParam(r, "year"))
+
+	wd, err := holidayService.GetWorkingDays(r.Context(), loginName, month, year)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, wd)
+}
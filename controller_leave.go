@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+func createLeavePolicy(w http.ResponseWriter, r *http.Request) {
+	p := &timesheets.LeavePolicy{}
+	if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+		log.Error().Err(err).Msg("Unable to parse leave policy json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	p, err := leaveService.CreatePolicy(r.Context(), p)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, p)
+}
+
+func getLeavePolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := leaveService.ListPolicies(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, policies)
+}
+
+func deleteLeavePolicy(w http.ResponseWriter, r *http.Request) {
+	policyID := chi.URLParam(r, "policyID")
+
+	if err := leaveService.DeletePolicy(r.Context(), policyID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, policyID)
+}
+
+//getLeaveBalances is open to the user, their manager and admins
+func getLeaveBalances(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	loginName := chi.URLParam(r, "loginName")
+
+	allowed, err := leaveService.CanView(r.Context(), principal, loginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	if !allowed {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.New("not allowed to view this leave balance")}, config.Debug.PrintRootCause)
+		return
+	}
+
+	balances, err := leaveService.GetBalances(r.Context(), loginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, balances)
+}
+
+func createLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+
+	lr := &timesheets.LeaveRequest{}
+	if err := json.NewDecoder(r.Body).Decode(lr); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse leave request json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	lr, err := leaveService.RequestLeave(r.Context(), principal.LoginName, lr)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, lr)
+}
+
+func getLeaveRequests(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+
+	requests, err := leaveService.ListRequests(r.Context(), principal.LoginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, requests)
+}
+
+func cancelLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+
+	lr, err := leaveService.CancelRequest(r.Context(), principal.LoginName, chi.URLParam(r, "requestID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, lr)
+}
+
+func getLeaveApprovals(w http.ResponseWriter, r *http.Request) {
+	requests, err := leaveService.ListPendingApprovals(r.Context(), auth.FromContext(r.Context()))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, requests)
+}
+
+func approveLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	decideLeaveRequest(w, r, true)
+}
+
+func rejectLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	decideLeaveRequest(w, r, false)
+}
+
+func decideLeaveRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	principal := auth.FromContext(r.Context())
+
+	//The decision comment is optional, so an empty body is fine.
+	decision := &timesheets.LeaveDecision{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(decision); err != nil {
+			log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse leave decision json to struct")
+			res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+			return
+		}
+	}
+
+	lr, err := leaveService.DecideRequest(r.Context(), principal, chi.URLParam(r, "requestID"), approve, decision)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, lr)
+}
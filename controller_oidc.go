@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/user"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//oidcStateCookie carries state, nonce and PKCE verifier between the redirect and the callback
+const oidcStateCookie = "TimesheetOIDC"
+
+const oidcStateTTL = 10 * time.Minute
+
+//oidcLogin starts the authorization code + PKCE flow by redirecting to the identity provider
+func oidcLogin(w http.ResponseWriter, r *http.Request) {
+	state, redirectURL, err := oidcService.BeginLogin(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"state":    state.State,
+		"nonce":    state.Nonce,
+		"verifier": state.CodeVerifier,
+		"exp":      time.Now().Add(oidcStateTTL).Unix(),
+	}).SignedString([]byte(config.Auth.JWTSecret))
+	if err != nil {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    signed,
+		Path:     "/iam/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+//oidcCallback completes the login, sets the session cookie and sends the browser to the application
+func oidcCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	//The state cookie is single use.
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/iam/oidc", MaxAge: -1})
+
+	if providerErr := q.Get("error"); providerErr != "" {
+		res.SendError(w, r, &res.AppError{ResponseCode: user.OIDCLoginFailed,
+			Cause: errors.Errorf("provider returned %s: %s", providerErr, q.Get("error_description"))}, config.Debug.PrintRootCause)
+		return
+	}
+
+	state, err := readOIDCState(r)
+	if err != nil || state.State == "" || state.State != q.Get("state") {
+		if err == nil {
+			err = errors.New("state mismatch")
+		}
+		res.SendError(w, r, &res.AppError{ResponseCode: user.OIDCLoginFailed, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	ctx, err := withOrganization(r.Context(), config.OIDC.Organization)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+
+	u, err := oidcService.CompleteLogin(ctx, state, q.Get("code"))
+	if err != nil {
+		log.Error().Err(err).Msg("Single sign-on login failed")
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+
+	jwtStr, err := issueSessionJWT(u.LoginName)
+	if err != nil {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    jwtStr,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, config.OIDC.PostLoginURL, http.StatusFound)
+}
+
+func readOIDCState(r *http.Request) (*user.OIDCLoginState, error) {
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		return nil, errors.Wrap(err, "login state cookie missing")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err = jwt.ParseWithClaims(cookie.Value, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(config.Auth.JWTSecret), nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "login state cookie invalid")
+	}
+
+	state := &user.OIDCLoginState{}
+	state.State, _ = claims["state"].(string)
+	state.Nonce, _ = claims["nonce"].(string)
+	state.CodeVerifier, _ = claims["verifier"].(string)
+	return state, nil
+}
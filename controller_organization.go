@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/res"
+	"timesheet/user"
+
+	"github.com/rs/zerolog/log"
+)
+
+func createOrganization(w http.ResponseWriter, r *http.Request) {
+	org := &user.Organization{}
+	if err := json.NewDecoder(r.Body).Decode(org); err != nil {
+		log.Error().Err(err).Msg("Unable to parse organization json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	org, err := organizationService.CreateOrganization(r.Context(), org)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, org)
+}
+
+func getOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := organizationService.ListOrganizations(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, orgs)
+}
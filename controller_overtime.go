@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func createOvertimeRule(w http.ResponseWriter, r *http.Request) {
+	rule := &timesheets.OvertimeRule{}
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		log.Error().Err(err).Msg("Unable to parse overtime rule json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	rule, err := overtimeService.CreateRule(r.Context(), rule)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rule)
+}
+
+func updateOvertimeRule(w http.ResponseWriter, r *http.Request) {
+	rule := &timesheets.OvertimeRule{}
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		log.Error().Err(err).Msg("Unable to parse overtime rule json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	rule, err := overtimeService.UpdateRule(r.Context(), chi.URLParam(r, "ruleID"), rule)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rule)
+}
+
+func deleteOvertimeRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "ruleID")
+
+	if err := overtimeService.DeleteRule(r.Context(), ruleID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, ruleID)
+}
+
+func getOvertimeRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := overtimeService.ListRules(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, rules)
+}
+
+func setContract(w http.ResponseWriter, r *http.Request) {
+	c := &timesheets.Contract{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Msg("Unable to parse contract json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := contractService.SetContract(r.Context(), chi.URLParam(r, "loginName"), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func getContract(w http.ResponseWriter, r *http.Request) {
+	c, err := contractService.GetContract(r.Context(), chi.URLParam(r, "loginName"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+func getContracts(w http.ResponseWriter, r *http.Request) {
+	contracts, err := contractService.ListContracts(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, contracts)
+}
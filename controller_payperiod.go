@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func setPayPeriodDefinition(w http.ResponseWriter, r *http.Request) {
+	d := &timesheets.PayPeriodDefinition{}
+	if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+		log.Error().Err(err).Msg("Unable to parse pay period definition json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	d, err := payPeriodService.SetDefinition(r.Context(), d)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, d)
+}
+
+func getPayPeriodDefinitions(w http.ResponseWriter, r *http.Request) {
+	definitions, err := payPeriodService.ListDefinitions(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, definitions)
+}
+
+func deletePayPeriodDefinition(w http.ResponseWriter, r *http.Request) {
+	definitionID := chi.URLParam(r, "definitionID")
+
+	if err := payPeriodService.DeleteDefinition(r.Context(), definitionID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, definitionID)
+}
+
+//resolvePayPeriod returns the user's pay period containing {date}
+func resolvePayPeriod(w http.ResponseWriter, r *http.Request) {
+	period, err := payPeriodService.ResolvePeriod(r.Context(), chi.URLParam(r, "loginName"), chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, period)
+}
+
+func getTimesheetForPeriod(w http.ResponseWriter, r *http.Request) {
+	timesheet, err := timesheetService.GetTimesheetForPeriod(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"), chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+	} else if timesheet == nil {
+		res.SendResponse(w, r, res.RecordNotFound, nil)
+	} else {
+		res.SendResponse(w, r, res.OK, timesheet)
+	}
+}
+
+func updateTimesheetForPeriod(w http.ResponseWriter, r *http.Request) {
+	t := &timesheets.Timesheet{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		log.Error().Err(err).Str("loginname", t.LoginName).Msg("Unable to parse timesheet json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	response, err := timesheetService.UpdateTimesheetForPeriod(r.Context(), auth.FromContext(r.Context()), t, chi.URLParam(r, "loginName"), chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	sendSubmission(w, r, t, response)
+}
+
+func deleteTimesheetForPeriod(w http.ResponseWriter, r *http.Request) {
+	response, err := timesheetService.DeleteTimesheetForPeriod(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"), chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, &response)
+}
+
+func approveTimesheetForPeriod(w http.ResponseWriter, r *http.Request) {
+	reviewTimesheetForPeriod(w, r, true)
+}
+
+func rejectTimesheetForPeriod(w http.ResponseWriter, r *http.Request) {
+	reviewTimesheetForPeriod(w, r, false)
+}
+
+func reviewTimesheetForPeriod(w http.ResponseWriter, r *http.Request, approve bool) {
+	result, err := timesheetService.ReviewTimesheetForPeriod(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "date"), approve)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, result)
+}
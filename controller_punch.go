@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//punch clocks the caller in or out at the server time
+func punch(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	p := &timesheets.Punch{}
+	if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse punch json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	day, err := punchService.Punch(r.Context(), principal.LoginName, p)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, day)
+}
+
+//getPunchDay is open to the user, their manager and admins
+func getPunchDay(w http.ResponseWriter, r *http.Request) {
+	day, err := punchService.GetDay(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"), chi.URLParam(r, "date"),
+		r.URL.Query().Get("timeZone"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, day)
+}
+
+func getMissingPunches(w http.ResponseWriter, r *http.Request) {
+	days, err := punchService.MissingPunches(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "date"),
+		r.URL.Query().Get("timeZone"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, days)
+}
+
+func addPunchCorrection(w http.ResponseWriter, r *http.Request) {
+	c := &timesheets.PunchCorrection{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Msg("Unable to parse punch correction json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	day, err := punchService.AddCorrection(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, day)
+}
+
+func correctPunch(w http.ResponseWriter, r *http.Request) {
+	c := &timesheets.PunchCorrection{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Msg("Unable to parse punch correction json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	day, err := punchService.CorrectPunch(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "punchID"), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, day)
+}
+
+func voidPunch(w http.ResponseWriter, r *http.Request) {
+	day, err := punchService.VoidPunch(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		chi.URLParam(r, "punchID"), r.URL.Query().Get("reason"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, day)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func getReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	p, err := reminderService.GetPreferences(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, p)
+}
+
+func setReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	p := &timesheets.ReminderPreferences{}
+	if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+		log.Error().Err(err).Msg("Unable to parse reminder preferences json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	p, err := reminderService.SetPreferences(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"), p)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, p)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+func getFiscalCalendar(w http.ResponseWriter, r *http.Request) {
+	calendar, err := fiscalCalendarService.GetCalendar(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, calendar)
+}
+
+func setFiscalCalendar(w http.ResponseWriter, r *http.Request) {
+	c := &timesheets.FiscalCalendar{}
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		log.Error().Err(err).Msg("Unable to parse fiscal calendar json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	c, err := fiscalCalendarService.SetCalendar(r.Context(), c)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, c)
+}
+
+//getFiscalDate returns the fiscal year, quarter, period and week of {date}
+func getFiscalDate(w http.ResponseWriter, r *http.Request) {
+	fd, err := fiscalCalendarService.Locate(r.Context(), chi.URLParam(r, "date"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, fd)
+}
+
+//getHoursReport exports the hours from..to by fiscal unit, as json or with format=csv as a csv file
+func getHoursReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	report, err := reportService.HoursReport(r.Context(), query.Get("loginName"), query.Get("from"), query.Get("to"),
+		query.Get("groupBy"), query.Get("timeZone"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	if query.Get("format") != "csv" {
+		res.SendResponse(w, r, res.OK, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="hours.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"LoginName", "FiscalYear", "Quarter", "Period", "Week", "Start", "End", "WorkedHours", "AbsenceHours"})
+	for _, row := range report.Rows {
+		out.Write([]string{row.LoginName, strconv.Itoa(row.FiscalYear), strconv.Itoa(row.Quarter), strconv.Itoa(row.Period),
+			strconv.Itoa(row.Week), row.Start.Format("2006-01-02"), row.End.Format("2006-01-02"),
+			strconv.FormatFloat(row.WorkedHours, 'f', -1, 64), strconv.FormatFloat(row.AbsenceHours, 'f', -1, 64)})
+	}
+	out.Flush()
+	if err = out.Error(); err != nil {
+		log.Error().Err(err).Msg("Error while writing the hours report csv")
+	}
+}
+
+//getPayrollExport exports the timesheets and approved expenses of the pay periods containing {date}, or with groupBy
+//overlapping its fiscal unit, as json or with format=csv as a csv file with a column per expense currency. With
+//currency the expenses are also converted to it and the rates used are listed.
+func getPayrollExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	export, err := reportService.PayrollExport(r.Context(), chi.URLParam(r, "date"), query.Get("groupBy"), query.Get("currency"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	if r.URL.Query().Get("format") != "csv" {
+		res.SendResponse(w, r, res.OK, export)
+		return
+	}
+
+	currencies := []string{}
+	seen := map[string]bool{}
+	for _, row := range export.Rows {
+		for currency := range row.Expenses {
+			if !seen[currency] {
+				seen[currency] = true
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	sort.Strings(currencies)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="payroll.csv"`)
+	out := csv.NewWriter(w)
+	header := []string{"LoginName", "PeriodStart", "PeriodEnd", "Status", "RegularHours", "OvertimeHours", "DoubleTimeHours",
+		"PayableHours", "AbsenceHours"}
+	for _, currency := range currencies {
+		header = append(header, "Expenses"+currency)
+	}
+	if export.Currency != "" {
+		header = append(header, "Expenses"+export.Currency+"Converted", "Rates")
+	}
+	out.Write(header)
+	for _, row := range export.Rows {
+		record := []string{row.LoginName, row.PeriodStart.Format("2006-01-02"), row.PeriodEnd.Format("2006-01-02"), row.Status,
+			strconv.FormatFloat(row.RegularHours, 'f', -1, 64), strconv.FormatFloat(row.OvertimeHours, 'f', -1, 64),
+			strconv.FormatFloat(row.DoubleTimeHours, 'f', -1, 64), strconv.FormatFloat(row.PayableHours, 'f', -1, 64),
+			strconv.FormatFloat(row.AbsenceHours, 'f', -1, 64)}
+		for _, currency := range currencies {
+			record = append(record, strconv.FormatFloat(row.Expenses[currency], 'f', 2, 64))
+		}
+		if export.Currency != "" {
+			rates := make([]string, len(row.Conversions))
+			for i, c := range row.Conversions {
+				rates[i] = formatConversion(c)
+			}
+			record = append(record, strconv.FormatFloat(*row.ConvertedExpenses, 'f', 2, 64), strings.Join(rates, ";"))
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	if err = out.Error(); err != nil {
+		log.Error().Err(err).Msg("Error while writing the payroll csv")
+	}
+}
+
+//getExpenseExport exports the approved expenses from..to for invoicing, as json or with format=csv as a csv file.
+//With currency every line is also converted to it with the rate of its date. With groupBy the csv file has the
+//totals of each fiscal unit instead of the lines.
+func getExpenseExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	export, err := reportService.ExpenseExport(r.Context(), query.Get("from"), query.Get("to"), query.Get("project"),
+		query.Get("groupBy"), query.Get("currency"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	if query.Get("format") != "csv" {
+		res.SendResponse(w, r, res.OK, export)
+		return
+	}
+	if export.GroupBy != "" {
+		writeExpenseUnits(w, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="expenses.csv"`)
+	out := csv.NewWriter(w)
+	header := []string{"Project", "Date", "LoginName", "Category", "Amount", "Currency", "Description", "ClaimID", "ReceiptID"}
+	if export.Currency != "" {
+		header = append(header, "Amount"+export.Currency, "Rate", "RateEffectiveFrom", "RateID")
+	}
+	out.Write(header)
+	for _, l := range export.Lines {
+		receipt := ""
+		if l.ReceiptID != nil {
+			receipt = l.ReceiptID.String()
+		}
+		record := []string{l.Project, l.Date.Format("2006-01-02"), l.LoginName, string(l.Category),
+			strconv.FormatFloat(l.Amount, 'f', 2, 64), l.Currency, l.Description, l.ClaimID.String(), receipt}
+		if c := l.Converted; c != nil {
+			rateID := ""
+			if c.RateID != nil {
+				rateID = c.RateID.String()
+			}
+			record = append(record, strconv.FormatFloat(c.Amount, 'f', 2, 64), strconv.FormatFloat(c.Rate, 'f', -1, 64),
+				formatRateDate(c.Conversion), rateID)
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	if err = out.Error(); err != nil {
+		log.Error().Err(err).Msg("Error while writing the expenses csv")
+	}
+}
+
+func writeExpenseUnits(w http.ResponseWriter, export *timesheets.ExpenseExport) {
+	currencies := []string{}
+	seen := map[string]bool{}
+	for _, u := range export.Units {
+		for currency := range u.Expenses {
+			if !seen[currency] {
+				seen[currency] = true
+				currencies = append(currencies, currency)
+			}
+		}
+	}
+	sort.Strings(currencies)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="expenses.csv"`)
+	out := csv.NewWriter(w)
+	header := []string{"FiscalYear", "Quarter", "Period", "Week", "Start", "End"}
+	for _, currency := range currencies {
+		header = append(header, "Expenses"+currency)
+	}
+	if export.Currency != "" {
+		header = append(header, "Expenses"+export.Currency+"Converted")
+	}
+	out.Write(header)
+	for _, u := range export.Units {
+		record := []string{strconv.Itoa(u.FiscalYear), strconv.Itoa(u.Quarter), strconv.Itoa(u.Period), strconv.Itoa(u.Week),
+			u.Start.Format("2006-01-02"), u.End.Format("2006-01-02")}
+		for _, currency := range currencies {
+			record = append(record, strconv.FormatFloat(u.Expenses[currency], 'f', 2, 64))
+		}
+		if u.Total != nil {
+			record = append(record, strconv.FormatFloat(*u.Total, 'f', 2, 64))
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Error().Err(err).Msg("Error while writing the expenses csv")
+	}
+}
+
+//getFiscalMissingTimesheets lists the users whose timesheets leave the fiscal unit of groupBy containing {date}
+//missing or under-filled
+func getFiscalMissingTimesheets(w http.ResponseWriter, r *http.Request) {
+	report, err := reportService.MissingTimesheets(r.Context(), chi.URLParam(r, "date"), r.URL.Query().Get("groupBy"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, report)
+}
+
+//getFiscalComplianceReport lists the compliance findings of the fiscal unit of groupBy containing {date}, of one
+//user with loginName
+func getFiscalComplianceReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	report, err := reportService.ComplianceReport(r.Context(), query.Get("loginName"), chi.URLParam(r, "date"), query.Get("groupBy"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, report)
+}
+
+//formatConversion writes the rate used for a currency as e.g. USD/EUR 0.923 2026-01-01
+func formatConversion(c *timesheets.Conversion) string {
+	return c.From + "/" + c.Currency + " " + strconv.FormatFloat(c.Rate, 'f', -1, 64) + " " + formatRateDate(c)
+}
+
+//formatRateDate is empty for amounts already in the reporting currency
+func formatRateDate(c *timesheets.Conversion) string {
+	if c.RateID == nil {
+		return ""
+	}
+	return c.EffectiveFrom.Format("2006-01-02")
+}
+
+func getInvoice(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	invoice, err := reportService.Invoice(r.Context(), query.Get("project"), query.Get("from"), query.Get("to"),
+		query.Get("currency"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	if query.Get("format") != "csv" {
+		res.SendResponse(w, r, res.OK, invoice)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="invoice.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"Kind", "Date", "LoginName", "Task", "Description", "Hours", "HourlyRate", "Amount", "Currency",
+		"Amount" + invoice.Currency, "Rate", "RateEffectiveFrom", "BillingRateID", "ExchangeRateID", "EntryID", "ClaimID", "ExpenseID"})
+	for _, l := range invoice.Lines {
+		c := l.Converted
+		out.Write([]string{string(l.Kind), l.Date.Format("2006-01-02"), l.LoginName, l.Task, l.Description,
+			strconv.FormatFloat(l.Hours, 'f', -1, 64), strconv.FormatFloat(l.HourlyRate, 'f', -1, 64),
+			strconv.FormatFloat(l.Amount, 'f', 2, 64), l.Currency, strconv.FormatFloat(c.Amount, 'f', 2, 64),
+			strconv.FormatFloat(c.Rate, 'f', -1, 64), formatRateDate(c.Conversion), formatID(l.RateID),
+			formatID(c.RateID), formatID(l.EntryID), formatID(l.ClaimID), formatID(l.ExpenseID)})
+	}
+	out.Flush()
+	if err = out.Error(); err != nil {
+		log.Error().Err(err).Msg("Error while writing the invoice csv")
+	}
+}
+
+//formatID is empty for a missing id
+func formatID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//scimAuthenticate admits the HR provisioning client, which authenticates with a shared bearer secret
+func scimAuthenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if config.SCIM.BearerToken == "" ||
+			subtle.ConstantTimeCompare([]byte(presented), []byte(config.SCIM.BearerToken)) != 1 {
+			sendSCIMError(w, r, &res.AppError{ResponseCode: res.Unauthorized})
+			return
+		}
+		ctx, err := withOrganization(r.Context(), config.SCIM.Organization)
+		if err != nil {
+			sendSCIMError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func createSCIMUser(w http.ResponseWriter, r *http.Request) {
+	su := &user.SCIMUser{}
+	if err := json.NewDecoder(r.Body).Decode(su); err != nil {
+		sendSCIMError(w, r, &res.AppError{ResponseCode: user.SCIMInvalidValue, Cause: err})
+		return
+	}
+
+	created, err := provisioningService.CreateSCIMUser(r.Context(), su)
+	if err != nil {
+		sendSCIMError(w, r, err)
+		return
+	}
+	sendSCIM(w, http.StatusCreated, created)
+}
+
+func getSCIMUser(w http.ResponseWriter, r *http.Request) {
+	su, err := provisioningService.GetSCIMUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		sendSCIMError(w, r, err)
+		return
+	}
+	sendSCIM(w, http.StatusOK, su)
+}
+
+func listSCIMUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	startIndex, _ := strconv.Atoi(q.Get("startIndex"))
+	count, _ := strconv.Atoi(q.Get("count"))
+
+	list, err := provisioningService.ListSCIMUsers(r.Context(), q.Get("filter"), startIndex, count)
+	if err != nil {
+		sendSCIMError(w, r, err)
+		return
+	}
+	sendSCIM(w, http.StatusOK, list)
+}
+
+func patchSCIMUser(w http.ResponseWriter, r *http.Request) {
+	patch := &user.SCIMPatchRequest{}
+	if err := json.NewDecoder(r.Body).Decode(patch); err != nil {
+		sendSCIMError(w, r, &res.AppError{ResponseCode: user.SCIMInvalidValue, Cause: err})
+		return
+	}
+
+	su, err := provisioningService.PatchSCIMUser(r.Context(), chi.URLParam(r, "id"), patch)
+	if err != nil {
+		sendSCIMError(w, r, err)
+		return
+	}
+	sendSCIM(w, http.StatusOK, su)
+}
+
+func deactivateSCIMUser(w http.ResponseWriter, r *http.Request) {
+	if err := provisioningService.DeactivateSCIMUser(r.Context(), chi.URLParam(r, "id")); err != nil {
+		sendSCIMError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//sendSCIM writes a SCIM resource. SCIM clients expect the bare resource, not the standard Response envelope.
+func sendSCIM(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to marshal SCIM response")
+		status, body = http.StatusInternalServerError, nil
+	}
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func sendSCIMError(w http.ResponseWriter, r *http.Request, err error) {
+	code := res.InternalServerError
+	detail := ""
+	if ae, ok := err.(*res.AppError); ok {
+		code = ae.ResponseCode
+		if ae.Cause != nil && config.Debug.PrintRootCause {
+			detail = ae.Cause.Error()
+		}
+	} else if _, ok := err.(*validate.ValidationError); ok {
+		code = user.SCIMInvalidValue
+	}
+	log.Error().Err(err).Str("path", r.URL.Path).Msg("SCIM request failed")
+
+	scimType := ""
+	switch code {
+	case user.UserAlreadyExists:
+		scimType = "uniqueness"
+	case user.SCIMInvalidFilter, user.SCIMInvalidPath, user.SCIMInvalidValue:
+		scimType = code.Code
+	}
+	if detail == "" {
+		detail = code.Message
+	}
+
+	sendSCIM(w, code.HttpStatus, user.SCIMError{
+		Schemas:  []string{user.SCIMSchemaError},
+		Status:   strconv.Itoa(code.HttpStatus),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
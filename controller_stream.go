@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/pkg/errors"
+)
+
+//streamHeartbeat keeps proxies from closing an idle stream
+const streamHeartbeat = 15 * time.Second
+
+//streamEvents is a server-sent events stream of the timesheet events the caller may see. The request
+//timeout ends the stream after a while, EventSource clients reconnect on their own.
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: errors.New("streaming is not supported")}, config.Debug.PrintRootCause)
+		return
+	}
+
+	subscription, err := eventStream.Subscribe(r.Context(), auth.FromContext(r.Context()))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 1000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e, open := <-subscription:
+			if !open {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
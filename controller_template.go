@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func createTemplate(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	t := &timesheets.TimesheetTemplate{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse template json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	t, err := templateService.CreateTemplate(r.Context(), principal.LoginName, t)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, t)
+}
+
+func getTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := templateService.ListTemplates(r.Context(), auth.FromContext(r.Context()).LoginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, templates)
+}
+
+func updateTemplate(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	t := &timesheets.TimesheetTemplate{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse template json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	t, err := templateService.UpdateTemplate(r.Context(), principal.LoginName, chi.URLParam(r, "templateID"), t)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, t)
+}
+
+func deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := templateService.DeleteTemplate(r.Context(), auth.FromContext(r.Context()).LoginName, chi.URLParam(r, "templateID")); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, nil)
+}
+
+func prefillTimesheet(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	req := &timesheets.PrefillRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse prefill json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	t, err := templateService.Prefill(r.Context(), principal, req)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	sendSubmission(w, r, t, t.LoginName)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func startTimer(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	t := &timesheets.Timer{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse timer json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	t, err := timerService.Start(r.Context(), principal.LoginName, t)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, t)
+}
+
+func stopTimer(w http.ResponseWriter, r *http.Request) {
+	e, err := timerService.Stop(r.Context(), auth.FromContext(r.Context()).LoginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, e)
+}
+
+func switchTimer(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	t := &timesheets.Timer{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse timer json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	switched, err := timerService.Switch(r.Context(), principal.LoginName, t)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, switched)
+}
+
+func getActiveTimer(w http.ResponseWriter, r *http.Request) {
+	t, err := timerService.Active(r.Context(), auth.FromContext(r.Context()).LoginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+	} else if t == nil {
+		res.SendResponse(w, r, res.RecordNotFound, nil)
+	} else {
+		res.SendResponse(w, r, res.OK, t)
+	}
+}
+
+func getTimeEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	entries, err := timerService.ListEntries(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"),
+		query.Get("from"), query.Get("to"), query.Get("timeZone"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, entries)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//getTimeZone returns the zone {loginName} counts days in and whether it is their own or the organization's
+func getTimeZone(w http.ResponseWriter, r *http.Request) {
+	setting, err := timeZoneService.GetTimeZone(r.Context(), chi.URLParam(r, "loginName"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, setting)
+}
+
+//setTimeZone sets the zone of {loginName}, or of the organization on the route without one
+func setTimeZone(w http.ResponseWriter, r *http.Request) {
+	setting := &timesheets.TimeZoneSetting{}
+	if err := json.NewDecoder(r.Body).Decode(setting); err != nil {
+		log.Error().Err(err).Msg("Unable to parse time zone json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	setting, err := timeZoneService.SetTimeZone(r.Context(), auth.FromContext(r.Context()), chi.URLParam(r, "loginName"), setting)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, setting)
+}
+
+func clearTimeZone(w http.ResponseWriter, r *http.Request) {
+	loginName := chi.URLParam(r, "loginName")
+
+	if err := timeZoneService.ClearTimeZone(r.Context(), auth.FromContext(r.Context()), loginName); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, loginName)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/user"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+//createAPIToken issues a personal api token for the logged in user. The secret is only returned in this response.
+func createAPIToken(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+
+	req := &user.CreateAPIToken{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Error().Err(err).Str("loginName", principal.LoginName).Msg("Unable to parse api token json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	token, err := tokenService.CreateToken(r.Context(), principal.LoginName, req)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, token)
+}
+
+func getAPITokens(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+
+	tokens, err := tokenService.ListTokens(r.Context(), principal.LoginName)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, tokens)
+}
+
+func revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	tokenID := chi.URLParam(r, "tokenID")
+
+	if err := tokenService.RevokeToken(r.Context(), principal.LoginName, tokenID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, tokenID)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"timesheet/commons/res"
+	"timesheet/timesheets"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func createWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	sub := &timesheets.WebhookSubscription{}
+	if err := json.NewDecoder(r.Body).Decode(sub); err != nil {
+		log.Error().Err(err).Msg("Unable to parse webhook subscription json to struct")
+		res.SendError(w, r, &res.AppError{ResponseCode: res.BadRequest, Cause: err}, config.Debug.PrintRootCause)
+		return
+	}
+
+	sub, err := webhookService.CreateSubscription(r.Context(), sub)
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, sub)
+}
+
+func getWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, subs)
+}
+
+func deleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "subscriptionID")
+
+	if err := webhookService.DeleteSubscription(r.Context(), subscriptionID); err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, subscriptionID)
+}
+
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := webhookService.ListDeliveries(r.Context(), chi.URLParam(r, "subscriptionID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, deliveries)
+}
+
+func redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	d, err := webhookService.Redeliver(r.Context(), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		res.SendError(w, r, err, config.Debug.PrintRootCause)
+		return
+	}
+	res.SendResponse(w, r, res.OK, d)
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+//Message is an event on its way from the outbox to the sinks. Delivery is at-least-once, Key is unique
+//per event so that sinks and their consumers can drop the duplicates.
+type Message struct {
+	Key        string
+	Type       string
+	Payload    []byte
+	OccurredAt time.Time
+}
+
+//Sink is where the outbox relay publishes to. A message only leaves the outbox once every sink took it,
+//so a sink sees a message again after any sink failed; Publish should treat a known Key as done.
+//A broker such as NATS or Kafka is added by implementing Sink on top of its client.
+type Sink interface {
+	Name() string
+
+	Publish(ctx context.Context, msg *Message) error
+}
+
+type logSink struct{}
+
+//NewLogSink logs every message, it is meant for development and as a trace of what was published
+func NewLogSink() Sink {
+	return &logSink{}
+}
+
+func (s *logSink) Name() string { return "log" }
+
+func (s *logSink) Publish(ctx context.Context, msg *Message) error {
+	log.Info().Str("key", msg.Key).Str("type", msg.Type).RawJSON("payload", msg.Payload).Msg("Event published")
+	return nil
+}
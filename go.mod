@@ -1,35 +1,38 @@
-module timesheet
-
-go 1.17
-
-require (
-	github.com/go-chi/chi/v5 v5.0.7
-	github.com/go-chi/cors v1.2.0
-	github.com/jackc/pgx/v4 v4.15.0
-	github.com/vrischmann/envconfig v1.3.0
-)
-
-require (
-	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
-	github.com/jackc/pgio v1.0.0 // indirect
-	github.com/jackc/pgpassfile v1.0.0 // indirect
-	github.com/jackc/pgproto3/v2 v2.2.0 // indirect
-	github.com/jackc/pgservicefile v0.0.0-20200714003250-2b9c44734f2b // indirect
-	github.com/jackc/pgtype v1.10.0 // indirect
-	github.com/jackc/puddle v1.2.1 // indirect
-	golang.org/x/text v0.3.6 // indirect
-)
-
-require (
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible
-	github.com/georgysavva/scany v0.3.0
-	github.com/go-chi/render v1.0.1
-	github.com/google/uuid v1.3.0
-	github.com/jackc/pgconn v1.11.0
-	github.com/jmoiron/sqlx v1.3.5
-	github.com/pkg/errors v0.9.1
-	github.com/rs/zerolog v1.26.1
-	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e
-	golang.org/x/sys v0.0.0-20211103235746-7861aae1554b // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
-)
+module timesheet
+
+go 1.17
+
+require (
+	github.com/go-chi/chi/v5 v5.0.7
+	github.com/go-chi/cors v1.2.0
+	github.com/jackc/pgx/v4 v4.15.0
+	github.com/vrischmann/envconfig v1.3.0
+)
+
+require (
+	github.com/Azure/go-ntlmssp v0.0.0-20200615164410-66371956d46c // indirect
+	github.com/go-asn1-ber/asn1-ber v1.5.1 // indirect
+	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
+	github.com/jackc/pgio v1.0.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgproto3/v2 v2.2.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20200714003250-2b9c44734f2b // indirect
+	github.com/jackc/pgtype v1.10.0 // indirect
+	github.com/jackc/puddle v1.2.1 // indirect
+	golang.org/x/text v0.3.6 // indirect
+)
+
+require (
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/georgysavva/scany v0.3.0
+	github.com/go-chi/render v1.0.1
+	github.com/go-ldap/ldap/v3 v3.4.1
+	github.com/google/uuid v1.3.0
+	github.com/jackc/pgconn v1.11.0
+	github.com/jmoiron/sqlx v1.3.5
+	github.com/pkg/errors v0.9.1
+	github.com/rs/zerolog v1.26.1
+	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e
+	golang.org/x/sys v0.0.0-20211103235746-7861aae1554b // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+)
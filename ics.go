@@ -0,0 +1,114 @@
+package timesheets
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//icsEvent is the part of a VEVENT a holiday needs. End is exclusive, as in RFC 5545.
+type icsEvent struct {
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	Recurring bool
+}
+
+//maxICSEventDays is the longest event imported as holidays, a longer one is more likely a mistake than a break
+const maxICSEventDays = 31
+
+//parseICS reads the VEVENTs of an iCalendar file. Only dates matter for holidays, so times and time zones
+//are dropped and every event is reduced to the days it covers.
+func parseICS(r io.Reader) ([]icsEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || strings.ToUpper(lines[0]) != "BEGIN:VCALENDAR" {
+		return nil, errors.New("not an iCalendar file")
+	}
+
+	events := []icsEvent{}
+	var current *icsEvent
+	for n, line := range lines {
+		name, params, value := splitICSProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &icsEvent{}
+		case name == "END" && value == "VEVENT":
+			if current == nil || current.Start.IsZero() {
+				return nil, errors.Errorf("property %d: event without DTSTART", n+1)
+			}
+			if current.End.IsZero() || !current.End.After(current.Start) {
+				current.End = current.Start.AddDate(0, 0, 1)
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "SUMMARY":
+			current.Summary = unescapeICSText(value)
+		case name == "DTSTART":
+			if current.Start, err = parseICSDate(value); err != nil {
+				return nil, errors.Wrapf(err, "property %d", n+1)
+			}
+		case name == "DTEND":
+			if current.End, err = parseICSDate(value); err != nil {
+				return nil, errors.Wrapf(err, "property %d", n+1)
+			}
+			//A timed event ending during a day still covers that day.
+			if !strings.Contains(params, "VALUE=DATE") && len(value) > 8 && value[8:] != "T000000" && value[8:] != "T000000Z" {
+				current.End = current.End.AddDate(0, 0, 1)
+			}
+		case name == "RRULE":
+			current.Recurring = true
+		}
+	}
+
+	return events, nil
+}
+
+//unfoldICS joins continuation lines, which start with a space or a tab
+func unfoldICS(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+//splitICSProperty splits NAME;PARAM=X:VALUE into its parts
+func splitICSProperty(line string) (string, string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+	name, value := line[:colon], line[colon+1:]
+	params := ""
+	if semi := strings.Index(name, ";"); semi >= 0 {
+		name, params = name[:semi], strings.ToUpper(name[semi+1:])
+	}
+	return strings.ToUpper(name), params, value
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, errors.Errorf("invalid date %q", value)
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
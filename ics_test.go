@@ -0,0 +1,72 @@
+package timesheets
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICS(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	calendar := func(lines ...string) string {
+		return "BEGIN:VCALENDAR\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+	}
+
+	cases := []struct {
+		name    string
+		ics     string
+		want    []icsEvent
+		wantErr bool
+	}{
+		{name: "all day event",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:New Year", "DTSTART;VALUE=DATE:20260101", "DTEND;VALUE=DATE:20260102", "END:VEVENT"),
+			want: []icsEvent{{Summary: "New Year", Start: date(2026, 1, 1), End: date(2026, 1, 2)}}},
+		{name: "event without end covers its start day",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:Labour Day", "DTSTART;VALUE=DATE:20260501", "END:VEVENT"),
+			want: []icsEvent{{Summary: "Labour Day", Start: date(2026, 5, 1), End: date(2026, 5, 2)}}},
+		{name: "multi day event",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:Easter", "DTSTART;VALUE=DATE:20260403", "DTEND;VALUE=DATE:20260407", "END:VEVENT"),
+			want: []icsEvent{{Summary: "Easter", Start: date(2026, 4, 3), End: date(2026, 4, 7)}}},
+		{name: "timed event ending during a day covers that day",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:Party", "DTSTART:20261224T180000Z", "DTEND:20261225T020000Z", "END:VEVENT"),
+			want: []icsEvent{{Summary: "Party", Start: date(2026, 12, 24), End: date(2026, 12, 26)}}},
+		{name: "timed event ending at midnight does not cover the next day",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:Eve", "DTSTART:20261231T000000", "DTEND:20270101T000000", "END:VEVENT"),
+			want: []icsEvent{{Summary: "Eve", Start: date(2026, 12, 31), End: date(2027, 1, 1)}}},
+		{name: "folded and escaped summary",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:Day of German\\, Unity", "  and more", "DTSTART;VALUE=DATE:20261003", "END:VEVENT"),
+			want: []icsEvent{{Summary: "Day of German, Unity and more", Start: date(2026, 10, 3), End: date(2026, 10, 4)}}},
+		{name: "recurring event is flagged",
+			ics:  calendar("BEGIN:VEVENT", "SUMMARY:Christmas", "DTSTART;VALUE=DATE:20261225", "RRULE:FREQ=YEARLY", "END:VEVENT"),
+			want: []icsEvent{{Summary: "Christmas", Start: date(2026, 12, 25), End: date(2026, 12, 26), Recurring: true}}},
+		{name: "properties outside events are ignored",
+			ics:  calendar("PRODID:-//test//EN", "SUMMARY:Calendar"),
+			want: []icsEvent{}},
+		{name: "event without start", ics: calendar("BEGIN:VEVENT", "SUMMARY:Nothing", "END:VEVENT"), wantErr: true},
+		{name: "invalid date", ics: calendar("BEGIN:VEVENT", "DTSTART:2026", "END:VEVENT"), wantErr: true},
+		{name: "not a calendar", ics: "BEGIN:VCARD\r\nEND:VCARD\r\n", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseICS(strings.NewReader(c.ics))
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(c.want))
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("event %d is %+v, want %+v", i, got[i], c.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/user"
+
+	"github.com/rs/zerolog/log"
+)
+
+//runPeriodically runs job every interval on its own goroutine until the process exits.
+//A panicking job is logged and retried on the next tick instead of taking the service down.
+func runPeriodically(name string, interval time.Duration, job func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Info().Str("job", name).Msg("Background job is disabled")
+		return
+	}
+
+	run := func() {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error().Interface("panic", p).Str("job", name).Msg("Background job panicked")
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		if err := job(ctx); err != nil {
+			log.Error().Err(err).Str("job", name).Msg("Background job failed")
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+	log.Info().Str("job", name).Dur("interval", interval).Msg("Background job scheduled")
+}
+
+//runForEachOrganization runs job every interval like runPeriodically, once per organization with its tenant in ctx.
+//A failing organization is logged and skipped, so it does not hold up the organizations listed after it.
+func runForEachOrganization(name string, interval time.Duration, job func(ctx context.Context, org *user.Organization) error) {
+	runPeriodically(name, interval, func(ctx context.Context) error {
+		orgs, err := organizationService.ListOrganizations(ctx)
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, org := range orgs {
+			if err = job(auth.WithTenant(ctx, org.ID), org); err != nil {
+				log.Error().Err(err).Str("job", name).Str("organization", org.Slug).Msg("Background job failed for the organization")
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d organizations failed", failed, len(orgs))
+		}
+		return nil
+	})
+}
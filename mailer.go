@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+//Mailer sends emails. Features that notify users depend on this interface only, so the SMTP
+//server can be swapped for a capture server in development or a provider API later on.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	//Timeout bounds one delivery from dialing to QUIT, defaultSMTPTimeout when 0
+	Timeout time.Duration
+}
+
+const defaultSMTPTimeout = 30 * time.Second
+
+type smtpMailer struct {
+	cfg SMTPConfig
+}
+
+//NewSMTPMailer sends through an SMTP server. Without a username no authentication is attempted,
+//which is how local capture servers such as MailHog or smtp4dev are used.
+func NewSMTPMailer(cfg SMTPConfig) Mailer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSMTPTimeout
+	}
+	return &smtpMailer{cfg: cfg}
+}
+
+//Send delivers msg like smtp.SendMail, but gives up when ctx is done or the timeout passes
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	if err := m.send(ctx, msg); err != nil {
+		return errors.Wrapf(err, "sending mail to %s", strings.Join(msg.To, ", "))
+	}
+	return nil
+}
+
+func (m *smtpMailer) send(ctx context.Context, msg *Message) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	//Closing the connection unblocks the conversation when ctx is cancelled before the deadline.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(m.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(m.compose(msg)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (m *smtpMailer) compose(msg *Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+type logMailer struct{}
+
+//NewLogMailer only logs the messages, it is used when no SMTP server is configured
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg *Message) error {
+	log.Info().Strs("to", msg.To).Str("subject", msg.Subject).Msg("Mail not sent, no SMTP server is configured")
+	return nil
+}
@@ -0,0 +1,159 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//captureServer is a minimal SMTP server that keeps the envelope and data of every mail it receives
+type captureServer struct {
+	listener net.Listener
+	silent   bool
+	mails    chan capturedMail
+}
+
+type capturedMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+func newCaptureServer(t *testing.T, silent bool) *captureServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &captureServer{listener: l, silent: silent, mails: make(chan capturedMail, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *captureServer) config(timeout time.Duration) SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: p, From: "timesheets@localhost", Timeout: timeout}
+}
+
+func (s *captureServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *captureServer) handle(conn net.Conn) {
+	defer conn.Close()
+	if s.silent {
+		//Never greets, like a server that accepted the connection and hung.
+		bufio.NewReader(conn).ReadString('\n')
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	mail := capturedMail{}
+	reply("220 localhost capture")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO" || verb == "HELO":
+			reply("250 localhost")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			mail.From = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			mail.To = append(mail.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case verb == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			mail.Data = data.String()
+			reply("250 OK")
+			s.mails <- mail
+		case verb == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	msg := &Message{To: []string{"alice@example.com", "bob@example.com"}, Subject: "Zeiterfassung fällig",
+		Body: "Please submit your timesheet.\nThanks"}
+
+	cases := []struct {
+		name     string
+		silent   bool
+		timeout  time.Duration
+		cancel   bool
+		wantSent bool
+	}{
+		{name: "delivers to every recipient", timeout: time.Second, wantSent: true},
+		{name: "gives up on a server that does not answer", silent: true, timeout: 200 * time.Millisecond},
+		{name: "gives up when the context is cancelled", silent: true, timeout: time.Minute, cancel: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := newCaptureServer(t, c.silent)
+			defer server.listener.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if c.cancel {
+				time.AfterFunc(200*time.Millisecond, cancel)
+			}
+
+			started := time.Now()
+			err := NewSMTPMailer(server.config(c.timeout)).Send(ctx, msg)
+			if !c.wantSent {
+				if err == nil {
+					t.Fatal("sent without an answering server")
+				}
+				if elapsed := time.Since(started); elapsed > 5*time.Second {
+					t.Fatalf("gave up after %s", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mail := <-server.mails
+			if mail.From != "timesheets@localhost" || strings.Join(mail.To, ",") != "alice@example.com,bob@example.com" {
+				t.Fatalf("envelope is from %s to %v", mail.From, mail.To)
+			}
+			if !strings.Contains(mail.Data, "Subject: =?utf-8?q?Zeiterfassung_f=C3=A4llig?=\r\n") {
+				t.Errorf("subject is not encoded in %q", mail.Data)
+			}
+			if !strings.HasSuffix(mail.Data, "\r\n\r\nPlease submit your timesheet.\r\nThanks\r\n") {
+				t.Errorf("body is not in %q", mail.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/user"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//sessionCookie is the cookie set by loginUser
+const sessionCookie = "Timesheet"
+
+//authenticate resolves the caller from a bearer credential (personal api token or JWT)
+//or from the session cookie, and rejects anonymous requests.
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := resolvePrincipal(r)
+		if err != nil {
+			log.Error().Err(err).Str("path", r.URL.Path).Msg("Authentication failed")
+			res.SendError(w, r, err, config.Debug.PrintRootCause)
+			return
+		}
+		principal.Admin = isAdmin(principal.LoginName)
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		if err = ensureActive(ctx, principal); err != nil {
+			log.Error().Err(err).Str("path", r.URL.Path).Msg("Authentication failed")
+			res.SendError(w, r, err, config.Debug.PrintRootCause)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+//requireScope only lets principals holding the given scope through. Must be mounted after authenticate.
+func requireScope(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal == nil || !principal.HasScope(scope) {
+				res.SendError(w, r, &res.AppError{ResponseCode: res.Forbidden,
+					Cause: fmt.Errorf("missing scope %s", scope)}, config.Debug.PrintRootCause)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//requireAdmin only lets the login names listed in AUTH_ADMINS through. Must be mounted after authenticate.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.FromContext(r.Context())
+		if principal == nil || !principal.Admin {
+			res.SendError(w, r, &res.AppError{ResponseCode: res.Forbidden,
+				Cause: errors.New("operation requires an administrator")}, config.Debug.PrintRootCause)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isAdmin(loginName string) bool {
+	for _, admin := range config.Auth.Admins {
+		if strings.EqualFold(admin, loginName) {
+			return true
+		}
+	}
+	return false
+}
+
+//requireSession rejects principals authenticated with an api token, e.g. so a token cannot mint new tokens.
+func requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.FromContext(r.Context())
+		if principal == nil || principal.Method != auth.MethodSession {
+			res.SendError(w, r, &res.AppError{ResponseCode: res.Forbidden,
+				Cause: errors.New("operation requires an interactive session")}, config.Debug.PrintRootCause)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//ensureActive rejects users deactivated by the directory even while their session has not expired yet
+func ensureActive(ctx context.Context, principal *auth.Principal) error {
+	active, err := provisioningService.IsActive(ctx, principal.LoginName)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return &res.AppError{ResponseCode: user.UserDeactivated, Cause: errors.Errorf("user %s is deactivated", principal.LoginName)}
+	}
+	return nil
+}
+
+func resolvePrincipal(r *http.Request) (*auth.Principal, error) {
+	credential := ""
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		credential = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	} else if cookie, err := r.Cookie(sessionCookie); err == nil {
+		credential = cookie.Value
+	}
+
+	if credential == "" {
+		return nil, &res.AppError{ResponseCode: res.Unauthorized, Cause: errors.New("no credential presented")}
+	}
+
+	if user.IsAPIToken(credential) {
+		token, err := tokenService.Authenticate(r.Context(), credential)
+		if err != nil {
+			return nil, err
+		}
+		scopes := make([]auth.Scope, 0, len(token.Scopes))
+		for _, s := range token.Scopes {
+			scopes = append(scopes, auth.Scope(s))
+		}
+		return &auth.Principal{
+			LoginName: token.LoginName,
+			OrgID:     token.OrgID,
+			Method:    auth.MethodAPIToken,
+			TokenID:   token.ID.String(),
+			Scopes:    scopes,
+		}, nil
+	}
+
+	loginName, err := parseSessionJWT(credential)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.Unauthorized, Cause: err}
+	}
+	orgID, err := organizationService.TenantOf(r.Context(), loginName)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{
+		LoginName: loginName,
+		OrgID:     orgID,
+		Method:    auth.MethodSession,
+		Scopes:    auth.AllScopes,
+	}, nil
+}
+
+//parseSessionJWT validates the token issued by user.Service.LoginUser and returns the login name it was issued for.
+func parseSessionJWT(credential string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(credential, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(config.Auth.JWTSecret), nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "invalid session token")
+	}
+
+	for _, claim := range []string{"loginName", "sub"} {
+		if v, ok := claims[claim].(string); ok && v != "" {
+			return strings.ToUpper(v), nil
+		}
+	}
+	return "", errors.New("session token has no subject")
+}
+
+//withOrganization scopes ctx to the organization with the given slug, for callers that are not a principal
+func withOrganization(ctx context.Context, slug string) (context.Context, error) {
+	orgID, err := organizationService.ResolveSlug(ctx, slug)
+	if err != nil {
+		return ctx, err
+	}
+	return auth.WithTenant(ctx, orgID), nil
+}
+
+//issueSessionJWT signs a session for a user that authenticated without a local password, e.g. through single sign-on.
+func issueSessionJWT(loginName string) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"loginName": strings.ToUpper(loginName),
+		"sub":       strings.ToUpper(loginName),
+		"iat":       now.Unix(),
+		"exp":       now.Add(config.Auth.SessionTTL).Unix(),
+	})
+	return token.SignedString([]byte(config.Auth.JWTSecret))
+}
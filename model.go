@@ -1,71 +1,164 @@
-package timesheets
-
-import (
-	"time"
-
-	"github.com/google/uuid"
-	sql "github.com/jmoiron/sqlx/types"
-)
-
-type Timesheet struct {
-	ID         uuid.UUID
-	LoginName  string
-	Status     string
-	Placement  string
-	Info       string
-	TotalHours float64
-	Month      int
-	Year       int
-	WeekHrs    sql.JSONText
-	WeekDay    sql.JSONText
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-}
-
-type GetAllTimesheets struct {
-	LoginName  string
-	Status     string
-	Placement  string
-	Info       string
-	TotalHours float64
-	Month      int
-	Year       int
-	WeekHrs    sql.JSONText `db:"week_hours_info"`
-	WeekDay    sql.JSONText `db:"week_day_info"`
-}
-
-type GetTimesheet struct {
-	LoginName  string
-	Status     string
-	Placement  string
-	Info       string
-	TotalHours float64
-	Month      int
-	Year       int
-	WeekData   WeekHrs
-}
-
-type timesheetStatus string
-
-const (
-	timesheetStatusSubmitted timesheetStatus = "Submitted"
-	timesheetStatusViewed    timesheetStatus = "Viewed"
-	timesheetStatusApproved  timesheetStatus = "Approved"
-	timesheetStatusRejected  timesheetStatus = "Rejected"
-)
-
-type WeekHrs struct {
-	WeekInfo int
-	Day1     float64
-	Day2     float64
-	Day3     float64
-	Day4     float64
-	Day5     float64
-}
-
-type AddorUpdateNotes struct {
-	LoginName string
-	Month     int
-	Year      int
-	Info      string
-}
+package timesheets
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+)
+
+//Timesheet covers one pay period of the user. On create the period is the one containing PeriodStart, or the
+//1st of Month and Year when PeriodStart is not given; Month and Year are then set to those of the period's start.
+//WeekHrs and Absences count their weeks from the month the period starts in. Info is posted to the timesheet's
+//comment thread as a note of the user, reads give the latest comment.
+type Timesheet struct {
+	ID           uuid.UUID
+	LoginName    string
+	Status       string
+	Placement    string
+	Info         string
+	TotalHours   float64
+	Month        int
+	Year         int
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	PayFrequency PayFrequency
+	WeekHrs      sql.JSONText
+	WeekDay      sql.JSONText
+	//Absences holds the []AbsenceEntry of the period. Their hours are counted in AbsenceHours, not TotalHours.
+	Absences     sql.JSONText
+	AbsenceHours float64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	//HoursBreakdown splits TotalHours by the overtime rule of the user
+	HoursBreakdown
+	//ComplianceWarnings are the non-blocking findings of the last create or update
+	ComplianceWarnings []ComplianceFinding `json:"-"`
+}
+
+//TimesheetEffects are the writes that follow from a timesheet change. The repository makes them in the transaction
+//of the change, so a timesheet is never stored without its leave usage, compliance findings and note.
+type TimesheetEffects struct {
+	Period     *PayPeriod
+	LeaveUsage *LeaveUsage
+	//Findings replace those of the period
+	Findings []ComplianceFinding
+	//Note is nil when the note does not change
+	Note *NoteChange
+	//Booking is what booked hours with the change, nil for other changes
+	Booking *DayBooking
+}
+
+//DayBooking is the punch rollup or time entries that hours on the timesheet were booked for
+type DayBooking struct {
+	Rollup *PunchRollup
+	//TimeEntryID is a stored entry, it is marked booked
+	TimeEntryID *uuid.UUID
+	//TimeEntries are stored booked
+	TimeEntries []*TimeEntry
+}
+
+type GetAllTimesheets struct {
+	LoginName    string
+	Status       string
+	Placement    string
+	Info         string
+	TotalHours   float64
+	Month        int
+	Year         int
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	PayFrequency PayFrequency `db:"period_frequency"`
+	WeekHrs      sql.JSONText `db:"week_hours_info"`
+	WeekDay      sql.JSONText `db:"week_day_info"`
+	Absences     sql.JSONText `db:"absence_info"`
+	AbsenceHours float64
+	HoursBreakdown
+}
+
+type GetTimesheet struct {
+	LoginName   string
+	Status      string
+	Placement   string
+	Info        string
+	TotalHours  float64
+	Month       int
+	Year        int
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	WeekData    WeekHrs
+	//Absences of the requested week and the period's absence hours per type
+	Absences      []AbsenceEntry
+	AbsenceHours  float64
+	AbsenceTotals map[AbsenceType]float64
+	HoursBreakdown
+}
+
+type timesheetStatus string
+
+const (
+	timesheetStatusSubmitted timesheetStatus = "Submitted"
+	timesheetStatusViewed    timesheetStatus = "Viewed"
+	timesheetStatusApproved  timesheetStatus = "Approved"
+	timesheetStatusRejected  timesheetStatus = "Rejected"
+)
+
+//WeekHrs are the hours of one week of the period, Day1 being Monday. Day6 and Day7 hold weekend work.
+type WeekHrs struct {
+	WeekInfo int
+	Day1     float64
+	Day2     float64
+	Day3     float64
+	Day4     float64
+	Day5     float64
+	Day6     float64
+	Day7     float64
+}
+
+func (w WeekHrs) days() []float64 {
+	return []float64{w.Day1, w.Day2, w.Day3, w.Day4, w.Day5, w.Day6, w.Day7}
+}
+
+func (w WeekHrs) total() float64 {
+	var total float64
+	for _, hours := range w.days() {
+		total += hours
+	}
+	return total
+}
+
+//setDay sets the hours of Day1..Day7
+func (w *WeekHrs) setDay(day int, hours float64) {
+	days := []*float64{&w.Day1, &w.Day2, &w.Day3, &w.Day4, &w.Day5, &w.Day6, &w.Day7}
+	*days[day-1] = hours
+}
+
+type AbsenceType string
+
+const (
+	AbsencePTO         AbsenceType = "PTO"
+	AbsenceSick        AbsenceType = "Sick"
+	AbsenceUnpaid      AbsenceType = "Unpaid"
+	AbsenceBereavement AbsenceType = "Bereavement"
+	AbsenceHoliday     AbsenceType = "Holiday"
+)
+
+var absenceTypes = []string{string(AbsencePTO), string(AbsenceSick), string(AbsenceUnpaid),
+	string(AbsenceBereavement), string(AbsenceHoliday)}
+
+//AbsenceEntry is a day, or part of a day, not worked. Days are addressed like WeekHrs: week of the period and Day1..Day5.
+type AbsenceEntry struct {
+	WeekInfo int
+	Day      int
+	Type     AbsenceType
+	Hours    float64
+	Note     string
+}
+
+//AddorUpdateNotes is the note of the notes endpoints, kept for the clients from before comment threads
+type AddorUpdateNotes struct {
+	LoginName string
+	Month     int
+	Year      int
+	Info      string
+}
@@ -0,0 +1,40 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//Attachment is a file kept with the timesheet of LoginName's pay period starting at PeriodStart, such as a signed
+//client approval sheet or a sick note. The content is in the storage under StorageKey.
+type Attachment struct {
+	ID          uuid.UUID
+	LoginName   string
+	PeriodStart time.Time
+	FileName    string
+	//ContentType is sniffed from the content, the type the client gave is not trusted
+	ContentType string
+	Size        int64
+	//Checksum is the hex SHA-256 of the content
+	Checksum   string
+	StorageKey string `json:"-"`
+	UploadedBy string
+	CreatedAt  time.Time
+}
+
+type AttachmentConfig struct {
+	//MaxSize is the largest file accepted, in bytes
+	MaxSize int64
+	//AllowedTypes are the MIME types accepted, such as application/pdf
+	AllowedTypes []string
+}
+
+var AttachmentNotFound = &res.ResponseCode{Code: "AttachmentNotFound", Message: "Attachment not found", HttpStatus: http.StatusNotFound}
+var AttachmentTooLarge = &res.ResponseCode{Code: "AttachmentTooLarge", Message: "The file is larger than allowed", HttpStatus: http.StatusRequestEntityTooLarge}
+var AttachmentTypeNotAllowed = &res.ResponseCode{Code: "AttachmentTypeNotAllowed", Message: "Files of this type can not be attached", HttpStatus: http.StatusUnsupportedMediaType}
+var AttachmentInUse = &res.ResponseCode{Code: "AttachmentInUse", Message: "The file is the receipt of an expense and can not be deleted", HttpStatus: http.StatusConflict}
+var AttachmentInfected = &res.ResponseCode{Code: "AttachmentInfected", Message: "The file was refused by the virus scan", HttpStatus: http.StatusUnprocessableEntity}
@@ -0,0 +1,70 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//BillingRate is what an hour on Project is billed at, in Currency, from EffectiveFrom until the next rate of the
+//project takes effect. A rate with a LoginName is the rate of that user and goes before the rate of the project for
+//everybody else. Like exchange rates, billing rates are not changed once stored: a later rate of the same project,
+//user and day supersedes them and deleted rates are kept with DeletedAt set.
+type BillingRate struct {
+	ID            uuid.UUID
+	Project       string
+	LoginName     string `json:",omitempty"`
+	HourlyRate    float64
+	Currency      string
+	EffectiveFrom time.Time
+	CreatedAt     time.Time
+	DeletedAt     *time.Time `json:",omitempty"`
+	DeletedBy     string     `json:",omitempty"`
+}
+
+type InvoiceLineKind string
+
+const (
+	InvoiceTime    InvoiceLineKind = "Time"
+	InvoiceExpense InvoiceLineKind = "Expense"
+)
+
+//Invoice bills a project for the days From to To, both included: the time entries of the project at the billing
+//rate in effect on their work date and its approved expenses. Lines are in the currency of their rate or expense,
+//and converted to Currency with the exchange rate of their date. Total is the sum of the converted amounts,
+//Conversions lists the exchange rates used once.
+type Invoice struct {
+	Project     string
+	From        time.Time
+	To          time.Time
+	Currency    string
+	Lines       []*InvoiceLine
+	Hours       float64
+	Total       float64
+	Conversions []*Conversion
+}
+
+//InvoiceLine is a time entry, with the billing rate it is charged at, or an expense line of an approved claim
+type InvoiceLine struct {
+	Kind      InvoiceLineKind
+	LoginName string
+	Date      time.Time
+	//EntryID and Task are those of a time entry, ClaimID and ExpenseID those of an expense
+	EntryID     *uuid.UUID `json:",omitempty"`
+	Task        string     `json:",omitempty"`
+	ClaimID     *uuid.UUID `json:",omitempty"`
+	ExpenseID   *uuid.UUID `json:",omitempty"`
+	Description string     `json:",omitempty"`
+	Hours       float64    `json:",omitempty"`
+	HourlyRate  float64    `json:",omitempty"`
+	RateID      *uuid.UUID `json:",omitempty"`
+	Amount      float64
+	Currency    string
+	Converted   *ConvertedAmount
+}
+
+var BillingRateNotFound = &res.ResponseCode{Code: "BillingRateNotFound", Message: "Billing rate not found", HttpStatus: http.StatusNotFound}
+var BillingRateMissing = &res.ResponseCode{Code: "BillingRateMissing", Message: "No billing rate of the project is in effect", HttpStatus: http.StatusUnprocessableEntity}
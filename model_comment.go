@@ -0,0 +1,50 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//TimesheetComment is one comment in the thread of the timesheet of LoginName's pay period starting at PeriodStart.
+//A comment is about the whole timesheet unless it is anchored to a Date of the period or to a time entry.
+type TimesheetComment struct {
+	ID          uuid.UUID
+	LoginName   string
+	PeriodStart time.Time
+	Author      string
+	Body        string
+	Date        *time.Time
+	EntryID     *uuid.UUID
+	CreatedAt   time.Time
+	//EditedAt is set once the comment was edited, the earlier texts are kept as CommentRevisions
+	EditedAt *time.Time
+	//Deleted comments stay in the thread without their Body
+	Deleted   bool
+	DeletedBy string
+}
+
+//NoteChange is a note written together with a timesheet change: Comment is inserted, or updated by Editor when
+//Edit is set. Event goes to the outbox with it.
+type NoteChange struct {
+	Comment *TimesheetComment
+	Edit    bool
+	Editor  string
+	Event   *Event
+}
+
+//CommentRevision is the text a comment had before an edit or delete by EditedBy
+type CommentRevision struct {
+	CommentID uuid.UUID
+	Body      string
+	EditedBy  string
+	EditedAt  time.Time
+}
+
+//maxCommentLength keeps comments to a few paragraphs
+const maxCommentLength = 4000
+
+var CommentNotFound = &res.ResponseCode{Code: "CommentNotFound", Message: "Comment not found", HttpStatus: http.StatusNotFound}
@@ -0,0 +1,80 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+)
+
+type ComplianceSeverity string
+
+const (
+	//SeverityWarning findings are stored and reported but the timesheet is accepted
+	SeverityWarning ComplianceSeverity = "warning"
+	//SeverityBlocking findings reject the timesheet with a validation error
+	SeverityBlocking ComplianceSeverity = "blocking"
+)
+
+//ComplianceConfig holds the working-time limits. A limit of 0 turns its check off.
+type ComplianceConfig struct {
+	MaxDailyHours           float64
+	MaxDailySeverity        ComplianceSeverity
+	MaxAverageWeeklyHours   float64
+	AverageWeeklySeverity   ComplianceSeverity
+	MinRestHours            float64
+	RestSeverity            ComplianceSeverity
+	MaxConsecutiveDays      int
+	ConsecutiveDaysSeverity ComplianceSeverity
+}
+
+//WorkDay is one day of a timesheet as the compliance checks see it
+type WorkDay struct {
+	Date     time.Time
+	WeekInfo int
+	Day      int
+	Hours    float64
+}
+
+//ComplianceSheet is the input of every check: the days of the pay period in calendar order. Month and Year
+//are those of the period's start. Before holds the days of earlier periods leading up to it, with WeekInfo and
+//Day 0, for checks that look back across the period's start.
+type ComplianceSheet struct {
+	LoginName string
+	Month     int
+	Year      int
+	Days      []WorkDay
+	Before    []WorkDay
+}
+
+//ComplianceFinding is one violation of a working-time rule. WeekInfo and Day are 0 for findings about the month.
+type ComplianceFinding struct {
+	LoginName string
+	Month     int
+	Year      int
+	Rule      string
+	Severity  ComplianceSeverity
+	WeekInfo  int
+	Day       int
+	//Date is the day of the finding, the first day of the period for a finding about the whole period
+	Date      time.Time
+	Value     float64
+	Limit     float64
+	Message   string
+	CreatedAt time.Time
+}
+
+//ComplianceReport lists the findings dated within a FiscalUnit
+type ComplianceReport struct {
+	GroupBy    ReportGrouping
+	FiscalUnit *FiscalUnit
+	Findings   []*ComplianceFinding
+}
+
+//SubmissionResult is returned instead of the plain result when a timesheet was accepted with warnings
+type SubmissionResult struct {
+	Result   string
+	Warnings []ComplianceFinding
+}
+
+var SubmittedWithWarnings = &res.ResponseCode{Code: "SubmittedWithWarnings", Message: "Your request is completed with compliance warnings", HttpStatus: http.StatusOK}
@@ -0,0 +1,32 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+)
+
+//Contract holds the employment terms of a user that time calculations depend on. ContractType is free text
+//chosen by the organization, e.g. hourly, salaried or union, and selects the overtime rule.
+//WeeklyHours are the hours of a full-time week and Percentage the part of it the user is employed for.
+type Contract struct {
+	LoginName    string
+	ContractType string
+	WeeklyHours  float64
+	Percentage   float64
+	UpdatedAt    time.Time
+}
+
+//Users without a contract are expected to work a full-time week of defaultWeeklyHours
+const (
+	defaultWeeklyHours = 40
+	defaultPercentage  = 100
+)
+
+//dailyHours are the hours expected on a working day
+func (c *Contract) dailyHours() float64 {
+	return c.WeeklyHours / 5 * c.Percentage / 100
+}
+
+var ContractNotFound = &res.ResponseCode{Code: "ContractNotFound", Message: "Contract not found", HttpStatus: http.StatusNotFound}
@@ -0,0 +1,54 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//ExchangeRate converts Currency to QuoteCurrency: one unit of Currency is Rate units of QuoteCurrency from
+//EffectiveFrom until the next rate of the pair takes effect. Source tells where the rate came from, e.g. the
+//name of an imported file. Rates are not changed once stored, a later rate of the same pair and day supersedes
+//them and deleted rates are kept with DeletedAt set.
+type ExchangeRate struct {
+	ID            uuid.UUID
+	Currency      string
+	QuoteCurrency string
+	Rate          float64
+	EffectiveFrom time.Time
+	Source        string
+	CreatedAt     time.Time
+	DeletedAt     *time.Time `json:",omitempty"`
+	DeletedBy     string     `json:",omitempty"`
+}
+
+//ExchangeRateImportReport tells how many rows of a CSV file became exchange rates
+type ExchangeRateImportReport struct {
+	Imported int
+	Skipped  []string
+}
+
+//Conversion records the rate an amount of From was converted to Currency with, so a report can be checked later.
+//RateID is the stored rate, nil when From is Currency. Inverted is set when the stored rate is of the pair the
+//other way around and Rate is its inverse.
+type Conversion struct {
+	From          string
+	Currency      string
+	Rate          float64
+	RateID        *uuid.UUID
+	EffectiveFrom time.Time
+	Inverted      bool
+}
+
+//ConvertedAmount is an amount in the reporting currency and the conversion it was made with
+type ConvertedAmount struct {
+	Amount float64
+	*Conversion
+}
+
+var ExchangeRateNotFound = &res.ResponseCode{Code: "ExchangeRateNotFound", Message: "Exchange rate not found", HttpStatus: http.StatusNotFound}
+var ExchangeRateMissing = &res.ResponseCode{Code: "ExchangeRateMissing", Message: "No exchange rate to the reporting currency is in effect", HttpStatus: http.StatusUnprocessableEntity}
+var InvalidExchangeRateCSV = &res.ResponseCode{Code: "InvalidExchangeRateCSV", Message: "The exchange rate file could not be read", HttpStatus: http.StatusBadRequest}
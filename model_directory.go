@@ -0,0 +1,51 @@
+package user
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//DirectoryEntry holds the attributes an external directory (SCIM, LDAP) manages for a user.
+//Department and JobTitle are mirrored onto the user record because CreateTimesheet builds Placement from them.
+type DirectoryEntry struct {
+	ID               uuid.UUID
+	LoginName        string
+	ExternalID       string
+	GivenName        string
+	FamilyName       string
+	Email            string
+	Department       string
+	JobTitle         string
+	ManagerLoginName string
+	Active           bool
+	Source           string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+const (
+	DirectorySourceSCIM = "scim"
+	DirectorySourceLDAP = "ldap"
+)
+
+//DirectoryFilter narrows a directory listing. Empty fields are ignored.
+type DirectoryFilter struct {
+	LoginName  string
+	ExternalID string
+	Email      string
+	Department string
+	JobTitle   string
+	Source     string
+	Active     *bool
+	//ManagerLoginName lists the direct reports of a manager
+	ManagerLoginName string
+}
+
+var UserAlreadyExists = &res.ResponseCode{Code: "UserAlreadyExists", Message: "User already exists", HttpStatus: http.StatusConflict}
+var UserDeactivated = &res.ResponseCode{Code: "UserDeactivated", Message: "User account is deactivated", HttpStatus: http.StatusUnauthorized}
+var DirectoryEntryNotFound = &res.ResponseCode{Code: "DirectoryEntryNotFound", Message: "User not found", HttpStatus: http.StatusNotFound}
+var DirectorySyncAborted = &res.ResponseCode{Code: "DirectorySyncAborted", Message: "Directory sync aborted to avoid disabling too many users", HttpStatus: http.StatusConflict}
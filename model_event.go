@@ -0,0 +1,76 @@
+package timesheets
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+)
+
+type EventType string
+
+const (
+	EventTimesheetCreated  EventType = "timesheet.created"
+	EventTimesheetUpdated  EventType = "timesheet.updated"
+	EventTimesheetApproved EventType = "timesheet.approved"
+	EventTimesheetRejected EventType = "timesheet.rejected"
+	EventTimesheetDeleted  EventType = "timesheet.deleted"
+
+	EventTimesheetNotesChanged EventType = "timesheet.notes_changed"
+)
+
+var eventTypes = []string{string(EventTimesheetCreated), string(EventTimesheetUpdated), string(EventTimesheetApproved),
+	string(EventTimesheetRejected), string(EventTimesheetDeleted), string(EventTimesheetNotesChanged)}
+
+//eventChannel is the Postgres notification channel every committed event is announced on
+const eventChannel = "timesheet_events"
+
+//eventNotification is the payload on eventChannel, the organization lets streams filter by tenant
+type eventNotification struct {
+	OrgID uuid.UUID
+	Event *Event
+}
+
+//Event tells about a change of a timesheet. ID is unique per change and lets receivers drop duplicates.
+//Month and Year are those of the start of the timesheet's pay period.
+type Event struct {
+	ID          uuid.UUID
+	Type        EventType
+	LoginName   string
+	Month       int
+	Year        int
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Status      string `json:",omitempty"`
+	OccurredAt  time.Time
+}
+
+//EventPublisher is told about every change of a timesheet
+type EventPublisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
+
+var TimesheetNotPending = &res.ResponseCode{Code: "TimesheetNotPending", Message: "Timesheet was already reviewed", HttpStatus: http.StatusConflict}
+
+//OutboxEntry is an event waiting in the outbox until every sink took it
+type OutboxEntry struct {
+	ID          uuid.UUID
+	EventType   EventType
+	Payload     sql.JSONText
+	Attempts    int
+	LastError   string
+	CreatedAt   time.Time
+	AvailableAt time.Time
+	PublishedAt *time.Time
+}
+
+//OutboxConfig controls the relay: failed events are retried after RetryBase, doubling up to an hour,
+//and published events are purged after Retention
+type OutboxConfig struct {
+	RetryBase time.Duration
+	Retention time.Duration
+}
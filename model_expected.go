@@ -0,0 +1,53 @@
+package timesheets
+
+import (
+	"time"
+)
+
+type FillStatus string
+
+const (
+	FillComplete    FillStatus = "Complete"
+	FillUnderFilled FillStatus = "UnderFilled"
+	FillMissing     FillStatus = "Missing"
+)
+
+//ExpectedHours compares what a user was expected to work in a month, or the days From to To, with what their
+//timesheets account for. FilledHours are the worked hours plus the absences other than public holidays, which
+//are no working days.
+type ExpectedHours struct {
+	LoginName     string
+	Department    string
+	Month         int `json:",omitempty"`
+	Year          int `json:",omitempty"`
+	From          time.Time
+	To            time.Time
+	WorkingDays   int
+	WeeklyHours   float64
+	Percentage    float64
+	ExpectedHours float64
+	FilledHours   float64
+	Status        FillStatus
+}
+
+//MissingTimesheetReport lists the users whose timesheet of the month, or of the FiscalUnit, is missing or
+//under-filled
+type MissingTimesheetReport struct {
+	Month      int            `json:",omitempty"`
+	Year       int            `json:",omitempty"`
+	GroupBy    ReportGrouping `json:",omitempty"`
+	FiscalUnit *FiscalUnit    `json:",omitempty"`
+	From       time.Time
+	To         time.Time
+	Users      []*ExpectedHours
+}
+
+//MonthFill is a user with their contract and the timesheets of the pay periods overlapping a range of days, of
+//which only the days in the range count
+type MonthFill struct {
+	LoginName   string
+	Department  string
+	WeeklyHours float64
+	Percentage  float64
+	Timesheets  []*GetAllTimesheets `db:"-"`
+}
@@ -0,0 +1,75 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+type ExpenseCategory string
+
+const (
+	ExpenseTravel  ExpenseCategory = "Travel"
+	ExpenseMileage ExpenseCategory = "Mileage"
+	ExpenseLodging ExpenseCategory = "Lodging"
+	ExpenseMeals   ExpenseCategory = "Meals"
+	//ExpensePerDiem is the daily allowance of a business trip, it needs no receipt
+	ExpensePerDiem ExpenseCategory = "PerDiem"
+	ExpenseOther   ExpenseCategory = "Other"
+)
+
+var expenseCategories = []string{string(ExpenseTravel), string(ExpenseMileage), string(ExpenseLodging), string(ExpenseMeals),
+	string(ExpensePerDiem), string(ExpenseOther)}
+
+type ExpenseStatus string
+
+const (
+	ExpenseSubmitted ExpenseStatus = "Submitted"
+	ExpenseApproved  ExpenseStatus = "Approved"
+	ExpenseRejected  ExpenseStatus = "Rejected"
+)
+
+//ExpenseClaim holds the costs of a user in one pay period, the period containing PeriodStart on create. Claims are
+//reviewed like timesheets; approved claims are paid with the payroll of their period.
+type ExpenseClaim struct {
+	ID          uuid.UUID
+	LoginName   string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Title       string
+	Status      ExpenseStatus
+	Lines       []*ExpenseLine
+	ReviewedBy  string
+	ReviewedAt  *time.Time
+	ReviewNote  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+//ExpenseLine is one cost of a claim. Amount is in Currency, an ISO 4217 code, and Date lies in the claim's period.
+//ReceiptID is an attachment of the user holding the receipt.
+type ExpenseLine struct {
+	ID          uuid.UUID
+	ClaimID     uuid.UUID `json:"-"`
+	Category    ExpenseCategory
+	Amount      float64
+	Currency    string
+	Date        time.Time
+	Project     string
+	Description string
+	ReceiptID   *uuid.UUID
+}
+
+//ExpenseReview approves or rejects a claim, Note tells the user why
+type ExpenseReview struct {
+	Note string
+}
+
+//maxExpenseLines keeps a claim to a month of receipts
+const maxExpenseLines = 100
+
+var ExpenseClaimNotFound = &res.ResponseCode{Code: "ExpenseClaimNotFound", Message: "Expense claim not found", HttpStatus: http.StatusNotFound}
+var ExpenseClaimNotPending = &res.ResponseCode{Code: "ExpenseClaimNotPending", Message: "Expense claim was already reviewed", HttpStatus: http.StatusConflict}
@@ -0,0 +1,49 @@
+package timesheets
+
+import (
+	"time"
+)
+
+//FiscalPattern is how many weeks the three periods of every fiscal quarter have
+type FiscalPattern string
+
+const (
+	Pattern445 FiscalPattern = "4-4-5"
+	Pattern454 FiscalPattern = "4-5-4"
+	Pattern544 FiscalPattern = "5-4-4"
+)
+
+var fiscalPatterns = []string{string(Pattern445), string(Pattern454), string(Pattern544)}
+
+//FiscalCalendar is the 52/53 week calendar finance reports on. A fiscal year starts on the StartWeekday nearest
+//to the 1st of StartMonth and runs until the next one does, which makes it 52 weeks long or, every five or six
+//years, 53. The 53rd week goes into the last period. Years are named after the calendar year they start in, or
+//the one they end in with NameByEndYear.
+type FiscalCalendar struct {
+	StartMonth    int
+	StartWeekday  time.Weekday
+	Pattern       FiscalPattern
+	NameByEndYear bool
+	UpdatedAt     time.Time
+}
+
+//defaultFiscalCalendar applies to organizations that have not set up their own
+var defaultFiscalCalendar = FiscalCalendar{StartMonth: 1, StartWeekday: time.Monday, Pattern: Pattern445}
+
+//FiscalDate places a day in the fiscal calendar, with the bounds of its week, period, quarter and year
+type FiscalDate struct {
+	Date         time.Time
+	FiscalYear   int
+	Quarter      int
+	Period       int
+	Week         int
+	YearWeeks    int
+	YearStart    time.Time
+	YearEnd      time.Time
+	QuarterStart time.Time
+	QuarterEnd   time.Time
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	WeekStart    time.Time
+	WeekEnd      time.Time
+}
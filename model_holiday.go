@@ -0,0 +1,57 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//HolidayCalendar is a set of public holidays, usually those of one country or region
+type HolidayCalendar struct {
+	ID        uuid.UUID
+	Name      string
+	Country   string
+	CreatedAt time.Time
+	Holidays  []*Holiday `db:"-"`
+}
+
+type Holiday struct {
+	ID         uuid.UUID
+	CalendarID uuid.UUID
+	Date       time.Time
+	Name       string
+}
+
+//CalendarAssignment decides which calendar applies to whom. A LoginName wins over a Department,
+//an assignment with neither is the organization's default.
+type CalendarAssignment struct {
+	CalendarID uuid.UUID
+	Department string
+	LoginName  string
+}
+
+//WorkingDays are the weekdays of a month a user is expected to work
+type WorkingDays struct {
+	LoginName   string
+	Month       int
+	Year        int
+	Weekdays    int
+	Holidays    []*Holiday
+	WorkingDays int
+}
+
+//ICSImportReport tells how many events of an iCalendar file became holidays
+type ICSImportReport struct {
+	Imported int
+	Skipped  []string
+}
+
+//holidayHours is what a public holiday on a weekday counts for when a timesheet is pre-filled
+const holidayHours = 8
+
+var HolidayCalendarNotFound = &res.ResponseCode{Code: "HolidayCalendarNotFound", Message: "Holiday calendar not found", HttpStatus: http.StatusNotFound}
+var HolidayNotFound = &res.ResponseCode{Code: "HolidayNotFound", Message: "Holiday not found", HttpStatus: http.StatusNotFound}
+var InvalidICS = &res.ResponseCode{Code: "InvalidICS", Message: "The iCalendar file could not be read", HttpStatus: http.StatusBadRequest}
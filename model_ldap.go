@@ -0,0 +1,59 @@
+package user
+
+import (
+	"time"
+)
+
+//LDAPConfig describes where users live in the directory and which attributes hold what
+type LDAPConfig struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+	PageSize     uint32
+
+	LoginAttr      string
+	GivenNameAttr  string
+	FamilyNameAttr string
+	EmailAttr      string
+	DepartmentAttr string
+	TitleAttr      string
+	ManagerAttr    string
+	//DisabledFilter matches accounts that exist but are locked, e.g. Active Directory's userAccountControl bit 2
+	DisabledFilter string
+}
+
+//SyncAction is what the reconciler did, or would do on a dry run, for one user
+type SyncAction string
+
+const (
+	SyncActionCreate  SyncAction = "Create"
+	SyncActionUpdate  SyncAction = "Update"
+	SyncActionDisable SyncAction = "Disable"
+	SyncActionEnable  SyncAction = "Enable"
+	SyncActionSkip    SyncAction = "Skip"
+)
+
+type SyncChange struct {
+	LoginName string
+	Action    SyncAction
+	Fields    []string `json:",omitempty"`
+	Reason    string   `json:",omitempty"`
+}
+
+//SyncReport summarises a directory sync run
+type SyncReport struct {
+	DryRun     bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Fetched    int
+	Created    int
+	Updated    int
+	Disabled   int
+	Enabled    int
+	Unchanged  int
+	Skipped    int
+	Changes    []SyncChange
+	Errors     []string `json:",omitempty"`
+}
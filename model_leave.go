@@ -0,0 +1,120 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+)
+
+type AccrualMethod string
+
+const (
+	//AccrualPerPeriod credits AccrualHours at the start of every pay period (calendar month)
+	AccrualPerPeriod AccrualMethod = "PerPeriod"
+	//AccrualAnnualGrant credits AccrualHours once at the start of every year
+	AccrualAnnualGrant AccrualMethod = "AnnualGrant"
+)
+
+//LeavePolicy defines how a balance for one absence type is built up. Department limits it to users of that
+//department, empty applies to everybody in the organization.
+type LeavePolicy struct {
+	ID            uuid.UUID
+	Name          string
+	AbsenceType   AbsenceType
+	Department    string
+	AccrualMethod AccrualMethod
+	AccrualHours  float64
+	//CarryOverCap is the most hours kept when a year ends, the rest is forfeited. Omitted means no cap.
+	CarryOverCap *float64
+	CreatedAt    time.Time
+}
+
+type LedgerEntryType string
+
+const (
+	LedgerAccrual    LedgerEntryType = "Accrual"
+	LedgerUsage      LedgerEntryType = "Usage"
+	LedgerForfeit    LedgerEntryType = "Forfeit"
+	LedgerAdjustment LedgerEntryType = "Adjustment"
+)
+
+//LedgerEntry is one movement of a leave balance. Hours are positive for credits and negative for debits.
+//Reference makes every automatic entry idempotent, e.g. accrual:2024-03 or timesheet:2024-03.
+type LedgerEntry struct {
+	ID            uuid.UUID
+	LoginName     string
+	PolicyID      uuid.UUID
+	AbsenceType   AbsenceType
+	EntryType     LedgerEntryType
+	Hours         float64
+	EffectiveDate time.Time
+	Reference     string
+	CreatedAt     time.Time
+}
+
+//LeaveBalance is the balance of one policy for a user together with the entries it is made of
+type LeaveBalance struct {
+	PolicyID    uuid.UUID
+	PolicyName  string
+	AbsenceType AbsenceType
+	Balance     float64
+	//PendingHours are requested or approved but not yet recorded on a timesheet
+	PendingHours float64
+	Available    float64
+	Ledger       []*LedgerEntry
+}
+
+type LeaveRequestStatus string
+
+const (
+	LeaveRequestPending   LeaveRequestStatus = "Pending"
+	LeaveRequestApproved  LeaveRequestStatus = "Approved"
+	LeaveRequestRejected  LeaveRequestStatus = "Rejected"
+	LeaveRequestCancelled LeaveRequestStatus = "Cancelled"
+)
+
+type LeaveRequest struct {
+	ID                uuid.UUID
+	LoginName         string
+	AbsenceType       AbsenceType
+	StartDate         time.Time
+	EndDate           time.Time
+	Hours             float64
+	Comment           string
+	Status            LeaveRequestStatus
+	ApproverLoginName string
+	DecisionComment   string
+	DecidedAt         *time.Time
+	CreatedAt         time.Time
+}
+
+//LeaveUsage is what the absences of a timesheet take from the balances: Entries replace the usages of Reference,
+//the usages of Reference of the Cleared policies are removed
+type LeaveUsage struct {
+	LoginName string
+	Reference string
+	Entries   []*LedgerEntry
+	Cleared   []uuid.UUID
+}
+
+//RecordedAbsences are the absences of one timesheet, their weeks counted from the month PeriodStart is in
+type RecordedAbsences struct {
+	PeriodStart time.Time
+	Absences    sql.JSONText `db:"absence_info"`
+}
+
+//LeaveDecision is the manager's answer to a request
+type LeaveDecision struct {
+	Comment string
+}
+
+var LeaveRequestNotFound = &res.ResponseCode{Code: "LeaveRequestNotFound", Message: "Leave request not found", HttpStatus: http.StatusNotFound}
+var LeavePolicyNotFound = &res.ResponseCode{Code: "LeavePolicyNotFound", Message: "Leave policy not found", HttpStatus: http.StatusNotFound}
+var LeaveRequestNotPending = &res.ResponseCode{Code: "LeaveRequestNotPending", Message: "Leave request was already decided", HttpStatus: http.StatusConflict}
+var InsufficientLeaveBalance = &res.ResponseCode{Code: "InsufficientLeaveBalance", Message: "Not enough leave balance", HttpStatus: http.StatusConflict}
+var LeaveNotApproved = &res.ResponseCode{Code: "LeaveNotApproved", Message: "Absence is not covered by an approved leave request", HttpStatus: http.StatusBadRequest}
+var LeaveAlreadyUsed = &res.ResponseCode{Code: "LeaveAlreadyUsed", Message: "Leave was already recorded on a timesheet", HttpStatus: http.StatusConflict}
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+)
+
+//OIDCConfig describes the identity provider and how its claims map onto a User. UsernameClaim names provisioned
+//accounts; it only links an identity to an existing account with TrustUsernameClaim, for providers where that claim
+//is unique and can not be changed by the user.
+type OIDCConfig struct {
+	IssuerURL          string
+	ClientID           string
+	ClientSecret       string
+	RedirectURL        string
+	Scopes             []string
+	UsernameClaim      string
+	TrustUsernameClaim bool
+	DepartmentClaim    string
+	JobTitleClaim      string
+	AutoProvision      bool
+}
+
+//OIDCIdentity links the subject of an issuer, the only pair of claims a provider keeps unique and stable, to the
+//account it logs in to
+type OIDCIdentity struct {
+	Issuer    string
+	Subject   string
+	LoginName string
+	CreatedAt time.Time
+}
+
+//OIDCLoginState is kept by the browser between the redirect to the provider and the callback
+type OIDCLoginState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+//oidcDiscovery is the subset of /.well-known/openid-configuration the login flow needs
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+var OIDCDisabled = &res.ResponseCode{Code: "OIDCDisabled", Message: "Single sign-on is not configured", HttpStatus: http.StatusNotFound}
+var OIDCLoginFailed = &res.ResponseCode{Code: "OIDCLoginFailed", Message: "Single sign-on login failed", HttpStatus: http.StatusUnauthorized}
+var OIDCUserNotProvisioned = &res.ResponseCode{Code: "OIDCUserNotProvisioned", Message: "No account exists for this identity", HttpStatus: http.StatusForbidden}
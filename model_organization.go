@@ -0,0 +1,26 @@
+package user
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//Organization is a tenant. Every user, token and timesheet belongs to exactly one.
+type Organization struct {
+	ID        uuid.UUID
+	Slug      string
+	Name      string
+	CreatedAt time.Time
+}
+
+//DefaultOrganizationID owns all rows that existed before organizations were introduced, see schema.sql
+var DefaultOrganizationID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+const DefaultOrganizationSlug = "default"
+
+var OrganizationNotFound = &res.ResponseCode{Code: "OrganizationNotFound", Message: "Organization not found", HttpStatus: http.StatusNotFound}
+var OrganizationAlreadyExists = &res.ResponseCode{Code: "OrganizationAlreadyExists", Message: "Organization already exists", HttpStatus: http.StatusConflict}
@@ -0,0 +1,48 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//OvertimeRule splits worked hours into regular, overtime and double time. Department and ContractType narrow
+//who it applies to; the rule matching most specifically wins and one with neither is the organization's default.
+//Thresholds of 0 are disabled.
+type OvertimeRule struct {
+	ID           uuid.UUID
+	Name         string
+	Department   string
+	ContractType string
+	//DailyOvertimeAfter and DailyDoubleTimeAfter are hours per day after which time becomes overtime or double time
+	DailyOvertimeAfter   float64
+	DailyDoubleTimeAfter float64
+	//WeeklyOvertimeAfter turns regular hours beyond it into overtime, per calendar week from Monday to Sunday. A week
+	//that began in the previous pay period counts the regular hours recorded there.
+	WeeklyOvertimeAfter float64
+	//WeekendMultiplier and HolidayMultiplier apply to every hour worked on such a day when above 1. The hours count
+	//as double time when the multiplier reaches DoubleTimeMultiplier, as overtime otherwise.
+	WeekendMultiplier    float64
+	HolidayMultiplier    float64
+	OvertimeMultiplier   float64
+	DoubleTimeMultiplier float64
+	CreatedAt            time.Time
+}
+
+//HoursBreakdown is what payroll needs from a timesheet. PayableHours weighs every hour with its multiplier.
+type HoursBreakdown struct {
+	RegularHours    float64
+	OvertimeHours   float64
+	DoubleTimeHours float64
+	PayableHours    float64
+}
+
+const (
+	defaultOvertimeMultiplier   = 1.5
+	defaultDoubleTimeMultiplier = 2
+)
+
+var OvertimeRuleNotFound = &res.ResponseCode{Code: "OvertimeRuleNotFound", Message: "Overtime rule not found", HttpStatus: http.StatusNotFound}
@@ -0,0 +1,113 @@
+package timesheets
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+type PayFrequency string
+
+const (
+	PayWeekly      PayFrequency = "Weekly"
+	PayBiWeekly    PayFrequency = "BiWeekly"
+	PaySemiMonthly PayFrequency = "SemiMonthly"
+	PayMonthly     PayFrequency = "Monthly"
+)
+
+var payFrequencies = []string{string(PayWeekly), string(PayBiWeekly), string(PaySemiMonthly), string(PayMonthly)}
+
+//PayPeriodDefinition tells how the time of a department is cut into pay periods, one with no Department is the
+//organization's default. Weekly and bi-weekly periods repeat from AnchorDate, the start of any one of them.
+//Users without a definition are paid monthly.
+type PayPeriodDefinition struct {
+	ID         uuid.UUID
+	Name       string
+	Frequency  PayFrequency
+	AnchorDate time.Time
+	Department string
+	CreatedAt  time.Time
+}
+
+//PayPeriod is the span of days one timesheet covers, Start and End included
+type PayPeriod struct {
+	Start     time.Time
+	End       time.Time
+	Frequency PayFrequency
+}
+
+//monthPeriod is the period of a monthly paid user
+func monthPeriod(month, year int) *PayPeriod {
+	from, to := monthRange(month, year)
+	return &PayPeriod{Start: from, End: to, Frequency: PayMonthly}
+}
+
+//Month and Year of a period are those of its start, they are what month based reports file the timesheet under
+func (p *PayPeriod) Month() int { return int(p.Start.Month()) }
+
+func (p *PayPeriod) Year() int { return p.Start.Year() }
+
+//isMonth tells whether the period is the whole month
+func (p *PayPeriod) isMonth(month, year int) bool {
+	from, to := monthRange(month, year)
+	return p.Start.Equal(from) && p.End.Equal(to)
+}
+
+func (p *PayPeriod) contains(date time.Time) bool {
+	return !date.Before(p.Start) && !date.After(p.End)
+}
+
+//slotOf addresses date like weekSlot, but counts the weeks from the month the period starts in so that a
+//period running into the next month goes on with week 6 or 7
+func (p *PayPeriod) slotOf(date time.Time) (int, int, bool) {
+	week, day := p.hoursSlot(date)
+	if day > 5 {
+		return 0, 0, false
+	}
+	return week, day, true
+}
+
+//hoursSlot is the week and day of date in WeekHrs, weekend days being Day6 and Day7
+func (p *PayPeriod) hoursSlot(date time.Time) (int, int) {
+	first := time.Date(p.Start.Year(), p.Start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(first.Weekday()) + 6) % 7
+	return (daysBetween(first, date)+offset)/7 + 1, (int(date.Weekday())+6)%7 + 1
+}
+
+//slotDate is the reverse of slotOf, weekend days being Day6 and Day7
+func (p *PayPeriod) slotDate(week, day int) time.Time {
+	date, _ := slotDate(p.Month(), p.Year(), week, day)
+	return date
+}
+
+//name is the month of a monthly period as 5/2024, otherwise its first and last day
+func (p *PayPeriod) name() string {
+	if p.Frequency == PayMonthly {
+		return fmt.Sprintf("%d/%d", p.Month(), p.Year())
+	}
+	return p.Start.Format(dateLayout) + " – " + p.End.Format(dateLayout)
+}
+
+//reference names the period in the leave ledger. Monthly periods keep the name they had before pay periods.
+func (p *PayPeriod) reference() string {
+	if p.Frequency == PayMonthly {
+		return timesheetReference(p.Month(), p.Year())
+	}
+	return fmt.Sprintf("timesheet:%s", p.Start.Format("2006-01-02"))
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+//maxPeriodWeeks is the highest WeekInfo a period can use, a bi-weekly one starting in the last week of a month
+const maxPeriodWeeks = 8
+
+var PayPeriodDefinitionNotFound = &res.ResponseCode{Code: "PayPeriodDefinitionNotFound", Message: "Pay period definition not found", HttpStatus: http.StatusNotFound}
+var PayPeriodNotMonthly = &res.ResponseCode{Code: "PayPeriodNotMonthly", Message: "The pay period is not the month, address the timesheet by a date of its period", HttpStatus: http.StatusBadRequest}
+var PayPeriodOverlaps = &res.ResponseCode{Code: "PayPeriodOverlaps", Message: "The pay period overlaps a timesheet of the user", HttpStatus: http.StatusConflict}
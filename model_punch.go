@@ -0,0 +1,93 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+type PunchKind string
+
+const (
+	PunchIn         PunchKind = "In"
+	PunchOut        PunchKind = "Out"
+	PunchBreakStart PunchKind = "BreakStart"
+	PunchBreakEnd   PunchKind = "BreakEnd"
+)
+
+var punchKinds = []string{string(PunchIn), string(PunchOut), string(PunchBreakStart), string(PunchBreakEnd)}
+
+//maxShiftLength is how long a shift may stay open. Later punches start a new work day and an older open shift
+//is missing its Out punch.
+const maxShiftLength = 16 * time.Hour
+
+//Punch is one clock event of a user. PunchedAt is the server time of the punch, or the time a manager corrected it
+//to. WorkDate is the local date in TimeZone of the shift the punch belongs to, so an Out after midnight still counts
+//for the day the shift started.
+type Punch struct {
+	ID        uuid.UUID
+	LoginName string
+	Kind      PunchKind
+	PunchedAt time.Time
+	TimeZone  string
+	WorkDate  time.Time
+	//CorrectedBy is the manager who added or last changed the punch, empty for punches of the user
+	CorrectedBy string
+	Reason      string
+	//Voided punches were removed by a manager and are not counted
+	Voided    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+//PunchCorrection adds or changes a punch on behalf of a user
+type PunchCorrection struct {
+	Kind      PunchKind
+	PunchedAt time.Time
+	TimeZone  string
+	Reason    string
+}
+
+type PunchIssueKind string
+
+const (
+	MissingPunchIn    PunchIssueKind = "MissingIn"
+	MissingPunchOut   PunchIssueKind = "MissingOut"
+	MissingBreakStart PunchIssueKind = "MissingBreakStart"
+	MissingBreakEnd   PunchIssueKind = "MissingBreakEnd"
+	//TimesheetNotUpdated means the hours could not be rolled up, e.g. because the timesheet is already approved
+	TimesheetNotUpdated PunchIssueKind = "TimesheetNotUpdated"
+)
+
+//PunchRollup is the worked hours of a work day last put on the timesheet. A later rollup of the day only books
+//the change since, the other hours of the day are left alone.
+type PunchRollup struct {
+	LoginName string
+	WorkDate  time.Time
+	Hours     float64
+}
+
+//PunchIssue points at the punch that showed a punch is missing, or at the last punch of a shift left open
+type PunchIssue struct {
+	Kind    PunchIssueKind
+	PunchID uuid.UUID
+	At      time.Time
+}
+
+//PunchDay are the punches of a user's work day rolled up into hours. Only completed stretches of work count, an
+//open shift adds its hours once it is punched out.
+type PunchDay struct {
+	LoginName   string
+	Date        time.Time
+	Punches     []*Punch
+	WorkedHours float64
+	BreakHours  float64
+	//Open is set while the user is punched in or on a break
+	Open   bool
+	Issues []PunchIssue
+}
+
+var PunchNotFound = &res.ResponseCode{Code: "PunchNotFound", Message: "Punch not found", HttpStatus: http.StatusNotFound}
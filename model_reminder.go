@@ -0,0 +1,55 @@
+package timesheets
+
+import (
+	"time"
+)
+
+type ReminderKind string
+
+const (
+	//ReminderMissingTimesheet goes to users without a timesheet in the days before the deadline
+	ReminderMissingTimesheet ReminderKind = "MissingTimesheet"
+	//ReminderPendingApprovals goes to managers whose reports have timesheets or leave waiting for them
+	ReminderPendingApprovals ReminderKind = "PendingApprovals"
+	//ReminderRejectedTimesheet goes to users until their rejected timesheet is corrected
+	ReminderRejectedTimesheet ReminderKind = "RejectedTimesheet"
+)
+
+//ReminderConfig tells when reminders are due. Timesheets are due on the last day of their month.
+type ReminderConfig struct {
+	DaysBeforeDeadline int
+}
+
+//ReminderPreferences are chosen by each user, users without preferences get reminders in defaultLocale
+type ReminderPreferences struct {
+	LoginName string
+	OptOut    bool
+	Locale    string
+	UpdatedAt time.Time
+}
+
+const defaultLocale = "en"
+
+//ReminderRecipient is a user with the address and preferences reminders are sent with
+type ReminderRecipient struct {
+	LoginName string
+	GivenName string
+	Email     string
+	OptOut    bool
+	Locale    string
+}
+
+//PendingApprovals counts what the direct reports of a manager wait for
+type PendingApprovals struct {
+	LoginName         string
+	PendingTimesheets int
+	PendingLeave      int
+}
+
+//RejectedTimesheet is a timesheet its owner has to correct
+type RejectedTimesheet struct {
+	LoginName    string
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	PayFrequency PayFrequency `db:"period_frequency"`
+}
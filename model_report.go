@@ -0,0 +1,106 @@
+package timesheets
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//ReportGrouping is the fiscal unit a report adds hours up by
+type ReportGrouping string
+
+const (
+	GroupByFiscalWeek    ReportGrouping = "fiscalWeek"
+	GroupByFiscalPeriod  ReportGrouping = "fiscalPeriod"
+	GroupByFiscalQuarter ReportGrouping = "fiscalQuarter"
+	GroupByFiscalYear    ReportGrouping = "fiscalYear"
+)
+
+var reportGroupings = []string{string(GroupByFiscalWeek), string(GroupByFiscalPeriod), string(GroupByFiscalQuarter),
+	string(GroupByFiscalYear)}
+
+//maxReportDays bounds the range of a report to about three fiscal years
+const maxReportDays = 3 * 371
+
+//HoursReport adds up the hours of the timesheets from From to To, both included. TimeZone is the zone the default
+//range was taken in.
+type HoursReport struct {
+	GroupBy  ReportGrouping
+	From     time.Time
+	To       time.Time
+	TimeZone string
+	Rows     []*ReportedHours
+}
+
+//FiscalUnit is the fiscal week, period, quarter or year a report groups by. The units finer than the grouping are
+//0, Start and End are the bounds of the unit.
+type FiscalUnit struct {
+	FiscalYear int
+	Quarter    int
+	Period     int
+	Week       int
+	Start      time.Time
+	End        time.Time
+}
+
+//ReportedHours are a user's hours in one fiscal unit, which may reach beyond the range of the report
+type ReportedHours struct {
+	LoginName string
+	FiscalUnit
+	WorkedHours  float64
+	AbsenceHours float64
+}
+
+//PayrollExport is what payroll pays for the pay periods containing Date: the hours of every timesheet and the
+//approved expense claims of each user. Grouped by a fiscal unit it is every pay period overlapping the FiscalUnit
+//containing Date instead. Currency is the reporting currency expenses were converted to, if any.
+type PayrollExport struct {
+	Date       time.Time
+	GroupBy    ReportGrouping `json:",omitempty"`
+	FiscalUnit *FiscalUnit    `json:",omitempty"`
+	Currency   string         `json:",omitempty"`
+	Rows       []*PayrollRow
+}
+
+//PayrollRow is a user's pay period. Status is empty when the user claimed expenses but has no timesheet.
+type PayrollRow struct {
+	LoginName    string
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	Status       string
+	AbsenceHours float64
+	HoursBreakdown
+	//Expenses adds up the approved claims of the period per currency
+	Expenses map[string]float64
+	//ConvertedExpenses adds up the claims in the reporting currency of the export, with Conversions the rates used
+	ConvertedExpenses *float64      `json:",omitempty"`
+	Conversions       []*Conversion `json:",omitempty"`
+}
+
+//ExpenseExport lists the approved expenses dated From to To, both included, for invoicing their projects.
+//Currency is the reporting currency the lines were converted to, if any, and Total their sum in it. Grouped by a
+//fiscal unit, Units adds the lines up per unit.
+type ExpenseExport struct {
+	From     time.Time
+	To       time.Time
+	GroupBy  ReportGrouping `json:",omitempty"`
+	Currency string         `json:",omitempty"`
+	Total    *float64       `json:",omitempty"`
+	Lines    []*ExportedExpense
+	Units    []*ExpenseUnit `json:",omitempty"`
+}
+
+//ExpenseUnit adds up the exported lines of a fiscal unit per currency, and in the reporting currency if any
+type ExpenseUnit struct {
+	FiscalUnit
+	Expenses map[string]float64
+	Total    *float64 `json:",omitempty"`
+}
+
+//ExportedExpense is a line of a claim, Converted is its amount in the reporting currency with the rate of its date
+type ExportedExpense struct {
+	LoginName string
+	ClaimID   uuid.UUID
+	*ExpenseLine
+	Converted *ConvertedAmount `json:",omitempty"`
+}
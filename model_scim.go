@@ -0,0 +1,94 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+)
+
+const (
+	SCIMSchemaUser           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaEnterpriseUser = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SCIMSchemaListResponse   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError          = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+//SCIMUser is the SCIM 2.0 representation of a user including the enterprise extension
+type SCIMUser struct {
+	Schemas    []string            `json:"schemas"`
+	ID         string              `json:"id,omitempty"`
+	ExternalID string              `json:"externalId,omitempty"`
+	UserName   string              `json:"userName"`
+	Name       *SCIMName           `json:"name,omitempty"`
+	Emails     []SCIMEmail         `json:"emails,omitempty"`
+	Title      string              `json:"title,omitempty"`
+	Active     *bool               `json:"active,omitempty"`
+	Enterprise *SCIMEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta       *SCIMMeta           `json:"meta,omitempty"`
+}
+
+type SCIMName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type SCIMEnterpriseUser struct {
+	Department string       `json:"department,omitempty"`
+	Manager    *SCIMManager `json:"manager,omitempty"`
+}
+
+type SCIMManager struct {
+	Value       string `json:"value,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    []*SCIMUser `json:"Resources"`
+}
+
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+const (
+	scimDefaultCount = 100
+	scimMaxCount     = 200
+)
+
+var SCIMInvalidFilter = &res.ResponseCode{Code: "invalidFilter", Message: "The filter is not supported", HttpStatus: http.StatusBadRequest}
+var SCIMInvalidPath = &res.ResponseCode{Code: "invalidPath", Message: "The patch path is not supported", HttpStatus: http.StatusBadRequest}
+var SCIMInvalidValue = &res.ResponseCode{Code: "invalidValue", Message: "A required value is missing or invalid", HttpStatus: http.StatusBadRequest}
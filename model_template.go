@@ -0,0 +1,66 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+)
+
+//TimesheetTemplate is a user's usual week. Lines holds the []TemplateLine it is made of.
+type TimesheetTemplate struct {
+	ID        uuid.UUID
+	LoginName string
+	Name      string
+	Lines     sql.JSONText
+	CreatedAt time.Time
+}
+
+//TemplateLine are the hours of a project and task on every weekday, Day6 and Day7 being the weekend
+type TemplateLine struct {
+	Project string
+	Task    string
+	Day1    float64
+	Day2    float64
+	Day3    float64
+	Day4    float64
+	Day5    float64
+	Day6    float64
+	Day7    float64
+}
+
+func (l TemplateLine) days() []float64 {
+	return []float64{l.Day1, l.Day2, l.Day3, l.Day4, l.Day5, l.Day6, l.Day7}
+}
+
+type PrefillSource string
+
+const (
+	PrefillFromTemplate PrefillSource = "Template"
+	//PrefillFromPreviousWeek repeats the calendar week before the one the period starts in
+	PrefillFromPreviousWeek PrefillSource = "PreviousWeek"
+	//PrefillFromPreviousMonth repeats the previous pay period: the first Monday of the new period gets the hours of
+	//the first Monday of the previous one and so on
+	PrefillFromPreviousMonth PrefillSource = "PreviousMonth"
+)
+
+var prefillSources = []string{string(PrefillFromTemplate), string(PrefillFromPreviousWeek), string(PrefillFromPreviousMonth)}
+
+//PrefillRequest creates the timesheet of the pay period containing PeriodStart, or the 1st of Month/Year, with the
+//hours of a template or of earlier timesheets. Holidays and days of approved leave are left empty.
+type PrefillRequest struct {
+	PeriodStart time.Time
+	Month       int
+	Year        int
+	Source      PrefillSource
+	TemplateID  uuid.UUID
+	Info        string
+}
+
+//maxTemplateLines keeps templates to a handful of projects
+const maxTemplateLines = 50
+
+var TemplateNotFound = &res.ResponseCode{Code: "TemplateNotFound", Message: "Template not found", HttpStatus: http.StatusNotFound}
@@ -0,0 +1,69 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//Timer is the running timer of a user, a user has at most one
+type Timer struct {
+	ID          uuid.UUID
+	LoginName   string
+	Project     string
+	Task        string
+	Description string
+	StartedAt   time.Time
+	//TimeZone decides the date the time is booked on, empty is the zone of the user
+	TimeZone string
+}
+
+type TimeEntrySource string
+
+const (
+	TimeEntryTimer TimeEntrySource = "Timer"
+	//TimeEntryAutoStopped entries come from timers that ran into TimerConfig.MaxDuration and were stopped for the user
+	TimeEntryAutoStopped TimeEntrySource = "AutoStopped"
+	//TimeEntryTemplate entries come from the lines of the template a timesheet was prefilled with
+	TimeEntryTemplate TimeEntrySource = "Template"
+)
+
+//TimeEntry is time spent on a project, booked on the timesheet of the pay period containing WorkDate. WorkDate is the
+//local date the timer started on, time after midnight counts for that day.
+type TimeEntry struct {
+	ID          uuid.UUID
+	LoginName   string
+	Project     string
+	Task        string
+	Description string
+	StartedAt   time.Time
+	EndedAt     time.Time
+	Hours       float64
+	WorkDate    time.Time
+	PeriodStart time.Time
+	Source      TimeEntrySource
+	//Capped is set when the timer ran longer than the maximum duration and the entry was cut to it
+	Capped bool
+	//Booked is set once the hours are on the timesheet. Entries the timesheet did not take, e.g. because it was
+	//approved, are booked again by the timer check.
+	Booked    bool
+	CreatedAt time.Time
+}
+
+//TimerSwitch is the result of switching to another task
+type TimerSwitch struct {
+	//Stopped is nil if no timer was running
+	Stopped *TimeEntry
+	Started *Timer
+}
+
+type TimerConfig struct {
+	//MaxDuration is the longest a timer may run, longer timers are treated as forgotten and cut to it
+	MaxDuration time.Duration
+}
+
+var TimerAlreadyRunning = &res.ResponseCode{Code: "TimerAlreadyRunning", Message: "Another timer is running, stop or switch it", HttpStatus: http.StatusConflict}
+var NoTimerRunning = &res.ResponseCode{Code: "NoTimerRunning", Message: "No timer is running", HttpStatus: http.StatusNotFound}
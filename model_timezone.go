@@ -0,0 +1,23 @@
+package timesheets
+
+import (
+	"time"
+)
+
+type TimeZoneSource string
+
+const (
+	TimeZoneOfUser         TimeZoneSource = "User"
+	TimeZoneOfOrganization TimeZoneSource = "Organization"
+	//TimeZoneDefault is UTC, used when neither the user nor the organization set a zone
+	TimeZoneDefault TimeZoneSource = "Default"
+)
+
+//TimeZoneSetting is the IANA time zone, e.g. Europe/Berlin, days and weeks of a user are counted in. An empty
+//LoginName sets the zone of the whole organization.
+type TimeZoneSetting struct {
+	LoginName string
+	TimeZone  string
+	Source    TimeZoneSource
+	UpdatedAt time.Time
+}
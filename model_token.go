@@ -0,0 +1,49 @@
+package user
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//APIToken is a personal access token used by scripts and integrations
+type APIToken struct {
+	ID         uuid.UUID
+	OrgID      uuid.UUID
+	LoginName  string
+	Name       string
+	Prefix     string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+//CreateAPIToken is the request to issue a new token
+type CreateAPIToken struct {
+	Name          string
+	Scopes        []string
+	ExpiresInDays int
+}
+
+//IssuedAPIToken is returned once when a token is created. Secret is never stored.
+type IssuedAPIToken struct {
+	APIToken
+	Secret string
+}
+
+const (
+	apiTokenPrefix        = "tsk_"
+	apiTokenMaxExpiryDays = 365
+)
+
+var TokenNotFound = &res.ResponseCode{Code: "TokenNotFound", Message: "API token not found", HttpStatus: http.StatusNotFound}
+var TokenInvalid = &res.ResponseCode{Code: "TokenInvalid", Message: "API token is invalid, expired or revoked", HttpStatus: http.StatusUnauthorized}
+
+var errTokenNotFound = errors.New("api token does not exist or is already revoked")
+var errTokenInvalid = errors.New("api token is unknown, expired or revoked")
@@ -0,0 +1,57 @@
+package timesheets
+
+import (
+	"net/http"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+)
+
+//WebhookSubscription receives the events of EventTypes, or all events when it is empty. Secret signs the
+//deliveries and is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID         uuid.UUID
+	URL        string
+	Secret     string `json:",omitempty"`
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "Pending"
+	DeliverySucceeded DeliveryStatus = "Succeeded"
+	DeliveryFailed    DeliveryStatus = "Failed"
+)
+
+//WebhookDelivery is one event sent to one subscription, with the outcome of its latest attempt
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      EventType
+	Payload        sql.JSONText
+	Status         DeliveryStatus
+	Attempts       int
+	ResponseStatus int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	URL            string `json:"-"`
+	Secret         string `json:"-"`
+}
+
+//WebhookConfig controls the retries, a failed attempt is retried after RetryBase, doubling every time
+type WebhookConfig struct {
+	Timeout     time.Duration
+	RetryBase   time.Duration
+	MaxAttempts int
+}
+
+var WebhookSubscriptionNotFound = &res.ResponseCode{Code: "WebhookSubscriptionNotFound", Message: "Webhook subscription not found", HttpStatus: http.StatusNotFound}
+var WebhookDeliveryNotFound = &res.ResponseCode{Code: "WebhookDeliveryNotFound", Message: "Webhook delivery not found", HttpStatus: http.StatusNotFound}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//Method tells how a principal was authenticated
+type Method string
+
+const (
+	MethodSession  Method = "Session"
+	MethodAPIToken Method = "APIToken"
+)
+
+//Scope limits what an authenticated principal is allowed to do
+type Scope string
+
+const (
+	ScopeTimesheetsRead  Scope = "timesheets:read"
+	ScopeTimesheetsWrite Scope = "timesheets:write"
+	ScopeApprove         Scope = "timesheets:approve"
+	ScopeExport          Scope = "timesheets:export"
+)
+
+//AllScopes are granted to interactive sessions and are the only scopes a token may request
+var AllScopes = []Scope{ScopeTimesheetsRead, ScopeTimesheetsWrite, ScopeApprove, ScopeExport}
+
+//Principal is the authenticated caller of a request
+type Principal struct {
+	LoginName string
+	OrgID     uuid.UUID
+	Method    Method
+	TokenID   string
+	Scopes    []Scope
+	Admin     bool
+}
+
+//HasScope reports whether the principal was granted the given scope
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//IsValidScope reports whether the given value is one of the known scopes
+func IsValidScope(value string) bool {
+	for _, s := range AllScopes {
+		if string(s) == value {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+//WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+//FromContext returns the principal stored in ctx, or nil when the request is anonymous
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+//ErrNoTenant is returned by data access that was attempted outside of any organization
+var ErrNoTenant = errors.New("no organization in context")
+
+type tenantKey struct{}
+
+//WithTenant scopes ctx to an organization for callers that are not a principal, such as
+//provisioning clients and background jobs.
+func WithTenant(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+//TenantFromContext returns the organization every query made with ctx must be limited to
+func TenantFromContext(ctx context.Context) (uuid.UUID, error) {
+	if orgID, ok := ctx.Value(tenantKey{}).(uuid.UUID); ok && orgID != uuid.Nil {
+		return orgID, nil
+	}
+	if p := FromContext(ctx); p != nil && p.OrgID != uuid.Nil {
+		return p.OrgID, nil
+	}
+	return uuid.Nil, ErrNoTenant
+}
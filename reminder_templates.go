@@ -0,0 +1,76 @@
+package timesheets
+
+import (
+	"bytes"
+	"sort"
+	"text/template"
+)
+
+//reminderTemplate renders the subject and body of a reminder from a reminderData
+type reminderTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+//reminderData is what the templates can refer to, fields that do not apply to a reminder kind are empty. Period
+//names the pay period of a timesheet.
+type reminderData struct {
+	Name              string
+	Month             int
+	Year              int
+	Period            string
+	Deadline          string
+	PendingTimesheets int
+	PendingLeave      int
+}
+
+func newReminderTemplate(subject, body string) reminderTemplate {
+	return reminderTemplate{subject: template.Must(template.New("subject").Parse(subject)),
+		body: template.Must(template.New("body").Parse(body))}
+}
+
+func (t reminderTemplate) render(data reminderData) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
+
+//reminderTemplates are keyed by locale, a locale is offered to users once it has a template for every kind
+var reminderTemplates = map[string]map[ReminderKind]reminderTemplate{
+	"en": {
+		ReminderMissingTimesheet: newReminderTemplate("Your timesheet for {{.Month}}/{{.Year}} is due",
+			"Hello {{.Name}},\n\nwe have not received your timesheet for {{.Month}}/{{.Year}} yet. "+
+				"Please submit it by {{.Deadline}}.\n"),
+		ReminderPendingApprovals: newReminderTemplate("Your team is waiting for your approval",
+			"Hello {{.Name}},\n\n{{.PendingTimesheets}} timesheet(s) and {{.PendingLeave}} leave request(s) "+
+				"of your team are waiting for your approval.\n"),
+		ReminderRejectedTimesheet: newReminderTemplate("Your timesheet for {{.Period}} was rejected",
+			"Hello {{.Name}},\n\nyour timesheet for {{.Period}} was rejected. "+
+				"Please correct and resubmit it.\n"),
+	},
+	"de": {
+		ReminderMissingTimesheet: newReminderTemplate("Ihr Stundenzettel für {{.Month}}/{{.Year}} ist fällig",
+			"Hallo {{.Name}},\n\nIhr Stundenzettel für {{.Month}}/{{.Year}} liegt uns noch nicht vor. "+
+				"Bitte reichen Sie ihn bis zum {{.Deadline}} ein.\n"),
+		ReminderPendingApprovals: newReminderTemplate("Ihr Team wartet auf Ihre Freigabe",
+			"Hallo {{.Name}},\n\n{{.PendingTimesheets}} Stundenzettel und {{.PendingLeave}} Urlaubsanträge "+
+				"Ihres Teams warten auf Ihre Freigabe.\n"),
+		ReminderRejectedTimesheet: newReminderTemplate("Ihr Stundenzettel für {{.Period}} wurde abgelehnt",
+			"Hallo {{.Name}},\n\nIhr Stundenzettel für {{.Period}} wurde abgelehnt. "+
+				"Bitte korrigieren Sie ihn und reichen Sie ihn erneut ein.\n"),
+	},
+}
+
+func reminderLocales() []string {
+	locales := []string{}
+	for locale := range reminderTemplates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
@@ -1,221 +1,478 @@
-package timesheets
-
-import (
-	"context"
-	"fmt"
-	"timesheet/commons/res"
-
-	"github.com/georgysavva/scany/pgxscan"
-	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
-	"github.com/rs/zerolog/log"
-)
-
-type Repository interface {
-	InsertTimesheet(ctx context.Context, ts *Timesheet) (string, error)
-
-	SelectTimesheetByLoginName(ctx context.Context, loginName string, month, year int) (bool, error)
-
-	UpdateTimesheetByGivenCriteria(ctx context.Context, ts *Timesheet, loginName string, month, year int) (string, error)
-
-	SelectAllTimesheetByLoginName(ctx context.Context, loginName string) ([]*GetAllTimesheets, error)
-
-	SelectTimesheetByWeek(ctx context.Context, loginName string, week, month, year int) (*GetAllTimesheets, error)
-
-	DeleteTimesheet(ctx context.Context, loginName string, month, year int) (string, error)
-
-	SelectTimesheetUUID(ctx context.Context, loginName string, month, year int) (string, error)
-
-	UpsertTimesheetNotes(ctx context.Context, notes *AddorUpdateNotes, uuids string) (string, error)
-
-	UpdateNotes(ctx context.Context, updnotes *AddorUpdateNotes) (string, error)
-}
-
-type repository struct {
-	db *pgxpool.Pool
-}
-
-func NewRepository(db *pgxpool.Pool) Repository {
-	return &repository{db: db}
-}
-
-func (r *repository) InsertTimesheet(ctx context.Context, ts *Timesheet) (string, error) {
-	var err error
-	var loginName string
-	insertTimesheetQry := `INSERT INTO public.timesheets
-						(id, status, placement, info, total_hours, "month", "year", 
-						week_hours_info, week_day_info, login_name)
-						VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
-
-	if _, err = r.db.Exec(ctx, insertTimesheetQry, ts.ID, ts.Status, ts.Placement,
-		ts.Info, ts.TotalHours, ts.Month, ts.Year, ts.WeekHrs, ts.WeekDay, ts.LoginName); err != nil {
-		log.Error().Err(err).Str("loginName", ts.LoginName).Msg("Error while inserting the timesheet data")
-		return "", err
-	}
-	loginName = ts.LoginName
-	return loginName, nil
-}
-
-func (repo *repository) SelectTimesheetByLoginName(ctx context.Context, loginName string, month, year int) (bool, error) {
-	var err error
-	var isExisting bool
-	var count int
-
-	selectQry := `select count(*) from timesheets t
-	 where t.login_name = $1 and t."month" = $2 and t."year" = $3;`
-	if err = pgxscan.Get(
-		ctx, repo.db, &count, selectQry, loginName, month, year,
-	); err != nil {
-		// Handle query or rows processing error.
-		if pgxscan.NotFound(err) {
-			//return nil, &res.AppError{ResponseCode: UserDoesNotExist, Cause: err}
-			//No error, but no user either
-			return false, nil
-		}
-		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
-	}
-
-	if count > 0 {
-		isExisting = true
-	}
-	return isExisting, nil
-}
-
-func (repo *repository) UpdateTimesheetByGivenCriteria(ctx context.Context, ts *Timesheet, loginName string, month, year int) (string, error) {
-
-	var err error
-	var res string
-	UpdateQry := `UPDATE public.timesheets
-	SET placement=$1, info=$2, total_hours=$3, week_hours_info=$4
-	WHERE login_name =$5 AND "year"=$6 AND "month"=$7;
-	`
-	if _, err = repo.db.Exec(ctx, UpdateQry, ts.Placement, ts.Info, ts.TotalHours, ts.WeekHrs,
-		loginName, year, month); err != nil {
-		return "", err
-	}
-
-	res = fmt.Sprintf("Updated Sucessfully with given criteria %s,%d,%d", loginName, month, year)
-	return res, nil
-
-}
-
-func (repo *repository) SelectAllTimesheetByLoginName(ctx context.Context, loginName string) ([]*GetAllTimesheets, error) {
-	var err error
-	var rows pgx.Rows
-	tsArr := []*GetAllTimesheets{}
-
-	selectQry := `select login_name,placement,info,"month","year",total_hours,status,week_hours_info,week_day_info from timesheets t 
-				  where t.login_name = $1;`
-
-	rows, err = repo.db.Query(ctx, selectQry, loginName)
-	if err != nil {
-		log.Error().Err(err).Str("loginName", loginName).Msg("Error while fetching the timesheet data")
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	for rows.Next() {
-		ts := &GetAllTimesheets{}
-		err = rows.Scan(&ts.LoginName, &ts.Placement, &ts.Info, &ts.Month, &ts.Year, &ts.TotalHours,
-			&ts.Status, &ts.WeekHrs, &ts.WeekDay)
-		if err != nil {
-			log.Error().Err(err).Str("loginName", loginName).Msg("Error while scaning each field from the timesheet")
-			return nil, err
-		}
-
-		tsArr = append(tsArr, ts)
-	}
-	log.Info().Str("loginName", loginName).Msg("Successfully return timesheet info")
-
-	return tsArr, nil
-}
-
-func (repo *repository) SelectTimesheetByWeek(ctx context.Context, loginName string, week, month, year int) (*GetAllTimesheets, error) {
-	var err error
-	ts := &GetAllTimesheets{}
-
-	selectQry := `select login_name,placement,info,"month","year",total_hours,status,week_hours_info,week_day_info from timesheets t 
-				  where t.login_name = $1
-				  and t."month" = $2
-				  and t."year" = $3;`
-
-	if err = pgxscan.Get(
-		ctx, repo.db, ts, selectQry, loginName, month, year,
-	); err != nil {
-		// Handle query or rows processing error.
-		if pgxscan.NotFound(err) {
-			//return nil, &res.AppError{ResponseCode: UserDoesNotExist, Cause: err}
-			//No error, but no user either
-			return nil, nil
-		}
-		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
-	}
-
-	return ts, nil
-}
-
-func (repo *repository) DeleteTimesheet(ctx context.Context, loginName string, month, year int) (string, error) {
-	var err error
-	var response string
-
-	deletQry := `delete from timesheets t
-				where t.login_name = $1
-				and t.month = $2
-				and t.year = $3`
-	if _, err = repo.db.Exec(ctx, deletQry, loginName, month, year); err != nil {
-		log.Error().Err(err).Str("loginName", loginName).Msg("Error while deleting the data")
-		return "", err
-	}
-	response = fmt.Sprintf("Successfully delete the record for the given criteria %s %d %d", loginName, month, year)
-
-	return response, nil
-}
-
-func (repo *repository) SelectTimesheetUUID(ctx context.Context, loginName string, month, year int) (string, error) {
-	var err error
-	var uuid string
-	selectQry := `select id from timesheets where login_name = $1 and month = $2 and year = $3;`
-
-	err = repo.db.QueryRow(ctx, selectQry, loginName, month, year).Scan(&uuid)
-	if err != nil {
-		return "", nil
-	}
-
-	log.Info().Msgf("UUID %s", uuid)
-	return uuid, nil
-}
-
-func (repo *repository) UpsertTimesheetNotes(ctx context.Context, notes *AddorUpdateNotes, uuids string) (string, error) {
-
-	var err error
-	var res string
-
-	if uuids == "" {
-		uuids = uuid.New().String()
-	}
-
-	upsertQry := `insert into timesheets(id,login_name,month,year,info) values($1,$2,$3,$4,$5)
-				 on conflict(id,month,year)
-				 do update set info = excluded.info`
-
-	if _, err = repo.db.Exec(ctx, upsertQry, uuids, notes.LoginName, notes.Month, notes.Year, notes.Info); err != nil {
-		return "", err
-	}
-
-	res = "Added notes successfully"
-	return res, nil
-}
-func (repo *repository) UpdateNotes(ctx context.Context, updnotes *AddorUpdateNotes) (string, error) {
-	var err error
-	var result string
-	UpdateQry := `update timesheets set info=$1 
-				where login_name=$2 and month=$3 and year=$4`
-	if _, err = repo.db.Exec(ctx, UpdateQry, updnotes.Info, updnotes.LoginName, updnotes.Month, updnotes.Year); err != nil {
-		return "", err
-	}
-
-	result = "Updated notes successfully"
-	return result, nil
-}
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type Repository interface {
+	//The mutations take the Event of the change and write it to the outbox in the same transaction, and so the
+	//effects fx of the change when they are given
+	InsertTimesheet(ctx context.Context, ts *Timesheet, e *Event, fx *TimesheetEffects) (string, error)
+
+	//Timesheets are keyed by the start of their pay period, periodStart below
+	SelectTimesheetByLoginName(ctx context.Context, loginName string, periodStart time.Time) (bool, error)
+
+	//UpdateTimesheetByGivenCriteria also submits a rejected timesheet again, e.Status tells the resulting status.
+	//Approved timesheets are locked, they are neither updated nor deleted but reported as TimesheetNotPending.
+	UpdateTimesheetByGivenCriteria(ctx context.Context, ts *Timesheet, loginName string, periodStart time.Time, e *Event, fx *TimesheetEffects) (string, error)
+
+	SelectAllTimesheetByLoginName(ctx context.Context, loginName string) ([]*GetAllTimesheets, error)
+
+	SelectTimesheetByPeriod(ctx context.Context, loginName string, periodStart time.Time) (*GetAllTimesheets, error)
+
+	//LockTimesheet runs fn in a transaction holding the lock of the user's timesheet of the period, also while there
+	//is no such timesheet yet. The methods of the repository called with the ctx given to fn take part in it.
+	LockTimesheet(ctx context.Context, loginName string, periodStart time.Time, fn func(ctx context.Context) error) error
+
+	//SelectPunchRollup returns the hours of the last punch rollup of workDate, 0 if there is none
+	SelectPunchRollup(ctx context.Context, loginName string, workDate time.Time) (float64, error)
+
+	//SelectWeekHoursBetween returns the periods and WeekHrs of the timesheets overlapping from..to, nothing else is set
+	SelectWeekHoursBetween(ctx context.Context, loginName string, from, to time.Time) ([]*GetAllTimesheets, error)
+
+	DeleteTimesheet(ctx context.Context, loginName string, periodStart time.Time, e *Event, fx *TimesheetEffects) (string, error)
+
+	//UpdateTimesheetStatus only changes the status while it is one of from, it reports whether it did
+	UpdateTimesheetStatus(ctx context.Context, loginName string, periodStart time.Time, status timesheetStatus, from []timesheetStatus, e *Event) (bool, error)
+}
+
+type repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &repository{db: db}
+}
+
+//tenantOf returns the organization every query must be limited to. There is no way to query timesheets
+//without one, which is what keeps organizations apart.
+func tenantOf(ctx context.Context) (uuid.UUID, error) {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+	return orgID, nil
+}
+
+//txKey holds the transaction of LockTimesheet in the context given to its function
+type txKey struct{}
+
+//beginTx begins a transaction, or a savepoint when ctx carries the transaction of LockTimesheet
+func beginTx(ctx context.Context, db *pgxpool.Pool) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx.Begin(ctx)
+	}
+	return db.Begin(ctx)
+}
+
+//querier reads in the transaction of LockTimesheet when ctx carries it, so locked rows are read as they are
+func querier(ctx context.Context, db *pgxpool.Pool) pgxscan.Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+//insertOutbox records e in the transaction of the change it is about, the relay publishes it after the commit.
+//Listeners on eventChannel, the live event streams of every instance, are notified on commit as well.
+func insertOutbox(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, e *Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	insertQry := `insert into event_outbox(id, org_id, event_type, payload, created_at, available_at) values($1, $2, $3, $4, $5, $5);`
+	if _, err = tx.Exec(ctx, insertQry, e.ID, orgID, e.Type, payload, e.OccurredAt); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	notification, err := json.Marshal(&eventNotification{OrgID: orgID, Event: e})
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, `select pg_notify($1, $2);`, eventChannel, string(notification)); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (r *repository) InsertTimesheet(ctx context.Context, ts *Timesheet, e *Event, fx *TimesheetEffects) (string, error) {
+	var err error
+	var loginName string
+	var orgID uuid.UUID
+	if orgID, err = tenantOf(ctx); err != nil {
+		return "", err
+	}
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	insertTimesheetQry := `INSERT INTO public.timesheets
+						(id, status, placement, total_hours, "month", "year", 
+						week_hours_info, week_day_info, login_name, org_id, absence_info, absence_hours,
+						regular_hours, overtime_hours, double_time_hours, payable_hours,
+						period_start, period_end, period_frequency)
+						VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19);`
+
+	if _, err = tx.Exec(ctx, insertTimesheetQry, ts.ID, ts.Status, ts.Placement,
+		ts.TotalHours, ts.Month, ts.Year, ts.WeekHrs, ts.WeekDay, ts.LoginName, orgID,
+		ts.Absences, ts.AbsenceHours, ts.RegularHours, ts.OvertimeHours, ts.DoubleTimeHours, ts.PayableHours,
+		ts.PeriodStart, ts.PeriodEnd, ts.PayFrequency); err != nil {
+		log.Error().Err(err).Str("loginName", ts.LoginName).Msg("Error while inserting the timesheet data")
+		return "", err
+	}
+	if err = writeEffects(ctx, tx, orgID, ts.LoginName, fx); err != nil {
+		return "", err
+	}
+	if err = insertOutbox(ctx, tx, orgID, e); err != nil {
+		return "", err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	loginName = ts.LoginName
+	return loginName, nil
+}
+
+func (repo *repository) SelectTimesheetByLoginName(ctx context.Context, loginName string, periodStart time.Time) (bool, error) {
+	var err error
+	var isExisting bool
+	var count int
+	var orgID uuid.UUID
+	if orgID, err = tenantOf(ctx); err != nil {
+		return false, err
+	}
+
+	selectQry := `select count(*) from timesheets t
+	 where t.login_name = $1 and t.period_start = $2 and t.org_id = $3;`
+	if err = pgxscan.Get(
+		ctx, querier(ctx, repo.db), &count, selectQry, loginName, periodStart, orgID,
+	); err != nil {
+		// Handle query or rows processing error.
+		if pgxscan.NotFound(err) {
+			//return nil, &res.AppError{ResponseCode: UserDoesNotExist, Cause: err}
+			//No error, but no user either
+			return false, nil
+		}
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	if count > 0 {
+		isExisting = true
+	}
+	return isExisting, nil
+}
+
+func (repo *repository) UpdateTimesheetByGivenCriteria(ctx context.Context, ts *Timesheet, loginName string, periodStart time.Time, e *Event, fx *TimesheetEffects) (string, error) {
+
+	var err error
+	var result string
+	var orgID uuid.UUID
+	if orgID, err = tenantOf(ctx); err != nil {
+		return "", err
+	}
+
+	tx, err := beginTx(ctx, repo.db)
+	if err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	UpdateQry := `UPDATE public.timesheets
+	SET placement=$1, total_hours=$2, week_hours_info=$3, absence_info=$7, absence_hours=$8,
+	regular_hours=$9, overtime_hours=$10, double_time_hours=$11, payable_hours=$12,
+	status = case when status = $13 then $14 else status end
+	WHERE login_name =$4 AND period_start=$5 AND org_id=$6 AND status <> $15
+	RETURNING status;
+	`
+	if err = tx.QueryRow(ctx, UpdateQry, ts.Placement, ts.TotalHours, ts.WeekHrs,
+		loginName, periodStart, orgID, ts.Absences, ts.AbsenceHours,
+		ts.RegularHours, ts.OvertimeHours, ts.DoubleTimeHours, ts.PayableHours,
+		timesheetStatusRejected, timesheetStatusSubmitted, timesheetStatusApproved).Scan(&e.Status); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", &res.AppError{ResponseCode: TimesheetNotPending,
+				Cause: errors.Errorf("timesheet of %s for the period from %s is approved", loginName, periodStart.Format(dateLayout))}
+		}
+		return "", err
+	}
+	if err = writeEffects(ctx, tx, orgID, loginName, fx); err != nil {
+		return "", err
+	}
+	if err = insertOutbox(ctx, tx, orgID, e); err != nil {
+		return "", err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	result = fmt.Sprintf("Updated Sucessfully with given criteria %s,%s", loginName, periodStart.Format(dateLayout))
+	return result, nil
+
+}
+
+func (repo *repository) SelectAllTimesheetByLoginName(ctx context.Context, loginName string) ([]*GetAllTimesheets, error) {
+	var err error
+	var rows pgx.Rows
+	var orgID uuid.UUID
+	tsArr := []*GetAllTimesheets{}
+	if orgID, err = tenantOf(ctx); err != nil {
+		return nil, err
+	}
+
+	selectQry := `select login_name,placement,` + latestNoteColumn + `,"month","year",total_hours,status,week_hours_info,week_day_info,
+				  absence_info,absence_hours,regular_hours,overtime_hours,double_time_hours,payable_hours,
+				  period_start,period_end,period_frequency from timesheets t 
+				  where t.login_name = $1 and t.org_id = $2
+				  order by t.period_start;`
+
+	rows, err = repo.db.Query(ctx, selectQry, loginName, orgID)
+	if err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while fetching the timesheet data")
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		ts := &GetAllTimesheets{}
+		err = rows.Scan(&ts.LoginName, &ts.Placement, &ts.Info, &ts.Month, &ts.Year, &ts.TotalHours,
+			&ts.Status, &ts.WeekHrs, &ts.WeekDay, &ts.Absences, &ts.AbsenceHours,
+			&ts.RegularHours, &ts.OvertimeHours, &ts.DoubleTimeHours, &ts.PayableHours,
+			&ts.PeriodStart, &ts.PeriodEnd, &ts.PayFrequency)
+		if err != nil {
+			log.Error().Err(err).Str("loginName", loginName).Msg("Error while scaning each field from the timesheet")
+			return nil, err
+		}
+
+		tsArr = append(tsArr, ts)
+	}
+	log.Info().Str("loginName", loginName).Msg("Successfully return timesheet info")
+
+	return tsArr, nil
+}
+
+func (repo *repository) SelectTimesheetByPeriod(ctx context.Context, loginName string, periodStart time.Time) (*GetAllTimesheets, error) {
+	var err error
+	var orgID uuid.UUID
+	ts := &GetAllTimesheets{}
+	if orgID, err = tenantOf(ctx); err != nil {
+		return nil, err
+	}
+
+	selectQry := `select login_name,placement,` + latestNoteColumn + `,"month","year",total_hours,status,week_hours_info,week_day_info,
+				  absence_info,absence_hours,regular_hours,overtime_hours,double_time_hours,payable_hours,
+				  period_start,period_end,period_frequency from timesheets t 
+				  where t.login_name = $1
+				  and t.period_start = $2
+				  and t.org_id = $3;`
+
+	if err = pgxscan.Get(
+		ctx, querier(ctx, repo.db), ts, selectQry, loginName, periodStart, orgID,
+	); err != nil {
+		// Handle query or rows processing error.
+		if pgxscan.NotFound(err) {
+			//return nil, &res.AppError{ResponseCode: UserDoesNotExist, Cause: err}
+			//No error, but no user either
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	return ts, nil
+}
+
+func (repo *repository) LockTimesheet(ctx context.Context, loginName string, periodStart time.Time, fn func(ctx context.Context) error) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	//The advisory lock stands in for the row of a timesheet that is not created yet
+	key := fmt.Sprintf("timesheet %s %s %s", orgID, loginName, periodStart.Format(dateLayout))
+	if _, err = tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext($1));`, key); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	lockQry := `select id from timesheets where org_id = $1 and login_name = $2 and period_start = $3 for update;`
+	if _, err = tx.Exec(ctx, lockQry, orgID, loginName, periodStart); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *repository) SelectPunchRollup(ctx context.Context, loginName string, workDate time.Time) (float64, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var hours float64
+	selectQry := `select hours from punch_rollups where org_id = $1 and login_name = $2 and work_date = $3;`
+	if err = pgxscan.Get(ctx, querier(ctx, repo.db), &hours, selectQry, orgID, loginName, workDate); err != nil {
+		if pgxscan.NotFound(err) {
+			return 0, nil
+		}
+		return 0, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return hours, nil
+}
+
+func (repo *repository) SelectWeekHoursBetween(ctx context.Context, loginName string, from, to time.Time) ([]*GetAllTimesheets, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tsArr := []*GetAllTimesheets{}
+	selectQry := `select period_start, period_end, period_frequency, week_hours_info from timesheets t
+				  where t.org_id = $1 and t.login_name = $2 and t.period_start <= $4 and t.period_end >= $3
+				  order by t.period_start;`
+	if err = pgxscan.Select(ctx, repo.db, &tsArr, selectQry, orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tsArr, nil
+}
+
+func (repo *repository) DeleteTimesheet(ctx context.Context, loginName string, periodStart time.Time, e *Event, fx *TimesheetEffects) (string, error) {
+	var err error
+	var response string
+	var orgID uuid.UUID
+	if orgID, err = tenantOf(ctx); err != nil {
+		return "", err
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	deletQry := `delete from timesheets t
+				where t.login_name = $1
+				and t.period_start = $2
+				and t.org_id = $3
+				and t.status <> $4
+				returning t.period_end`
+	var periodEnd time.Time
+	if err = tx.QueryRow(ctx, deletQry, loginName, periodStart, orgID, timesheetStatusApproved).Scan(&periodEnd); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", &res.AppError{ResponseCode: TimesheetNotPending,
+				Cause: errors.Errorf("timesheet of %s for the period from %s is approved", loginName, periodStart.Format(dateLayout))}
+		}
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while deleting the data")
+		return "", err
+	}
+	//The punched hours went with the timesheet, the next rollups book them again
+	if _, err = tx.Exec(ctx, `delete from punch_rollups where org_id = $1 and login_name = $2 and work_date between $3 and $4;`,
+		orgID, loginName, periodStart, periodEnd); err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if err = writeEffects(ctx, tx, orgID, loginName, fx); err != nil {
+		return "", err
+	}
+	if err = insertOutbox(ctx, tx, orgID, e); err != nil {
+		return "", err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	response = fmt.Sprintf("Successfully delete the record for the given criteria %s %s", loginName, periodStart.Format(dateLayout))
+
+	return response, nil
+}
+
+func (repo *repository) UpdateTimesheetStatus(ctx context.Context, loginName string, periodStart time.Time, status timesheetStatus, from []timesheetStatus, e *Event) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	fromStatus := []string{}
+	for _, f := range from {
+		fromStatus = append(fromStatus, string(f))
+	}
+	updateQry := `update timesheets set status = $1
+				where login_name = $2 and period_start = $3 and org_id = $4 and status = any($5)`
+	tag, err := tx.Exec(ctx, updateQry, status, loginName, periodStart, orgID, fromStatus)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err = insertOutbox(ctx, tx, orgID, e); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return true, nil
+}
+
+//writeEffects writes the effects of a timesheet change in its transaction
+func writeEffects(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, loginName string, fx *TimesheetEffects) error {
+	if fx == nil {
+		return nil
+	}
+	if fx.LeaveUsage != nil {
+		if err := writeLeaveUsage(ctx, tx, orgID, fx.LeaveUsage); err != nil {
+			return err
+		}
+	}
+	if err := replaceFindings(ctx, tx, orgID, loginName, fx.Period, fx.Findings); err != nil {
+		return err
+	}
+	if fx.Booking != nil && fx.Booking.Rollup != nil {
+		if err := writePunchRollup(ctx, tx, orgID, fx.Booking.Rollup); err != nil {
+			return err
+		}
+	}
+	if fx.Booking != nil && fx.Booking.TimeEntryID != nil {
+		if err := markTimeEntryBooked(ctx, tx, orgID, *fx.Booking.TimeEntryID); err != nil {
+			return err
+		}
+	}
+	if fx.Booking != nil {
+		for _, e := range fx.Booking.TimeEntries {
+			if err := insertTimeEntry(ctx, tx, orgID, e); err != nil {
+				return err
+			}
+		}
+	}
+	if fx.Note != nil {
+		return writeNote(ctx, tx, orgID, fx.Note)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type AttachmentRepository interface {
+	InsertAttachment(ctx context.Context, a *Attachment) error
+
+	SelectAttachments(ctx context.Context, loginName string, periodStart time.Time) ([]*Attachment, error)
+
+	//SelectAttachment returns nil if the user's timesheets have no such attachment
+	SelectAttachment(ctx context.Context, loginName string, id uuid.UUID) (*Attachment, error)
+
+	DeleteAttachment(ctx context.Context, loginName string, id uuid.UUID) (bool, error)
+}
+
+type attachmentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAttachmentRepository(db *pgxpool.Pool) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+const selectAttachmentColumns = `select id, login_name, period_start, file_name, content_type, size, checksum, storage_key,
+								 uploaded_by, created_at from attachments a`
+
+func (repo *attachmentRepository) InsertAttachment(ctx context.Context, a *Attachment) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into attachments(id, org_id, login_name, period_start, file_name, content_type, size, checksum,
+				  storage_key, uploaded_by, created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
+	if _, err = repo.db.Exec(ctx, insertQry, a.ID, orgID, a.LoginName, a.PeriodStart, a.FileName, a.ContentType, a.Size,
+		a.Checksum, a.StorageKey, a.UploadedBy, a.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", a.LoginName).Msg("Error while inserting the attachment")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *attachmentRepository) SelectAttachments(ctx context.Context, loginName string, periodStart time.Time) ([]*Attachment, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []*Attachment{}
+	if err = pgxscan.Select(ctx, repo.db, &attachments, selectAttachmentColumns+`
+		where a.org_id = $1 and a.login_name = $2 and a.period_start = $3 order by a.created_at;`,
+		orgID, loginName, periodStart); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return attachments, nil
+}
+
+func (repo *attachmentRepository) SelectAttachment(ctx context.Context, loginName string, id uuid.UUID) (*Attachment, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Attachment{}
+	if err = pgxscan.Get(ctx, repo.db, a, selectAttachmentColumns+` where a.org_id = $1 and a.login_name = $2 and a.id = $3;`,
+		orgID, loginName, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return a, nil
+}
+
+func (repo *attachmentRepository) DeleteAttachment(ctx context.Context, loginName string, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from attachments where org_id = $1 and login_name = $2 and id = $3;`, orgID, loginName, id)
+	if err != nil {
+		//expense_lines restricts the deletion of receipts
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return false, &res.AppError{ResponseCode: AttachmentInUse, Cause: err}
+		}
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
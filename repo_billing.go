@@ -0,0 +1,79 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type BillingRateRepository interface {
+	InsertRate(ctx context.Context, r *BillingRate) error
+
+	//SelectRates lists the rates of the organization newest first, by the day they take effect and then by when they
+	//were stored. It is limited to one project when project is not empty, deleted rates are only listed withDeleted.
+	SelectRates(ctx context.Context, project string, withDeleted bool) ([]*BillingRate, error)
+
+	//DeleteRate marks the rate deleted by deletedBy, it returns false if there is no such rate that is not deleted
+	DeleteRate(ctx context.Context, id uuid.UUID, deletedBy string) (bool, error)
+}
+
+type billingRateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBillingRateRepository(db *pgxpool.Pool) BillingRateRepository {
+	return &billingRateRepository{db: db}
+}
+
+func (repo *billingRateRepository) InsertRate(ctx context.Context, r *BillingRate) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into billing_rates(id, org_id, project, login_name, hourly_rate, currency, effective_from, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8);`
+	if _, err = repo.db.Exec(ctx, insertQry, r.ID, orgID, r.Project, r.LoginName, r.HourlyRate, r.Currency, r.EffectiveFrom,
+		r.CreatedAt); err != nil {
+		log.Error().Err(err).Str("project", r.Project).Msg("Error while storing the billing rate")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *billingRateRepository) SelectRates(ctx context.Context, project string, withDeleted bool) ([]*BillingRate, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := []*BillingRate{}
+	selectQry := `select id, project, login_name, hourly_rate, currency, effective_from, created_at, deleted_at, deleted_by
+				  from billing_rates r
+				  where r.org_id = $1 and ($2 = '' or lower(r.project) = lower($2)) and ($3 or r.deleted_at is null)
+				  order by r.effective_from desc, r.created_at desc, r.project, r.login_name;`
+	if err = pgxscan.Select(ctx, repo.db, &rates, selectQry, orgID, project, withDeleted); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return rates, nil
+}
+
+func (repo *billingRateRepository) DeleteRate(ctx context.Context, id uuid.UUID, deletedBy string) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deleteQry := `update billing_rates set deleted_at = now(), deleted_by = $3
+				  where org_id = $1 and id = $2 and deleted_at is null;`
+	tag, err := repo.db.Exec(ctx, deleteQry, orgID, id, deletedBy)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
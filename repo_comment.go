@@ -0,0 +1,216 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type CommentRepository interface {
+	//The mutations write e to the outbox in the same transaction
+	InsertComment(ctx context.Context, c *TimesheetComment, e *Event) error
+
+	//SelectComments lists the thread of a timesheet, oldest first
+	SelectComments(ctx context.Context, loginName string, periodStart time.Time) ([]*TimesheetComment, error)
+
+	//SelectComment returns nil if the user's timesheets have no such comment
+	SelectComment(ctx context.Context, loginName string, id uuid.UUID) (*TimesheetComment, error)
+
+	//UpdateComment and DeleteComment keep the former text as a revision. They return false if the comment is
+	//deleted or gone.
+	UpdateComment(ctx context.Context, c *TimesheetComment, editor string, e *Event) (bool, error)
+
+	DeleteComment(ctx context.Context, c *TimesheetComment, e *Event) (bool, error)
+
+	SelectRevisions(ctx context.Context, commentID uuid.UUID) ([]*CommentRevision, error)
+}
+
+type commentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommentRepository(db *pgxpool.Pool) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+const selectCommentColumns = `select id, login_name, period_start, author, body, anchor_date as date, entry_id, created_at,
+							  edited_at, deleted_at is not null as deleted, deleted_by from timesheet_comments c`
+
+//latestNoteColumn fills the Info of the timesheets t with the latest comment of their thread
+const latestNoteColumn = `coalesce((select c.body from timesheet_comments c
+						  where c.org_id = t.org_id and c.login_name = t.login_name and c.period_start = t.period_start
+						  and c.deleted_at is null order by c.created_at desc limit 1), '') as info`
+
+func (repo *commentRepository) InsertComment(ctx context.Context, c *TimesheetComment, e *Event) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	return repo.inTx(ctx, orgID, e, func(tx pgx.Tx) (bool, error) {
+		return true, insertComment(ctx, tx, orgID, c)
+	})
+}
+
+func (repo *commentRepository) SelectComments(ctx context.Context, loginName string, periodStart time.Time) ([]*TimesheetComment, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []*TimesheetComment{}
+	if err = pgxscan.Select(ctx, repo.db, &comments, selectCommentColumns+`
+		where c.org_id = $1 and c.login_name = $2 and c.period_start = $3 order by c.created_at;`,
+		orgID, loginName, periodStart); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return comments, nil
+}
+
+func (repo *commentRepository) SelectComment(ctx context.Context, loginName string, id uuid.UUID) (*TimesheetComment, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &TimesheetComment{}
+	if err = pgxscan.Get(ctx, repo.db, c, selectCommentColumns+` where c.org_id = $1 and c.login_name = $2 and c.id = $3;`,
+		orgID, loginName, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return c, nil
+}
+
+func (repo *commentRepository) UpdateComment(ctx context.Context, c *TimesheetComment, editor string, e *Event) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	updated := false
+	err = repo.inTx(ctx, orgID, e, func(tx pgx.Tx) (bool, error) {
+		updated, err = updateComment(ctx, tx, orgID, c, editor)
+		return updated, err
+	})
+	return updated, err
+}
+
+func (repo *commentRepository) DeleteComment(ctx context.Context, c *TimesheetComment, e *Event) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deleted := false
+	err = repo.inTx(ctx, orgID, e, func(tx pgx.Tx) (bool, error) {
+		if err := insertRevision(ctx, tx, orgID, c.ID, c.DeletedBy, e.OccurredAt); err != nil {
+			return false, err
+		}
+		tag, err := tx.Exec(ctx, `update timesheet_comments set body = '', deleted_at = $3, deleted_by = $4
+								  where org_id = $1 and id = $2 and deleted_at is null;`, orgID, c.ID, e.OccurredAt, c.DeletedBy)
+		if err != nil {
+			return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+		deleted = tag.RowsAffected() > 0
+		return deleted, nil
+	})
+	return deleted, err
+}
+
+func (repo *commentRepository) SelectRevisions(ctx context.Context, commentID uuid.UUID) ([]*CommentRevision, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []*CommentRevision{}
+	if err = pgxscan.Select(ctx, repo.db, &revisions, `select comment_id, body, edited_by, edited_at
+		from timesheet_comment_revisions r where r.org_id = $1 and r.comment_id = $2 order by r.edited_at;`,
+		orgID, commentID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return revisions, nil
+}
+
+func insertComment(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, c *TimesheetComment) error {
+	insertQry := `insert into timesheet_comments(id, org_id, login_name, period_start, author, body, anchor_date, entry_id,
+				  created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	if _, err := tx.Exec(ctx, insertQry, c.ID, orgID, c.LoginName, c.PeriodStart, c.Author, c.Body, c.Date,
+		c.EntryID, c.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", c.LoginName).Msg("Error while inserting the comment")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+//updateComment returns false if the comment is deleted or gone
+func updateComment(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, c *TimesheetComment, editor string) (bool, error) {
+	if err := insertRevision(ctx, tx, orgID, c.ID, editor, *c.EditedAt); err != nil {
+		return false, err
+	}
+	tag, err := tx.Exec(ctx, `update timesheet_comments set body = $3, edited_at = $4
+							  where org_id = $1 and id = $2 and deleted_at is null;`, orgID, c.ID, c.Body, c.EditedAt)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+//writeNote writes the note of a timesheet change in its transaction
+func writeNote(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, n *NoteChange) error {
+	if n.Edit {
+		updated, err := updateComment(ctx, tx, orgID, n.Comment, n.Editor)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return &res.AppError{ResponseCode: CommentNotFound, Cause: errors.Errorf("comment %s was deleted", n.Comment.ID)}
+		}
+	} else if err := insertComment(ctx, tx, orgID, n.Comment); err != nil {
+		return err
+	}
+	return insertOutbox(ctx, tx, orgID, n.Event)
+}
+
+//insertRevision keeps the current text of a comment that is not deleted
+func insertRevision(ctx context.Context, tx pgx.Tx, orgID, commentID uuid.UUID, editor string, at time.Time) error {
+	insertQry := `insert into timesheet_comment_revisions(comment_id, org_id, body, edited_by, edited_at)
+				  select id, org_id, body, $3, $4 from timesheet_comments
+				  where org_id = $1 and id = $2 and deleted_at is null;`
+	if _, err := tx.Exec(ctx, insertQry, orgID, commentID, editor, at); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+//inTx runs change and, if it reports a change, writes e to the outbox before committing
+func (repo *commentRepository) inTx(ctx context.Context, orgID uuid.UUID, e *Event, change func(tx pgx.Tx) (bool, error)) error {
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	changed, err := change(tx)
+	if err != nil || !changed {
+		return err
+	}
+	if err = insertOutbox(ctx, tx, orgID, e); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
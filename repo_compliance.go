@@ -0,0 +1,82 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type ComplianceRepository interface {
+	//SelectFindings lists the findings of a month, of one user if loginName is not empty
+	SelectFindings(ctx context.Context, loginName string, month, year int) ([]*ComplianceFinding, error)
+
+	//SelectFindingsBetween lists the findings dated from..to, of one user if loginName is not empty
+	SelectFindingsBetween(ctx context.Context, loginName string, from, to time.Time) ([]*ComplianceFinding, error)
+}
+
+type complianceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewComplianceRepository(db *pgxpool.Pool) ComplianceRepository {
+	return &complianceRepository{db: db}
+}
+
+func (repo *complianceRepository) SelectFindings(ctx context.Context, loginName string, month, year int) ([]*ComplianceFinding, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []*ComplianceFinding{}
+	selectQry := selectFindingColumns + ` where f.org_id = $1 and f."month" = $2 and f."year" = $3 and ($4 = '' or f.login_name = $4)
+				  order by f.login_name, f.week_info, f.day, f.rule;`
+	if err = pgxscan.Select(ctx, repo.db, &findings, selectQry, orgID, month, year, loginName); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return findings, nil
+}
+
+func (repo *complianceRepository) SelectFindingsBetween(ctx context.Context, loginName string, from, to time.Time) ([]*ComplianceFinding, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []*ComplianceFinding{}
+	selectQry := selectFindingColumns + ` where f.org_id = $1 and f.date between $2 and $3 and ($4 = '' or f.login_name = $4)
+				  order by f.login_name, f.date, f.rule;`
+	if err = pgxscan.Select(ctx, repo.db, &findings, selectQry, orgID, from, to, loginName); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return findings, nil
+}
+
+const selectFindingColumns = `select login_name, "month", "year", rule, severity, week_info, day, date, value,
+							  limit_value as "limit", message, created_at from compliance_findings f`
+
+//replaceFindings swaps the findings of a timesheet for the latest evaluation in the transaction of the timesheet change
+func replaceFindings(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, loginName string, period *PayPeriod, findings []ComplianceFinding) error {
+	deleteQry := `delete from compliance_findings where org_id = $1 and login_name = $2 and period_start = $3;`
+	if _, err := tx.Exec(ctx, deleteQry, orgID, loginName, period.Start); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	insertQry := `insert into compliance_findings(org_id, login_name, "month", "year", period_start, rule, severity, week_info,
+				  day, date, value, limit_value, message, created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`
+	for _, f := range findings {
+		if _, err := tx.Exec(ctx, insertQry, orgID, loginName, period.Month(), period.Year(), period.Start, f.Rule, f.Severity,
+			f.WeekInfo, f.Day, f.Date, f.Value, f.Limit, f.Message, f.CreatedAt); err != nil {
+			log.Error().Err(err).Str("loginName", loginName).Msg("Error while storing the compliance findings")
+			return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+	}
+	return nil
+}
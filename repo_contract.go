@@ -0,0 +1,73 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ContractRepository interface {
+	UpsertContract(ctx context.Context, c *Contract) error
+
+	SelectContract(ctx context.Context, loginName string) (*Contract, error)
+
+	SelectContracts(ctx context.Context) ([]*Contract, error)
+}
+
+type contractRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewContractRepository(db *pgxpool.Pool) ContractRepository {
+	return &contractRepository{db: db}
+}
+
+const selectContractColumns = `select login_name, contract_type, weekly_hours, percentage, updated_at from user_contracts c`
+
+func (repo *contractRepository) UpsertContract(ctx context.Context, c *Contract) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into user_contracts(org_id, login_name, contract_type, weekly_hours, percentage, updated_at)
+				  values($1, $2, $3, $4, $5, $6)
+				  on conflict (org_id, login_name) do update set contract_type = excluded.contract_type,
+				  weekly_hours = excluded.weekly_hours, percentage = excluded.percentage, updated_at = excluded.updated_at;`
+	if _, err = repo.db.Exec(ctx, upsertQry, orgID, c.LoginName, c.ContractType, c.WeeklyHours, c.Percentage, c.UpdatedAt); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *contractRepository) SelectContract(ctx context.Context, loginName string) (*Contract, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Contract{}
+	if err = pgxscan.Get(ctx, repo.db, c, selectContractColumns+` where c.org_id = $1 and c.login_name = $2;`, orgID, loginName); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return c, nil
+}
+
+func (repo *contractRepository) SelectContracts(ctx context.Context) ([]*Contract, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := []*Contract{}
+	if err = pgxscan.Select(ctx, repo.db, &contracts, selectContractColumns+` where c.org_id = $1 order by c.login_name;`, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return contracts, nil
+}
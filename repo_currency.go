@@ -0,0 +1,79 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type ExchangeRateRepository interface {
+	InsertRate(ctx context.Context, r *ExchangeRate) error
+
+	//SelectRates lists the rates of the organization newest first, by the day they take effect and then by when they
+	//were stored. It is limited to one currency when currency is not empty, deleted rates are only listed withDeleted.
+	SelectRates(ctx context.Context, currency string, withDeleted bool) ([]*ExchangeRate, error)
+
+	//DeleteRate marks the rate deleted by deletedBy, it returns false if there is no such rate that is not deleted
+	DeleteRate(ctx context.Context, id uuid.UUID, deletedBy string) (bool, error)
+}
+
+type exchangeRateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExchangeRateRepository(db *pgxpool.Pool) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+func (repo *exchangeRateRepository) InsertRate(ctx context.Context, r *ExchangeRate) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into exchange_rates(id, org_id, currency, quote_currency, rate, effective_from, source, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8);`
+	if _, err = repo.db.Exec(ctx, insertQry, r.ID, orgID, r.Currency, r.QuoteCurrency, r.Rate, r.EffectiveFrom, r.Source,
+		r.CreatedAt); err != nil {
+		log.Error().Err(err).Str("currency", r.Currency).Str("quoteCurrency", r.QuoteCurrency).Msg("Error while storing the exchange rate")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *exchangeRateRepository) SelectRates(ctx context.Context, currency string, withDeleted bool) ([]*ExchangeRate, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := []*ExchangeRate{}
+	selectQry := `select id, currency, quote_currency, rate, effective_from, source, created_at, deleted_at, deleted_by
+				  from exchange_rates r
+				  where r.org_id = $1 and ($2 = '' or $2 in (r.currency, r.quote_currency)) and ($3 or r.deleted_at is null)
+				  order by r.effective_from desc, r.created_at desc, r.currency, r.quote_currency;`
+	if err = pgxscan.Select(ctx, repo.db, &rates, selectQry, orgID, currency, withDeleted); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return rates, nil
+}
+
+func (repo *exchangeRateRepository) DeleteRate(ctx context.Context, id uuid.UUID, deletedBy string) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deleteQry := `update exchange_rates set deleted_at = now(), deleted_by = $3
+				  where org_id = $1 and id = $2 and deleted_at is null;`
+	tag, err := repo.db.Exec(ctx, deleteQry, orgID, id, deletedBy)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,194 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type DirectoryRepository interface {
+	InsertDirectoryEntry(ctx context.Context, e *DirectoryEntry) error
+
+	UpdateDirectoryEntry(ctx context.Context, e *DirectoryEntry) error
+
+	SelectDirectoryEntryByID(ctx context.Context, id uuid.UUID) (*DirectoryEntry, error)
+
+	SelectDirectoryEntryByLoginName(ctx context.Context, loginName string) (*DirectoryEntry, error)
+
+	SelectDirectoryEntries(ctx context.Context, filter DirectoryFilter, offset, limit int) ([]*DirectoryEntry, int, error)
+
+	UpdateDirectoryEntryActive(ctx context.Context, loginName string, active bool) error
+}
+
+type directoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDirectoryRepository(db *pgxpool.Pool) DirectoryRepository {
+	return &directoryRepository{db: db}
+}
+
+const selectDirectoryColumns = `select id, login_name, external_id, given_name, family_name, email, department,
+	job_title, manager_login_name, active, source, created_at, updated_at from user_directory d`
+
+func (repo *directoryRepository) InsertDirectoryEntry(ctx context.Context, e *DirectoryEntry) error {
+	insertQry := `insert into user_directory(id, org_id, login_name, external_id, given_name, family_name, email, department,
+				  job_title, manager_login_name, active, source, created_at, updated_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`
+
+	return repo.inTx(ctx, e.LoginName, func(tx pgx.Tx, orgID uuid.UUID) error {
+		if _, err := tx.Exec(ctx, insertQry, e.ID, orgID, e.LoginName, e.ExternalID, e.GivenName, e.FamilyName, e.Email,
+			e.Department, e.JobTitle, e.ManagerLoginName, e.Active, e.Source, e.CreatedAt, e.UpdatedAt); err != nil {
+			return err
+		}
+		return repo.syncUserPlacement(ctx, tx, orgID, e)
+	})
+}
+
+func (repo *directoryRepository) UpdateDirectoryEntry(ctx context.Context, e *DirectoryEntry) error {
+	updateQry := `update user_directory set external_id=$1, given_name=$2, family_name=$3, email=$4, department=$5,
+				  job_title=$6, manager_login_name=$7, active=$8, updated_at=$9
+				  where id=$10 and org_id=$11;`
+
+	return repo.inTx(ctx, e.LoginName, func(tx pgx.Tx, orgID uuid.UUID) error {
+		if _, err := tx.Exec(ctx, updateQry, e.ExternalID, e.GivenName, e.FamilyName, e.Email, e.Department,
+			e.JobTitle, e.ManagerLoginName, e.Active, e.UpdatedAt, e.ID, orgID); err != nil {
+			return err
+		}
+		return repo.syncUserPlacement(ctx, tx, orgID, e)
+	})
+}
+
+//syncUserPlacement keeps the users table in line with the directory so new timesheets get the right Placement
+func (repo *directoryRepository) syncUserPlacement(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, e *DirectoryEntry) error {
+	updateQry := `update users set department=$1, job_title=$2 where login_name=$3 and org_id=$4;`
+	_, err := tx.Exec(ctx, updateQry, e.Department, e.JobTitle, e.LoginName, orgID)
+	return err
+}
+
+func (repo *directoryRepository) inTx(ctx context.Context, loginName string, fn func(tx pgx.Tx, orgID uuid.UUID) error) error {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(tx, orgID); err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return &res.AppError{ResponseCode: UserAlreadyExists, Cause: err}
+		}
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while writing the directory entry")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *directoryRepository) SelectDirectoryEntryByID(ctx context.Context, id uuid.UUID) (*DirectoryEntry, error) {
+	return repo.selectOne(ctx, selectDirectoryColumns+` where d.org_id = $1 and d.id = $2;`, id)
+}
+
+func (repo *directoryRepository) SelectDirectoryEntryByLoginName(ctx context.Context, loginName string) (*DirectoryEntry, error) {
+	return repo.selectOne(ctx, selectDirectoryColumns+` where d.org_id = $1 and d.login_name = $2;`, loginName)
+}
+
+//selectOne runs qry with the caller's organization as the first argument
+func (repo *directoryRepository) selectOne(ctx context.Context, qry string, args ...interface{}) (*DirectoryEntry, error) {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	e := &DirectoryEntry{}
+	if err = pgxscan.Get(ctx, repo.db, e, qry, append([]interface{}{orgID}, args...)...); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return e, nil
+}
+
+func (repo *directoryRepository) SelectDirectoryEntries(ctx context.Context, filter DirectoryFilter, offset, limit int) ([]*DirectoryEntry, int, error) {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return nil, 0, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	where := []string{"d.org_id = $1"}
+	args := []interface{}{orgID}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.LoginName != "" {
+		add("d.login_name = upper($%d)", filter.LoginName)
+	}
+	if filter.ExternalID != "" {
+		add("d.external_id = $%d", filter.ExternalID)
+	}
+	if filter.Email != "" {
+		add("lower(d.email) = lower($%d)", filter.Email)
+	}
+	if filter.Department != "" {
+		add("d.department = $%d", filter.Department)
+	}
+	if filter.JobTitle != "" {
+		add("d.job_title = $%d", filter.JobTitle)
+	}
+	if filter.Source != "" {
+		add("d.source = $%d", filter.Source)
+	}
+	if filter.ManagerLoginName != "" {
+		add("d.manager_login_name = upper($%d)", filter.ManagerLoginName)
+	}
+	if filter.Active != nil {
+		add("d.active = $%d", *filter.Active)
+	}
+	whereClause := " where " + strings.Join(where, " and ")
+
+	var total int
+	if err = pgxscan.Get(ctx, repo.db, &total, `select count(*) from user_directory d`+whereClause, args...); err != nil {
+		return nil, 0, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	entries := []*DirectoryEntry{}
+	args = append(args, limit, offset)
+	selectQry := fmt.Sprintf("%s%s order by d.login_name limit $%d offset $%d;", selectDirectoryColumns, whereClause, len(args)-1, len(args))
+	if err = pgxscan.Select(ctx, repo.db, &entries, selectQry, args...); err != nil {
+		log.Error().Err(err).Msg("Error while listing the directory entries")
+		return nil, 0, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return entries, total, nil
+}
+
+func (repo *directoryRepository) UpdateDirectoryEntryActive(ctx context.Context, loginName string, active bool) error {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	updateQry := `update user_directory set active=$1, updated_at=now() where org_id=$2 and login_name=$3;`
+	if _, err = repo.db.Exec(ctx, updateQry, active, orgID, loginName); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
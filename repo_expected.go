@@ -0,0 +1,65 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ExpectedHoursRepository interface {
+	//SelectFills lists the active users with their timesheets of the days from..to, one user if loginName is not empty.
+	//The timesheets are those of every pay period overlapping the range, with their periods, WeekHrs and Absences.
+	SelectFills(ctx context.Context, loginName string, from, to time.Time) ([]*MonthFill, error)
+}
+
+type expectedHoursRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExpectedHoursRepository(db *pgxpool.Pool) ExpectedHoursRepository {
+	return &expectedHoursRepository{db: db}
+}
+
+func (repo *expectedHoursRepository) SelectFills(ctx context.Context, loginName string, from, to time.Time) ([]*MonthFill, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fills := []*MonthFill{}
+	selectQry := `select u.login_name, coalesce(u.department, '') as department,
+				  coalesce(c.weekly_hours, $3) as weekly_hours, coalesce(c.percentage, $4) as percentage
+				  from users u
+				  left join user_contracts c on c.org_id = u.org_id and c.login_name = u.login_name
+				  where u.org_id = $1 and ($2 = '' or u.login_name = $2)
+				  and not exists (select 1 from user_directory d where d.org_id = u.org_id and d.login_name = u.login_name and not d.active)
+				  order by u.login_name;`
+	if err = pgxscan.Select(ctx, repo.db, &fills, selectQry, orgID, loginName,
+		float64(defaultWeeklyHours), float64(defaultPercentage)); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	timesheets := []*GetAllTimesheets{}
+	timesheetQry := `select login_name, period_start, period_end, period_frequency, week_hours_info, absence_info from timesheets t
+					 where t.org_id = $1 and ($2 = '' or t.login_name = $2) and t.period_start <= $4 and t.period_end >= $3
+					 order by t.login_name, t.period_start;`
+	if err = pgxscan.Select(ctx, repo.db, &timesheets, timesheetQry, orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+
+	byLoginName := map[string]*MonthFill{}
+	for _, f := range fills {
+		f.Timesheets = []*GetAllTimesheets{}
+		byLoginName[f.LoginName] = f
+	}
+	for _, ts := range timesheets {
+		if f := byLoginName[ts.LoginName]; f != nil {
+			f.Timesheets = append(f.Timesheets, ts)
+		}
+	}
+	return fills, nil
+}
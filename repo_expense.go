@@ -0,0 +1,231 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type ExpenseRepository interface {
+	InsertClaim(ctx context.Context, c *ExpenseClaim) error
+
+	//UpdateClaim replaces the title and lines of a claim while its status is one of from and submits it again
+	UpdateClaim(ctx context.Context, c *ExpenseClaim, from []ExpenseStatus) (bool, error)
+
+	//SelectClaims lists the user's claims of the pay periods starting from..to
+	SelectClaims(ctx context.Context, loginName string, from, to time.Time) ([]*ExpenseClaim, error)
+
+	//SelectClaim returns nil if the user has no such claim
+	SelectClaim(ctx context.Context, loginName string, id uuid.UUID) (*ExpenseClaim, error)
+
+	DeleteClaim(ctx context.Context, loginName string, id uuid.UUID, from []ExpenseStatus) (bool, error)
+
+	//ReviewClaim sets the status, reviewer and note of a claim while its status is one of from
+	ReviewClaim(ctx context.Context, c *ExpenseClaim, from []ExpenseStatus) (bool, error)
+
+	//SelectApprovedClaims lists the approved claims of all users, or of loginName, whose pay period overlaps from..to
+	SelectApprovedClaims(ctx context.Context, loginName string, from, to time.Time) ([]*ExpenseClaim, error)
+}
+
+type expenseRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExpenseRepository(db *pgxpool.Pool) ExpenseRepository {
+	return &expenseRepository{db: db}
+}
+
+const selectClaimColumns = `select id, login_name, period_start, period_end, title, status, reviewed_by, reviewed_at,
+							review_note, created_at, updated_at from expense_claims c`
+
+func (repo *expenseRepository) InsertClaim(ctx context.Context, c *ExpenseClaim) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	insertQry := `insert into expense_claims(id, org_id, login_name, period_start, period_end, title, status, created_at,
+				  updated_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	if _, err = tx.Exec(ctx, insertQry, c.ID, orgID, c.LoginName, c.PeriodStart, c.PeriodEnd, c.Title, c.Status,
+		c.CreatedAt, c.UpdatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", c.LoginName).Msg("Error while inserting the expense claim")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if err = insertExpenseLines(ctx, tx, orgID, c); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *expenseRepository) UpdateClaim(ctx context.Context, c *ExpenseClaim, from []ExpenseStatus) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	updateQry := `update expense_claims set title = $4, status = $5, updated_at = $6, reviewed_by = '', reviewed_at = null,
+				  review_note = '' where org_id = $1 and login_name = $2 and id = $3 and status = any($7);`
+	tag, err := tx.Exec(ctx, updateQry, orgID, c.LoginName, c.ID, c.Title, c.Status, c.UpdatedAt, expenseStatuses(from))
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err = tx.Exec(ctx, `delete from expense_lines where org_id = $1 and claim_id = $2;`, orgID, c.ID); err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if err = insertExpenseLines(ctx, tx, orgID, c); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return true, nil
+}
+
+func insertExpenseLines(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, c *ExpenseClaim) error {
+	insertQry := `insert into expense_lines(id, org_id, claim_id, category, amount, currency, date, project, description,
+				  receipt_id) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+	for _, l := range c.Lines {
+		if _, err := tx.Exec(ctx, insertQry, l.ID, orgID, c.ID, l.Category, l.Amount, l.Currency, l.Date, l.Project,
+			l.Description, l.ReceiptID); err != nil {
+			log.Error().Err(err).Str("loginName", c.LoginName).Msg("Error while inserting the expense lines")
+			return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+	}
+	return nil
+}
+
+func (repo *expenseRepository) SelectClaims(ctx context.Context, loginName string, from, to time.Time) ([]*ExpenseClaim, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := []*ExpenseClaim{}
+	if err = pgxscan.Select(ctx, repo.db, &claims, selectClaimColumns+`
+		where c.org_id = $1 and c.login_name = $2 and c.period_start between $3 and $4 order by c.period_start, c.created_at;`,
+		orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return claims, repo.selectLines(ctx, orgID, claims)
+}
+
+func (repo *expenseRepository) SelectClaim(ctx context.Context, loginName string, id uuid.UUID) (*ExpenseClaim, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ExpenseClaim{}
+	if err = pgxscan.Get(ctx, repo.db, c, selectClaimColumns+` where c.org_id = $1 and c.login_name = $2 and c.id = $3;`,
+		orgID, loginName, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return c, repo.selectLines(ctx, orgID, []*ExpenseClaim{c})
+}
+
+func (repo *expenseRepository) DeleteClaim(ctx context.Context, loginName string, id uuid.UUID, from []ExpenseStatus) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	//The lines go with the claim through on delete cascade
+	tag, err := repo.db.Exec(ctx, `delete from expense_claims where org_id = $1 and login_name = $2 and id = $3 and status = any($4);`,
+		orgID, loginName, id, expenseStatuses(from))
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *expenseRepository) ReviewClaim(ctx context.Context, c *ExpenseClaim, from []ExpenseStatus) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	updateQry := `update expense_claims set status = $4, reviewed_by = $5, reviewed_at = $6, review_note = $7, updated_at = $6
+				  where org_id = $1 and login_name = $2 and id = $3 and status = any($8);`
+	tag, err := repo.db.Exec(ctx, updateQry, orgID, c.LoginName, c.ID, c.Status, c.ReviewedBy, c.ReviewedAt, c.ReviewNote,
+		expenseStatuses(from))
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *expenseRepository) SelectApprovedClaims(ctx context.Context, loginName string, from, to time.Time) ([]*ExpenseClaim, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := []*ExpenseClaim{}
+	if err = pgxscan.Select(ctx, repo.db, &claims, selectClaimColumns+`
+		where c.org_id = $1 and ($2 = '' or c.login_name = $2) and c.status = $3 and c.period_start <= $5 and c.period_end >= $4
+		order by c.login_name, c.period_start;`, orgID, loginName, ExpenseApproved, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return claims, repo.selectLines(ctx, orgID, claims)
+}
+
+//selectLines fills in the lines of the claims
+func (repo *expenseRepository) selectLines(ctx context.Context, orgID uuid.UUID, claims []*ExpenseClaim) error {
+	if len(claims) == 0 {
+		return nil
+	}
+	byID := map[uuid.UUID]*ExpenseClaim{}
+	ids := []uuid.UUID{}
+	for _, c := range claims {
+		c.Lines = []*ExpenseLine{}
+		byID[c.ID] = c
+		ids = append(ids, c.ID)
+	}
+
+	lines := []*ExpenseLine{}
+	selectQry := `select id, claim_id, category, amount, currency, date, project, description, receipt_id
+				  from expense_lines l where l.org_id = $1 and l.claim_id = any($2) order by l.date, l.id;`
+	if err := pgxscan.Select(ctx, repo.db, &lines, selectQry, orgID, ids); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	for _, l := range lines {
+		byID[l.ClaimID].Lines = append(byID[l.ClaimID].Lines, l)
+	}
+	return nil
+}
+
+func expenseStatuses(statuses []ExpenseStatus) []string {
+	values := make([]string, len(statuses))
+	for i, s := range statuses {
+		values[i] = string(s)
+	}
+	return values
+}
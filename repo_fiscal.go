@@ -0,0 +1,62 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type FiscalCalendarRepository interface {
+	UpsertFiscalCalendar(ctx context.Context, c *FiscalCalendar) error
+
+	//SelectFiscalCalendar returns nil when the organization has not set up a fiscal calendar
+	SelectFiscalCalendar(ctx context.Context) (*FiscalCalendar, error)
+}
+
+type fiscalCalendarRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFiscalCalendarRepository(db *pgxpool.Pool) FiscalCalendarRepository {
+	return &fiscalCalendarRepository{db: db}
+}
+
+func (repo *fiscalCalendarRepository) UpsertFiscalCalendar(ctx context.Context, c *FiscalCalendar) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into fiscal_calendars(org_id, start_month, start_weekday, pattern, name_by_end_year, updated_at)
+				  values($1, $2, $3, $4, $5, $6)
+				  on conflict (org_id)
+				  do update set start_month = excluded.start_month, start_weekday = excluded.start_weekday,
+				  pattern = excluded.pattern, name_by_end_year = excluded.name_by_end_year, updated_at = excluded.updated_at;`
+	if _, err = repo.db.Exec(ctx, upsertQry, orgID, c.StartMonth, int(c.StartWeekday), c.Pattern, c.NameByEndYear,
+		c.UpdatedAt); err != nil {
+		log.Error().Err(err).Msg("Error while storing the fiscal calendar")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *fiscalCalendarRepository) SelectFiscalCalendar(ctx context.Context) (*FiscalCalendar, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &FiscalCalendar{}
+	selectQry := `select start_month, start_weekday, pattern, name_by_end_year, updated_at from fiscal_calendars where org_id = $1;`
+	if err = pgxscan.Get(ctx, repo.db, c, selectQry, orgID); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return c, nil
+}
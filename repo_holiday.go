@@ -0,0 +1,229 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type HolidayRepository interface {
+	InsertCalendar(ctx context.Context, c *HolidayCalendar) error
+
+	SelectCalendars(ctx context.Context) ([]*HolidayCalendar, error)
+
+	SelectCalendar(ctx context.Context, id uuid.UUID) (*HolidayCalendar, error)
+
+	UpdateCalendar(ctx context.Context, c *HolidayCalendar) (bool, error)
+
+	DeleteCalendar(ctx context.Context, id uuid.UUID) (bool, error)
+
+	//UpsertHoliday keeps one holiday per calendar and date, a second one renames it
+	UpsertHoliday(ctx context.Context, h *Holiday) error
+
+	DeleteHoliday(ctx context.Context, calendarID, holidayID uuid.UUID) (bool, error)
+
+	SelectHolidays(ctx context.Context, calendarID uuid.UUID, from, to time.Time) ([]*Holiday, error)
+
+	UpsertAssignment(ctx context.Context, a *CalendarAssignment) error
+
+	DeleteAssignment(ctx context.Context, a *CalendarAssignment) (bool, error)
+
+	SelectAssignments(ctx context.Context, calendarID uuid.UUID) ([]*CalendarAssignment, error)
+
+	//SelectAssignedCalendarID returns the calendar that applies to the user, uuid.Nil if there is none
+	SelectAssignedCalendarID(ctx context.Context, loginName, department string) (uuid.UUID, error)
+}
+
+type holidayRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHolidayRepository(db *pgxpool.Pool) HolidayRepository {
+	return &holidayRepository{db: db}
+}
+
+func (repo *holidayRepository) InsertCalendar(ctx context.Context, c *HolidayCalendar) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into holiday_calendars(id, org_id, name, country, created_at) values($1, $2, $3, $4, $5);`
+	if _, err = repo.db.Exec(ctx, insertQry, c.ID, orgID, c.Name, c.Country, c.CreatedAt); err != nil {
+		log.Error().Err(err).Str("calendar", c.Name).Msg("Error while inserting the holiday calendar")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *holidayRepository) SelectCalendars(ctx context.Context) ([]*HolidayCalendar, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := []*HolidayCalendar{}
+	selectQry := `select id, name, country, created_at from holiday_calendars c where c.org_id = $1 order by c.name;`
+	if err = pgxscan.Select(ctx, repo.db, &calendars, selectQry, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return calendars, nil
+}
+
+func (repo *holidayRepository) SelectCalendar(ctx context.Context, id uuid.UUID) (*HolidayCalendar, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &HolidayCalendar{}
+	selectQry := `select id, name, country, created_at from holiday_calendars c where c.org_id = $1 and c.id = $2;`
+	if err = pgxscan.Get(ctx, repo.db, c, selectQry, orgID, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return c, nil
+}
+
+func (repo *holidayRepository) UpdateCalendar(ctx context.Context, c *HolidayCalendar) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	updateQry := `update holiday_calendars set name = $1, country = $2 where org_id = $3 and id = $4;`
+	tag, err := repo.db.Exec(ctx, updateQry, c.Name, c.Country, orgID, c.ID)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *holidayRepository) DeleteCalendar(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from holiday_calendars where org_id = $1 and id = $2;`, orgID, id)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *holidayRepository) UpsertHoliday(ctx context.Context, h *Holiday) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into holidays(id, org_id, calendar_id, holiday_date, name) values($1, $2, $3, $4, $5)
+				  on conflict (calendar_id, holiday_date) do update set name = excluded.name;`
+	if _, err = repo.db.Exec(ctx, upsertQry, h.ID, orgID, h.CalendarID, h.Date, h.Name); err != nil {
+		log.Error().Err(err).Str("holiday", h.Name).Msg("Error while writing the holiday")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *holidayRepository) DeleteHoliday(ctx context.Context, calendarID, holidayID uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deleteQry := `delete from holidays where org_id = $1 and calendar_id = $2 and id = $3;`
+	tag, err := repo.db.Exec(ctx, deleteQry, orgID, calendarID, holidayID)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *holidayRepository) SelectHolidays(ctx context.Context, calendarID uuid.UUID, from, to time.Time) ([]*Holiday, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := []*Holiday{}
+	selectQry := `select id, calendar_id, holiday_date as date, name from holidays h
+				  where h.org_id = $1 and h.calendar_id = $2 and h.holiday_date between $3 and $4
+				  order by h.holiday_date;`
+	if err = pgxscan.Select(ctx, repo.db, &holidays, selectQry, orgID, calendarID, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return holidays, nil
+}
+
+func (repo *holidayRepository) UpsertAssignment(ctx context.Context, a *CalendarAssignment) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into holiday_calendar_assignments(org_id, calendar_id, department, login_name) values($1, $2, $3, $4)
+				  on conflict (org_id, department, login_name) do update set calendar_id = excluded.calendar_id;`
+	if _, err = repo.db.Exec(ctx, upsertQry, orgID, a.CalendarID, a.Department, a.LoginName); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *holidayRepository) DeleteAssignment(ctx context.Context, a *CalendarAssignment) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deleteQry := `delete from holiday_calendar_assignments
+				  where org_id = $1 and calendar_id = $2 and department = $3 and login_name = $4;`
+	tag, err := repo.db.Exec(ctx, deleteQry, orgID, a.CalendarID, a.Department, a.LoginName)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *holidayRepository) SelectAssignments(ctx context.Context, calendarID uuid.UUID) ([]*CalendarAssignment, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := []*CalendarAssignment{}
+	selectQry := `select calendar_id, department, login_name from holiday_calendar_assignments a
+				  where a.org_id = $1 and a.calendar_id = $2 order by a.department, a.login_name;`
+	if err = pgxscan.Select(ctx, repo.db, &assignments, selectQry, orgID, calendarID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return assignments, nil
+}
+
+func (repo *holidayRepository) SelectAssignedCalendarID(ctx context.Context, loginName, department string) (uuid.UUID, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var calendarID uuid.UUID
+	selectQry := `select calendar_id from holiday_calendar_assignments a
+				  where a.org_id = $1 and (a.login_name = $2 or (a.login_name = '' and a.department in ($3, '')))
+				  order by a.login_name desc, a.department desc limit 1;`
+	if err = pgxscan.Get(ctx, repo.db, &calendarID, selectQry, orgID, loginName, department); err != nil {
+		if pgxscan.NotFound(err) {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return calendarID, nil
+}
@@ -0,0 +1,322 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+//LeaveUser is the part of a user the accrual job needs to pick the applicable policies
+type LeaveUser struct {
+	LoginName  string
+	Department string
+}
+
+type LeaveRepository interface {
+	InsertLeavePolicy(ctx context.Context, p *LeavePolicy) error
+
+	SelectLeavePolicies(ctx context.Context) ([]*LeavePolicy, error)
+
+	DeleteLeavePolicy(ctx context.Context, id uuid.UUID) (bool, error)
+
+	//SelectLeavePolicy returns the department specific policy for the type, falling back to the organization wide one
+	SelectLeavePolicy(ctx context.Context, absenceType AbsenceType, department string) (*LeavePolicy, error)
+
+	SelectLeaveUsers(ctx context.Context) ([]*LeaveUser, error)
+
+	//InsertLedgerEntryIfAbsent is used by accruals, which must never be applied twice for the same reference
+	InsertLedgerEntryIfAbsent(ctx context.Context, e *LedgerEntry) error
+
+	SelectLedger(ctx context.Context, loginName string, policyID uuid.UUID) ([]*LedgerEntry, error)
+
+	SelectBalance(ctx context.Context, loginName string, policyID uuid.UUID, before time.Time) (float64, error)
+
+	SelectPendingLeaveHours(ctx context.Context, loginName string, policyID uuid.UUID, absenceType AbsenceType) (float64, error)
+
+	InsertLeaveRequest(ctx context.Context, lr *LeaveRequest) error
+
+	SelectLeaveRequest(ctx context.Context, id uuid.UUID) (*LeaveRequest, error)
+
+	//SelectLeaveRequests filters by login names unless they are nil and by status unless it is empty
+	SelectLeaveRequests(ctx context.Context, loginNames []string, status LeaveRequestStatus) ([]*LeaveRequest, error)
+
+	//UpdateLeaveRequestStatus only changes the request while it still has the expected status
+	UpdateLeaveRequestStatus(ctx context.Context, lr *LeaveRequest, expected LeaveRequestStatus) (bool, error)
+
+	//SelectRecordedAbsences returns the absences of the user's timesheets whose period overlaps the given range
+	SelectRecordedAbsences(ctx context.Context, loginName string, from, to time.Time) ([]*RecordedAbsences, error)
+}
+
+type leaveRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLeaveRepository(db *pgxpool.Pool) LeaveRepository {
+	return &leaveRepository{db: db}
+}
+
+const selectLeavePolicyColumns = `select id, name, absence_type, department, accrual_method, accrual_hours,
+	carry_over_cap, created_at from leave_policies p`
+
+const selectLedgerColumns = `select id, login_name, policy_id, absence_type, entry_type, hours, effective_date,
+	reference, created_at from leave_ledger l`
+
+const selectLeaveRequestColumns = `select id, login_name, absence_type, start_date, end_date, hours, comment, status,
+	approver_login_name, decision_comment, decided_at, created_at from leave_requests r`
+
+func (repo *leaveRepository) InsertLeavePolicy(ctx context.Context, p *LeavePolicy) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into leave_policies(id, org_id, name, absence_type, department, accrual_method, accrual_hours,
+				  carry_over_cap, created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	if _, err = repo.db.Exec(ctx, insertQry, p.ID, orgID, p.Name, p.AbsenceType, p.Department, p.AccrualMethod,
+		p.AccrualHours, p.CarryOverCap, p.CreatedAt); err != nil {
+		log.Error().Err(err).Str("policy", p.Name).Msg("Error while inserting the leave policy")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *leaveRepository) SelectLeavePolicies(ctx context.Context) ([]*LeavePolicy, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []*LeavePolicy{}
+	if err = pgxscan.Select(ctx, repo.db, &policies, selectLeavePolicyColumns+` where p.org_id = $1 order by p.name;`, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return policies, nil
+}
+
+func (repo *leaveRepository) DeleteLeavePolicy(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from leave_policies where org_id = $1 and id = $2;`, orgID, id)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *leaveRepository) SelectLeavePolicy(ctx context.Context, absenceType AbsenceType, department string) (*LeavePolicy, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &LeavePolicy{}
+	selectQry := selectLeavePolicyColumns + ` where p.org_id = $1 and p.absence_type = $2 and p.department in ($3, '')
+				 order by p.department desc limit 1;`
+	if err = pgxscan.Get(ctx, repo.db, p, selectQry, orgID, absenceType, department); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return p, nil
+}
+
+func (repo *leaveRepository) SelectLeaveUsers(ctx context.Context) ([]*LeaveUser, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*LeaveUser{}
+	selectQry := `select u.login_name, coalesce(u.department, '') as department from users u
+				  where u.org_id = $1
+				  and not exists (select 1 from user_directory d where d.org_id = u.org_id and d.login_name = u.login_name and not d.active);`
+	if err = pgxscan.Select(ctx, repo.db, &users, selectQry, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return users, nil
+}
+
+func (repo *leaveRepository) InsertLedgerEntryIfAbsent(ctx context.Context, e *LedgerEntry) error {
+	return repo.writeLedgerEntry(ctx, e, `on conflict (org_id, login_name, policy_id, reference) do nothing`)
+}
+
+func (repo *leaveRepository) writeLedgerEntry(ctx context.Context, e *LedgerEntry, onConflict string) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into leave_ledger(id, org_id, login_name, policy_id, absence_type, entry_type, hours,
+				  effective_date, reference, created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ` + onConflict + `;`
+	if _, err = repo.db.Exec(ctx, insertQry, e.ID, orgID, e.LoginName, e.PolicyID, e.AbsenceType, e.EntryType, e.Hours,
+		e.EffectiveDate, e.Reference, e.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", e.LoginName).Str("reference", e.Reference).Msg("Error while writing the leave ledger")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *leaveRepository) SelectLedger(ctx context.Context, loginName string, policyID uuid.UUID) ([]*LedgerEntry, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*LedgerEntry{}
+	selectQry := selectLedgerColumns + ` where l.org_id = $1 and l.login_name = $2 and l.policy_id = $3
+				 order by l.effective_date, l.created_at;`
+	if err = pgxscan.Select(ctx, repo.db, &entries, selectQry, orgID, loginName, policyID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return entries, nil
+}
+
+func (repo *leaveRepository) SelectBalance(ctx context.Context, loginName string, policyID uuid.UUID, before time.Time) (float64, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var balance float64
+	selectQry := `select coalesce(sum(l.hours), 0) from leave_ledger l
+				  where l.org_id = $1 and l.login_name = $2 and l.policy_id = $3 and l.effective_date < $4;`
+	if err = pgxscan.Get(ctx, repo.db, &balance, selectQry, orgID, loginName, policyID, before); err != nil {
+		return 0, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return balance, nil
+}
+
+func (repo *leaveRepository) SelectPendingLeaveHours(ctx context.Context, loginName string, policyID uuid.UUID, absenceType AbsenceType) (float64, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	//A request stops being pending once the timesheet of its month has recorded the usage.
+	var hours float64
+	selectQry := `select coalesce(sum(r.hours), 0) from leave_requests r
+				  where r.org_id = $1 and r.login_name = $2 and r.absence_type = $3 and r.status in ($4, $5)
+				  and not exists (select 1 from leave_ledger l
+				  	where l.org_id = r.org_id and l.login_name = r.login_name and l.policy_id = $6
+				  	and l.reference = 'timesheet:' || to_char(r.start_date, 'YYYY-MM'));`
+	if err = pgxscan.Get(ctx, repo.db, &hours, selectQry, orgID, loginName, absenceType,
+		LeaveRequestPending, LeaveRequestApproved, policyID); err != nil {
+		return 0, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return hours, nil
+}
+
+func (repo *leaveRepository) InsertLeaveRequest(ctx context.Context, lr *LeaveRequest) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into leave_requests(id, org_id, login_name, absence_type, start_date, end_date, hours, comment,
+				  status, approver_login_name, decision_comment, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8, $9, '', '', $10);`
+	if _, err = repo.db.Exec(ctx, insertQry, lr.ID, orgID, lr.LoginName, lr.AbsenceType, lr.StartDate, lr.EndDate,
+		lr.Hours, lr.Comment, lr.Status, lr.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", lr.LoginName).Msg("Error while inserting the leave request")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *leaveRepository) SelectLeaveRequest(ctx context.Context, id uuid.UUID) (*LeaveRequest, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lr := &LeaveRequest{}
+	if err = pgxscan.Get(ctx, repo.db, lr, selectLeaveRequestColumns+` where r.org_id = $1 and r.id = $2;`, orgID, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return lr, nil
+}
+
+func (repo *leaveRepository) SelectLeaveRequests(ctx context.Context, loginNames []string, status LeaveRequestStatus) ([]*LeaveRequest, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []*LeaveRequest{}
+	selectQry := selectLeaveRequestColumns + ` where r.org_id = $1 and ($2::text[] is null or r.login_name = any($2)) and ($3 = '' or r.status = $3)
+				 order by r.start_date desc;`
+	if err = pgxscan.Select(ctx, repo.db, &requests, selectQry, orgID, loginNames, string(status)); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return requests, nil
+}
+
+func (repo *leaveRepository) UpdateLeaveRequestStatus(ctx context.Context, lr *LeaveRequest, expected LeaveRequestStatus) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	updateQry := `update leave_requests set status = $1, approver_login_name = $2, decision_comment = $3, decided_at = $4
+				  where org_id = $5 and id = $6 and status = $7;`
+	tag, err := repo.db.Exec(ctx, updateQry, lr.Status, lr.ApproverLoginName, lr.DecisionComment, lr.DecidedAt,
+		orgID, lr.ID, expected)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *leaveRepository) SelectRecordedAbsences(ctx context.Context, loginName string, from, to time.Time) ([]*RecordedAbsences, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := []*RecordedAbsences{}
+	selectQry := `select period_start, absence_info from timesheets t
+				  where t.org_id = $1 and t.login_name = $2 and t.period_start <= $4 and t.period_end >= $3
+				  and jsonb_array_length(t.absence_info) > 0;`
+	if err = pgxscan.Select(ctx, repo.db, &recorded, selectQry, orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return recorded, nil
+}
+
+//writeLeaveUsage replaces the usages of a timesheet in the transaction of the timesheet change. Usages follow the
+//timesheet they reference when it is updated.
+func writeLeaveUsage(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, u *LeaveUsage) error {
+	deleteQry := `delete from leave_ledger where org_id = $1 and login_name = $2 and policy_id = $3 and reference = $4;`
+	for _, policyID := range u.Cleared {
+		if _, err := tx.Exec(ctx, deleteQry, orgID, u.LoginName, policyID, u.Reference); err != nil {
+			return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+	}
+
+	upsertQry := `insert into leave_ledger(id, org_id, login_name, policy_id, absence_type, entry_type, hours,
+				  effective_date, reference, created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				  on conflict (org_id, login_name, policy_id, reference)
+				  do update set hours = excluded.hours, effective_date = excluded.effective_date;`
+	for _, e := range u.Entries {
+		if _, err := tx.Exec(ctx, upsertQry, e.ID, orgID, e.LoginName, e.PolicyID, e.AbsenceType, e.EntryType, e.Hours,
+			e.EffectiveDate, e.Reference, e.CreatedAt); err != nil {
+			log.Error().Err(err).Str("loginName", e.LoginName).Str("reference", e.Reference).Msg("Error while writing the leave ledger")
+			return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+	}
+	return nil
+}
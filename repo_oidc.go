@@ -0,0 +1,60 @@
+package user
+
+import (
+	"context"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+//OIDCIdentityRepository keeps which account an identity of a provider logs in to
+type OIDCIdentityRepository interface {
+	//SelectIdentity returns nil when the identity was not linked to an account yet
+	SelectIdentity(ctx context.Context, issuer, subject string) (*OIDCIdentity, error)
+
+	InsertIdentity(ctx context.Context, i *OIDCIdentity) error
+}
+
+type oidcIdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOIDCIdentityRepository(db *pgxpool.Pool) OIDCIdentityRepository {
+	return &oidcIdentityRepository{db: db}
+}
+
+func (repo *oidcIdentityRepository) SelectIdentity(ctx context.Context, issuer, subject string) (*OIDCIdentity, error) {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	i := &OIDCIdentity{}
+	selectQry := `select issuer, subject, login_name, created_at from oidc_identities i
+				  where i.org_id = $1 and i.issuer = $2 and i.subject = $3;`
+	if err = pgxscan.Get(ctx, repo.db, i, selectQry, orgID, issuer, subject); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return i, nil
+}
+
+func (repo *oidcIdentityRepository) InsertIdentity(ctx context.Context, i *OIDCIdentity) error {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	insertQry := `insert into oidc_identities(org_id, issuer, subject, login_name, created_at) values($1, $2, $3, $4, $5);`
+	if _, err = repo.db.Exec(ctx, insertQry, orgID, i.Issuer, i.Subject, i.LoginName, i.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", i.LoginName).Msg("Error while linking the single sign-on identity")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+//OrganizationRepository is the only repository that is not scoped to a tenant, as it is what resolves the tenant.
+type OrganizationRepository interface {
+	InsertOrganization(ctx context.Context, org *Organization) error
+
+	SelectOrganizations(ctx context.Context) ([]*Organization, error)
+
+	SelectOrganizationBySlug(ctx context.Context, slug string) (*Organization, error)
+
+	SelectOrganizationIDByLoginName(ctx context.Context, loginName string) (uuid.UUID, error)
+
+	//UpdateUserOrganization moves a user the user service just created in the default organization into the organization
+	//of ctx. A user of any other organization is never moved.
+	UpdateUserOrganization(ctx context.Context, loginName string) error
+}
+
+type organizationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOrganizationRepository(db *pgxpool.Pool) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (repo *organizationRepository) InsertOrganization(ctx context.Context, org *Organization) error {
+	insertQry := `insert into organizations(id, slug, name, created_at) values($1, $2, $3, $4);`
+
+	if _, err := repo.db.Exec(ctx, insertQry, org.ID, org.Slug, org.Name, org.CreatedAt); err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			return &res.AppError{ResponseCode: OrganizationAlreadyExists, Cause: err}
+		}
+		log.Error().Err(err).Str("slug", org.Slug).Msg("Error while inserting the organization")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *organizationRepository) SelectOrganizations(ctx context.Context) ([]*Organization, error) {
+	orgs := []*Organization{}
+	selectQry := `select id, slug, name, created_at from organizations o order by o.slug;`
+	if err := pgxscan.Select(ctx, repo.db, &orgs, selectQry); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return orgs, nil
+}
+
+func (repo *organizationRepository) SelectOrganizationBySlug(ctx context.Context, slug string) (*Organization, error) {
+	org := &Organization{}
+	selectQry := `select id, slug, name, created_at from organizations o where o.slug = $1;`
+	if err := pgxscan.Get(ctx, repo.db, org, selectQry, slug); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return org, nil
+}
+
+func (repo *organizationRepository) SelectOrganizationIDByLoginName(ctx context.Context, loginName string) (uuid.UUID, error) {
+	var orgID uuid.UUID
+	selectQry := `select org_id from users u where u.login_name = $1;`
+	if err := pgxscan.Get(ctx, repo.db, &orgID, selectQry, loginName); err != nil {
+		if pgxscan.NotFound(err) {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return orgID, nil
+}
+
+func (repo *organizationRepository) UpdateUserOrganization(ctx context.Context, loginName string) error {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	updateQry := `update users set org_id = $1 where login_name = $2 and org_id = $3;`
+	tag, err := repo.db.Exec(ctx, updateQry, orgID, loginName, DefaultOrganizationID)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return &res.AppError{ResponseCode: UserAlreadyExists,
+			Cause: fmt.Errorf("user %s is not a new user of the default organization", loginName)}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//OutboxRepository is read by the relay, the entries are written by the repositories whose changes they describe
+type OutboxRepository interface {
+	//ClaimOutbox takes up to limit unpublished entries that are available at now, oldest first, and makes them
+	//unavailable until leaseUntil so that the relays of other instances leave them alone
+	ClaimOutbox(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*OutboxEntry, error)
+
+	MarkPublished(ctx context.Context, id uuid.UUID, publishedAt time.Time) error
+
+	MarkFailed(ctx context.Context, e *OutboxEntry) error
+
+	PurgePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (repo *outboxRepository) ClaimOutbox(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*OutboxEntry, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*OutboxEntry{}
+	claimQry := `with due as (
+					select id from event_outbox
+					where org_id = $1 and published_at is null and available_at <= $2
+					order by created_at limit $4 for update skip locked
+				 )
+				 update event_outbox o set available_at = $3 from due where o.id = due.id
+				 returning o.id, o.event_type, o.payload, o.attempts, o.last_error, o.created_at, o.available_at, o.published_at;`
+	if err = pgxscan.Select(ctx, repo.db, &entries, claimQry, orgID, now, leaseUntil, limit); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return entries, nil
+}
+
+func (repo *outboxRepository) MarkPublished(ctx context.Context, id uuid.UUID, publishedAt time.Time) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = repo.db.Exec(ctx, `update event_outbox set published_at = $1 where id = $2 and org_id = $3;`,
+		publishedAt, id, orgID); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *outboxRepository) MarkFailed(ctx context.Context, e *OutboxEntry) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	updateQry := `update event_outbox set attempts = $1, last_error = $2, available_at = $3 where id = $4 and org_id = $5;`
+	if _, err = repo.db.Exec(ctx, updateQry, e.Attempts, e.LastError, e.AvailableAt, e.ID, orgID); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *outboxRepository) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from event_outbox where org_id = $1 and published_at < $2;`, orgID, before)
+	if err != nil {
+		return 0, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected(), nil
+}
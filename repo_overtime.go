@@ -0,0 +1,120 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type OvertimeRepository interface {
+	InsertOvertimeRule(ctx context.Context, rule *OvertimeRule) error
+
+	UpdateOvertimeRule(ctx context.Context, rule *OvertimeRule) (bool, error)
+
+	DeleteOvertimeRule(ctx context.Context, id uuid.UUID) (bool, error)
+
+	SelectOvertimeRules(ctx context.Context) ([]*OvertimeRule, error)
+
+	//SelectApplicableRule prefers a rule for both department and contract type, then contract type,
+	//then department and finally the default. It returns nil when the organization has no rule at all.
+	SelectApplicableRule(ctx context.Context, department, contractType string) (*OvertimeRule, error)
+}
+
+type overtimeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOvertimeRepository(db *pgxpool.Pool) OvertimeRepository {
+	return &overtimeRepository{db: db}
+}
+
+const selectOvertimeRuleColumns = `select id, name, department, contract_type, daily_overtime_after, daily_double_time_after,
+	weekly_overtime_after, weekend_multiplier, holiday_multiplier, overtime_multiplier, double_time_multiplier,
+	created_at from overtime_rules o`
+
+func (repo *overtimeRepository) InsertOvertimeRule(ctx context.Context, rule *OvertimeRule) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into overtime_rules(id, org_id, name, department, contract_type, daily_overtime_after,
+				  daily_double_time_after, weekly_overtime_after, weekend_multiplier, holiday_multiplier,
+				  overtime_multiplier, double_time_multiplier, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);`
+	if _, err = repo.db.Exec(ctx, insertQry, rule.ID, orgID, rule.Name, rule.Department, rule.ContractType,
+		rule.DailyOvertimeAfter, rule.DailyDoubleTimeAfter, rule.WeeklyOvertimeAfter, rule.WeekendMultiplier,
+		rule.HolidayMultiplier, rule.OvertimeMultiplier, rule.DoubleTimeMultiplier, rule.CreatedAt); err != nil {
+		log.Error().Err(err).Str("rule", rule.Name).Msg("Error while inserting the overtime rule")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *overtimeRepository) UpdateOvertimeRule(ctx context.Context, rule *OvertimeRule) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	updateQry := `update overtime_rules set name=$1, department=$2, contract_type=$3, daily_overtime_after=$4,
+				  daily_double_time_after=$5, weekly_overtime_after=$6, weekend_multiplier=$7, holiday_multiplier=$8,
+				  overtime_multiplier=$9, double_time_multiplier=$10
+				  where org_id=$11 and id=$12;`
+	tag, err := repo.db.Exec(ctx, updateQry, rule.Name, rule.Department, rule.ContractType, rule.DailyOvertimeAfter,
+		rule.DailyDoubleTimeAfter, rule.WeeklyOvertimeAfter, rule.WeekendMultiplier, rule.HolidayMultiplier,
+		rule.OvertimeMultiplier, rule.DoubleTimeMultiplier, orgID, rule.ID)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *overtimeRepository) DeleteOvertimeRule(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from overtime_rules where org_id = $1 and id = $2;`, orgID, id)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *overtimeRepository) SelectOvertimeRules(ctx context.Context) ([]*OvertimeRule, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []*OvertimeRule{}
+	if err = pgxscan.Select(ctx, repo.db, &rules, selectOvertimeRuleColumns+` where o.org_id = $1 order by o.name;`, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return rules, nil
+}
+
+func (repo *overtimeRepository) SelectApplicableRule(ctx context.Context, department, contractType string) (*OvertimeRule, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &OvertimeRule{}
+	selectQry := selectOvertimeRuleColumns + ` where o.org_id = $1 and o.department in ($2, '') and o.contract_type in ($3, '')
+				 order by o.contract_type desc, o.department desc limit 1;`
+	if err = pgxscan.Get(ctx, repo.db, rule, selectQry, orgID, department, contractType); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return rule, nil
+}
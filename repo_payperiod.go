@@ -0,0 +1,118 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type PayPeriodRepository interface {
+	//UpsertDefinition keeps one definition per department, a second one replaces it
+	UpsertDefinition(ctx context.Context, d *PayPeriodDefinition) error
+
+	SelectDefinitions(ctx context.Context) ([]*PayPeriodDefinition, error)
+
+	DeleteDefinition(ctx context.Context, id uuid.UUID) (bool, error)
+
+	//SelectApplicableDefinition prefers the department's definition over the default, nil if there is neither
+	SelectApplicableDefinition(ctx context.Context, department string) (*PayPeriodDefinition, error)
+
+	//SelectTimesheetPeriods lists the periods of the user's timesheets that overlap from..to
+	SelectTimesheetPeriods(ctx context.Context, loginName string, from, to time.Time) ([]*PayPeriod, error)
+}
+
+type payPeriodRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPayPeriodRepository(db *pgxpool.Pool) PayPeriodRepository {
+	return &payPeriodRepository{db: db}
+}
+
+const selectDefinitionColumns = `select id, name, frequency, anchor_date, department, created_at from pay_period_definitions p`
+
+func (repo *payPeriodRepository) UpsertDefinition(ctx context.Context, d *PayPeriodDefinition) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into pay_period_definitions(id, org_id, name, frequency, anchor_date, department, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7)
+				  on conflict (org_id, department)
+				  do update set name = excluded.name, frequency = excluded.frequency, anchor_date = excluded.anchor_date
+				  returning id, created_at;`
+	if err = repo.db.QueryRow(ctx, upsertQry, d.ID, orgID, d.Name, d.Frequency, d.AnchorDate, d.Department,
+		d.CreatedAt).Scan(&d.ID, &d.CreatedAt); err != nil {
+		log.Error().Err(err).Str("definition", d.Name).Msg("Error while storing the pay period definition")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *payPeriodRepository) SelectDefinitions(ctx context.Context) ([]*PayPeriodDefinition, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []*PayPeriodDefinition{}
+	if err = pgxscan.Select(ctx, repo.db, &definitions, selectDefinitionColumns+` where p.org_id = $1 order by p.department;`,
+		orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return definitions, nil
+}
+
+func (repo *payPeriodRepository) DeleteDefinition(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from pay_period_definitions where id = $1 and org_id = $2;`, id, orgID)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *payPeriodRepository) SelectApplicableDefinition(ctx context.Context, department string) (*PayPeriodDefinition, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &PayPeriodDefinition{}
+	selectQry := selectDefinitionColumns + ` where p.org_id = $1 and p.department in ($2, '')
+				 order by p.department desc limit 1;`
+	if err = pgxscan.Get(ctx, repo.db, d, selectQry, orgID, department); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return d, nil
+}
+
+func (repo *payPeriodRepository) SelectTimesheetPeriods(ctx context.Context, loginName string, from, to time.Time) ([]*PayPeriod, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := []*PayPeriod{}
+	selectQry := `select period_start as start, period_end as "end", period_frequency as frequency from timesheets
+				  where org_id = $1 and login_name = $2 and period_start <= $4 and period_end >= $3
+				  order by period_start;`
+	if err = pgxscan.Select(ctx, repo.db, &periods, selectQry, orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return periods, nil
+}
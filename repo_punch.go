@@ -0,0 +1,132 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type PunchRepository interface {
+	InsertPunch(ctx context.Context, p *Punch) error
+
+	UpdatePunch(ctx context.Context, p *Punch) error
+
+	SelectPunch(ctx context.Context, id uuid.UUID) (*Punch, error)
+
+	//SelectLastPunch returns the user's last punch before the given time that is not voided, nil if there is none
+	SelectLastPunch(ctx context.Context, loginName string, before time.Time) (*Punch, error)
+
+	//SelectPunches lists the punches of workDate in order, of all users if loginNames is nil
+	SelectPunches(ctx context.Context, loginNames []string, workDate time.Time) ([]*Punch, error)
+}
+
+type punchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPunchRepository(db *pgxpool.Pool) PunchRepository {
+	return &punchRepository{db: db}
+}
+
+const selectPunchColumns = `select id, login_name, kind, punched_at, time_zone, work_date, corrected_by, reason, voided,
+							created_at, updated_at from punches p`
+
+func (repo *punchRepository) InsertPunch(ctx context.Context, p *Punch) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into punches(id, org_id, login_name, kind, punched_at, time_zone, work_date, corrected_by, reason,
+				  voided, created_at, updated_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);`
+	if _, err = repo.db.Exec(ctx, insertQry, p.ID, orgID, p.LoginName, p.Kind, p.PunchedAt, p.TimeZone, p.WorkDate,
+		p.CorrectedBy, p.Reason, p.Voided, p.CreatedAt, p.UpdatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", p.LoginName).Msg("Error while inserting the punch")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *punchRepository) UpdatePunch(ctx context.Context, p *Punch) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	updateQry := `update punches set kind = $3, punched_at = $4, time_zone = $5, work_date = $6, corrected_by = $7,
+				  reason = $8, voided = $9, updated_at = $10
+				  where org_id = $1 and id = $2;`
+	if _, err = repo.db.Exec(ctx, updateQry, orgID, p.ID, p.Kind, p.PunchedAt, p.TimeZone, p.WorkDate, p.CorrectedBy,
+		p.Reason, p.Voided, p.UpdatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", p.LoginName).Msg("Error while updating the punch")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *punchRepository) SelectPunch(ctx context.Context, id uuid.UUID) (*Punch, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Punch{}
+	if err = pgxscan.Get(ctx, repo.db, p, selectPunchColumns+` where p.org_id = $1 and p.id = $2;`, orgID, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return p, nil
+}
+
+func (repo *punchRepository) SelectLastPunch(ctx context.Context, loginName string, before time.Time) (*Punch, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Punch{}
+	selectQry := selectPunchColumns + ` where p.org_id = $1 and p.login_name = $2 and p.punched_at < $3 and not p.voided
+				 order by p.punched_at desc limit 1;`
+	if err = pgxscan.Get(ctx, repo.db, p, selectQry, orgID, loginName, before); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return p, nil
+}
+
+func (repo *punchRepository) SelectPunches(ctx context.Context, loginNames []string, workDate time.Time) ([]*Punch, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	punches := []*Punch{}
+	selectQry := selectPunchColumns + ` where p.org_id = $1 and ($2::text[] is null or p.login_name = any($2)) and p.work_date = $3
+				 order by p.login_name, p.punched_at;`
+	if err = pgxscan.Select(ctx, repo.db, &punches, selectQry, orgID, loginNames, workDate); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return punches, nil
+}
+
+//writePunchRollup stores the hours of the rollup in the transaction of the timesheet change that booked them
+func writePunchRollup(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, r *PunchRollup) error {
+	upsertQry := `insert into punch_rollups(org_id, login_name, work_date, hours, updated_at) values($1, $2, $3, $4, now())
+				  on conflict (org_id, login_name, work_date) do update set hours = excluded.hours, updated_at = excluded.updated_at;`
+	if _, err := tx.Exec(ctx, upsertQry, orgID, r.LoginName, r.WorkDate, r.Hours); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
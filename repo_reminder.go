@@ -0,0 +1,151 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ReminderRepository interface {
+	UpsertPreferences(ctx context.Context, p *ReminderPreferences) error
+
+	SelectPreferences(ctx context.Context, loginName string) (*ReminderPreferences, error)
+
+	//SelectRecipients returns the active users among loginNames that have an email address
+	SelectRecipients(ctx context.Context, loginNames []string) ([]*ReminderRecipient, error)
+
+	SelectPendingApprovals(ctx context.Context) ([]*PendingApprovals, error)
+
+	SelectRejectedTimesheets(ctx context.Context) ([]*RejectedTimesheet, error)
+
+	//WasReminded tells whether the reminder with reference was already sent to loginName
+	WasReminded(ctx context.Context, loginName string, kind ReminderKind, reference string) (bool, error)
+
+	InsertReminderSent(ctx context.Context, loginName string, kind ReminderKind, reference string) error
+}
+
+type reminderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReminderRepository(db *pgxpool.Pool) ReminderRepository {
+	return &reminderRepository{db: db}
+}
+
+func (repo *reminderRepository) UpsertPreferences(ctx context.Context, p *ReminderPreferences) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into reminder_preferences(org_id, login_name, opt_out, locale, updated_at) values($1, $2, $3, $4, $5)
+				  on conflict (org_id, login_name) do update set opt_out = excluded.opt_out, locale = excluded.locale,
+				  updated_at = excluded.updated_at;`
+	if _, err = repo.db.Exec(ctx, upsertQry, orgID, p.LoginName, p.OptOut, p.Locale, p.UpdatedAt); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *reminderRepository) SelectPreferences(ctx context.Context, loginName string) (*ReminderPreferences, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ReminderPreferences{}
+	selectQry := `select login_name, opt_out, locale, updated_at from reminder_preferences where org_id = $1 and login_name = $2;`
+	if err = pgxscan.Get(ctx, repo.db, p, selectQry, orgID, loginName); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return p, nil
+}
+
+func (repo *reminderRepository) SelectRecipients(ctx context.Context, loginNames []string) ([]*ReminderRecipient, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := []*ReminderRecipient{}
+	selectQry := `select d.login_name, d.given_name, d.email, coalesce(p.opt_out, false) as opt_out,
+				  coalesce(p.locale, $3) as locale
+				  from user_directory d
+				  left join reminder_preferences p on p.org_id = d.org_id and p.login_name = d.login_name
+				  where d.org_id = $1 and d.active and d.email <> '' and d.login_name = any($2);`
+	if err = pgxscan.Select(ctx, repo.db, &recipients, selectQry, orgID, loginNames, defaultLocale); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return recipients, nil
+}
+
+func (repo *reminderRepository) SelectPendingApprovals(ctx context.Context) ([]*PendingApprovals, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*PendingApprovals{}
+	selectQry := `select d.manager_login_name as login_name, count(distinct t.id) as pending_timesheets,
+				  count(distinct l.id) as pending_leave
+				  from user_directory d
+				  left join timesheets t on t.org_id = d.org_id and t.login_name = d.login_name and t.status = any($2)
+				  left join leave_requests l on l.org_id = d.org_id and l.login_name = d.login_name and l.status = $3
+				  where d.org_id = $1 and d.active and d.manager_login_name <> ''
+				  group by d.manager_login_name
+				  having count(t.id) + count(l.id) > 0;`
+	if err = pgxscan.Select(ctx, repo.db, &pending, selectQry, orgID,
+		[]string{string(timesheetStatusSubmitted), string(timesheetStatusViewed)}, LeaveRequestPending); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return pending, nil
+}
+
+func (repo *reminderRepository) SelectRejectedTimesheets(ctx context.Context) ([]*RejectedTimesheet, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected := []*RejectedTimesheet{}
+	selectQry := `select login_name, period_start, period_end, period_frequency from timesheets where org_id = $1 and status = $2
+				  order by login_name, period_start;`
+	if err = pgxscan.Select(ctx, repo.db, &rejected, selectQry, orgID, timesheetStatusRejected); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return rejected, nil
+}
+
+func (repo *reminderRepository) WasReminded(ctx context.Context, loginName string, kind ReminderKind, reference string) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var sent bool
+	selectQry := `select exists(select 1 from reminders_sent where org_id = $1 and login_name = $2 and kind = $3 and reference = $4);`
+	if err = repo.db.QueryRow(ctx, selectQry, orgID, loginName, kind, reference).Scan(&sent); err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return sent, nil
+}
+
+func (repo *reminderRepository) InsertReminderSent(ctx context.Context, loginName string, kind ReminderKind, reference string) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into reminders_sent(org_id, login_name, kind, reference) values($1, $2, $3, $4)
+				  on conflict do nothing;`
+	if _, err = repo.db.Exec(ctx, insertQry, orgID, loginName, kind, reference); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ReportRepository interface {
+	//SelectTimesheetsBetween lists the timesheets whose pay period overlaps from..to, of one user if loginName is not empty
+	SelectTimesheetsBetween(ctx context.Context, loginName string, from, to time.Time) ([]*GetAllTimesheets, error)
+}
+
+type reportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReportRepository(db *pgxpool.Pool) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+func (repo *reportRepository) SelectTimesheetsBetween(ctx context.Context, loginName string, from, to time.Time) ([]*GetAllTimesheets, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := []*GetAllTimesheets{}
+	selectQry := `select login_name,placement,` + latestNoteColumn + `,"month","year",total_hours,status,week_hours_info,week_day_info,
+				  absence_info,absence_hours,regular_hours,overtime_hours,double_time_hours,payable_hours,
+				  period_start,period_end,period_frequency from timesheets t
+				  where t.org_id = $1 and ($2 = '' or t.login_name = $2) and t.period_start <= $4 and t.period_end >= $3
+				  order by t.login_name, t.period_start;`
+	if err = pgxscan.Select(ctx, repo.db, &sheets, selectQry, orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return sheets, nil
+}
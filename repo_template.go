@@ -0,0 +1,107 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type TemplateRepository interface {
+	InsertTemplate(ctx context.Context, t *TimesheetTemplate) error
+
+	UpdateTemplate(ctx context.Context, t *TimesheetTemplate) (bool, error)
+
+	SelectTemplates(ctx context.Context, loginName string) ([]*TimesheetTemplate, error)
+
+	//SelectTemplate returns nil if the user has no such template
+	SelectTemplate(ctx context.Context, loginName string, id uuid.UUID) (*TimesheetTemplate, error)
+
+	DeleteTemplate(ctx context.Context, loginName string, id uuid.UUID) (bool, error)
+}
+
+type templateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTemplateRepository(db *pgxpool.Pool) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+func (repo *templateRepository) InsertTemplate(ctx context.Context, t *TimesheetTemplate) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into timesheet_templates(id, org_id, login_name, name, lines, created_at) values($1, $2, $3, $4, $5, $6);`
+	if _, err = repo.db.Exec(ctx, insertQry, t.ID, orgID, t.LoginName, t.Name, t.Lines, t.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", t.LoginName).Msg("Error while inserting the template")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *templateRepository) UpdateTemplate(ctx context.Context, t *TimesheetTemplate) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	updateQry := `update timesheet_templates set name = $4, lines = $5 where org_id = $1 and login_name = $2 and id = $3;`
+	tag, err := repo.db.Exec(ctx, updateQry, orgID, t.LoginName, t.ID, t.Name, t.Lines)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *templateRepository) SelectTemplates(ctx context.Context, loginName string) ([]*TimesheetTemplate, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []*TimesheetTemplate{}
+	selectQry := `select id, login_name, name, lines, created_at from timesheet_templates
+				  where org_id = $1 and login_name = $2 order by name;`
+	if err = pgxscan.Select(ctx, repo.db, &templates, selectQry, orgID, loginName); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return templates, nil
+}
+
+func (repo *templateRepository) SelectTemplate(ctx context.Context, loginName string, id uuid.UUID) (*TimesheetTemplate, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TimesheetTemplate{}
+	selectQry := `select id, login_name, name, lines, created_at from timesheet_templates
+				  where org_id = $1 and login_name = $2 and id = $3;`
+	if err = pgxscan.Get(ctx, repo.db, t, selectQry, orgID, loginName, id); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return t, nil
+}
+
+func (repo *templateRepository) DeleteTemplate(ctx context.Context, loginName string, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from timesheet_templates where org_id = $1 and login_name = $2 and id = $3;`, orgID, loginName, id)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
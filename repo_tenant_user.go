@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+)
+
+//TenantUserRepository looks users up within the organization of the caller
+type TenantUserRepository interface {
+	//SelectUserByLoginName returns nil when the user does not exist or belongs to another organization
+	SelectUserByLoginName(ctx context.Context, loginName string) (*User, error)
+}
+
+//tenantRepository limits user lookups to the organization of the caller. The inner repository is not embedded so
+//none of its unscoped methods can be reached through the wrapper.
+type tenantRepository struct {
+	inner   Repository
+	orgRepo OrganizationRepository
+}
+
+//NewTenantRepository wraps the user repository so lookups can not cross organizations
+func NewTenantRepository(inner Repository, orgRepo OrganizationRepository) TenantUserRepository {
+	return &tenantRepository{inner: inner, orgRepo: orgRepo}
+}
+
+func (repo *tenantRepository) SelectUserByLoginName(ctx context.Context, loginName string) (*User, error) {
+	tenant, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	orgID, err := repo.orgRepo.SelectOrganizationIDByLoginName(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if orgID != tenant {
+		return nil, nil
+	}
+	return repo.inner.SelectUserByLoginName(ctx, loginName)
+}
@@ -0,0 +1,202 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type TimerRepository interface {
+	//InsertTimer fails with TimerAlreadyRunning if the user has a timer
+	InsertTimer(ctx context.Context, t *Timer) error
+
+	//SelectTimer returns the user's running timer, nil if there is none
+	SelectTimer(ctx context.Context, loginName string) (*Timer, error)
+
+	SelectTimersStartedBefore(ctx context.Context, before time.Time) ([]*Timer, error)
+
+	//StopTimer removes the timer and stores its entry in one transaction, then starts next if it is not nil.
+	//It returns false if the timer was stopped in the meantime.
+	StopTimer(ctx context.Context, timerID uuid.UUID, e *TimeEntry, next *Timer) (bool, error)
+
+	//SelectTimeEntries lists the user's entries worked from..to
+	SelectTimeEntries(ctx context.Context, loginName string, from, to time.Time) ([]*TimeEntry, error)
+
+	//SelectUnbookedEntries lists the entries whose hours are not on the timesheet yet, oldest first
+	SelectUnbookedEntries(ctx context.Context) ([]*TimeEntry, error)
+
+	//SelectProjectEntries lists the entries of all users worked on project from..to, the project in any case
+	SelectProjectEntries(ctx context.Context, project string, from, to time.Time) ([]*TimeEntry, error)
+}
+
+type timerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTimerRepository(db *pgxpool.Pool) TimerRepository {
+	return &timerRepository{db: db}
+}
+
+const selectTimerColumns = `select id, login_name, project, task, description, started_at, time_zone from timers t`
+
+const selectTimeEntryColumns = `select id, login_name, project, task, description, started_at, ended_at, hours, work_date,
+								period_start, source, capped, booked, created_at from time_entries e`
+
+const insertTimerQry = `insert into timers(id, org_id, login_name, project, task, description, started_at, time_zone)
+						values($1, $2, $3, $4, $5, $6, $7, $8);`
+
+func (repo *timerRepository) InsertTimer(ctx context.Context, t *Timer) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = repo.db.Exec(ctx, insertTimerQry, t.ID, orgID, t.LoginName, t.Project, t.Task, t.Description,
+		t.StartedAt, t.TimeZone); err != nil {
+		return timerInsertError(t, err)
+	}
+	return nil
+}
+
+func timerInsertError(t *Timer, err error) error {
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+		return &res.AppError{ResponseCode: TimerAlreadyRunning, Cause: err}
+	}
+	log.Error().Err(err).Str("loginName", t.LoginName).Msg("Error while starting the timer")
+	return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+}
+
+func (repo *timerRepository) SelectTimer(ctx context.Context, loginName string) (*Timer, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Timer{}
+	if err = pgxscan.Get(ctx, repo.db, t, selectTimerColumns+` where t.org_id = $1 and t.login_name = $2;`, orgID, loginName); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return t, nil
+}
+
+func (repo *timerRepository) SelectTimersStartedBefore(ctx context.Context, before time.Time) ([]*Timer, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timers := []*Timer{}
+	if err = pgxscan.Select(ctx, repo.db, &timers, selectTimerColumns+` where t.org_id = $1 and t.started_at < $2;`, orgID, before); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return timers, nil
+}
+
+func (repo *timerRepository) StopTimer(ctx context.Context, timerID uuid.UUID, e *TimeEntry, next *Timer) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `delete from timers where org_id = $1 and id = $2;`, orgID, timerID)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err = insertTimeEntry(ctx, tx, orgID, e); err != nil {
+		return false, err
+	}
+	if next != nil {
+		if _, err = tx.Exec(ctx, insertTimerQry, next.ID, orgID, next.LoginName, next.Project, next.Task, next.Description,
+			next.StartedAt, next.TimeZone); err != nil {
+			return false, timerInsertError(next, err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return true, nil
+}
+
+func insertTimeEntry(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, e *TimeEntry) error {
+	insertQry := `insert into time_entries(id, org_id, login_name, project, task, description, started_at, ended_at, hours,
+				  work_date, period_start, source, capped, booked, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);`
+	if _, err := tx.Exec(ctx, insertQry, e.ID, orgID, e.LoginName, e.Project, e.Task, e.Description, e.StartedAt,
+		e.EndedAt, e.Hours, e.WorkDate, e.PeriodStart, e.Source, e.Capped, e.Booked, e.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", e.LoginName).Msg("Error while inserting the time entry")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *timerRepository) SelectTimeEntries(ctx context.Context, loginName string, from, to time.Time) ([]*TimeEntry, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*TimeEntry{}
+	selectQry := selectTimeEntryColumns + ` where e.org_id = $1 and e.login_name = $2 and e.work_date between $3 and $4
+				  order by e.started_at;`
+	if err = pgxscan.Select(ctx, repo.db, &entries, selectQry, orgID, loginName, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return entries, nil
+}
+
+func (repo *timerRepository) SelectUnbookedEntries(ctx context.Context) ([]*TimeEntry, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*TimeEntry{}
+	selectQry := selectTimeEntryColumns + ` where e.org_id = $1 and not e.booked order by e.created_at;`
+	if err = pgxscan.Select(ctx, repo.db, &entries, selectQry, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return entries, nil
+}
+
+func (repo *timerRepository) SelectProjectEntries(ctx context.Context, project string, from, to time.Time) ([]*TimeEntry, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*TimeEntry{}
+	selectQry := selectTimeEntryColumns + ` where e.org_id = $1 and lower(e.project) = lower($2) and e.work_date between $3 and $4
+				  order by e.work_date, e.login_name, e.started_at;`
+	if err = pgxscan.Select(ctx, repo.db, &entries, selectQry, orgID, project, from, to); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return entries, nil
+}
+
+//markTimeEntryBooked marks the entry booked in the transaction of the timesheet change that booked its hours
+func markTimeEntryBooked(ctx context.Context, tx pgx.Tx, orgID uuid.UUID, id uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `update time_entries set booked = true where org_id = $1 and id = $2;`, orgID, id); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
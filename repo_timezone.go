@@ -0,0 +1,75 @@
+package timesheets
+
+import (
+	"context"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type TimeZoneRepository interface {
+	UpsertTimeZone(ctx context.Context, s *TimeZoneSetting) error
+
+	DeleteTimeZone(ctx context.Context, loginName string) error
+
+	//SelectTimeZone returns the zone of the user, else the one of the organization, nil if neither is set
+	SelectTimeZone(ctx context.Context, loginName string) (*TimeZoneSetting, error)
+}
+
+type timeZoneRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTimeZoneRepository(db *pgxpool.Pool) TimeZoneRepository {
+	return &timeZoneRepository{db: db}
+}
+
+func (repo *timeZoneRepository) UpsertTimeZone(ctx context.Context, s *TimeZoneSetting) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	upsertQry := `insert into time_zones(org_id, login_name, time_zone, updated_at) values($1, $2, $3, $4)
+				  on conflict (org_id, login_name)
+				  do update set time_zone = excluded.time_zone, updated_at = excluded.updated_at;`
+	if _, err = repo.db.Exec(ctx, upsertQry, orgID, s.LoginName, s.TimeZone, s.UpdatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", s.LoginName).Msg("Error while storing the time zone")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *timeZoneRepository) DeleteTimeZone(ctx context.Context, loginName string) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = repo.db.Exec(ctx, `delete from time_zones where org_id = $1 and login_name = $2;`, orgID, loginName); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *timeZoneRepository) SelectTimeZone(ctx context.Context, loginName string) (*TimeZoneSetting, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TimeZoneSetting{}
+	selectQry := `select login_name, time_zone, updated_at from time_zones z
+				  where z.org_id = $1 and z.login_name in ($2, '')
+				  order by z.login_name desc limit 1;`
+	if err = pgxscan.Get(ctx, repo.db, s, selectQry, orgID, loginName); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return s, nil
+}
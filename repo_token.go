@@ -0,0 +1,127 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+type TokenRepository interface {
+	InsertAPIToken(ctx context.Context, t *APIToken, secretHash string) error
+
+	SelectAPITokensByLoginName(ctx context.Context, loginName string) ([]*APIToken, error)
+
+	SelectAPITokenByHash(ctx context.Context, secretHash string) (*APIToken, error)
+
+	RevokeAPIToken(ctx context.Context, loginName string, id uuid.UUID) (bool, error)
+
+	RevokeAllAPITokens(ctx context.Context, loginName string) error
+
+	UpdateAPITokenLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type tokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRepository(db *pgxpool.Pool) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+const selectAPITokenColumns = `select id, org_id, login_name, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at from api_tokens t`
+
+func (repo *tokenRepository) InsertAPIToken(ctx context.Context, t *APIToken, secretHash string) error {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+	t.OrgID = orgID
+
+	insertQry := `insert into api_tokens(id, org_id, login_name, name, prefix, secret_hash, scopes, expires_at, created_at)
+				  values($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+
+	if _, err = repo.db.Exec(ctx, insertQry, t.ID, t.OrgID, t.LoginName, t.Name, t.Prefix, secretHash,
+		t.Scopes, t.ExpiresAt, t.CreatedAt); err != nil {
+		log.Error().Err(err).Str("loginName", t.LoginName).Msg("Error while inserting the api token")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *tokenRepository) SelectAPITokensByLoginName(ctx context.Context, loginName string) ([]*APIToken, error) {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+	tokens := []*APIToken{}
+
+	selectQry := selectAPITokenColumns + ` where t.org_id = $1 and t.login_name = $2 order by t.created_at desc;`
+	if err = pgxscan.Select(ctx, repo.db, &tokens, selectQry, orgID, loginName); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while fetching the api tokens")
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tokens, nil
+}
+
+//SelectAPITokenByHash is deliberately not scoped: the token is how the caller's organization is found.
+func (repo *tokenRepository) SelectAPITokenByHash(ctx context.Context, secretHash string) (*APIToken, error) {
+	t := &APIToken{}
+
+	selectQry := selectAPITokenColumns + ` where t.secret_hash = $1;`
+	if err := pgxscan.Get(ctx, repo.db, t, selectQry, secretHash); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return t, nil
+}
+
+func (repo *tokenRepository) RevokeAPIToken(ctx context.Context, loginName string, id uuid.UUID) (bool, error) {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	updateQry := `update api_tokens set revoked_at = now()
+				  where org_id = $1 and id = $2 and login_name = $3 and revoked_at is null;`
+
+	tag, err := repo.db.Exec(ctx, updateQry, orgID, id, loginName)
+	if err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while revoking the api token")
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *tokenRepository) RevokeAllAPITokens(ctx context.Context, loginName string) error {
+	orgID, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+
+	updateQry := `update api_tokens set revoked_at = now() where org_id = $1 and login_name = $2 and revoked_at is null;`
+
+	if _, err = repo.db.Exec(ctx, updateQry, orgID, loginName); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while revoking the api tokens")
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+//UpdateAPITokenLastUsed runs while the token is being authenticated, before a tenant is known, and is keyed by the token id.
+func (repo *tokenRepository) UpdateAPITokenLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	updateQry := `update api_tokens set last_used_at = $1 where id = $2;`
+
+	if _, err := repo.db.Exec(ctx, updateQry, usedAt, id); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
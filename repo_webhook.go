@@ -0,0 +1,188 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type WebhookRepository interface {
+	InsertSubscription(ctx context.Context, sub *WebhookSubscription) error
+
+	SelectSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+
+	//SelectSubscriptionsFor lists the subscriptions that receive eventType
+	SelectSubscriptionsFor(ctx context.Context, eventType EventType) ([]*WebhookSubscription, error)
+
+	DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error)
+
+	//InsertDelivery does nothing when the subscription has a delivery of the event already
+	InsertDelivery(ctx context.Context, d *WebhookDelivery) error
+
+	//ClaimDueDeliveries takes up to limit pending deliveries that are due at now and pushes their next attempt
+	//to leaseUntil, so that other instances leave them alone while they are being sent
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*WebhookDelivery, error)
+
+	UpdateDelivery(ctx context.Context, d *WebhookDelivery) error
+
+	SelectDelivery(ctx context.Context, id uuid.UUID) (*WebhookDelivery, error)
+
+	SelectDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]*WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const selectDeliveryColumns = `select d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+							   d.response_status, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at
+							   from webhook_deliveries d`
+
+func (repo *webhookRepository) InsertSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into webhook_subscriptions(id, org_id, url, secret, event_types, created_at) values($1, $2, $3, $4, $5, $6);`
+	if _, err = repo.db.Exec(ctx, insertQry, sub.ID, orgID, sub.URL, sub.Secret, sub.EventTypes, sub.CreatedAt); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *webhookRepository) SelectSubscriptions(ctx context.Context) ([]*WebhookSubscription, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := []*WebhookSubscription{}
+	selectQry := `select id, url, event_types, created_at from webhook_subscriptions where org_id = $1 order by created_at;`
+	if err = pgxscan.Select(ctx, repo.db, &subs, selectQry, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return subs, nil
+}
+
+func (repo *webhookRepository) SelectSubscriptionsFor(ctx context.Context, eventType EventType) ([]*WebhookSubscription, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := []*WebhookSubscription{}
+	selectQry := `select id, url, secret, event_types, created_at from webhook_subscriptions
+				  where org_id = $1 and (cardinality(event_types) = 0 or $2 = any(event_types));`
+	if err = pgxscan.Select(ctx, repo.db, &subs, selectQry, orgID, string(eventType)); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return subs, nil
+}
+
+func (repo *webhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := repo.db.Exec(ctx, `delete from webhook_subscriptions where id = $1 and org_id = $2;`, id, orgID)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (repo *webhookRepository) InsertDelivery(ctx context.Context, d *WebhookDelivery) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	insertQry := `insert into webhook_deliveries(id, org_id, subscription_id, event_id, event_type, payload, status, attempts,
+				  next_attempt_at, created_at) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				  on conflict (subscription_id, event_id) do nothing;`
+	if _, err = repo.db.Exec(ctx, insertQry, d.ID, orgID, d.SubscriptionID, d.EventID, d.EventType, d.Payload, d.Status,
+		d.Attempts, d.NextAttemptAt, d.CreatedAt); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *webhookRepository) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*WebhookDelivery, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []*WebhookDelivery{}
+	claimQry := `with due as (
+					select id from webhook_deliveries
+					where org_id = $1 and status = $2 and next_attempt_at <= $3
+					order by next_attempt_at limit $5 for update skip locked
+				 ), claimed as (
+					update webhook_deliveries d set next_attempt_at = $4 from due where d.id = due.id
+					returning d.*
+				 )
+				 select c.id, c.subscription_id, c.event_id, c.event_type, c.payload, c.status, c.attempts, c.response_status,
+				 c.last_error, c.next_attempt_at, c.delivered_at, c.created_at, s.url, s.secret
+				 from claimed c join webhook_subscriptions s on s.id = c.subscription_id;`
+	if err = pgxscan.Select(ctx, repo.db, &deliveries, claimQry, orgID, DeliveryPending, now, leaseUntil, limit); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return deliveries, nil
+}
+
+func (repo *webhookRepository) UpdateDelivery(ctx context.Context, d *WebhookDelivery) error {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	updateQry := `update webhook_deliveries set status = $1, attempts = $2, response_status = $3, last_error = $4,
+				  next_attempt_at = $5, delivered_at = $6 where id = $7 and org_id = $8;`
+	if _, err = repo.db.Exec(ctx, updateQry, d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt,
+		d.DeliveredAt, d.ID, orgID); err != nil {
+		return &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return nil
+}
+
+func (repo *webhookRepository) SelectDelivery(ctx context.Context, id uuid.UUID) (*WebhookDelivery, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &WebhookDelivery{}
+	if err = pgxscan.Get(ctx, repo.db, d, selectDeliveryColumns+` where d.id = $1 and d.org_id = $2;`, id, orgID); err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, nil
+		}
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return d, nil
+}
+
+func (repo *webhookRepository) SelectDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]*WebhookDelivery, error) {
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []*WebhookDelivery{}
+	selectQry := selectDeliveryColumns + ` where d.subscription_id = $1 and d.org_id = $2 order by d.created_at desc limit 100;`
+	if err = pgxscan.Select(ctx, repo.db, &deliveries, selectQry, subscriptionID, orgID); err != nil {
+		return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+	}
+	return deliveries, nil
+}
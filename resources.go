@@ -1,59 +1,401 @@
-package main
-
-import (
-	"log"
-	"os"
-	"timesheet/db"
-	"timesheet/timesheets"
-
-	"timesheet/user"
-
-	"github.com/jackc/pgx/v4/pgxpool"
-)
-
-//commandDB variable
-//database/sql, sqlx, pgx,
-var commandDB *pgxpool.Pool
-
-func initResourcesOrFail() {
-	if !initCommandDatabase() {
-		log.Printf("Exiting as initializing command database failed")
-		os.Exit(1)
-	}
-
-	initServices()
-}
-
-func initCommandDatabase() bool {
-
-	pool, err := db.ConnectPool(config.CommandDatabase.URL)
-	if err != nil {
-		log.Printf("Unable to connect to Command database: %s\n", err.Error())
-		return false
-	}
-
-	//run test query to see if everything is alright.
-	if !db.IsPoolHealthy(pool) {
-		log.Println("Pool is unhealthy")
-		return false
-	}
-
-	commandDB = pool
-
-	log.Printf("Command database initialized")
-	return true
-}
-
-var userService user.Service
-
-var timesheetService timesheets.Service
-
-func initServices() {
-	log.Println("Initialising services")
-
-	userService = user.NewService(user.NewRepository(commandDB))
-
-	timesheetService = timesheets.NewService(timesheets.NewRepository(commandDB), user.NewRepository(commandDB))
-
-	log.Println("Initialising services done")
-}
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+	"timesheet/commons/events"
+	"timesheet/commons/mailer"
+	"timesheet/commons/storage"
+	"timesheet/db"
+	"timesheet/timesheets"
+
+	"timesheet/user"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//commandDB variable
+//database/sql, sqlx, pgx,
+var commandDB *pgxpool.Pool
+
+func initResourcesOrFail() {
+	if !initCommandDatabase() {
+		log.Printf("Exiting as initializing command database failed")
+		os.Exit(1)
+	}
+
+	initServices()
+
+	initJobs()
+}
+
+func initCommandDatabase() bool {
+
+	pool, err := db.ConnectPool(config.CommandDatabase.URL)
+	if err != nil {
+		log.Printf("Unable to connect to Command database: %s\n", err.Error())
+		return false
+	}
+
+	//run test query to see if everything is alright.
+	if !db.IsPoolHealthy(pool) {
+		log.Println("Pool is unhealthy")
+		return false
+	}
+
+	commandDB = pool
+
+	log.Printf("Command database initialized")
+	return true
+}
+
+var userService user.Service
+
+var timesheetService timesheets.Service
+
+var leaveService timesheets.LeaveService
+
+var holidayService timesheets.HolidayService
+
+var overtimeService timesheets.OvertimeService
+
+var contractService timesheets.ContractService
+
+var complianceService timesheets.ComplianceService
+
+var payPeriodService timesheets.PayPeriodService
+
+var fiscalCalendarService timesheets.FiscalCalendarService
+
+var timeZoneService timesheets.TimeZoneService
+
+var punchService timesheets.PunchService
+
+var timerService timesheets.TimerService
+
+var templateService timesheets.TemplateService
+
+var commentService timesheets.CommentService
+
+var attachmentService timesheets.AttachmentService
+
+var expenseService timesheets.ExpenseService
+
+var exchangeRateService timesheets.ExchangeRateService
+var billingRateService timesheets.BillingRateService
+
+var reportService timesheets.ReportService
+
+var expectedHoursService timesheets.ExpectedHoursService
+
+var reminderService timesheets.ReminderService
+
+var webhookService timesheets.WebhookService
+
+var outboxRelay timesheets.OutboxRelay
+
+var eventStream timesheets.EventStream
+
+var tokenService user.TokenService
+
+var oidcService user.OIDCService
+
+var provisioningService user.ProvisioningService
+
+var organizationService user.OrganizationService
+
+//directorySyncService is nil unless LDAP_URL is set
+var directorySyncService user.DirectorySyncService
+
+func initServices() {
+	log.Println("Initialising services")
+
+	userService = user.NewService(user.NewRepository(commandDB))
+
+	//Everything but the login itself looks users up within the caller's organization only.
+	orgRepo := user.NewOrganizationRepository(commandDB)
+	tenantUserRepo := user.NewTenantRepository(user.NewRepository(commandDB), orgRepo)
+
+	organizationService = user.NewOrganizationService(orgRepo)
+
+	leaveService = timesheets.NewLeaveService(timesheets.NewLeaveRepository(commandDB), tenantUserRepo,
+		user.NewDirectoryRepository(commandDB))
+	timesheetAccess := timesheets.NewTimesheetAccess(user.NewDirectoryRepository(commandDB))
+
+	holidayService = timesheets.NewHolidayService(timesheets.NewHolidayRepository(commandDB), tenantUserRepo)
+
+	contractRepo := timesheets.NewContractRepository(commandDB)
+	contractService = timesheets.NewContractService(contractRepo, tenantUserRepo)
+
+	expectedHoursService = timesheets.NewExpectedHoursService(timesheets.NewExpectedHoursRepository(commandDB), holidayService)
+
+	var m mailer.Mailer = mailer.NewLogMailer()
+	if config.SMTP.Host != "" {
+		m = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     config.SMTP.Host,
+			Port:     config.SMTP.Port,
+			Username: config.SMTP.Username,
+			Password: config.SMTP.Password,
+			From:     config.SMTP.From,
+			Timeout:  config.SMTP.Timeout,
+		})
+	}
+	reminderService = timesheets.NewReminderService(timesheets.NewReminderRepository(commandDB), tenantUserRepo,
+		expectedHoursService, m, timesheets.ReminderConfig{DaysBeforeDeadline: config.Reminders.DaysBeforeDeadline})
+
+	overtimeService = timesheets.NewOvertimeService(timesheets.NewOvertimeRepository(commandDB), contractRepo, holidayService)
+
+	complianceService = timesheets.NewComplianceService(timesheets.NewComplianceRepository(commandDB),
+		timesheets.ComplianceConfig{
+			MaxDailyHours:           config.Compliance.MaxDailyHours,
+			MaxDailySeverity:        timesheets.ComplianceSeverity(config.Compliance.MaxDailySeverity),
+			MaxAverageWeeklyHours:   config.Compliance.MaxAverageWeeklyHours,
+			AverageWeeklySeverity:   timesheets.ComplianceSeverity(config.Compliance.AverageWeeklySeverity),
+			MinRestHours:            config.Compliance.MinRestHours,
+			RestSeverity:            timesheets.ComplianceSeverity(config.Compliance.RestSeverity),
+			MaxConsecutiveDays:      config.Compliance.MaxConsecutiveDays,
+			ConsecutiveDaysSeverity: timesheets.ComplianceSeverity(config.Compliance.ConsecutiveDaysSeverity),
+		})
+
+	webhookService = timesheets.NewWebhookService(timesheets.NewWebhookRepository(commandDB), timesheets.WebhookConfig{
+		Timeout:     config.Webhooks.Timeout,
+		RetryBase:   config.Webhooks.RetryBase,
+		MaxAttempts: config.Webhooks.MaxAttempts,
+	})
+
+	sinks := []events.Sink{}
+	for _, name := range config.Outbox.Sinks {
+		switch name {
+		case "webhooks":
+			sinks = append(sinks, timesheets.NewWebhookSink(webhookService))
+		case "log":
+			sinks = append(sinks, events.NewLogSink())
+		default:
+			log.Fatalf("Unknown outbox sink %s", name)
+		}
+	}
+	outboxRelay = timesheets.NewOutboxRelay(timesheets.NewOutboxRepository(commandDB), sinks, timesheets.OutboxConfig{
+		RetryBase: config.Outbox.RetryBase,
+		Retention: config.Outbox.Retention,
+	})
+
+	eventStream = timesheets.NewEventStream(commandDB, user.NewDirectoryRepository(commandDB))
+
+	payPeriodService = timesheets.NewPayPeriodService(timesheets.NewPayPeriodRepository(commandDB), tenantUserRepo)
+
+	timeZoneService = timesheets.NewTimeZoneService(timesheets.NewTimeZoneRepository(commandDB))
+
+	fiscalCalendarService = timesheets.NewFiscalCalendarService(timesheets.NewFiscalCalendarRepository(commandDB))
+
+	exchangeRateService = timesheets.NewExchangeRateService(timesheets.NewExchangeRateRepository(commandDB))
+	billingRateService = timesheets.NewBillingRateService(timesheets.NewBillingRateRepository(commandDB))
+
+	reportService = timesheets.NewReportService(timesheets.NewReportRepository(commandDB), fiscalCalendarService,
+		timeZoneService, timesheets.NewExpenseRepository(commandDB), exchangeRateService, expectedHoursService, complianceService,
+		billingRateService, timesheets.NewTimerRepository(commandDB))
+
+	commentService = timesheets.NewCommentService(timesheets.NewCommentRepository(commandDB),
+		timesheets.NewTimerRepository(commandDB), payPeriodService, timesheetAccess)
+
+	attachmentService = timesheets.NewAttachmentService(timesheets.NewAttachmentRepository(commandDB), newStore(),
+		storage.NewNoScanner(), payPeriodService, timesheetAccess, timesheets.AttachmentConfig{
+			MaxSize:      config.Attachments.MaxSize,
+			AllowedTypes: config.Attachments.AllowedTypes,
+		})
+
+	expenseService = timesheets.NewExpenseService(timesheets.NewExpenseRepository(commandDB),
+		timesheets.NewAttachmentRepository(commandDB), payPeriodService, timesheetAccess)
+
+	timesheetService = timesheets.NewService(timesheets.NewRepository(commandDB), tenantUserRepo, leaveService,
+		timesheetAccess, holidayService, overtimeService, complianceService, payPeriodService, commentService)
+
+	punchService = timesheets.NewPunchService(timesheets.NewPunchRepository(commandDB), timesheetService, timesheetAccess,
+		user.NewDirectoryRepository(commandDB), timeZoneService)
+
+	timerService = timesheets.NewTimerService(timesheets.NewTimerRepository(commandDB), timesheetService, payPeriodService,
+		timesheetAccess, timeZoneService, timesheets.TimerConfig{MaxDuration: config.Timers.MaxDuration})
+
+	templateService = timesheets.NewTemplateService(timesheets.NewTemplateRepository(commandDB), timesheetService,
+		payPeriodService, timesheets.NewReportRepository(commandDB), holidayService, leaveService, timeZoneService, tenantUserRepo)
+
+	tokenService = user.NewTokenService(user.NewTokenRepository(commandDB))
+
+	provisioningService = user.NewProvisioningService(user.NewDirectoryRepository(commandDB), userService,
+		user.NewTokenRepository(commandDB), orgRepo)
+
+	oidcService = user.NewOIDCService(user.OIDCConfig{
+		IssuerURL:          config.OIDC.IssuerURL,
+		ClientID:           config.OIDC.ClientID,
+		ClientSecret:       config.OIDC.ClientSecret,
+		RedirectURL:        config.OIDC.RedirectURL,
+		Scopes:             config.OIDC.Scopes,
+		UsernameClaim:      config.OIDC.UsernameClaim,
+		TrustUsernameClaim: config.OIDC.TrustUsernameClaim,
+		DepartmentClaim:    config.OIDC.DepartmentClaim,
+		JobTitleClaim:      config.OIDC.JobTitleClaim,
+		AutoProvision:      config.OIDC.AutoProvision,
+	}, nil, tenantUserRepo, user.NewOIDCIdentityRepository(commandDB), user.NewDirectoryRepository(commandDB), userService, orgRepo)
+
+	if config.LDAP.URL != "" {
+		directorySyncService = user.NewDirectorySyncService(user.NewLDAPSource(user.LDAPConfig{
+			URL:            config.LDAP.URL,
+			BindDN:         config.LDAP.BindDN,
+			BindPassword:   config.LDAP.BindPassword,
+			BaseDN:         config.LDAP.BaseDN,
+			UserFilter:     config.LDAP.UserFilter,
+			DisabledFilter: config.LDAP.DisabledFilter,
+			LoginAttr:      config.LDAP.LoginAttr,
+			GivenNameAttr:  config.LDAP.GivenNameAttr,
+			FamilyNameAttr: config.LDAP.FamilyNameAttr,
+			EmailAttr:      config.LDAP.EmailAttr,
+			DepartmentAttr: config.LDAP.DepartmentAttr,
+			TitleAttr:      config.LDAP.TitleAttr,
+			ManagerAttr:    config.LDAP.ManagerAttr,
+		}), user.NewDirectoryRepository(commandDB), userService, user.NewTokenRepository(commandDB), orgRepo,
+			config.LDAP.MaxDisableShare)
+	}
+
+	log.Println("Initialising services done")
+}
+
+//newStore opens the storage attachments are kept in
+func newStore() storage.Store {
+	switch config.Storage.Backend {
+	case "local":
+		store, err := storage.NewLocalStore(config.Storage.LocalDir)
+		if err != nil {
+			log.Fatalf("Failed opening the attachment storage. err=%s\n", err.Error())
+		}
+		return store
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  config.Storage.S3Endpoint,
+			Region:    config.Storage.S3Region,
+			Bucket:    config.Storage.S3Bucket,
+			AccessKey: config.Storage.S3AccessKey,
+			SecretKey: config.Storage.S3SecretKey,
+			Timeout:   config.Storage.S3Timeout,
+		})
+	default:
+		log.Fatalf("Unknown storage backend %s", config.Storage.Backend)
+		return nil
+	}
+}
+
+//initJobs schedules the background jobs that are enabled in the configuration
+func initJobs() {
+	//The event stream listens for the lifetime of the process
+	go eventStream.Listen(context.Background())
+	if directorySyncService != nil {
+		runPeriodically("ldap-sync", config.LDAP.SyncInterval, func(ctx context.Context) error {
+			ctx, err := withOrganization(ctx, config.LDAP.Organization)
+			if err != nil {
+				return err
+			}
+			_, err = directorySyncService.Sync(ctx, false)
+			return err
+		})
+	}
+
+	runForEachOrganization("leave-accrual", config.Leave.AccrualInterval, func(ctx context.Context, org *user.Organization) error {
+		now, err := organizationNow(ctx)
+		if err != nil {
+			return err
+		}
+		credited, err := leaveService.RunAccruals(ctx, now)
+		if err != nil {
+			return err
+		}
+		log.Printf("Leave accruals of %s are up to date, %d entries due", org.Slug, credited)
+		return nil
+	})
+
+	runForEachOrganization("missing-timesheets", config.Reports.MissingTimesheetsInterval, func(ctx context.Context, org *user.Organization) error {
+		now, err := organizationNow(ctx)
+		if err != nil {
+			return err
+		}
+		lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+		report, err := expectedHoursService.MissingTimesheets(ctx, int(lastMonth.Month()), lastMonth.Year())
+		if err != nil {
+			return err
+		}
+		for _, u := range report.Users {
+			log.Printf("Timesheet of %s in %s for %d/%d is %s: %.2f of %.2f hours", u.LoginName, org.Slug,
+				report.Month, report.Year, u.Status, u.FilledHours, u.ExpectedHours)
+		}
+		log.Printf("Missing timesheets of %s for %d/%d: %d users", org.Slug, report.Month, report.Year, len(report.Users))
+		return nil
+	})
+
+	runForEachOrganization("missing-punches", config.Reports.MissingPunchesInterval, func(ctx context.Context, org *user.Organization) error {
+		now, err := organizationNow(ctx)
+		if err != nil {
+			return err
+		}
+		yesterday := now.AddDate(0, 0, -1)
+		days, err := punchService.DaysWithIssues(ctx, yesterday)
+		if err != nil {
+			return err
+		}
+		for _, d := range days {
+			log.Printf("Punches of %s in %s on %s are incomplete: %d issues", d.LoginName, org.Slug,
+				d.Date.Format("2006-01-02"), len(d.Issues))
+		}
+		log.Printf("Missing punches of %s on %s: %d users", org.Slug, yesterday.Format("2006-01-02"), len(days))
+		return nil
+	})
+
+	runForEachOrganization("forgotten-timers", config.Timers.CheckInterval, func(ctx context.Context, org *user.Organization) error {
+		stopped, err := timerService.StopForgotten(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		if stopped > 0 {
+			log.Printf("Stopped %d forgotten timers of %s", stopped, org.Slug)
+		}
+		booked, err := timerService.BookPending(ctx)
+		if err != nil {
+			return err
+		}
+		if booked > 0 {
+			log.Printf("Booked %d time entries of %s", booked, org.Slug)
+		}
+		return nil
+	})
+
+	runForEachOrganization("reminders", config.Reminders.Interval, func(ctx context.Context, org *user.Organization) error {
+		now, err := organizationNow(ctx)
+		if err != nil {
+			return err
+		}
+		sent, err := reminderService.SendReminders(ctx, now)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reminders of %s sent: %d", org.Slug, sent)
+		return nil
+	})
+
+	runForEachOrganization("outbox-relay", config.Outbox.RelayInterval, func(ctx context.Context, org *user.Organization) error {
+		_, err := outboxRelay.Relay(ctx, time.Now())
+		return err
+	})
+
+	runForEachOrganization("webhook-deliveries", config.Webhooks.DeliveryInterval, func(ctx context.Context, org *user.Organization) error {
+		_, err := webhookService.DeliverDue(ctx, time.Now())
+		return err
+	})
+}
+
+//organizationNow is the current time in the zone of the organization in ctx, so jobs cut days and months where its
+//users do
+func organizationNow(ctx context.Context) (time.Time, error) {
+	loc, err := timeZoneService.Location(ctx, "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
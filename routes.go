@@ -1,64 +1,240 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"net/http"
-
-	"github.com/go-chi/chi/v5"
-)
-
-//addRoutes will have different routes  functions and calls those functions...
-func addRoutes(r *chi.Mux) {
-	log.Println("Registering routes")
-	addIAMRoutes(r)
-	addTimesheetRoutes(r)
-
-	log.Println("Registering routes .. done")
-}
-
-//http://localhost:8085/iam/users
-
-func addIAMRoutes(r *chi.Mux) {
-	r.Route("/iam", func(r chi.Router) {
-
-		//createUser is a POST handler which is used to create a user
-		r.Post("/users", createUser)
-		r.Post("/users/login", loginUser)
-		r.Put("/users/{loginName}", forgotPassword)
-
-	})
-}
-
-func addTimesheetRoutes(r *chi.Mux) {
-	r.Route("/users", func(r chi.Router) {
-		//http://localhost:8085/users/timesheets
-		r.Post("/timesheets", createTimesheet)
-
-		r.Get("/timesheets/{loginName}", getListofTimesheets)
-
-		r.Get("/timesheets/{loginName}/{week}/{month}/{year}", getTimesheetsByWeek)
-
-		r.Put("/timesheets/{loginName}/{month}/{year}", updateTimesheet)
-
-		r.Put("/timesheets/updnotes/{loginName}/{month}/{year}", updateNotes)
-
-		r.Delete("/timesheets/{loginName}/{month}/{year}", deleteTimesheet)
-
-		r.Post("/timesheets/notes", addorUpdateNotes)
-	})
-}
-
-func printRoutes(r *chi.Mux) {
-
-	log.Println("Following routes are supported")
-
-	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-		fmt.Printf("%s %s\n", method, route)
-		return nil
-	}
-
-	if err := chi.Walk(r, walkFunc); err != nil {
-		fmt.Printf("Logging err: %s\n", err.Error())
-	}
-}
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"timesheet/commons/auth"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//addRoutes will have different routes  functions and calls those functions...
+func addRoutes(r *chi.Mux) {
+	log.Println("Registering routes")
+	addIAMRoutes(r)
+	addTimesheetRoutes(r)
+	addSCIMRoutes(r)
+
+	log.Println("Registering routes .. done")
+}
+
+//http://localhost:8085/iam/users
+
+func addIAMRoutes(r *chi.Mux) {
+	r.Route("/iam", func(r chi.Router) {
+
+		//createUser is a POST handler which is used to create a user
+		r.Post("/users", createUser)
+		r.Post("/users/login", loginUser)
+		r.Put("/users/{loginName}", forgotPassword)
+
+		//Single sign-on through the company identity provider
+		r.Get("/oidc/login", oidcLogin)
+		r.Get("/oidc/callback", oidcCallback)
+
+		//Personal api tokens can only be managed from an interactive session
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, requireSession)
+			r.Post("/tokens", createAPIToken)
+			r.Get("/tokens", getAPITokens)
+			r.Delete("/tokens/{tokenID}", revokeAPIToken)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, requireAdmin)
+			r.Post("/directory/sync", syncDirectory)
+			r.Post("/organizations", createOrganization)
+			r.Get("/organizations", getOrganizations)
+		})
+	})
+}
+
+func addTimesheetRoutes(r *chi.Mux) {
+	r.Route("/users", func(r chi.Router) {
+		r.Use(authenticate)
+
+		read := r.With(requireScope(auth.ScopeTimesheetsRead))
+		write := r.With(requireScope(auth.ScopeTimesheetsWrite))
+
+		//http://localhost:8085/users/timesheets
+		write.Post("/timesheets", createTimesheet)
+
+		read.Get("/timesheets/{loginName}", getListofTimesheets)
+
+		read.Get("/timesheets/{loginName}/{week}/{month}/{year}", getTimesheetsByWeek)
+
+		write.Put("/timesheets/{loginName}/{month}/{year}", updateTimesheet)
+
+		write.Put("/timesheets/updnotes/{loginName}/{month}/{year}", updateNotes)
+
+		write.Delete("/timesheets/{loginName}/{month}/{year}", deleteTimesheet)
+
+		write.Post("/timesheets/notes", addorUpdateNotes)
+
+		//Templates are the caller's own, prefill creates a timesheet from one or from the previous week or month
+		write.Post("/timesheets/prefill", prefillTimesheet)
+		write.Post("/templates", createTemplate)
+		read.Get("/templates", getTemplates)
+		write.Put("/templates/{templateID}", updateTemplate)
+		write.Delete("/templates/{templateID}", deleteTemplate)
+
+		//The same timesheets addressed by any date of their pay period, formatted as 2006-01-02
+		read.Get("/timesheets/{loginName}/periods/{date}", getTimesheetForPeriod)
+		write.Put("/timesheets/{loginName}/periods/{date}", updateTimesheetForPeriod)
+		write.Delete("/timesheets/{loginName}/periods/{date}", deleteTimesheetForPeriod)
+
+		//The comment thread of a timesheet, the notes endpoints above post to it too
+		read.Get("/timesheets/{loginName}/periods/{date}/comments", getComments)
+		write.Post("/timesheets/{loginName}/periods/{date}/comments", addComment)
+		write.Put("/timesheets/{loginName}/comments/{commentID}", editComment)
+		write.Delete("/timesheets/{loginName}/comments/{commentID}", deleteComment)
+		read.Get("/timesheets/{loginName}/comments/{commentID}/history", getCommentHistory)
+
+		//Files such as signed approval sheets and sick notes, kept in the configured storage
+		write.Post("/timesheets/{loginName}/periods/{date}/attachments", uploadAttachment)
+		read.Get("/timesheets/{loginName}/periods/{date}/attachments", getAttachments)
+		read.Get("/timesheets/{loginName}/attachments/{attachmentID}", downloadAttachment)
+		write.Delete("/timesheets/{loginName}/attachments/{attachmentID}", deleteAttachment)
+
+		//Expense claims are made for the caller and reviewed by the same managers as timesheets
+		write.Post("/expenses", createExpenseClaim)
+		read.Get("/expenses/{loginName}", getExpenseClaims)
+		read.Get("/expenses/{loginName}/{claimID}", getExpenseClaim)
+		write.Put("/expenses/{loginName}/{claimID}", updateExpenseClaim)
+		write.Delete("/expenses/{loginName}/{claimID}", deleteExpenseClaim)
+
+		read.Get("/payperiods/{loginName}/{date}", resolvePayPeriod)
+		write.Post("/punches", punch)
+		read.Get("/punches/{loginName}/{date}", getPunchDay)
+
+		//A user has at most one running timer, stopping it books a time entry on the timesheet
+		write.Post("/timers/start", startTimer)
+		write.Post("/timers/stop", stopTimer)
+		write.Post("/timers/switch", switchTimer)
+		read.Get("/timers/active", getActiveTimer)
+		read.Get("/timeentries/{loginName}", getTimeEntries)
+
+		read.Get("/timezones/{loginName}", getTimeZone)
+		write.Put("/timezones/{loginName}", setTimeZone)
+		write.Delete("/timezones/{loginName}", clearTimeZone)
+
+		read.Get("/fiscal/calendar", getFiscalCalendar)
+		read.Get("/fiscal/dates/{date}", getFiscalDate)
+
+		read.Get("/leave/balances/{loginName}", getLeaveBalances)
+		read.Get("/leave/requests", getLeaveRequests)
+		write.Post("/leave/requests", createLeaveRequest)
+		write.Delete("/leave/requests/{requestID}", cancelLeaveRequest)
+
+		//Managers decide on the requests of their direct reports
+		approve := r.With(requireScope(auth.ScopeApprove))
+		approve.Get("/leave/approvals", getLeaveApprovals)
+		approve.Post("/leave/requests/{requestID}/approve", approveLeaveRequest)
+		approve.Post("/leave/requests/{requestID}/reject", rejectLeaveRequest)
+		approve.Get("/compliance/{month}/{year}", getComplianceReport)
+		approve.Get("/timesheets/missing/{month}/{year}", getMissingTimesheets)
+		approve.Get("/reports/missing/{date}", getFiscalMissingTimesheets)
+		approve.Get("/reports/compliance/{date}", getFiscalComplianceReport)
+		approve.Post("/timesheets/{loginName}/{month}/{year}/approve", approveTimesheet)
+		approve.Post("/timesheets/{loginName}/{month}/{year}/reject", rejectTimesheet)
+		approve.Post("/timesheets/{loginName}/periods/{date}/approve", approveTimesheetForPeriod)
+		approve.Post("/timesheets/{loginName}/periods/{date}/reject", rejectTimesheetForPeriod)
+		approve.Get("/punches/missing/{date}", getMissingPunches)
+		approve.Post("/punches/{loginName}/corrections", addPunchCorrection)
+		approve.Put("/punches/{loginName}/corrections/{punchID}", correctPunch)
+		approve.Delete("/punches/{loginName}/corrections/{punchID}", voidPunch)
+		approve.Post("/expenses/{loginName}/{claimID}/approve", approveExpenseClaim)
+		approve.Post("/expenses/{loginName}/{claimID}/reject", rejectExpenseClaim)
+
+		export := r.With(requireScope(auth.ScopeExport))
+		export.Get("/reports/hours", getHoursReport)
+		export.Get("/reports/payroll/{date}", getPayrollExport)
+		export.Get("/reports/expenses", getExpenseExport)
+		export.Get("/reports/invoice", getInvoice)
+
+		admin := r.With(requireAdmin)
+		admin.Post("/leave/policies", createLeavePolicy)
+		admin.Get("/leave/policies", getLeavePolicies)
+		admin.Delete("/leave/policies/{policyID}", deleteLeavePolicy)
+
+		read.Get("/holidays/calendars", getHolidayCalendars)
+		read.Get("/holidays/calendars/{calendarID}", getHolidayCalendar)
+		read.Get("/holidays/workingdays/{loginName}/{month}/{year}", getWorkingDays)
+		read.Get("/expectedhours/{loginName}/{month}/{year}", getExpectedHours)
+
+		read.Get("/events/stream", streamEvents)
+
+		read.Get("/reminders/preferences/{loginName}", getReminderPreferences)
+		write.Put("/reminders/preferences/{loginName}", setReminderPreferences)
+		admin.Post("/holidays/calendars", createHolidayCalendar)
+		admin.Put("/holidays/calendars/{calendarID}", updateHolidayCalendar)
+		admin.Delete("/holidays/calendars/{calendarID}", deleteHolidayCalendar)
+		admin.Post("/holidays/calendars/{calendarID}/holidays", addHoliday)
+		admin.Delete("/holidays/calendars/{calendarID}/holidays/{holidayID}", deleteHoliday)
+		admin.Post("/holidays/calendars/{calendarID}/import", importHolidays)
+		admin.Get("/holidays/calendars/{calendarID}/assignments", getCalendarAssignments)
+		admin.Put("/holidays/calendars/{calendarID}/assignments", assignHolidayCalendar)
+		admin.Delete("/holidays/calendars/{calendarID}/assignments", unassignHolidayCalendar)
+
+		admin.Post("/overtime/rules", createOvertimeRule)
+		admin.Get("/overtime/rules", getOvertimeRules)
+		admin.Put("/overtime/rules/{ruleID}", updateOvertimeRule)
+		admin.Delete("/overtime/rules/{ruleID}", deleteOvertimeRule)
+
+		admin.Put("/payperiods", setPayPeriodDefinition)
+		admin.Get("/payperiods", getPayPeriodDefinitions)
+		admin.Delete("/payperiods/{definitionID}", deletePayPeriodDefinition)
+
+		admin.Put("/fiscal/calendar", setFiscalCalendar)
+
+		admin.Post("/exchange-rates", setExchangeRate)
+		admin.Get("/exchange-rates", getExchangeRates)
+		admin.Delete("/exchange-rates/{rateID}", deleteExchangeRate)
+		admin.Post("/exchange-rates/import", importExchangeRates)
+
+		admin.Post("/billing-rates", setBillingRate)
+		admin.Get("/billing-rates", getBillingRates)
+		admin.Delete("/billing-rates/{rateID}", deleteBillingRate)
+
+		admin.Get("/timezones", getTimeZone)
+		admin.Put("/timezones", setTimeZone)
+
+		admin.Get("/contracts", getContracts)
+		admin.Get("/contracts/{loginName}", getContract)
+		admin.Put("/contracts/{loginName}", setContract)
+
+		admin.Post("/webhooks", createWebhookSubscription)
+		admin.Get("/webhooks", getWebhookSubscriptions)
+		admin.Delete("/webhooks/{subscriptionID}", deleteWebhookSubscription)
+		admin.Get("/webhooks/{subscriptionID}/deliveries", getWebhookDeliveries)
+		admin.Post("/webhooks/deliveries/{deliveryID}/redeliver", redeliverWebhook)
+	})
+}
+
+func addSCIMRoutes(r *chi.Mux) {
+	r.Route("/scim/v2", func(r chi.Router) {
+		r.Use(scimAuthenticate)
+
+		r.Post("/Users", createSCIMUser)
+		r.Get("/Users", listSCIMUsers)
+		r.Get("/Users/{id}", getSCIMUser)
+		r.Patch("/Users/{id}", patchSCIMUser)
+		r.Delete("/Users/{id}", deactivateSCIMUser)
+	})
+}
+
+func printRoutes(r *chi.Mux) {
+
+	log.Println("Following routes are supported")
+
+	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		fmt.Printf("%s %s\n", method, route)
+		return nil
+	}
+
+	if err := chi.Walk(r, walkFunc); err != nil {
+		fmt.Printf("Logging err: %s\n", err.Error())
+	}
+}
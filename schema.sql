@@ -0,0 +1,512 @@
+-- Tables added on top of the existing users and timesheets tables.
+-- Apply in order against the command database.
+
+-- Organizations (tenants). Rows that predate them belong to the default organization.
+create table if not exists organizations (
+	id         uuid primary key,
+	slug       varchar(50)  not null unique,
+	name       varchar(100) not null,
+	created_at timestamptz  not null default now()
+);
+insert into organizations(id, slug, name)
+	values('00000000-0000-0000-0000-000000000001', 'default', 'Default')
+	on conflict do nothing;
+
+alter table users add column if not exists org_id uuid not null
+	default '00000000-0000-0000-0000-000000000001' references organizations(id);
+-- The organization of a session or token is resolved from the login name, so login names stay unique across
+-- organizations rather than per organization
+create unique index if not exists users_login_name_idx on users(login_name);
+alter table timesheets add column if not exists org_id uuid not null
+	default '00000000-0000-0000-0000-000000000001' references organizations(id);
+create index if not exists timesheets_org_login_idx on timesheets(org_id, login_name, "year", "month");
+
+-- Personal api tokens (user.TokenRepository)
+create table if not exists api_tokens (
+	id           uuid primary key,
+	org_id       uuid         not null references organizations(id),
+	login_name   varchar(100) not null,
+	name         varchar(100) not null,
+	prefix       varchar(20)  not null,
+	secret_hash  char(64)     not null unique,
+	scopes       text[]       not null,
+	expires_at   timestamptz,
+	last_used_at timestamptz,
+	revoked_at   timestamptz,
+	created_at   timestamptz  not null default now()
+);
+create index if not exists api_tokens_login_name_idx on api_tokens(login_name);
+
+-- Directory managed attributes of users, written by SCIM provisioning and LDAP sync (user.DirectoryRepository)
+create table if not exists user_directory (
+	id                 uuid primary key,
+	org_id             uuid         not null references organizations(id),
+	login_name         varchar(100) not null unique,
+	external_id        varchar(255) not null default '',
+	given_name         varchar(100) not null default '',
+	family_name        varchar(100) not null default '',
+	email              varchar(255) not null default '',
+	department         varchar(100) not null default '',
+	job_title          varchar(100) not null default '',
+	manager_login_name varchar(100) not null default '',
+	active             boolean      not null default true,
+	source             varchar(20)  not null,
+	created_at         timestamptz  not null default now(),
+	updated_at         timestamptz  not null default now()
+);
+-- Login names are unique across organizations, like the users they describe
+drop index if exists user_directory_org_login_idx;
+create unique index if not exists user_directory_login_name_key on user_directory(login_name);
+create index if not exists user_directory_external_id_idx on user_directory(external_id);
+
+-- Absences (PTO, sick, unpaid, bereavement, holiday) recorded on a timesheet, see timesheets.AbsenceEntry
+alter table timesheets add column if not exists absence_info jsonb not null default '[]';
+alter table timesheets add column if not exists absence_hours double precision not null default 0;
+
+-- Leave policies, the ledger of balance movements and the leave requests managers approve (timesheets.LeaveRepository)
+create table if not exists leave_policies (
+	id             uuid primary key,
+	org_id         uuid             not null references organizations(id),
+	name           varchar(100)     not null,
+	absence_type   varchar(20)      not null,
+	department     varchar(100)     not null default '',
+	accrual_method varchar(20)      not null,
+	accrual_hours  double precision not null,
+	carry_over_cap double precision,
+	created_at     timestamptz      not null default now(),
+	unique (org_id, absence_type, department)
+);
+-- A missing carry-over cap means no cap, it used to be -1
+alter table leave_policies alter column carry_over_cap drop not null, alter column carry_over_cap drop default;
+update leave_policies set carry_over_cap = null where carry_over_cap < 0;
+
+create table if not exists leave_ledger (
+	id             uuid primary key,
+	org_id         uuid             not null references organizations(id),
+	login_name     varchar(100)     not null,
+	policy_id      uuid             not null references leave_policies(id) on delete cascade,
+	absence_type   varchar(20)      not null,
+	entry_type     varchar(20)      not null,
+	hours          double precision not null,
+	effective_date date             not null,
+	reference      varchar(50)      not null,
+	created_at     timestamptz      not null default now(),
+	unique (org_id, login_name, policy_id, reference)
+);
+
+create table if not exists leave_requests (
+	id                  uuid primary key,
+	org_id              uuid             not null references organizations(id),
+	login_name          varchar(100)     not null,
+	absence_type        varchar(20)      not null,
+	start_date          date             not null,
+	end_date            date             not null,
+	hours               double precision not null,
+	comment             varchar(500)     not null default '',
+	status              varchar(20)      not null,
+	approver_login_name varchar(100)     not null default '',
+	decision_comment    varchar(500)     not null default '',
+	decided_at          timestamptz,
+	created_at          timestamptz      not null default now()
+);
+create index if not exists leave_requests_login_name_idx on leave_requests(org_id, login_name);
+
+-- Public holiday calendars, their days and who they apply to (timesheets.HolidayRepository)
+create table if not exists holiday_calendars (
+	id         uuid primary key,
+	org_id     uuid         not null references organizations(id),
+	name       varchar(100) not null,
+	country    varchar(2)   not null default '',
+	created_at timestamptz  not null default now()
+);
+
+create table if not exists holidays (
+	id           uuid primary key,
+	org_id       uuid         not null references organizations(id),
+	calendar_id  uuid         not null references holiday_calendars(id) on delete cascade,
+	holiday_date date         not null,
+	name         varchar(100) not null,
+	unique (calendar_id, holiday_date)
+);
+
+-- An empty login_name assigns a department, empty department and login_name make the organization's default
+create table if not exists holiday_calendar_assignments (
+	org_id      uuid         not null references organizations(id),
+	calendar_id uuid         not null references holiday_calendars(id) on delete cascade,
+	department  varchar(100) not null default '',
+	login_name  varchar(100) not null default '',
+	primary key (org_id, department, login_name)
+);
+
+-- Employment terms per user, the contract type selects the overtime rule (timesheets.ContractRepository)
+create table if not exists user_contracts (
+	org_id        uuid         not null references organizations(id),
+	login_name    varchar(100) not null,
+	contract_type varchar(50)  not null default '',
+	updated_at    timestamptz  not null default now(),
+	primary key (org_id, login_name)
+);
+
+-- Overtime rules and the split they produce on every timesheet (timesheets.OvertimeRepository)
+create table if not exists overtime_rules (
+	id                      uuid primary key,
+	org_id                  uuid             not null references organizations(id),
+	name                    varchar(100)     not null,
+	department              varchar(100)     not null default '',
+	contract_type           varchar(50)      not null default '',
+	daily_overtime_after    double precision not null default 0,
+	daily_double_time_after double precision not null default 0,
+	weekly_overtime_after   double precision not null default 0,
+	weekend_multiplier      double precision not null default 0,
+	holiday_multiplier      double precision not null default 0,
+	overtime_multiplier     double precision not null default 1.5,
+	double_time_multiplier  double precision not null default 2,
+	created_at              timestamptz      not null default now(),
+	unique (org_id, department, contract_type)
+);
+
+alter table timesheets add column if not exists regular_hours double precision not null default 0;
+alter table timesheets add column if not exists overtime_hours double precision not null default 0;
+alter table timesheets add column if not exists double_time_hours double precision not null default 0;
+alter table timesheets add column if not exists payable_hours double precision not null default 0;
+
+-- Working-time rule violations of the latest submission of each timesheet (timesheets.ComplianceRepository)
+create table if not exists compliance_findings (
+	org_id      uuid             not null references organizations(id),
+	login_name  varchar(100)     not null,
+	"month"     int              not null,
+	"year"      int              not null,
+	rule        varchar(50)      not null,
+	severity    varchar(20)      not null,
+	week_info   int              not null default 0,
+	day         int              not null default 0,
+	value       double precision not null,
+	limit_value double precision not null,
+	message     text             not null,
+	created_at  timestamptz      not null default now()
+);
+
+create index if not exists compliance_findings_month_idx on compliance_findings (org_id, "year", "month", login_name);
+
+-- Contracted hours, a full-time week and the part of it the user works (timesheets.ContractRepository)
+alter table user_contracts add column if not exists weekly_hours double precision not null default 40;
+alter table user_contracts add column if not exists percentage double precision not null default 100;
+
+-- Reminder opt-out and language per user, and the reminders already sent (timesheets.ReminderRepository)
+create table if not exists reminder_preferences (
+	org_id     uuid         not null references organizations(id),
+	login_name varchar(100) not null,
+	opt_out    boolean      not null default false,
+	locale     varchar(10)  not null default 'en',
+	updated_at timestamptz  not null default now(),
+	primary key (org_id, login_name)
+);
+
+create table if not exists reminders_sent (
+	org_id     uuid         not null references organizations(id),
+	login_name varchar(100) not null,
+	kind       varchar(30)  not null,
+	reference  varchar(50)  not null,
+	sent_at    timestamptz  not null default now(),
+	primary key (org_id, login_name, kind, reference)
+);
+
+-- Webhook subscriptions and their delivery log (timesheets.WebhookRepository)
+create table if not exists webhook_subscriptions (
+	id          uuid primary key,
+	org_id      uuid          not null references organizations(id),
+	url         varchar(2000) not null,
+	secret      varchar(200)  not null,
+	event_types text[]        not null default '{}',
+	created_at  timestamptz   not null default now()
+);
+
+create table if not exists webhook_deliveries (
+	id              uuid primary key,
+	org_id          uuid         not null references organizations(id),
+	subscription_id uuid         not null references webhook_subscriptions(id) on delete cascade,
+	event_id        uuid         not null,
+	event_type      varchar(50)  not null,
+	payload         jsonb        not null,
+	status          varchar(20)  not null,
+	attempts        int          not null default 0,
+	response_status int          not null default 0,
+	last_error      text         not null default '',
+	next_attempt_at timestamptz  not null,
+	delivered_at    timestamptz,
+	created_at      timestamptz  not null default now()
+);
+create index if not exists webhook_deliveries_due_idx on webhook_deliveries(org_id, status, next_attempt_at);
+create index if not exists webhook_deliveries_subscription_idx on webhook_deliveries(subscription_id, created_at);
+create unique index if not exists webhook_deliveries_event_idx on webhook_deliveries(subscription_id, event_id);
+
+-- Events written with the change they describe and published by the relay (timesheets.OutboxRepository)
+create table if not exists event_outbox (
+	id           uuid primary key,
+	org_id       uuid        not null references organizations(id),
+	event_type   varchar(50) not null,
+	payload      jsonb       not null,
+	attempts     int         not null default 0,
+	last_error   text        not null default '',
+	created_at   timestamptz not null default now(),
+	available_at timestamptz not null default now(),
+	published_at timestamptz
+);
+create index if not exists event_outbox_pending_idx on event_outbox(org_id, created_at) where published_at is null;
+
+-- Pay periods per department, an empty department being the organization's default (timesheets.PayPeriodRepository)
+create table if not exists pay_period_definitions (
+	id          uuid primary key,
+	org_id      uuid         not null references organizations(id),
+	name        varchar(100) not null,
+	frequency   varchar(20)  not null,
+	anchor_date date         not null,
+	department  varchar(100) not null default '',
+	created_at  timestamptz  not null default now(),
+	unique (org_id, department)
+);
+
+-- Timesheets are keyed by their pay period, the existing ones cover their month
+alter table timesheets add column if not exists period_start date;
+alter table timesheets add column if not exists period_end date;
+alter table timesheets add column if not exists period_frequency varchar(20) not null default 'Monthly';
+update timesheets set period_start = make_date("year", "month", 1),
+	period_end = (make_date("year", "month", 1) + interval '1 month' - interval '1 day')::date
+	where period_start is null;
+alter table timesheets alter column period_start set not null;
+alter table timesheets alter column period_end set not null;
+create unique index if not exists timesheets_period_idx on timesheets(org_id, login_name, period_start);
+
+alter table compliance_findings add column if not exists period_start date;
+update compliance_findings set period_start = make_date("year", "month", 1) where period_start is null;
+alter table compliance_findings alter column period_start set not null;
+
+-- The day of a finding, for reports by fiscal unit; findings about a whole period are dated by its start
+alter table compliance_findings add column if not exists date date;
+update compliance_findings set date = case when week_info > 0
+	then date_trunc('month', period_start)::date + (week_info - 1) * 7 + day - extract(isodow from date_trunc('month', period_start))::int
+	else period_start end where date is null;
+alter table compliance_findings alter column date set not null;
+create index if not exists compliance_findings_date_idx on compliance_findings (org_id, date, login_name);
+
+-- One fiscal calendar per organization, organizations without one use the calendar year starting on a Monday with 4-4-5
+create table if not exists fiscal_calendars (
+	org_id           uuid primary key references organizations(id),
+	start_month      int         not null,
+	start_weekday    int         not null,
+	pattern          varchar(10) not null,
+	name_by_end_year boolean     not null default false,
+	updated_at       timestamptz not null default now()
+);
+
+-- Clock punches, work_date is the local date of the shift the punch belongs to
+create table if not exists punches (
+	id           uuid primary key,
+	org_id       uuid         not null references organizations(id),
+	login_name   varchar(100) not null,
+	kind         varchar(20)  not null,
+	punched_at   timestamptz  not null,
+	time_zone    varchar(64)  not null default 'UTC',
+	work_date    date         not null,
+	corrected_by varchar(100) not null default '',
+	reason       varchar(500) not null default '',
+	voided       boolean      not null default false,
+	created_at   timestamptz  not null default now(),
+	updated_at   timestamptz  not null default now()
+);
+create index if not exists punches_work_date_idx on punches(org_id, work_date, login_name);
+create index if not exists punches_login_idx on punches(org_id, login_name, punched_at);
+
+-- Worked hours of a work day last rolled up into the timesheet, later rollups book the change since
+create table if not exists punch_rollups (
+	org_id     uuid             not null references organizations(id),
+	login_name varchar(100)     not null,
+	work_date  date             not null,
+	hours      double precision not null,
+	updated_at timestamptz      not null default now(),
+	primary key (org_id, login_name, work_date)
+);
+
+-- Running timers, one per user, and the time entries of stopped timers
+create table if not exists timers (
+	id          uuid primary key,
+	org_id      uuid         not null references organizations(id),
+	login_name  varchar(100) not null,
+	project     varchar(100) not null,
+	task        varchar(100) not null default '',
+	description varchar(500) not null default '',
+	started_at  timestamptz  not null,
+	time_zone   varchar(64)  not null default 'UTC',
+	unique (org_id, login_name)
+);
+
+create table if not exists time_entries (
+	id           uuid primary key,
+	org_id       uuid             not null references organizations(id),
+	login_name   varchar(100)     not null,
+	project      varchar(100)     not null,
+	task         varchar(100)     not null default '',
+	description  varchar(500)     not null default '',
+	started_at   timestamptz      not null,
+	ended_at     timestamptz      not null,
+	hours        double precision not null,
+	work_date    date             not null,
+	period_start date             not null,
+	source       varchar(20)      not null,
+	capped       boolean          not null default false,
+	created_at   timestamptz      not null default now()
+);
+create index if not exists time_entries_login_idx on time_entries(org_id, login_name, work_date);
+-- Entries are stored unbooked and marked booked with the timesheet change that books their hours, the entries from
+-- before were booked when their timer stopped
+alter table time_entries add column if not exists booked boolean not null default true;
+create index if not exists time_entries_unbooked_idx on time_entries(org_id, created_at) where not booked;
+
+-- Time zones days are counted in, per user or for the organization (login_name '')
+create table if not exists time_zones (
+	org_id     uuid         not null references organizations(id),
+	login_name varchar(100) not null default '',
+	time_zone  varchar(64)  not null,
+	updated_at timestamptz  not null default now(),
+	primary key (org_id, login_name)
+);
+
+-- A user's usual weeks, lines holds the project/task hours per weekday
+create table if not exists timesheet_templates (
+	id         uuid         primary key,
+	org_id     uuid         not null references organizations(id),
+	login_name varchar(100) not null,
+	name       varchar(100) not null,
+	lines      jsonb        not null,
+	created_at timestamptz  not null default now()
+);
+create index if not exists timesheet_templates_login_idx on timesheet_templates(org_id, login_name);
+
+-- Comment threads of timesheets and the former texts of edited or deleted comments (timesheets.CommentRepository).
+-- They replace timesheets.info, which is copied into the threads once and no longer written.
+create table if not exists timesheet_comments (
+	id           uuid         primary key,
+	org_id       uuid         not null references organizations(id),
+	login_name   varchar(100) not null,
+	period_start date         not null,
+	author       varchar(100) not null,
+	body         text         not null,
+	anchor_date  date,
+	entry_id     uuid,
+	created_at   timestamptz  not null default now(),
+	edited_at    timestamptz,
+	deleted_at   timestamptz,
+	deleted_by   varchar(100) not null default ''
+);
+create index if not exists timesheet_comments_period_idx on timesheet_comments(org_id, login_name, period_start);
+
+create table if not exists timesheet_comment_revisions (
+	comment_id uuid         not null references timesheet_comments(id),
+	org_id     uuid         not null references organizations(id),
+	body       text         not null,
+	edited_by  varchar(100) not null,
+	edited_at  timestamptz  not null
+);
+create index if not exists timesheet_comment_revisions_idx on timesheet_comment_revisions(org_id, comment_id);
+
+insert into timesheet_comments(id, org_id, login_name, period_start, author, body)
+	select id, org_id, login_name, period_start, login_name, info from timesheets
+	where coalesce(info, '') <> ''
+	on conflict do nothing;
+
+-- Files attached to timesheets, their content is in the configured storage (timesheets.AttachmentRepository)
+create table if not exists attachments (
+	id           uuid         primary key,
+	org_id       uuid         not null references organizations(id),
+	login_name   varchar(100) not null,
+	period_start date         not null,
+	file_name    varchar(255) not null,
+	content_type varchar(100) not null,
+	size         bigint       not null,
+	checksum     char(64)     not null,
+	storage_key  varchar(500) not null,
+	uploaded_by  varchar(100) not null,
+	created_at   timestamptz  not null default now()
+);
+create index if not exists attachments_period_idx on attachments(org_id, login_name, period_start);
+
+-- Expense claims of a pay period and their lines (timesheets.ExpenseRepository)
+create table if not exists expense_claims (
+	id           uuid         primary key,
+	org_id       uuid         not null references organizations(id),
+	login_name   varchar(100) not null,
+	period_start date         not null,
+	period_end   date         not null,
+	title        varchar(200) not null,
+	status       varchar(20)  not null,
+	reviewed_by  varchar(100) not null default '',
+	reviewed_at  timestamptz,
+	review_note  varchar(1000) not null default '',
+	created_at   timestamptz  not null default now(),
+	updated_at   timestamptz  not null default now()
+);
+create index if not exists expense_claims_period_idx on expense_claims(org_id, login_name, period_start);
+create index if not exists expense_claims_status_idx on expense_claims(org_id, status, period_start);
+
+create table if not exists expense_lines (
+	id          uuid          primary key,
+	org_id      uuid          not null references organizations(id),
+	claim_id    uuid          not null references expense_claims(id) on delete cascade,
+	category    varchar(20)   not null,
+	amount      numeric(12,2) not null,
+	currency    char(3)       not null,
+	date        date          not null,
+	project     varchar(100)  not null default '',
+	description varchar(500)  not null default '',
+	receipt_id  uuid          references attachments(id) on delete restrict
+);
+create index if not exists expense_lines_claim_idx on expense_lines(org_id, claim_id);
+create index if not exists expense_lines_date_idx on expense_lines(org_id, date);
+-- Receipts of expenses may not be deleted, claims approved for payment must keep them
+alter table expense_lines drop constraint if exists expense_lines_receipt_id_fkey;
+alter table expense_lines add constraint expense_lines_receipt_id_fkey foreign key (receipt_id) references attachments(id)
+	on delete restrict;
+
+-- Exchange rates reports convert to a reporting currency with (timesheets.ExchangeRateRepository)
+create table if not exists exchange_rates (
+	id             uuid          primary key,
+	org_id         uuid          not null references organizations(id),
+	currency       char(3)       not null,
+	quote_currency char(3)       not null,
+	rate           numeric(18,8) not null,
+	effective_from date          not null,
+	source         varchar(255)  not null default '',
+	created_at     timestamptz   not null default now(),
+	-- Rates are never changed so conversions keep pointing at the rate they used: a rate entered again for the same
+	-- pair and day supersedes the older one, and a deleted rate is kept with who deleted it when
+	deleted_at     timestamptz,
+	deleted_by     varchar(100)  not null default ''
+);
+create index if not exists exchange_rates_pair_idx on exchange_rates(org_id, currency, quote_currency, effective_from);
+
+-- Hourly rates projects are invoiced at, login_name '' is the rate for everybody (timesheets.BillingRateRepository).
+-- Like exchange rates they are superseded and deleted but never changed.
+create table if not exists billing_rates (
+	id             uuid          primary key,
+	org_id         uuid          not null references organizations(id),
+	project        varchar(100)  not null,
+	login_name     varchar(100)  not null default '',
+	hourly_rate    numeric(12,2) not null,
+	currency       char(3)       not null,
+	effective_from date          not null,
+	created_at     timestamptz   not null default now(),
+	deleted_at     timestamptz,
+	deleted_by     varchar(100)  not null default ''
+);
+create index if not exists billing_rates_project_idx on billing_rates(org_id, lower(project), effective_from);
+create index if not exists time_entries_project_idx on time_entries(org_id, lower(project), work_date);
+
+-- Single sign-on identities, keyed by issuer and subject, and the account each logs in to (user.OIDCIdentityRepository)
+create table if not exists oidc_identities (
+	org_id     uuid         not null references organizations(id),
+	issuer     varchar(255) not null,
+	subject    varchar(255) not null,
+	login_name varchar(100) not null,
+	created_at timestamptz  not null default now(),
+	primary key (org_id, issuer, subject)
+);
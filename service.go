@@ -1,289 +1,858 @@
-package timesheets
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"strings"
-	"timesheet/user"
-
-	"github.com/google/uuid"
-	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
-)
-
-type Service interface {
-	CreateTimesheet(ctx context.Context, ts *Timesheet) (string, error)
-
-	UpdateTimesheet(ctx context.Context, ts *Timesheet, loginName string, month, year int) (string, error)
-
-	GetListofTimesheets(ctx context.Context, loginName string) ([]*GetAllTimesheets, error)
-
-	GetTimesheetsByWeek(ctx context.Context, loginName string, week, month, year int) (*GetTimesheet, error)
-
-	DeleteTimesheet(ctx context.Context, loginName string, month, year int) (string, error)
-
-	AddorUpdatenotes(ctx context.Context, notes *AddorUpdateNotes) (string, error)
-
-	UpdateNotes(ctx context.Context, updnotes *AddorUpdateNotes) (string, error)
-}
-
-type service struct {
-	repo     Repository
-	userRepo user.Repository
-}
-
-func NewService(repo Repository, userRepo user.Repository) Service {
-	return &service{repo: repo,
-		userRepo: userRepo}
-}
-
-func (s *service) CreateTimesheet(ctx context.Context, ts *Timesheet) (string, error) {
-	var err error
-	var loginName string
-	user := &user.User{}
-
-	if ts.LoginName == "" {
-		err = errors.New("loginName is empty")
-		return "", err
-	}
-
-	//Transforming fields
-	ts.LoginName = strings.ToUpper(ts.LoginName)
-
-	user, err = s.userRepo.SelectUserByLoginName(ctx, ts.LoginName)
-	if err != nil {
-		log.Error().Err(err).Str("loginName", ts.LoginName).Msg("User details not found for the given loginName")
-		return "", err
-	}
-
-	log.Info().Str("loginName", user.LoginName).Msg("logging the timesheet info")
-
-	ts.ID = uuid.New()
-
-	ts.LoginName = user.LoginName
-	ts.Placement = user.Department + " " + user.JobTitle
-	ts.Status = string(timesheetStatusSubmitted)
-
-	//Unmarshalling the week hrs json
-	wArr := []WeekHrs{}
-
-	if err = json.Unmarshal(ts.WeekHrs, &wArr); err != nil {
-		log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
-	}
-
-	for _, eachDayHrs := range wArr {
-		ts.TotalHours = eachDayHrs.Day1 + eachDayHrs.Day2 + eachDayHrs.Day3 + eachDayHrs.Day4 + eachDayHrs.Day5
-	}
-
-	if loginName, err = s.repo.InsertTimesheet(ctx, ts); err != nil {
-		log.Error().Err(err).Str("loginName", loginName).Msg("Error while calling repo in timesheet service")
-		return "", err
-	}
-
-	return loginName, nil
-}
-
-func (s *service) UpdateTimesheet(ctx context.Context, ts *Timesheet, loginName string, month, year int) (string, error) {
-	/*
-		Step1: Check  loginName,month,Year are not empty.
-		Step2 : check whether timesheets is having a record with this login
-		Step3: Call repo from service.
-	*/
-	var err error
-	var isExisting bool
-	var res string
-
-	if loginName == "" {
-		err = errors.New("loginName is empty")
-		return "", err
-	}
-
-	if month == 0 {
-		err = errors.New("loginName is empty")
-		return "", err
-	}
-
-	if year == 0 {
-		err = errors.New("loginName is empty")
-		return "", err
-	}
-
-	isExisting, err = s.repo.SelectTimesheetByLoginName(ctx, loginName, month, year)
-	if err != nil {
-		log.Error().Err(err).Msgf("Error while fetching Timesheet with given LoginName")
-		return "", err
-	}
-
-	if isExisting {
-		//Unmarshalling the week hrs json
-		wArr := []WeekHrs{}
-
-		if err = json.Unmarshal(ts.WeekHrs, &wArr); err != nil {
-			log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
-		}
-
-		for _, eachDayHrs := range wArr {
-			ts.TotalHours = eachDayHrs.Day1 + eachDayHrs.Day2 + eachDayHrs.Day3 + eachDayHrs.Day4 + eachDayHrs.Day5
-		}
-
-		res, err = s.repo.UpdateTimesheetByGivenCriteria(ctx, ts, loginName, month, year)
-		if err != nil {
-			log.Error().Err(err).Msgf("update Timesheet is failed with given criteria %s,%d,%d ", loginName, month, year)
-			return "", err
-		}
-	}
-	return res, nil
-}
-
-func (s *service) GetListofTimesheets(ctx context.Context, loginName string) ([]*GetAllTimesheets, error) {
-	var err error
-	ts := []*GetAllTimesheets{}
-
-	if loginName == "" {
-		err = errors.New("loginName is empty")
-		return nil, err
-	}
-	if ts, err = s.repo.SelectAllTimesheetByLoginName(ctx, loginName); err != nil {
-		log.Error().Err(err).Msgf("Error while fetching timesheet info by the given login Name %s", loginName)
-		return nil, err
-	}
-	return ts, nil
-}
-
-func (s *service) GetTimesheetsByWeek(ctx context.Context, loginName string, week, month, year int) (*GetTimesheet, error) {
-	var err error
-	ts := &GetAllTimesheets{}
-
-	if loginName == "" {
-		err = errors.New("loginName is empty")
-		return nil, err
-	}
-
-	if week == 0 {
-		err = errors.New("invalid week")
-		return nil, err
-	}
-
-	if month == 0 {
-		err = errors.New("invalid month")
-		return nil, err
-	}
-
-	if year == 0 {
-		err = errors.New("invalid year")
-		return nil, err
-	}
-
-	if ts, err = s.repo.SelectTimesheetByWeek(ctx, loginName, week, month, year); err != nil {
-		log.Error().Err(err).Str("loginName", loginName).Msgf("Error while fetching timeshhet infor by the given week %d", week)
-		return nil, err
-	}
-
-	log.Info().Msgf("json data from db %v", ts.WeekHrs)
-
-	//Step1 : Unmarshal the week hrs info data
-
-	wArr := []WeekHrs{}
-
-	if err = json.Unmarshal(ts.WeekHrs, &wArr); err != nil {
-		log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
-	}
-
-	log.Info().Msgf("un marshalled data into golang struct %v", wArr)
-
-	w := WeekHrs{}
-
-	for _, wI := range wArr {
-		if wI.WeekInfo == week {
-			w.WeekInfo = wI.WeekInfo
-			w.Day1 = wI.Day1
-			w.Day2 = wI.Day2
-			w.Day3 = wI.Day3
-			w.Day4 = wI.Day4
-			w.Day5 = wI.Day5
-		}
-	}
-
-	timesheet := &GetTimesheet{
-		LoginName:  ts.LoginName,
-		Status:     ts.Status,
-		Placement:  ts.Placement,
-		Info:       ts.Info,
-		TotalHours: ts.TotalHours,
-		Month:      ts.Month,
-		Year:       ts.Year,
-		WeekData:   w,
-	}
-
-	return timesheet, nil
-}
-
-func (s *service) DeleteTimesheet(ctx context.Context, loginName string, month, year int) (string, error) {
-	var err error
-	var response string
-	var isExisting bool
-
-	if loginName == "" {
-		err = errors.New("Login name is invalid")
-		return "", err
-	}
-
-	isExisting, err = s.repo.SelectTimesheetByLoginName(ctx, loginName, month, year)
-	if err != nil {
-		log.Error().Err(err).Msgf("Error while fetching Timesheet with given LoginName")
-		return "", err
-	}
-	if !isExisting {
-
-		response = fmt.Sprintf("We don't have data with the given login Name %s", loginName)
-		return response, nil
-	}
-
-	if isExisting {
-		response, err = s.repo.DeleteTimesheet(ctx, loginName, month, year)
-		if err != nil {
-			log.Error().Err(err).Str("loginname", loginName).Msg("Error while calling repo DeleteTimesheet")
-			return "", err
-		}
-	}
-	return response, nil
-}
-
-func (s *service) AddorUpdatenotes(ctx context.Context, notes *AddorUpdateNotes) (string, error) {
-	var err error
-	var res string
-
-	var uuid string
-
-	if notes.LoginName == "" && notes.Month == 0 && notes.Year == 0 {
-		err = errors.New("Criteria is not valid")
-		return "", err
-	}
-
-	uuid, err = s.repo.SelectTimesheetUUID(ctx, notes.LoginName, notes.Month, notes.Year)
-	if err != nil {
-		return "", err
-	}
-
-	res, err = s.repo.UpsertTimesheetNotes(ctx, notes, uuid)
-	if err != nil {
-		return "", err
-	}
-
-	return res, nil
-}
-func (s *service) UpdateNotes(ctx context.Context, updnotes *AddorUpdateNotes) (string, error) {
-	var err error
-	var result string
-
-	if updnotes.LoginName == "" && updnotes.Month == 0 && updnotes.Year == 0 {
-		err = errors.New("Criteria is not valid")
-		return "", err
-	}
-	result, err = s.repo.UpdateNotes(ctx, updnotes)
-	if err != nil {
-		return "", err
-	}
-	return result, nil
-}
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type Service interface {
+	//The timesheets of a user are open to the user, their manager and admins, caller below is checked for that
+	CreateTimesheet(ctx context.Context, caller *auth.Principal, ts *Timesheet) (string, error)
+
+	//CreateTimesheetWithEntries creates the timesheet like CreateTimesheet and stores the time entries its hours
+	//come from with it
+	CreateTimesheetWithEntries(ctx context.Context, caller *auth.Principal, ts *Timesheet, entries []*TimeEntry) (string, error)
+
+	//The month and year of UpdateTimesheet, GetTimesheetsByWeek, DeleteTimesheet, ReviewTimesheet and the notes
+	//address the timesheet of the pay period containing the 1st of that month
+	UpdateTimesheet(ctx context.Context, caller *auth.Principal, ts *Timesheet, loginName string, month, year int) (string, error)
+
+	GetListofTimesheets(ctx context.Context, caller *auth.Principal, loginName string) ([]*GetAllTimesheets, error)
+
+	GetTimesheetsByWeek(ctx context.Context, caller *auth.Principal, loginName string, week, month, year int) (*GetTimesheet, error)
+
+	DeleteTimesheet(ctx context.Context, caller *auth.Principal, loginName string, month, year int) (string, error)
+
+	//The ...ForPeriod variants address the timesheet of the pay period containing date, formatted as 2006-01-02
+	GetTimesheetForPeriod(ctx context.Context, caller *auth.Principal, loginName, date string) (*GetAllTimesheets, error)
+
+	UpdateTimesheetForPeriod(ctx context.Context, caller *auth.Principal, ts *Timesheet, loginName, date string) (string, error)
+
+	DeleteTimesheetForPeriod(ctx context.Context, caller *auth.Principal, loginName, date string) (string, error)
+
+	ReviewTimesheetForPeriod(ctx context.Context, reviewer *auth.Principal, loginName, date string, approve bool) (string, error)
+
+	//AddorUpdatenotes posts Info as a comment of author on the timesheet, UpdateNotes edits author's latest one.
+	//Both are kept for clients of the single notes field, see CommentService for the thread.
+	AddorUpdatenotes(ctx context.Context, author *auth.Principal, notes *AddorUpdateNotes) (string, error)
+
+	UpdateNotes(ctx context.Context, author *auth.Principal, updnotes *AddorUpdateNotes) (string, error)
+
+	//ReviewTimesheet approves or rejects a submitted timesheet, reviewer must be an admin or the user's manager
+	ReviewTimesheet(ctx context.Context, reviewer *auth.Principal, loginName string, month, year int, approve bool) (string, error)
+
+	//BookTimeEntry adds the hours of e to its work date on the timesheet of its pay period, creating the timesheet
+	//when there is none yet, and marks e booked with the change. Approved timesheets are not changed.
+	BookTimeEntry(ctx context.Context, e *TimeEntry) error
+
+	//RollupDayHours books the punched hours of date like BookTimeEntry. Only the change since the last rollup of the
+	//day is added, so hours entered by hand or booked by timers are kept.
+	RollupDayHours(ctx context.Context, loginName string, date time.Time, punchedHours float64) error
+}
+
+type service struct {
+	repo       Repository
+	userRepo   user.TenantUserRepository
+	leave      LeaveService
+	access     TimesheetAccess
+	holidays   HolidayService
+	overtime   OvertimeService
+	compliance ComplianceService
+	periods    PayPeriodService
+	comments   CommentService
+}
+
+func NewService(repo Repository, userRepo user.TenantUserRepository, leave LeaveService, access TimesheetAccess,
+	holidays HolidayService, overtime OvertimeService, compliance ComplianceService, periods PayPeriodService,
+	comments CommentService) Service {
+	return &service{repo: repo,
+		userRepo:   userRepo,
+		leave:      leave,
+		access:     access,
+		holidays:   holidays,
+		overtime:   overtime,
+		compliance: compliance,
+		periods:    periods,
+		comments:   comments}
+}
+
+func (s *service) CreateTimesheet(ctx context.Context, caller *auth.Principal, ts *Timesheet) (string, error) {
+	if ts.LoginName == "" {
+		return "", errors.New("loginName is empty")
+	}
+	if err := s.access.CheckWrite(ctx, caller, ts.LoginName); err != nil {
+		return "", err
+	}
+	return s.createTimesheet(ctx, caller, ts, nil)
+}
+
+func (s *service) CreateTimesheetWithEntries(ctx context.Context, caller *auth.Principal, ts *Timesheet, entries []*TimeEntry) (string, error) {
+	if ts.LoginName == "" {
+		return "", errors.New("loginName is empty")
+	}
+	if err := s.access.CheckWrite(ctx, caller, ts.LoginName); err != nil {
+		return "", err
+	}
+	return s.createTimesheet(ctx, caller, ts, &DayBooking{TimeEntries: entries})
+}
+
+//createTimesheet stores booking with the new timesheet when it is given. caller writes the note of Info, it is nil
+//for the timesheets created by bookings, which have none.
+func (s *service) createTimesheet(ctx context.Context, caller *auth.Principal, ts *Timesheet, booking *DayBooking) (string, error) {
+	var err error
+	var loginName string
+	user := &user.User{}
+
+	//Transforming fields
+	ts.LoginName = strings.ToUpper(ts.LoginName)
+
+	user, err = s.userRepo.SelectUserByLoginName(ctx, ts.LoginName)
+	if err != nil {
+		log.Error().Err(err).Str("loginName", ts.LoginName).Msg("User details not found for the given loginName")
+		return "", err
+	}
+	if user == nil {
+		return "", &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", ts.LoginName)}
+	}
+
+	log.Info().Str("loginName", user.LoginName).Msg("logging the timesheet info")
+
+	date := ts.PeriodStart
+	if date.IsZero() {
+		if ve := validateMonth(ts.Month, ts.Year); ve.HasErrors() {
+			return "", ve
+		}
+		date, _ = monthRange(ts.Month, ts.Year)
+	}
+	period, err := s.periods.OpenPeriod(ctx, user.LoginName, date)
+	if err != nil {
+		return "", err
+	}
+	if ts.PeriodStart.IsZero() {
+		if err = checkMonthPeriod(period, ts.Month, ts.Year); err != nil {
+			return "", err
+		}
+	}
+	ts.PeriodStart, ts.PeriodEnd, ts.PayFrequency = period.Start, period.End, period.Frequency
+	ts.Month, ts.Year = period.Month(), period.Year()
+
+	ts.ID = uuid.New()
+
+	ts.LoginName = user.LoginName
+	ts.Placement = user.Department + " " + user.JobTitle
+	ts.Status = string(timesheetStatusSubmitted)
+
+	//Unmarshalling the week hrs json
+	wArr := []WeekHrs{}
+
+	if err = json.Unmarshal(ts.WeekHrs, &wArr); err != nil {
+		log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
+	}
+
+	ts.TotalHours = 0
+	for _, eachDayHrs := range wArr {
+		ts.TotalHours += eachDayHrs.total()
+	}
+
+	breakdown, err := s.breakdown(ctx, user, period, wArr)
+	if err != nil {
+		return "", err
+	}
+	ts.HoursBreakdown = *breakdown
+
+	if ts.ComplianceWarnings, err = s.evaluate(ctx, ts.LoginName, period, wArr); err != nil {
+		return "", err
+	}
+
+	//Public holidays of the user's calendar are filled in up front
+	holidays, err := s.holidays.HolidaysBetween(ctx, user.LoginName, user.Department, period.Start, period.End)
+	if err != nil {
+		return "", err
+	}
+	if err = addHolidayAbsences(ts, period, holidays); err != nil {
+		return "", err
+	}
+
+	if err = normalizeAbsences(ts); err != nil {
+		return "", err
+	}
+
+	absences, _, _, _ := summarizeAbsences(ts.Absences)
+	if err = checkPeriodDays(period, wArr, absences); err != nil {
+		return "", err
+	}
+	if err = s.leave.CheckTimesheetAbsences(ctx, ts.LoginName, period, absences); err != nil {
+		return "", err
+	}
+
+	//Info starts the comment thread of the timesheet
+	fx, err := s.effects(ctx, caller, ts.LoginName, period, absences, ts.ComplianceWarnings, ts.Info, false)
+	if err != nil {
+		return "", err
+	}
+	fx.Booking = booking
+
+	if loginName, err = s.repo.InsertTimesheet(ctx, ts, newEvent(EventTimesheetCreated, ts.LoginName, period, ts.Status), fx); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while calling repo in timesheet service")
+		return "", err
+	}
+
+	return loginName, nil
+}
+
+//effects are the leave usage, compliance findings and note that the repository writes with a timesheet change.
+//A note is only planned for a non-empty info.
+func (s *service) effects(ctx context.Context, author *auth.Principal, loginName string, period *PayPeriod, absences []AbsenceEntry,
+	findings []ComplianceFinding, info string, replace bool) (*TimesheetEffects, error) {
+	usage, err := s.leave.TimesheetUsage(ctx, loginName, period, absences)
+	if err != nil {
+		return nil, err
+	}
+	fx := &TimesheetEffects{Period: period, LeaveUsage: usage, Findings: findings}
+	if info != "" {
+		if fx.Note, err = s.comments.PlanNote(ctx, author, loginName, period, info, replace); err != nil {
+			return nil, err
+		}
+	}
+	return fx, nil
+}
+
+func (s *service) UpdateTimesheet(ctx context.Context, caller *auth.Principal, ts *Timesheet, loginName string, month, year int) (string, error) {
+	/*
+		Step1: Check  loginName,month,Year are not empty.
+		Step2 : resolve the pay period and check whether timesheets is having a record with this login
+		Step3: Call repo from service.
+	*/
+	var err error
+
+	if loginName == "" {
+		err = errors.New("loginName is empty")
+		return "", err
+	}
+
+	if month == 0 {
+		err = errors.New("loginName is empty")
+		return "", err
+	}
+
+	if year == 0 {
+		err = errors.New("loginName is empty")
+		return "", err
+	}
+
+	if err = s.access.CheckWrite(ctx, caller, loginName); err != nil {
+		return "", err
+	}
+	period, err := s.periods.MonthPeriod(ctx, loginName, month, year)
+	if err != nil {
+		return "", err
+	}
+	return s.updateTimesheet(ctx, caller, ts, loginName, period.Start, nil)
+}
+
+func (s *service) UpdateTimesheetForPeriod(ctx context.Context, caller *auth.Principal, ts *Timesheet, loginName, date string) (string, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return "", ve
+	}
+	if err := s.access.CheckWrite(ctx, caller, loginName); err != nil {
+		return "", err
+	}
+	return s.updateTimesheet(ctx, caller, ts, loginName, day, nil)
+}
+
+//updateTimesheet updates the timesheet of the pay period containing date, if there is one. booking is stored with
+//the change when it is given, caller is nil for bookings like in createTimesheet.
+func (s *service) updateTimesheet(ctx context.Context, caller *auth.Principal, ts *Timesheet, loginName string, date time.Time,
+	booking *DayBooking) (string, error) {
+	var err error
+	var isExisting bool
+	var res string
+
+	if loginName == "" {
+		err = errors.New("loginName is empty")
+		return "", err
+	}
+
+	period, err := s.periods.PeriodFor(ctx, loginName, date)
+	if err != nil {
+		return "", err
+	}
+
+	isExisting, err = s.repo.SelectTimesheetByLoginName(ctx, loginName, period.Start)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error while fetching Timesheet with given LoginName")
+		return "", err
+	}
+
+	if isExisting {
+		//Unmarshalling the week hrs json
+		wArr := []WeekHrs{}
+
+		if err = json.Unmarshal(ts.WeekHrs, &wArr); err != nil {
+			log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
+		}
+
+		ts.TotalHours = 0
+		for _, eachDayHrs := range wArr {
+			ts.TotalHours += eachDayHrs.total()
+		}
+
+		breakdown, err := s.breakdownOf(ctx, loginName, period, wArr)
+		if err != nil {
+			return "", err
+		}
+		ts.HoursBreakdown = *breakdown
+
+		if ts.ComplianceWarnings, err = s.evaluate(ctx, loginName, period, wArr); err != nil {
+			return "", err
+		}
+
+		if err = normalizeAbsences(ts); err != nil {
+			return "", err
+		}
+
+		absences, _, _, _ := summarizeAbsences(ts.Absences)
+		if err = checkPeriodDays(period, wArr, absences); err != nil {
+			return "", err
+		}
+		if err = s.leave.CheckTimesheetAbsences(ctx, loginName, period, absences); err != nil {
+			return "", err
+		}
+
+		//A changed Info edits the caller's own note, the comments of others are kept
+		fx, err := s.effects(ctx, caller, loginName, period, absences, ts.ComplianceWarnings, ts.Info, true)
+		if err != nil {
+			return "", err
+		}
+		fx.Booking = booking
+
+		res, err = s.repo.UpdateTimesheetByGivenCriteria(ctx, ts, loginName, period.Start,
+			newEvent(EventTimesheetUpdated, loginName, period, ""), fx)
+		if err != nil {
+			log.Error().Err(err).Msgf("update Timesheet is failed with given criteria %s,%s ", loginName, period.Start.Format(dateLayout))
+			return "", err
+		}
+	}
+	return res, nil
+}
+
+func (s *service) GetListofTimesheets(ctx context.Context, caller *auth.Principal, loginName string) ([]*GetAllTimesheets, error) {
+	var err error
+	ts := []*GetAllTimesheets{}
+
+	if loginName == "" {
+		err = errors.New("loginName is empty")
+		return nil, err
+	}
+	if err = s.access.CheckView(ctx, caller, loginName); err != nil {
+		return nil, err
+	}
+	if ts, err = s.repo.SelectAllTimesheetByLoginName(ctx, loginName); err != nil {
+		log.Error().Err(err).Msgf("Error while fetching timesheet info by the given login Name %s", loginName)
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (s *service) GetTimesheetsByWeek(ctx context.Context, caller *auth.Principal, loginName string, week, month, year int) (*GetTimesheet, error) {
+	var err error
+	ts := &GetAllTimesheets{}
+
+	if loginName == "" {
+		err = errors.New("loginName is empty")
+		return nil, err
+	}
+
+	if week == 0 {
+		err = errors.New("invalid week")
+		return nil, err
+	}
+
+	if month == 0 {
+		err = errors.New("invalid month")
+		return nil, err
+	}
+
+	if year == 0 {
+		err = errors.New("invalid year")
+		return nil, err
+	}
+	if err = s.access.CheckView(ctx, caller, loginName); err != nil {
+		return nil, err
+	}
+
+	//Weeks count from the month, the timesheet is the one of the period the week's first day in the month falls in
+	from, _ := monthRange(month, year)
+	date, _ := slotDate(month, year, week, 1)
+	if date.Before(from) {
+		date = from
+	}
+	period, err := s.periods.PeriodFor(ctx, loginName, date)
+	if err != nil {
+		return nil, err
+	}
+	week, _ = period.hoursSlot(date)
+
+	if ts, err = s.repo.SelectTimesheetByPeriod(ctx, loginName, period.Start); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msgf("Error while fetching timeshhet infor by the given week %d", week)
+		return nil, err
+	}
+
+	if ts == nil {
+		return nil, nil
+	}
+
+	log.Info().Msgf("json data from db %v", ts.WeekHrs)
+
+	//Step1 : Unmarshal the week hrs info data
+
+	wArr := []WeekHrs{}
+
+	if err = json.Unmarshal(ts.WeekHrs, &wArr); err != nil {
+		log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
+	}
+
+	log.Info().Msgf("un marshalled data into golang struct %v", wArr)
+
+	w := WeekHrs{}
+
+	for _, wI := range wArr {
+		if wI.WeekInfo == week {
+			w.WeekInfo = wI.WeekInfo
+			w.Day1 = wI.Day1
+			w.Day2 = wI.Day2
+			w.Day3 = wI.Day3
+			w.Day4 = wI.Day4
+			w.Day5 = wI.Day5
+			w.Day6 = wI.Day6
+			w.Day7 = wI.Day7
+		}
+	}
+
+	absences, totals, absenceHours, err := summarizeAbsences(ts.Absences)
+	if err != nil {
+		log.Error().Err(err).Msg("Error while unmarshalling absence json")
+	}
+
+	weekAbsences := []AbsenceEntry{}
+	for _, a := range absences {
+		if a.WeekInfo == week {
+			weekAbsences = append(weekAbsences, a)
+		}
+	}
+
+	timesheet := &GetTimesheet{
+		LoginName:      ts.LoginName,
+		Status:         ts.Status,
+		Placement:      ts.Placement,
+		Info:           ts.Info,
+		TotalHours:     ts.TotalHours,
+		Month:          ts.Month,
+		Year:           ts.Year,
+		PeriodStart:    ts.PeriodStart,
+		PeriodEnd:      ts.PeriodEnd,
+		WeekData:       w,
+		Absences:       weekAbsences,
+		AbsenceHours:   absenceHours,
+		AbsenceTotals:  totals,
+		HoursBreakdown: ts.HoursBreakdown,
+	}
+
+	return timesheet, nil
+}
+
+func (s *service) GetTimesheetForPeriod(ctx context.Context, caller *auth.Principal, loginName, date string) (*GetAllTimesheets, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	if err := s.access.CheckView(ctx, caller, loginName); err != nil {
+		return nil, err
+	}
+
+	period, err := s.periods.PeriodFor(ctx, loginName, day)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SelectTimesheetByPeriod(ctx, strings.ToUpper(loginName), period.Start)
+}
+
+//breakdownOf looks up the user's department, which the overtime rule depends on, and splits the hours
+func (s *service) breakdownOf(ctx context.Context, loginName string, period *PayPeriod, weeks []WeekHrs) (*HoursBreakdown, error) {
+	u, err := s.userRepo.SelectUserByLoginName(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+	return s.breakdown(ctx, u, period, weeks)
+}
+
+//breakdown splits the hours with the days of the period's first calendar week that belong to the previous period
+func (s *service) breakdown(ctx context.Context, u *user.User, period *PayPeriod, weeks []WeekHrs) (*HoursBreakdown, error) {
+	earlier, err := s.hoursBefore(ctx, u.LoginName, period, (int(period.Start.Weekday())+6)%7)
+	if err != nil {
+		return nil, err
+	}
+	return s.overtime.Breakdown(ctx, u.LoginName, u.Department, period, weeks, earlier)
+}
+
+//complianceLookbackDays is how far back earlier timesheets are read for the compliance checks
+const complianceLookbackDays = 31
+
+//evaluate runs the compliance checks with the days of the previous periods they look back on
+func (s *service) evaluate(ctx context.Context, loginName string, period *PayPeriod, weeks []WeekHrs) ([]ComplianceFinding, error) {
+	earlier, err := s.hoursBefore(ctx, loginName, period, complianceLookbackDays)
+	if err != nil {
+		return nil, err
+	}
+	return s.compliance.Evaluate(ctx, loginName, period, weeks, earlier)
+}
+
+//hoursBefore returns the hours per day recorded on earlier timesheets for the given number of days before the period
+func (s *service) hoursBefore(ctx context.Context, loginName string, period *PayPeriod, days int) (map[time.Time]float64, error) {
+	hours := map[time.Time]float64{}
+	if days <= 0 {
+		return hours, nil
+	}
+
+	from := period.Start.AddDate(0, 0, -days)
+	earlier, err := s.repo.SelectWeekHoursBetween(ctx, loginName, from, period.Start.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	for _, ts := range earlier {
+		weeks := []WeekHrs{}
+		if err = json.Unmarshal(ts.WeekHrs, &weeks); err != nil {
+			return nil, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+		p := &PayPeriod{Start: ts.PeriodStart, End: ts.PeriodEnd, Frequency: ts.PayFrequency}
+		for _, w := range weeks {
+			for i, h := range w.days() {
+				date := p.slotDate(w.WeekInfo, i+1)
+				if h > 0 && p.contains(date) && !date.Before(from) && date.Before(period.Start) {
+					hours[date] += h
+				}
+			}
+		}
+	}
+	return hours, nil
+}
+
+//normalizeAbsences validates the absence entries of ts and sets AbsenceHours from them
+func normalizeAbsences(ts *Timesheet) error {
+	if len(ts.Absences) == 0 {
+		ts.Absences = sql.JSONText("[]")
+	}
+
+	absences, _, total, err := summarizeAbsences(ts.Absences)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+
+	ve := validate.New()
+	for _, a := range absences {
+		ve.IsWithin("Absences.Type", string(a.Type), absenceTypes)
+		ve.IsNumberInRange("Absences.WeekInfo", a.WeekInfo, 1, maxPeriodWeeks)
+		ve.IsNumberInRange("Absences.Day", a.Day, 1, 5)
+		if a.Hours <= 0 || a.Hours > 24 {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: "Absences.Hours", Constraint: validate.Range,
+				Message: "Value is not within range", Args: []interface{}{0, 24}})
+		}
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+
+	ts.AbsenceHours = total
+	return nil
+}
+
+//checkPeriodDays rejects hours and absences on days outside the pay period
+func checkPeriodDays(period *PayPeriod, weeks []WeekHrs, absences []AbsenceEntry) error {
+	ve := validate.New()
+	for _, w := range weeks {
+		for day, hours := range w.days() {
+			if hours != 0 && !period.contains(period.slotDate(w.WeekInfo, day+1)) {
+				ve.Errors = append(ve.Errors, validate.FieldError{Field: fmt.Sprintf("WeekHrs[%d].Day%d", w.WeekInfo, day+1),
+					Constraint: validate.Range, Message: "Day is outside of the pay period",
+					Args: []interface{}{period.Start.Format(dateLayout), period.End.Format(dateLayout)}})
+			}
+		}
+	}
+	for _, a := range absences {
+		if !period.contains(period.slotDate(a.WeekInfo, a.Day)) {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: fmt.Sprintf("Absences[%d].Day%d", a.WeekInfo, a.Day),
+				Constraint: validate.Range, Message: "Day is outside of the pay period",
+				Args: []interface{}{period.Start.Format(dateLayout), period.End.Format(dateLayout)}})
+		}
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+	return nil
+}
+
+//summarizeAbsences decodes the absence entries and totals their hours per type and overall
+func summarizeAbsences(raw sql.JSONText) ([]AbsenceEntry, map[AbsenceType]float64, float64, error) {
+	absences := []AbsenceEntry{}
+	totals := map[AbsenceType]float64{}
+	if len(raw) == 0 {
+		return absences, totals, 0, nil
+	}
+
+	if err := json.Unmarshal(raw, &absences); err != nil {
+		return absences, totals, 0, err
+	}
+
+	var total float64
+	for _, a := range absences {
+		totals[a.Type] += a.Hours
+		total += a.Hours
+	}
+	return absences, totals, total, nil
+}
+
+func (s *service) DeleteTimesheet(ctx context.Context, caller *auth.Principal, loginName string, month, year int) (string, error) {
+	if err := s.access.CheckWrite(ctx, caller, loginName); err != nil {
+		return "", err
+	}
+	period, err := s.periods.MonthPeriod(ctx, loginName, month, year)
+	if err != nil {
+		return "", err
+	}
+	return s.deleteTimesheet(ctx, loginName, period.Start)
+}
+
+func (s *service) DeleteTimesheetForPeriod(ctx context.Context, caller *auth.Principal, loginName, date string) (string, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return "", ve
+	}
+	if err := s.access.CheckWrite(ctx, caller, loginName); err != nil {
+		return "", err
+	}
+	return s.deleteTimesheet(ctx, loginName, day)
+}
+
+func (s *service) deleteTimesheet(ctx context.Context, loginName string, date time.Time) (string, error) {
+	var err error
+	var response string
+	var isExisting bool
+
+	if loginName == "" {
+		err = errors.New("Login name is invalid")
+		return "", err
+	}
+
+	period, err := s.periods.PeriodFor(ctx, loginName, date)
+	if err != nil {
+		return "", err
+	}
+
+	isExisting, err = s.repo.SelectTimesheetByLoginName(ctx, loginName, period.Start)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error while fetching Timesheet with given LoginName")
+		return "", err
+	}
+	if !isExisting {
+
+		response = fmt.Sprintf("We don't have data with the given login Name %s", loginName)
+		return response, nil
+	}
+
+	if isExisting {
+		//The leave the timesheet took is given back and its findings removed
+		fx, err := s.effects(ctx, nil, loginName, period, nil, nil, "", false)
+		if err != nil {
+			return "", err
+		}
+
+		response, err = s.repo.DeleteTimesheet(ctx, loginName, period.Start, newEvent(EventTimesheetDeleted, loginName, period, ""), fx)
+		if err != nil {
+			log.Error().Err(err).Str("loginname", loginName).Msg("Error while calling repo DeleteTimesheet")
+			return "", err
+		}
+	}
+	return response, nil
+}
+
+func (s *service) ReviewTimesheet(ctx context.Context, reviewer *auth.Principal, loginName string, month, year int, approve bool) (string, error) {
+	period, err := s.periods.MonthPeriod(ctx, loginName, month, year)
+	if err != nil {
+		return "", err
+	}
+	return s.reviewTimesheet(ctx, reviewer, loginName, period.Start, approve)
+}
+
+func (s *service) ReviewTimesheetForPeriod(ctx context.Context, reviewer *auth.Principal, loginName, date string, approve bool) (string, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return "", ve
+	}
+	return s.reviewTimesheet(ctx, reviewer, loginName, day, approve)
+}
+
+func (s *service) reviewTimesheet(ctx context.Context, reviewer *auth.Principal, loginName string, date time.Time, approve bool) (string, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckReview(ctx, reviewer, loginName); err != nil {
+		return "", err
+	}
+
+	period, err := s.periods.PeriodFor(ctx, loginName, date)
+	if err != nil {
+		return "", err
+	}
+	start := period.Start.Format(dateLayout)
+
+	status, eventType := timesheetStatusRejected, EventTimesheetRejected
+	if approve {
+		status, eventType = timesheetStatusApproved, EventTimesheetApproved
+	}
+	reviewed, err := s.repo.UpdateTimesheetStatus(ctx, loginName, period.Start, status,
+		[]timesheetStatus{timesheetStatusSubmitted, timesheetStatusViewed}, newEvent(eventType, loginName, period, string(status)))
+	if err != nil {
+		return "", err
+	}
+	if !reviewed {
+		exists, err := s.repo.SelectTimesheetByLoginName(ctx, loginName, period.Start)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("no timesheet of %s for the period from %s", loginName, start)}
+		}
+		return "", &res.AppError{ResponseCode: TimesheetNotPending, Cause: errors.Errorf("timesheet of %s for the period from %s is not pending", loginName, start)}
+	}
+
+	return fmt.Sprintf("Timesheet %s %s,%s", strings.ToLower(string(status)), loginName, start), nil
+}
+
+func (s *service) BookTimeEntry(ctx context.Context, e *TimeEntry) error {
+	return s.changeDayHours(ctx, strings.ToUpper(e.LoginName), e.WorkDate, func(ctx context.Context) (float64, *DayBooking, error) {
+		return e.Hours, &DayBooking{TimeEntryID: &e.ID}, nil
+	})
+}
+
+func (s *service) RollupDayHours(ctx context.Context, loginName string, date time.Time, punchedHours float64) error {
+	loginName = strings.ToUpper(loginName)
+	return s.changeDayHours(ctx, loginName, date, func(ctx context.Context) (float64, *DayBooking, error) {
+		previous, err := s.repo.SelectPunchRollup(ctx, loginName, date)
+		if err != nil {
+			return 0, nil, err
+		}
+		return roundHours(punchedHours - previous), &DayBooking{Rollup: &PunchRollup{LoginName: loginName, WorkDate: date,
+			Hours: punchedHours}}, nil
+	})
+}
+
+//changeDayHours adds the hours given by book to the hours of date on the timesheet of its pay period, a day does
+//not go below zero. The timesheet is locked from reading it to storing the change, which book is called in, so
+//concurrent punches, timers and edits do not overwrite each other. The booking is stored with the change.
+func (s *service) changeDayHours(ctx context.Context, loginName string, date time.Time,
+	book func(ctx context.Context) (float64, *DayBooking, error)) error {
+	period, err := s.periods.PeriodFor(ctx, loginName, date)
+	if err != nil {
+		return err
+	}
+	return s.repo.LockTimesheet(ctx, loginName, period.Start, func(ctx context.Context) error {
+		return s.changeLockedDayHours(ctx, loginName, date, period, book)
+	})
+}
+
+func (s *service) changeLockedDayHours(ctx context.Context, loginName string, date time.Time, period *PayPeriod,
+	book func(ctx context.Context) (float64, *DayBooking, error)) error {
+	hours, booking, err := book(ctx)
+	if err != nil || hours == 0 {
+		return err
+	}
+	change := func(current float64) float64 { return math.Max(0, roundHours(current+hours)) }
+	week, day := period.hoursSlot(date)
+
+	current, err := s.repo.SelectTimesheetByPeriod(ctx, loginName, period.Start)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		w := WeekHrs{WeekInfo: week}
+		w.setDay(day, change(0))
+		weeks, _ := json.Marshal([]WeekHrs{w})
+		_, err = s.createTimesheet(ctx, nil, &Timesheet{LoginName: loginName, PeriodStart: period.Start, WeekHrs: weeks}, booking)
+		return err
+	}
+	if current.Status == string(timesheetStatusApproved) {
+		return &res.AppError{ResponseCode: TimesheetNotPending,
+			Cause: errors.Errorf("timesheet of %s for the period from %s is approved", loginName, period.Start.Format(dateLayout))}
+	}
+
+	weeks := []WeekHrs{}
+	if len(current.WeekHrs) > 0 {
+		if err = json.Unmarshal(current.WeekHrs, &weeks); err != nil {
+			log.Error().Err(err).Msg("Error while unmarshalling week hrs json")
+		}
+	}
+	found := false
+	for i := range weeks {
+		if weeks[i].WeekInfo == week {
+			weeks[i].setDay(day, change(weeks[i].days()[day-1]))
+			found = true
+		}
+	}
+	if !found {
+		w := WeekHrs{WeekInfo: week}
+		w.setDay(day, change(0))
+		weeks = append(weeks, w)
+	}
+	weekHrs, _ := json.Marshal(weeks)
+
+	ts := &Timesheet{Placement: current.Placement, WeekHrs: weekHrs, Absences: current.Absences}
+	_, err = s.updateTimesheet(ctx, nil, ts, loginName, date, booking)
+	return err
+}
+
+//newEvent describes a change for the outbox, the repository writes it along with the change
+func newEvent(eventType EventType, loginName string, period *PayPeriod, status string) *Event {
+	return &Event{ID: uuid.New(), Type: eventType, LoginName: strings.ToUpper(loginName), Month: period.Month(), Year: period.Year(),
+		PeriodStart: period.Start, PeriodEnd: period.End, Status: status, OccurredAt: time.Now()}
+}
+
+func (s *service) AddorUpdatenotes(ctx context.Context, author *auth.Principal, notes *AddorUpdateNotes) (string, error) {
+	if err := s.note(ctx, author, notes, false); err != nil {
+		return "", err
+	}
+	return "Added notes successfully", nil
+}
+
+func (s *service) UpdateNotes(ctx context.Context, author *auth.Principal, updnotes *AddorUpdateNotes) (string, error) {
+	if err := s.note(ctx, author, updnotes, true); err != nil {
+		return "", err
+	}
+	return "Updated notes successfully", nil
+}
+
+func (s *service) note(ctx context.Context, author *auth.Principal, notes *AddorUpdateNotes, replace bool) error {
+	if notes.LoginName == "" && notes.Month == 0 && notes.Year == 0 {
+		return errors.New("Criteria is not valid")
+	}
+
+	period, err := s.periods.MonthPeriod(ctx, notes.LoginName, notes.Month, notes.Year)
+	if err != nil {
+		return err
+	}
+	return s.comments.Note(ctx, author, notes.LoginName, period, notes.Info, replace)
+}
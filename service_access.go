@@ -0,0 +1,80 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/user"
+
+	"github.com/pkg/errors"
+)
+
+//TimesheetAccess decides who may see, write and review the timesheets of a user and everything booked on them:
+//time entries, punches, comments, attachments and expense claims
+type TimesheetAccess interface {
+	//CheckView lets the user, their manager and admins see the timesheets of loginName
+	CheckView(ctx context.Context, viewer *auth.Principal, loginName string) error
+
+	//CheckWrite lets only the user and admins create, change or delete the timesheets of loginName. A manager may see
+	//them but not write what they are going to approve.
+	CheckWrite(ctx context.Context, writer *auth.Principal, loginName string) error
+
+	//CheckReview lets admins and the manager of loginName approve or reject, never the user themselves
+	CheckReview(ctx context.Context, reviewer *auth.Principal, loginName string) error
+}
+
+type timesheetAccess struct {
+	dirRepo user.DirectoryRepository
+}
+
+func NewTimesheetAccess(dirRepo user.DirectoryRepository) TimesheetAccess {
+	return &timesheetAccess{dirRepo: dirRepo}
+}
+
+func (a *timesheetAccess) CheckView(ctx context.Context, viewer *auth.Principal, loginName string) error {
+	loginName = strings.ToUpper(loginName)
+	if viewer.Admin || viewer.LoginName == loginName {
+		return nil
+	}
+	isManager, err := a.isManagerOf(ctx, viewer.LoginName, loginName)
+	if err != nil {
+		return err
+	}
+	if !isManager {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not see the timesheets of %s", viewer.LoginName, loginName)}
+	}
+	return nil
+}
+
+func (a *timesheetAccess) CheckWrite(ctx context.Context, writer *auth.Principal, loginName string) error {
+	loginName = strings.ToUpper(loginName)
+	if !writer.Admin && writer.LoginName != loginName {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not change the timesheets of %s", writer.LoginName, loginName)}
+	}
+	return nil
+}
+
+func (a *timesheetAccess) CheckReview(ctx context.Context, reviewer *auth.Principal, loginName string) error {
+	loginName = strings.ToUpper(loginName)
+	if reviewer.Admin {
+		return nil
+	}
+	isManager, err := a.isManagerOf(ctx, reviewer.LoginName, loginName)
+	if err != nil {
+		return err
+	}
+	if !isManager || reviewer.LoginName == loginName {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s is not the manager of %s", reviewer.LoginName, loginName)}
+	}
+	return nil
+}
+
+func (a *timesheetAccess) isManagerOf(ctx context.Context, manager, loginName string) (bool, error) {
+	e, err := a.dirRepo.SelectDirectoryEntryByLoginName(ctx, loginName)
+	if err != nil {
+		return false, err
+	}
+	return e != nil && e.ManagerLoginName != "" && e.ManagerLoginName == manager, nil
+}
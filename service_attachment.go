@@ -0,0 +1,195 @@
+package timesheets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/storage"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//AttachmentService keeps the files attached to timesheets. The user, their manager and admins see and attach
+//files; a file is deleted by whoever attached it or an admin.
+type AttachmentService interface {
+	//Upload attaches content to the timesheet of the pay period containing date, formatted as 2006-01-02
+	Upload(ctx context.Context, uploader *auth.Principal, loginName, date, fileName string, content io.Reader) (*Attachment, error)
+
+	ListAttachments(ctx context.Context, viewer *auth.Principal, loginName, date string) ([]*Attachment, error)
+
+	//Download returns the attachment and its content, the caller closes the content
+	Download(ctx context.Context, viewer *auth.Principal, loginName, attachmentID string) (*Attachment, io.ReadCloser, error)
+
+	//DeleteAttachment fails with AttachmentInUse for the receipt of an expense
+	DeleteAttachment(ctx context.Context, editor *auth.Principal, loginName, attachmentID string) error
+}
+
+type attachmentService struct {
+	repo    AttachmentRepository
+	store   storage.Store
+	scanner storage.Scanner
+	periods PayPeriodService
+	access  TimesheetAccess
+	cfg     AttachmentConfig
+}
+
+func NewAttachmentService(repo AttachmentRepository, store storage.Store, scanner storage.Scanner, periods PayPeriodService,
+	access TimesheetAccess, cfg AttachmentConfig) AttachmentService {
+	return &attachmentService{repo: repo, store: store, scanner: scanner, periods: periods, access: access, cfg: cfg}
+}
+
+func (s *attachmentService) Upload(ctx context.Context, uploader *auth.Principal, loginName, date, fileName string, content io.Reader) (*Attachment, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, uploader, loginName); err != nil {
+		return nil, err
+	}
+	day, ve := parseDate("date", date)
+	//Only the last part of a path sent by the client is kept
+	fileName = path.Base(strings.ReplaceAll(fileName, `\`, "/"))
+	ve.IsSizeInRange("FileName", strings.Trim(fileName, "/."), 1, 255)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	period, err := s.periods.PeriodFor(ctx, loginName, day)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, s.cfg.MaxSize+1))
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	if int64(len(data)) > s.cfg.MaxSize {
+		return nil, &res.AppError{ResponseCode: AttachmentTooLarge, Cause: errors.Errorf("%s is larger than %d bytes", fileName, s.cfg.MaxSize)}
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !s.allowed(contentType) {
+		return nil, &res.AppError{ResponseCode: AttachmentTypeNotAllowed, Cause: errors.Errorf("%s is of type %s", fileName, contentType)}
+	}
+
+	if err = s.scanner.Scan(ctx, fileName, data); err != nil {
+		if errors.Is(err, storage.ErrInfected) {
+			log.Warn().Err(err).Str("loginName", loginName).Str("fileName", fileName).Msg("Refused an infected attachment")
+			return nil, &res.AppError{ResponseCode: AttachmentInfected, Cause: err}
+		}
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while scanning the attachment")
+		return nil, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}
+	}
+
+	orgID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	a := &Attachment{ID: uuid.New(), LoginName: loginName, PeriodStart: period.Start, FileName: fileName,
+		ContentType: contentType, Size: int64(len(data)), Checksum: hex.EncodeToString(sum[:]),
+		UploadedBy: uploader.LoginName, CreatedAt: time.Now()}
+	a.StorageKey = strings.Join([]string{orgID.String(), loginName, period.Start.Format(dateLayout), a.ID.String()}, "/")
+
+	if err = s.store.Put(ctx, a.StorageKey, bytes.NewReader(data), a.Size, a.ContentType); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Error while storing the attachment")
+		return nil, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}
+	}
+	if err = s.repo.InsertAttachment(ctx, a); err != nil {
+		if err := s.store.Delete(ctx, a.StorageKey); err != nil {
+			log.Error().Err(err).Str("key", a.StorageKey).Msg("Error while removing the content of a failed attachment")
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *attachmentService) ListAttachments(ctx context.Context, viewer *auth.Principal, loginName, date string) ([]*Attachment, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	period, err := s.periods.PeriodFor(ctx, loginName, day)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SelectAttachments(ctx, loginName, period.Start)
+}
+
+func (s *attachmentService) Download(ctx context.Context, viewer *auth.Principal, loginName, attachmentID string) (*Attachment, io.ReadCloser, error) {
+	a, err := s.findAttachment(ctx, viewer, loginName, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.store.Get(ctx, a.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, &res.AppError{ResponseCode: AttachmentNotFound, Cause: err}
+		}
+		return nil, nil, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}
+	}
+	return a, content, nil
+}
+
+func (s *attachmentService) DeleteAttachment(ctx context.Context, editor *auth.Principal, loginName, attachmentID string) error {
+	a, err := s.findAttachment(ctx, editor, loginName, attachmentID)
+	if err != nil {
+		return err
+	}
+	if a.UploadedBy != editor.LoginName && !editor.Admin {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not delete a file attached by %s", editor.LoginName, a.UploadedBy)}
+	}
+
+	deleted, err := s.repo.DeleteAttachment(ctx, a.LoginName, a.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &res.AppError{ResponseCode: AttachmentNotFound, Cause: errors.Errorf("attachment %s not found", attachmentID)}
+	}
+	//The row is gone, content left behind by a failed delete is only wasted space
+	if err = s.store.Delete(ctx, a.StorageKey); err != nil {
+		log.Error().Err(err).Str("key", a.StorageKey).Msg("Error while deleting the content of an attachment")
+	}
+	return nil
+}
+
+func (s *attachmentService) findAttachment(ctx context.Context, viewer *auth.Principal, loginName, attachmentID string) (*Attachment, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(attachmentID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	a, err := s.repo.SelectAttachment(ctx, loginName, id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, &res.AppError{ResponseCode: AttachmentNotFound, Cause: errors.Errorf("attachment %s not found", attachmentID)}
+	}
+	return a, nil
+}
+
+func (s *attachmentService) allowed(contentType string) bool {
+	for _, t := range s.cfg.AllowedTypes {
+		if strings.EqualFold(strings.TrimSpace(t), contentType) {
+			return true
+		}
+	}
+	return false
+}
+
@@ -0,0 +1,127 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//BillingRateService keeps the hourly rates projects are invoiced at
+type BillingRateService interface {
+	//SetRate stores a rate. It supersedes a rate of the same project and user taking effect on the same day, which
+	//is kept.
+	SetRate(ctx context.Context, r *BillingRate) (*BillingRate, error)
+
+	//ListRates lists the rates newest first, of one project when project is not empty. Deleted rates are only
+	//listed withDeleted.
+	ListRates(ctx context.Context, project string, withDeleted bool) ([]*BillingRate, error)
+
+	//DeleteRate takes a rate out of invoices, the rate is kept for the invoices that used it
+	DeleteRate(ctx context.Context, admin *auth.Principal, rateID string) error
+
+	//Rates gives the rates of project stored when it is made
+	Rates(ctx context.Context, project string) (*ProjectRates, error)
+}
+
+type billingRateService struct {
+	repo BillingRateRepository
+}
+
+func NewBillingRateService(repo BillingRateRepository) BillingRateService {
+	return &billingRateService{repo: repo}
+}
+
+//maxHourlyRate bounds a billing rate, no hour is billed at more in any currency
+const maxHourlyRate = 1000000
+
+func (s *billingRateService) SetRate(ctx context.Context, r *BillingRate) (*BillingRate, error) {
+	if ve := validateBillingRate(r); ve.HasErrors() {
+		return nil, ve
+	}
+
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now()
+	r.DeletedAt, r.DeletedBy = nil, ""
+	if err := s.repo.InsertRate(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *billingRateService) ListRates(ctx context.Context, project string, withDeleted bool) ([]*BillingRate, error) {
+	return s.repo.SelectRates(ctx, strings.TrimSpace(project), withDeleted)
+}
+
+func (s *billingRateService) DeleteRate(ctx context.Context, admin *auth.Principal, rateID string) error {
+	id, err := uuid.Parse(rateID)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	deleted, err := s.repo.DeleteRate(ctx, id, admin.LoginName)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &res.AppError{ResponseCode: BillingRateNotFound, Cause: errors.Errorf("billing rate %s not found", rateID)}
+	}
+	return nil
+}
+
+func (s *billingRateService) Rates(ctx context.Context, project string) (*ProjectRates, error) {
+	project = strings.TrimSpace(project)
+	rates, err := s.repo.SelectRates(ctx, project, false)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectRates{Project: project, rates: rates}, nil
+}
+
+func validateBillingRate(r *BillingRate) *validate.ValidationError {
+	ve := validate.New()
+	r.Project, r.LoginName = strings.TrimSpace(r.Project), strings.ToUpper(strings.TrimSpace(r.LoginName))
+	r.Currency = strings.ToUpper(r.Currency)
+	r.EffectiveFrom = dayOf(r.EffectiveFrom)
+	ve.IsSizeInRange("Project", r.Project, 1, 100)
+	ve.IsSizeInRange("LoginName", r.LoginName, 0, 100)
+	validateCurrency(ve, "Currency", r.Currency)
+	if r.HourlyRate <= 0 || r.HourlyRate > maxHourlyRate {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "HourlyRate", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, maxHourlyRate}})
+	}
+	if r.EffectiveFrom.IsZero() {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "EffectiveFrom", Constraint: validate.Required,
+			Message: "Field is required", Args: nil})
+	}
+	return ve
+}
+
+//ProjectRates finds the billing rate of a project for a user on a day
+type ProjectRates struct {
+	Project string
+	//rates are newest first, of the same day the last stored first
+	rates []*BillingRate
+}
+
+//rateOn is the newest rate of the user that took effect by date, or else the newest such rate for everybody
+func (p *ProjectRates) rateOn(loginName string, date time.Time) *BillingRate {
+	var everybody *BillingRate
+	for _, r := range p.rates {
+		if r.EffectiveFrom.After(date) {
+			continue
+		}
+		if r.LoginName == loginName {
+			return r
+		}
+		if r.LoginName == "" && everybody == nil {
+			everybody = r
+		}
+	}
+	return everybody
+}
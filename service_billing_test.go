@@ -0,0 +1,39 @@
+package timesheets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProjectRatesRateOn(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	aliceMarch := &BillingRate{LoginName: "ALICE", HourlyRate: 120, Currency: "EUR", EffectiveFrom: date(2026, 3, 1)}
+	everybodyFebruary := &BillingRate{HourlyRate: 95, Currency: "USD", EffectiveFrom: date(2026, 2, 1)}
+	everybodyFebruaryFirst := &BillingRate{HourlyRate: 90, Currency: "USD", EffectiveFrom: date(2026, 2, 1)}
+	everybodyJanuary := &BillingRate{HourlyRate: 80, Currency: "EUR", EffectiveFrom: date(2026, 1, 1)}
+	rates := &ProjectRates{Project: "Apollo",
+		rates: []*BillingRate{aliceMarch, everybodyFebruary, everybodyFebruaryFirst, everybodyJanuary}}
+
+	cases := []struct {
+		name      string
+		loginName string
+		date      time.Time
+		want      *BillingRate
+	}{
+		{name: "rate of the user", loginName: "ALICE", date: date(2026, 3, 15), want: aliceMarch},
+		{name: "rate for everybody before the rate of the user", loginName: "ALICE", date: date(2026, 2, 28),
+			want: everybodyFebruary},
+		{name: "rate for everybody of another user", loginName: "BOB", date: date(2026, 3, 15), want: everybodyFebruary},
+		{name: "last stored of the same day", loginName: "BOB", date: date(2026, 2, 1), want: everybodyFebruary},
+		{name: "older rate", loginName: "BOB", date: date(2026, 1, 31), want: everybodyJanuary},
+		{name: "no rate before the first", loginName: "BOB", date: date(2025, 12, 31)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := rates.rateOn(c.loginName, c.date); got != c.want {
+				t.Fatalf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
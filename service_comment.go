@@ -0,0 +1,266 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//CommentService keeps the comment threads of timesheets. The user, their manager and admins take part in the
+//thread of a timesheet; comments are edited by their author and deleted by their author or an admin.
+type CommentService interface {
+	//AddComment comments on the timesheet of the pay period containing date, formatted as 2006-01-02
+	AddComment(ctx context.Context, author *auth.Principal, loginName, date string, c *TimesheetComment) (*TimesheetComment, error)
+
+	ListComments(ctx context.Context, viewer *auth.Principal, loginName, date string) ([]*TimesheetComment, error)
+
+	EditComment(ctx context.Context, editor *auth.Principal, loginName, commentID, body string) (*TimesheetComment, error)
+
+	DeleteComment(ctx context.Context, editor *auth.Principal, loginName, commentID string) error
+
+	//CommentHistory lists the texts a comment had before each edit and its delete
+	CommentHistory(ctx context.Context, viewer *auth.Principal, loginName, commentID string) ([]*CommentRevision, error)
+
+	//Note posts body as a comment of author about the whole timesheet of the period. With replace it edits the
+	//author's latest such comment instead, if there is one. Nothing changes when body is already the latest
+	//comment of the thread.
+	Note(ctx context.Context, author *auth.Principal, loginName string, period *PayPeriod, body string, replace bool) error
+
+	//PlanNote is the change Note makes, for a timesheet change to write in its transaction. It is nil when nothing
+	//changes.
+	PlanNote(ctx context.Context, author *auth.Principal, loginName string, period *PayPeriod, body string, replace bool) (*NoteChange, error)
+}
+
+type commentService struct {
+	repo    CommentRepository
+	timers  TimerRepository
+	periods PayPeriodService
+	access  TimesheetAccess
+}
+
+func NewCommentService(repo CommentRepository, timers TimerRepository, periods PayPeriodService, access TimesheetAccess) CommentService {
+	return &commentService{repo: repo, timers: timers, periods: periods, access: access}
+}
+
+func (s *commentService) AddComment(ctx context.Context, author *auth.Principal, loginName, date string, c *TimesheetComment) (*TimesheetComment, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, author, loginName); err != nil {
+		return nil, err
+	}
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	period, err := s.periods.PeriodFor(ctx, loginName, day)
+	if err != nil {
+		return nil, err
+	}
+
+	ve = validate.New()
+	ve.IsSizeInRange("Body", c.Body, 1, maxCommentLength)
+	if c.Date != nil {
+		*c.Date = dayOf(*c.Date)
+		if !period.contains(*c.Date) {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: "Date", Constraint: validate.Range,
+				Message: "Date is not within the pay period", Args: []interface{}{period.Start, period.End}})
+		}
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	if c.EntryID != nil {
+		if err = s.checkEntry(ctx, loginName, period, *c.EntryID); err != nil {
+			return nil, err
+		}
+	}
+
+	c.LoginName, c.PeriodStart, c.Author = loginName, period.Start, author.LoginName
+	if err = s.insert(ctx, c, period); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *commentService) ListComments(ctx context.Context, viewer *auth.Principal, loginName, date string) ([]*TimesheetComment, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	period, err := s.periods.PeriodFor(ctx, loginName, day)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SelectComments(ctx, loginName, period.Start)
+}
+
+func (s *commentService) EditComment(ctx context.Context, editor *auth.Principal, loginName, commentID, body string) (*TimesheetComment, error) {
+	c, period, err := s.findComment(ctx, editor, loginName, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Author != editor.LoginName {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not edit a comment of %s", editor.LoginName, c.Author)}
+	}
+	ve := validate.New()
+	ve.IsSizeInRange("Body", body, 1, maxCommentLength)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	if err = s.edit(ctx, c, period, editor.LoginName, body); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *commentService) DeleteComment(ctx context.Context, editor *auth.Principal, loginName, commentID string) error {
+	c, period, err := s.findComment(ctx, editor, loginName, commentID)
+	if err != nil {
+		return err
+	}
+	if c.Author != editor.LoginName && !editor.Admin {
+		return &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not delete a comment of %s", editor.LoginName, c.Author)}
+	}
+
+	c.DeletedBy = editor.LoginName
+	deleted, err := s.repo.DeleteComment(ctx, c, newEvent(EventTimesheetNotesChanged, c.LoginName, period, ""))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &res.AppError{ResponseCode: CommentNotFound, Cause: errors.Errorf("comment %s was deleted", commentID)}
+	}
+	return nil
+}
+
+func (s *commentService) CommentHistory(ctx context.Context, viewer *auth.Principal, loginName, commentID string) ([]*CommentRevision, error) {
+	c, _, err := s.findComment(ctx, viewer, loginName, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SelectRevisions(ctx, c.ID)
+}
+
+func (s *commentService) Note(ctx context.Context, author *auth.Principal, loginName string, period *PayPeriod, body string, replace bool) error {
+	n, err := s.PlanNote(ctx, author, loginName, period, body, replace)
+	if err != nil || n == nil {
+		return err
+	}
+	if n.Edit {
+		return s.edit(ctx, n.Comment, period, n.Editor, n.Comment.Body)
+	}
+	return s.insert(ctx, n.Comment, period)
+}
+
+func (s *commentService) PlanNote(ctx context.Context, author *auth.Principal, loginName string, period *PayPeriod, body string, replace bool) (*NoteChange, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, author, loginName); err != nil {
+		return nil, err
+	}
+	ve := validate.New()
+	ve.IsSizeInRange("Info", body, 1, maxCommentLength)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	comments, err := s.repo.SelectComments(ctx, loginName, period.Start)
+	if err != nil {
+		return nil, err
+	}
+	var latest, own *TimesheetComment
+	for _, c := range comments {
+		if c.Deleted {
+			continue
+		}
+		latest = c
+		if c.Author == author.LoginName && c.Date == nil && c.EntryID == nil {
+			own = c
+		}
+	}
+	if latest != nil && latest.Body == body {
+		return nil, nil
+	}
+
+	now := time.Now()
+	n := &NoteChange{Editor: author.LoginName, Event: newEvent(EventTimesheetNotesChanged, loginName, period, "")}
+	if replace && own != nil {
+		own.Body, own.EditedAt = body, &now
+		n.Comment, n.Edit = own, true
+		return n, nil
+	}
+	n.Comment = &TimesheetComment{ID: uuid.New(), LoginName: loginName, PeriodStart: period.Start, Author: author.LoginName,
+		Body: body, CreatedAt: now}
+	return n, nil
+}
+
+func (s *commentService) insert(ctx context.Context, c *TimesheetComment, period *PayPeriod) error {
+	c.ID = uuid.New()
+	c.CreatedAt = time.Now()
+	return s.repo.InsertComment(ctx, c, newEvent(EventTimesheetNotesChanged, c.LoginName, period, ""))
+}
+
+func (s *commentService) edit(ctx context.Context, c *TimesheetComment, period *PayPeriod, editor, body string) error {
+	editedAt := time.Now()
+	c.Body, c.EditedAt = body, &editedAt
+	updated, err := s.repo.UpdateComment(ctx, c, editor, newEvent(EventTimesheetNotesChanged, c.LoginName, period, ""))
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return &res.AppError{ResponseCode: CommentNotFound, Cause: errors.Errorf("comment %s was deleted", c.ID)}
+	}
+	return nil
+}
+
+//findComment returns a comment of the user's timesheets that viewer may see, and the pay period it is in
+func (s *commentService) findComment(ctx context.Context, viewer *auth.Principal, loginName, commentID string) (*TimesheetComment, *PayPeriod, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, nil, err
+	}
+	id, err := uuid.Parse(commentID)
+	if err != nil {
+		return nil, nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	c, err := s.repo.SelectComment(ctx, loginName, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c == nil {
+		return nil, nil, &res.AppError{ResponseCode: CommentNotFound, Cause: errors.Errorf("comment %s not found", commentID)}
+	}
+	period, err := s.periods.PeriodFor(ctx, loginName, c.PeriodStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, period, nil
+}
+
+//checkEntry makes sure a comment is anchored to a time entry booked on the timesheet
+func (s *commentService) checkEntry(ctx context.Context, loginName string, period *PayPeriod, entryID uuid.UUID) error {
+	entries, err := s.timers.SelectTimeEntries(ctx, loginName, period.Start, period.End)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.ID == entryID {
+			return nil
+		}
+	}
+	ve := validate.New()
+	ve.Errors = append(ve.Errors, validate.FieldError{Field: "EntryID", Constraint: validate.Like,
+		Message: "Must be a time entry of the pay period", Args: nil})
+	return ve
+}
+
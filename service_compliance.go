@@ -0,0 +1,243 @@
+package timesheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"timesheet/commons/validate"
+
+	"github.com/rs/zerolog/log"
+)
+
+//ComplianceCheck is one working-time rule. Checks are independent of each other and of the database,
+//further rules are added with ComplianceService.Register.
+type ComplianceCheck interface {
+	Name() string
+
+	Check(sheet *ComplianceSheet) []ComplianceFinding
+}
+
+type ComplianceService interface {
+	Register(check ComplianceCheck)
+
+	//Evaluate runs every check. Blocking findings are returned as a validation error, warnings as findings. earlier
+	//holds the hours per day of previous periods, for the checks that run across the period's start.
+	Evaluate(ctx context.Context, loginName string, period *PayPeriod, weeks []WeekHrs, earlier map[time.Time]float64) ([]ComplianceFinding, error)
+
+	Report(ctx context.Context, loginName string, month, year int) ([]*ComplianceFinding, error)
+
+	//ReportBetween lists the findings dated from..to, both included, of one user if loginName is not empty
+	ReportBetween(ctx context.Context, loginName string, from, to time.Time) ([]*ComplianceFinding, error)
+}
+
+type complianceService struct {
+	repo   ComplianceRepository
+	checks []ComplianceCheck
+}
+
+//NewComplianceService registers the checks whose limit is configured
+func NewComplianceService(repo ComplianceRepository, cfg ComplianceConfig) ComplianceService {
+	s := &complianceService{repo: repo}
+	if cfg.MaxDailyHours > 0 {
+		s.Register(&maxDailyHoursCheck{limit: cfg.MaxDailyHours, severity: severityOrWarning(cfg.MaxDailySeverity)})
+	}
+	if cfg.MaxAverageWeeklyHours > 0 {
+		s.Register(&averageWeeklyHoursCheck{limit: cfg.MaxAverageWeeklyHours, severity: severityOrWarning(cfg.AverageWeeklySeverity)})
+	}
+	if cfg.MinRestHours > 0 {
+		s.Register(&restPeriodCheck{minRest: cfg.MinRestHours, severity: severityOrWarning(cfg.RestSeverity)})
+	}
+	if cfg.MaxConsecutiveDays > 0 {
+		s.Register(&consecutiveDaysCheck{limit: cfg.MaxConsecutiveDays, severity: severityOrWarning(cfg.ConsecutiveDaysSeverity)})
+	}
+	return s
+}
+
+func severityOrWarning(severity ComplianceSeverity) ComplianceSeverity {
+	if severity == SeverityBlocking {
+		return SeverityBlocking
+	}
+	return SeverityWarning
+}
+
+func (s *complianceService) Register(check ComplianceCheck) {
+	s.checks = append(s.checks, check)
+}
+
+func (s *complianceService) Evaluate(ctx context.Context, loginName string, period *PayPeriod, weeks []WeekHrs, earlier map[time.Time]float64) ([]ComplianceFinding, error) {
+	sheet := newComplianceSheet(loginName, period, weeks, earlier)
+
+	findings := []ComplianceFinding{}
+	ve := validate.New()
+	for _, check := range s.checks {
+		for _, f := range check.Check(sheet) {
+			f.LoginName, f.Month, f.Year, f.CreatedAt = loginName, period.Month(), period.Year(), time.Now()
+			f.Date = period.Start
+			if f.WeekInfo > 0 {
+				f.Date = period.slotDate(f.WeekInfo, f.Day)
+			}
+			if f.Severity == SeverityBlocking {
+				field := "WeekHrs"
+				if f.WeekInfo > 0 {
+					field = fmt.Sprintf("WeekHrs[%d].Day%d", f.WeekInfo, f.Day)
+				}
+				ve.Errors = append(ve.Errors, validate.FieldError{Field: field, Constraint: validate.Compliance,
+					Message: f.Message, Args: []interface{}{f.Rule, f.Value, f.Limit}})
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	if ve.HasErrors() {
+		log.Info().Str("loginName", loginName).Int("violations", len(ve.Errors)).Msg("Timesheet rejected by compliance checks")
+		return findings, ve
+	}
+	return findings, nil
+}
+
+func (s *complianceService) Report(ctx context.Context, loginName string, month, year int) ([]*ComplianceFinding, error) {
+	ve := validate.New()
+	ve.IsNumberInRange("month", month, 1, 12)
+	ve.IsNumberInRange("year", year, 1900, 9999)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	return s.repo.SelectFindings(ctx, loginName, month, year)
+}
+
+func (s *complianceService) ReportBetween(ctx context.Context, loginName string, from, to time.Time) ([]*ComplianceFinding, error) {
+	return s.repo.SelectFindingsBetween(ctx, strings.ToUpper(loginName), from, to)
+}
+
+//newComplianceSheet lays the weeks out as every calendar day of the period, days without hours being rest days.
+//The earlier days become Before, from the first of them up to the period's start.
+func newComplianceSheet(loginName string, period *PayPeriod, weeks []WeekHrs, earlier map[time.Time]float64) *ComplianceSheet {
+	hours := map[[2]int]float64{}
+	for _, w := range weeks {
+		for i, h := range w.days() {
+			hours[[2]int{w.WeekInfo, i + 1}] += h
+		}
+	}
+
+	sheet := &ComplianceSheet{LoginName: loginName, Month: period.Month(), Year: period.Year()}
+	first := time.Date(period.Year(), period.Start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(first.Weekday()) + 6) % 7
+	for date := period.Start; !date.After(period.End); date = date.AddDate(0, 0, 1) {
+		week, day := (daysBetween(first, date)+offset)/7+1, (int(date.Weekday())+6)%7+1
+		sheet.Days = append(sheet.Days, WorkDay{Date: date, WeekInfo: week, Day: day, Hours: hours[[2]int{week, day}]})
+	}
+
+	from := period.Start
+	for date := range earlier {
+		if date.Before(from) {
+			from = date
+		}
+	}
+	for date := from; date.Before(period.Start); date = date.AddDate(0, 0, 1) {
+		sheet.Before = append(sheet.Before, WorkDay{Date: date, Hours: earlier[date]})
+	}
+	return sheet
+}
+
+type maxDailyHoursCheck struct {
+	limit    float64
+	severity ComplianceSeverity
+}
+
+func (c *maxDailyHoursCheck) Name() string { return "MaxDailyHours" }
+
+func (c *maxDailyHoursCheck) Check(sheet *ComplianceSheet) []ComplianceFinding {
+	findings := []ComplianceFinding{}
+	for _, d := range sheet.Days {
+		if d.Hours > c.limit {
+			findings = append(findings, ComplianceFinding{Rule: c.Name(), Severity: c.severity, WeekInfo: d.WeekInfo,
+				Day: d.Day, Value: d.Hours, Limit: c.limit,
+				Message: fmt.Sprintf("%.2f hours worked on %s, at most %.2f are allowed", d.Hours, d.Date.Format("2006-01-02"), c.limit)})
+		}
+	}
+	return findings
+}
+
+//averageWeeklyHoursCheck spreads the hours of the month evenly over its calendar weeks
+type averageWeeklyHoursCheck struct {
+	limit    float64
+	severity ComplianceSeverity
+}
+
+func (c *averageWeeklyHoursCheck) Name() string { return "AverageWeeklyHours" }
+
+func (c *averageWeeklyHoursCheck) Check(sheet *ComplianceSheet) []ComplianceFinding {
+	if len(sheet.Days) == 0 {
+		return nil
+	}
+
+	var total float64
+	for _, d := range sheet.Days {
+		total += d.Hours
+	}
+	average := total / float64(len(sheet.Days)) * 7
+	if average <= c.limit {
+		return nil
+	}
+	return []ComplianceFinding{{Rule: c.Name(), Severity: c.severity, Value: roundHours(average), Limit: c.limit,
+		Message: fmt.Sprintf("%.2f hours per week on average, at most %.2f are allowed", average, c.limit)}}
+}
+
+//restPeriodCheck has no clock times to go by, so the rest between two working days is taken as what the first
+//day leaves of its 24 hours. That is the longest rest possible and a shortfall is therefore certain.
+type restPeriodCheck struct {
+	minRest  float64
+	severity ComplianceSeverity
+}
+
+func (c *restPeriodCheck) Name() string { return "MinRestPeriod" }
+
+func (c *restPeriodCheck) Check(sheet *ComplianceSheet) []ComplianceFinding {
+	findings := []ComplianceFinding{}
+	for i := 0; i+1 < len(sheet.Days); i++ {
+		d, next := sheet.Days[i], sheet.Days[i+1]
+		rest := 24 - d.Hours
+		if d.Hours > 0 && next.Hours > 0 && rest < c.minRest {
+			findings = append(findings, ComplianceFinding{Rule: c.Name(), Severity: c.severity, WeekInfo: d.WeekInfo,
+				Day: d.Day, Value: rest, Limit: c.minRest,
+				Message: fmt.Sprintf("at most %.2f hours of rest after %s, at least %.2f are required", rest, d.Date.Format("2006-01-02"), c.minRest)})
+		}
+	}
+	return findings
+}
+
+type consecutiveDaysCheck struct {
+	limit    int
+	severity ComplianceSeverity
+}
+
+func (c *consecutiveDaysCheck) Name() string { return "MaxConsecutiveDays" }
+
+func (c *consecutiveDaysCheck) Check(sheet *ComplianceSheet) []ComplianceFinding {
+	findings := []ComplianceFinding{}
+	//A streak running into the period from the previous one counts with its days.
+	streak := 0
+	for _, d := range sheet.Before {
+		if d.Hours <= 0 {
+			streak = 0
+			continue
+		}
+		streak++
+	}
+	for i, d := range sheet.Days {
+		if d.Hours <= 0 {
+			streak = 0
+			continue
+		}
+		streak++
+		//Reported once per period, on the first day beyond the limit.
+		if streak == c.limit+1 || (i == 0 && streak > c.limit+1) {
+			findings = append(findings, ComplianceFinding{Rule: c.Name(), Severity: c.severity, WeekInfo: d.WeekInfo,
+				Day: d.Day, Value: float64(streak), Limit: float64(c.limit),
+				Message: fmt.Sprintf("more than %d consecutive working days up to %s", c.limit, d.Date.Format("2006-01-02"))})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,83 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/pkg/errors"
+)
+
+type ContractService interface {
+	SetContract(ctx context.Context, loginName string, c *Contract) (*Contract, error)
+
+	GetContract(ctx context.Context, loginName string) (*Contract, error)
+
+	ListContracts(ctx context.Context) ([]*Contract, error)
+}
+
+type contractService struct {
+	repo     ContractRepository
+	userRepo user.TenantUserRepository
+}
+
+func NewContractService(repo ContractRepository, userRepo user.TenantUserRepository) ContractService {
+	return &contractService{repo: repo, userRepo: userRepo}
+}
+
+func (s *contractService) SetContract(ctx context.Context, loginName string, c *Contract) (*Contract, error) {
+	if c.WeeklyHours == 0 {
+		c.WeeklyHours = defaultWeeklyHours
+	}
+	if c.Percentage == 0 {
+		c.Percentage = defaultPercentage
+	}
+
+	ve := validate.New()
+	ve.IsSizeInRange("ContractType", c.ContractType, 0, 50)
+	if c.WeeklyHours < 0 || c.WeeklyHours > 80 {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "WeeklyHours", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, 80}})
+	}
+	if c.Percentage < 0 || c.Percentage > 100 {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Percentage", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, 100}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	u, err := s.userRepo.SelectUserByLoginName(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+
+	c.LoginName = u.LoginName
+	c.UpdatedAt = time.Now()
+	if err = s.repo.UpsertContract(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *contractService) GetContract(ctx context.Context, loginName string) (*Contract, error) {
+	c, err := s.repo.SelectContract(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, &res.AppError{ResponseCode: ContractNotFound, Cause: errors.Errorf("no contract for %s", loginName)}
+	}
+	return c, nil
+}
+
+func (s *contractService) ListContracts(ctx context.Context) ([]*Contract, error) {
+	return s.repo.SelectContracts(ctx)
+}
@@ -0,0 +1,228 @@
+package timesheets
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//ExchangeRateService keeps the exchange rates of the organization that reports convert amounts to a reporting
+//currency with
+type ExchangeRateService interface {
+	//SetRate stores a rate. It supersedes a rate of the same pair taking effect on the same day, which is kept.
+	SetRate(ctx context.Context, r *ExchangeRate) (*ExchangeRate, error)
+
+	//ListRates lists the rates newest first, of one currency when currency is not empty. Deleted rates are only
+	//listed withDeleted.
+	ListRates(ctx context.Context, currency string, withDeleted bool) ([]*ExchangeRate, error)
+
+	//DeleteRate takes a rate out of conversions, the rate is kept for the reports that used it
+	DeleteRate(ctx context.Context, admin *auth.Principal, rateID string) error
+
+	//ImportCSV stores a rate for every row currency,quoteCurrency,effectiveFrom,rate of a CSV file, e.g.
+	//EUR,USD,2026-01-01,1.0834. A first row that is not a rate is taken as the header. source is kept with the rates.
+	ImportCSV(ctx context.Context, source string, r io.Reader) (*ExchangeRateImportReport, error)
+
+	//Converter converts to currency with the rates stored when it is made
+	Converter(ctx context.Context, currency string) (*CurrencyConverter, error)
+}
+
+type exchangeRateService struct {
+	repo ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo ExchangeRateRepository) ExchangeRateService {
+	return &exchangeRateService{repo: repo}
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+//maxExchangeRate bounds a rate, even the weakest currencies stay well below it against the strongest
+const maxExchangeRate = 1000000
+
+func (s *exchangeRateService) SetRate(ctx context.Context, r *ExchangeRate) (*ExchangeRate, error) {
+	if ve := validateRate(r); ve.HasErrors() {
+		return nil, ve
+	}
+
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now()
+	r.DeletedAt, r.DeletedBy = nil, ""
+	if err := s.repo.InsertRate(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *exchangeRateService) ListRates(ctx context.Context, currency string, withDeleted bool) ([]*ExchangeRate, error) {
+	currency = strings.ToUpper(currency)
+	if currency != "" {
+		ve := validate.New()
+		if validateCurrency(ve, "currency", currency); ve.HasErrors() {
+			return nil, ve
+		}
+	}
+	return s.repo.SelectRates(ctx, currency, withDeleted)
+}
+
+func (s *exchangeRateService) DeleteRate(ctx context.Context, admin *auth.Principal, rateID string) error {
+	id, err := uuid.Parse(rateID)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	deleted, err := s.repo.DeleteRate(ctx, id, admin.LoginName)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &res.AppError{ResponseCode: ExchangeRateNotFound, Cause: errors.Errorf("exchange rate %s not found", rateID)}
+	}
+	return nil
+}
+
+func (s *exchangeRateService) ImportCSV(ctx context.Context, source string, r io.Reader) (*ExchangeRateImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: InvalidExchangeRateCSV, Cause: err}
+	}
+
+	report := &ExchangeRateImportReport{Skipped: []string{}}
+	for i, record := range records {
+		line := i + 1
+		if len(record) != 4 {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("line %d: expected 4 fields, got %d", line, len(record)))
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if err != nil {
+			if i > 0 {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("line %d: %q is not a rate", line, record[3]))
+			}
+			continue
+		}
+		effectiveFrom, err := time.Parse(dateLayout, strings.TrimSpace(record[2]))
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("line %d: %q is not a date formatted as 2006-01-02", line, record[2]))
+			continue
+		}
+
+		er := &ExchangeRate{Currency: strings.TrimSpace(record[0]), QuoteCurrency: strings.TrimSpace(record[1]), Rate: rate,
+			EffectiveFrom: effectiveFrom, Source: source}
+		if _, err = s.SetRate(ctx, er); err != nil {
+			if _, invalid := err.(*validate.ValidationError); !invalid {
+				return nil, err
+			}
+			report.Skipped = append(report.Skipped, fmt.Sprintf("line %d: %s", line, err))
+			continue
+		}
+		report.Imported++
+	}
+
+	log.Info().Str("source", source).Int("imported", report.Imported).Int("skipped", len(report.Skipped)).Msg("Exchange rates imported")
+	return report, nil
+}
+
+func (s *exchangeRateService) Converter(ctx context.Context, currency string) (*CurrencyConverter, error) {
+	currency = strings.ToUpper(currency)
+	ve := validate.New()
+	if validateCurrency(ve, "currency", currency); ve.HasErrors() {
+		return nil, ve
+	}
+	rates, err := s.repo.SelectRates(ctx, currency, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CurrencyConverter{Currency: currency, rates: map[string][]*ExchangeRate{}}
+	for _, r := range rates {
+		key := r.Currency + "/" + r.QuoteCurrency
+		c.rates[key] = append(c.rates[key], r)
+	}
+	return c, nil
+}
+
+func validateRate(r *ExchangeRate) *validate.ValidationError {
+	ve := validate.New()
+	r.Currency, r.QuoteCurrency = strings.ToUpper(r.Currency), strings.ToUpper(r.QuoteCurrency)
+	r.EffectiveFrom = dayOf(r.EffectiveFrom)
+	validateCurrency(ve, "Currency", r.Currency)
+	validateCurrency(ve, "QuoteCurrency", r.QuoteCurrency)
+	if r.Currency == r.QuoteCurrency {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "QuoteCurrency", Constraint: validate.Like,
+			Message: "Must differ from Currency", Args: nil})
+	}
+	if r.Rate <= 0 || r.Rate > maxExchangeRate {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Rate", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, maxExchangeRate}})
+	}
+	if r.EffectiveFrom.IsZero() {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "EffectiveFrom", Constraint: validate.Required,
+			Message: "Field is required", Args: nil})
+	}
+	ve.IsSizeInRange("Source", r.Source, 0, 255)
+	return ve
+}
+
+func validateCurrency(ve *validate.ValidationError, field, currency string) {
+	if !currencyPattern.MatchString(currency) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: field, Constraint: validate.Like,
+			Message: "Must be an ISO 4217 currency code such as EUR", Args: []interface{}{currencyPattern.String()}})
+	}
+}
+
+//CurrencyConverter converts amounts to Currency with the rate of their pair in effect on their date. A rate stored
+//for the pair the other way around is used inverted when it took effect later.
+type CurrencyConverter struct {
+	Currency string
+	//rates are keyed by currency/quoteCurrency and newest first, of the same day the last stored first
+	rates map[string][]*ExchangeRate
+}
+
+func (c *CurrencyConverter) Convert(amount float64, from string, date time.Time) (*ConvertedAmount, error) {
+	if from == c.Currency {
+		return &ConvertedAmount{Amount: amount, Conversion: &Conversion{From: from, Currency: c.Currency, Rate: 1}}, nil
+	}
+
+	direct := rateOn(c.rates[from+"/"+c.Currency], date)
+	inverse := rateOn(c.rates[c.Currency+"/"+from], date)
+	var conversion *Conversion
+	switch {
+	case direct != nil && (inverse == nil || !inverse.EffectiveFrom.After(direct.EffectiveFrom)):
+		conversion = &Conversion{From: from, Currency: c.Currency, Rate: direct.Rate, RateID: &direct.ID,
+			EffectiveFrom: direct.EffectiveFrom}
+	case inverse != nil:
+		conversion = &Conversion{From: from, Currency: c.Currency, Rate: 1 / inverse.Rate, RateID: &inverse.ID,
+			EffectiveFrom: inverse.EffectiveFrom, Inverted: true}
+	default:
+		return nil, &res.AppError{ResponseCode: ExchangeRateMissing,
+			Cause: errors.Errorf("no rate from %s to %s on %s", from, c.Currency, date.Format(dateLayout))}
+	}
+	return &ConvertedAmount{Amount: math.Round(amount*conversion.Rate*100) / 100, Conversion: conversion}, nil
+}
+
+//rateOn is the newest of rates that took effect by date
+func rateOn(rates []*ExchangeRate, date time.Time) *ExchangeRate {
+	for _, r := range rates {
+		if !r.EffectiveFrom.After(date) {
+			return r
+		}
+	}
+	return nil
+}
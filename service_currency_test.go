@@ -0,0 +1,88 @@
+package timesheets
+
+import (
+	"testing"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+func TestCurrencyConverterConvert(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	rate := func(currency, quoteCurrency string, rate float64, effectiveFrom time.Time) *ExchangeRate {
+		return &ExchangeRate{ID: uuid.New(), Currency: currency, QuoteCurrency: quoteCurrency, Rate: rate, EffectiveFrom: effectiveFrom}
+	}
+	eurJanuary := rate("EUR", "USD", 1.1, date(2024, 1, 1))
+	eurMarch := rate("EUR", "USD", 1.08, date(2024, 3, 1))
+	//Entered later on the same day, it supersedes eurMarch
+	eurMarchAgain := rate("EUR", "USD", 1.09, date(2024, 3, 1))
+	usdFebruary := rate("USD", "GBP", 0.8, date(2024, 2, 1))
+	usdApril := rate("USD", "GBP", 0.78, date(2024, 4, 1))
+	//GBP to USD directly, newer than the reverse rate of February but older than the one of April
+	gbpMarch := rate("GBP", "USD", 1.3, date(2024, 3, 15))
+	converter := &CurrencyConverter{Currency: "USD", rates: map[string][]*ExchangeRate{
+		"EUR/USD": {eurMarchAgain, eurMarch, eurJanuary},
+		"USD/GBP": {usdApril, usdFebruary},
+		"GBP/USD": {gbpMarch},
+	}}
+
+	cases := []struct {
+		name         string
+		amount       float64
+		from         string
+		date         time.Time
+		wantAmount   float64
+		wantRate     *ExchangeRate
+		wantInverted bool
+		wantErr      *res.ResponseCode
+	}{
+		{name: "reporting currency is not converted", amount: 12.5, from: "USD", date: date(2024, 1, 1), wantAmount: 12.5},
+		{name: "rate in effect on the date", amount: 100, from: "EUR", date: date(2024, 2, 29),
+			wantAmount: 110, wantRate: eurJanuary},
+		{name: "rate taking effect on the date", amount: 100, from: "EUR", date: date(2024, 3, 1),
+			wantAmount: 109, wantRate: eurMarchAgain},
+		{name: "amount is rounded to cents", amount: 10.01, from: "EUR", date: date(2024, 1, 15),
+			wantAmount: 11.01, wantRate: eurJanuary},
+		{name: "no rate before the first one", amount: 100, from: "EUR", date: date(2023, 12, 31), wantErr: ExchangeRateMissing},
+		{name: "direct rate newer than the reverse one", amount: 100, from: "GBP", date: date(2024, 3, 20),
+			wantAmount: 130, wantRate: gbpMarch},
+		{name: "only a rate of the reverse pair is in effect", amount: 80, from: "GBP", date: date(2024, 2, 10),
+			wantAmount: 100, wantRate: usdFebruary, wantInverted: true},
+		{name: "newer rate of the reverse pair is inverted", amount: 78, from: "GBP", date: date(2024, 4, 2),
+			wantAmount: 100, wantRate: usdApril, wantInverted: true},
+		{name: "unknown currency", amount: 100, from: "JPY", date: date(2024, 3, 1), wantErr: ExchangeRateMissing},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			converted, err := converter.Convert(c.amount, c.from, c.date)
+			if c.wantErr != nil {
+				if !res.IsAppErrorEquals(err, c.wantErr) {
+					t.Fatalf("got %v, want %s", err, c.wantErr.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if converted.Amount != c.wantAmount || converted.From != c.from || converted.Currency != "USD" {
+				t.Fatalf("got %v %s from %s, want %v USD from %s", converted.Amount, converted.Currency, converted.From,
+					c.wantAmount, c.from)
+			}
+			if c.wantRate == nil {
+				if converted.RateID != nil || converted.Rate != 1 {
+					t.Fatalf("got rate %v of %v, want no stored rate", converted.Rate, converted.RateID)
+				}
+				return
+			}
+			if converted.RateID == nil || *converted.RateID != c.wantRate.ID || !converted.EffectiveFrom.Equal(c.wantRate.EffectiveFrom) ||
+				converted.Inverted != c.wantInverted {
+				t.Fatalf("got rate %v of %v effective from %s, inverted %t, want %v of %s, inverted %t", converted.Rate,
+					converted.RateID, converted.EffectiveFrom.Format(dateLayout), converted.Inverted, c.wantRate.Rate,
+					c.wantRate.EffectiveFrom.Format(dateLayout), c.wantInverted)
+			}
+		})
+	}
+}
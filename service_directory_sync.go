@@ -0,0 +1,224 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//DirectorySyncService reconciles users from an external directory into the user store
+type DirectorySyncService interface {
+	//Sync creates, updates and disables users so they match the directory. With dryRun nothing is written
+	//and the report lists what would have changed.
+	Sync(ctx context.Context, dryRun bool) (*SyncReport, error)
+}
+
+type directorySyncService struct {
+	source          DirectorySource
+	dirRepo         DirectoryRepository
+	tokenRepo       TokenRepository
+	provisioner     *accountProvisioner
+	maxDisableShare float64
+}
+
+//NewDirectorySyncService reconciles with source. A sync that would disable more than maxDisableShare of the active
+//directory users, e.g. 0.1 for a tenth, is aborted as the directory answer is more likely truncated than real.
+func NewDirectorySyncService(source DirectorySource, dirRepo DirectoryRepository, userSvc Service,
+	tokenRepo TokenRepository, orgRepo OrganizationRepository, maxDisableShare float64) DirectorySyncService {
+	return &directorySyncService{source: source, dirRepo: dirRepo, tokenRepo: tokenRepo, maxDisableShare: maxDisableShare,
+		provisioner: &accountProvisioner{userSvc: userSvc, orgRepo: orgRepo}}
+}
+
+const directorySyncPageSize = 1000
+
+func (s *directorySyncService) Sync(ctx context.Context, dryRun bool) (*SyncReport, error) {
+	report := &SyncReport{DryRun: dryRun, StartedAt: time.Now().UTC(), Changes: []SyncChange{}}
+
+	fetched, err := s.source.FetchUsers(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to fetch users from the directory")
+		return nil, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}
+	}
+	report.Fetched = len(fetched)
+
+	seen := map[string]bool{}
+	for _, src := range fetched {
+		seen[src.LoginName] = true
+	}
+
+	//Users that disappeared from the directory are disabled, never deleted, as timesheets refer to them.
+	active, stale, err := s.staleEntries(ctx, seen)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkDisableShare(len(fetched), active, len(stale)); err != nil {
+		log.Error().Err(err).Int("fetched", len(fetched)).Int("active", active).Int("stale", len(stale)).Msg("Directory sync aborted")
+		if !dryRun {
+			return nil, err
+		}
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	for _, src := range fetched {
+		if err = s.reconcile(ctx, src, report); err != nil {
+			log.Error().Err(err).Str("loginName", src.LoginName).Msg("Directory sync failed for user")
+			report.Errors = append(report.Errors, src.LoginName+": "+err.Error())
+		}
+	}
+
+	for _, loginName := range stale {
+		report.Disabled++
+		report.Changes = append(report.Changes, SyncChange{LoginName: loginName, Action: SyncActionDisable, Reason: "not in directory"})
+		if !dryRun {
+			if err = s.dirRepo.UpdateDirectoryEntryActive(ctx, loginName, false); err != nil {
+				report.Errors = append(report.Errors, loginName+": "+err.Error())
+				continue
+			}
+			s.revokeAccess(ctx, loginName)
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	log.Info().Bool("dryRun", dryRun).Int("created", report.Created).Int("updated", report.Updated).
+		Int("disabled", report.Disabled).Int("errors", len(report.Errors)).Msg("Directory sync finished")
+	return report, nil
+}
+
+//staleEntries counts the active users synced from the directory and lists those of them the directory no longer has
+func (s *directorySyncService) staleEntries(ctx context.Context, seen map[string]bool) (int, []string, error) {
+	active, stale := 0, []string{}
+	for offset := 0; ; offset += directorySyncPageSize {
+		existing, _, err := s.dirRepo.SelectDirectoryEntries(ctx, DirectoryFilter{Source: DirectorySourceLDAP}, offset, directorySyncPageSize)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, e := range existing {
+			if !e.Active {
+				continue
+			}
+			active++
+			if !seen[e.LoginName] {
+				stale = append(stale, e.LoginName)
+			}
+		}
+		if len(existing) < directorySyncPageSize {
+			return active, stale, nil
+		}
+	}
+}
+
+//checkDisableShare refuses a sync that fetched nothing or would disable too many users
+func (s *directorySyncService) checkDisableShare(fetched, active, stale int) error {
+	switch {
+	case stale == 0:
+		return nil
+	case fetched == 0:
+		return &res.AppError{ResponseCode: DirectorySyncAborted,
+			Cause: errors.Errorf("the directory returned no users, refusing to disable %d", stale)}
+	case float64(stale) > s.maxDisableShare*float64(active):
+		return &res.AppError{ResponseCode: DirectorySyncAborted,
+			Cause: errors.Errorf("%d of %d active users would be disabled, more than the allowed share of %g", stale, active, s.maxDisableShare)}
+	}
+	return nil
+}
+
+func (s *directorySyncService) reconcile(ctx context.Context, src *DirectoryEntry, report *SyncReport) error {
+	existing, err := s.dirRepo.SelectDirectoryEntryByLoginName(ctx, src.LoginName)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		report.Created++
+		report.Changes = append(report.Changes, SyncChange{LoginName: src.LoginName, Action: SyncActionCreate})
+		if report.DryRun {
+			return nil
+		}
+		return s.create(ctx, src)
+	}
+
+	if existing.Source != DirectorySourceLDAP {
+		report.Skipped++
+		report.Changes = append(report.Changes, SyncChange{LoginName: src.LoginName, Action: SyncActionSkip,
+			Reason: "managed by " + existing.Source})
+		return nil
+	}
+
+	fields := diffDirectoryEntries(existing, src)
+	if len(fields) == 0 {
+		report.Unchanged++
+		return nil
+	}
+
+	action := SyncActionUpdate
+	switch {
+	case existing.Active && !src.Active:
+		action = SyncActionDisable
+		report.Disabled++
+	case !existing.Active && src.Active:
+		action = SyncActionEnable
+		report.Enabled++
+	default:
+		report.Updated++
+	}
+	report.Changes = append(report.Changes, SyncChange{LoginName: src.LoginName, Action: action, Fields: fields})
+	if report.DryRun {
+		return nil
+	}
+
+	src.ID = existing.ID
+	src.CreatedAt = existing.CreatedAt
+	src.UpdatedAt = time.Now().UTC()
+	if err = s.dirRepo.UpdateDirectoryEntry(ctx, src); err != nil {
+		return err
+	}
+	if action == SyncActionDisable {
+		s.revokeAccess(ctx, src.LoginName)
+	}
+	return nil
+}
+
+func (s *directorySyncService) create(ctx context.Context, src *DirectoryEntry) error {
+	if _, err := s.provisioner.ensureUser(ctx, &User{LoginName: src.LoginName, Department: src.Department, JobTitle: src.JobTitle}); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	src.ID = uuid.New()
+	src.CreatedAt = now
+	src.UpdatedAt = now
+	return s.dirRepo.InsertDirectoryEntry(ctx, src)
+}
+
+func (s *directorySyncService) revokeAccess(ctx context.Context, loginName string) {
+	if err := s.tokenRepo.RevokeAllAPITokens(ctx, loginName); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Unable to revoke api tokens of disabled user")
+	}
+}
+
+//diffDirectoryEntries returns the names of the synced fields that differ
+func diffDirectoryEntries(current, desired *DirectoryEntry) []string {
+	fields := []string{}
+	check := func(name, a, b string) {
+		if a != b {
+			fields = append(fields, name)
+		}
+	}
+	check("ExternalID", current.ExternalID, desired.ExternalID)
+	check("GivenName", current.GivenName, desired.GivenName)
+	check("FamilyName", current.FamilyName, desired.FamilyName)
+	check("Email", strings.ToLower(current.Email), strings.ToLower(desired.Email))
+	check("Department", current.Department, desired.Department)
+	check("JobTitle", current.JobTitle, desired.JobTitle)
+	check("ManagerLoginName", current.ManagerLoginName, desired.ManagerLoginName)
+	if current.Active != desired.Active {
+		fields = append(fields, "Active")
+	}
+	return fields
+}
@@ -0,0 +1,124 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"timesheet/commons/res"
+)
+
+type fakeDirectorySource struct {
+	users []*DirectoryEntry
+}
+
+func (src *fakeDirectorySource) FetchUsers(ctx context.Context) ([]*DirectoryEntry, error) {
+	return src.users, nil
+}
+
+//fakeSyncRepository keeps directory entries by login name
+type fakeSyncRepository struct {
+	DirectoryRepository
+	entries map[string]*DirectoryEntry
+}
+
+func (repo *fakeSyncRepository) SelectDirectoryEntryByLoginName(ctx context.Context, loginName string) (*DirectoryEntry, error) {
+	if e, ok := repo.entries[loginName]; ok {
+		copied := *e
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (repo *fakeSyncRepository) SelectDirectoryEntries(ctx context.Context, filter DirectoryFilter, offset, limit int) ([]*DirectoryEntry, int, error) {
+	found := []*DirectoryEntry{}
+	for _, e := range repo.entries {
+		if e.Source == filter.Source {
+			found = append(found, e)
+		}
+	}
+	if offset > 0 {
+		return []*DirectoryEntry{}, len(found), nil
+	}
+	return found, len(found), nil
+}
+
+func (repo *fakeSyncRepository) UpdateDirectoryEntryActive(ctx context.Context, loginName string, active bool) error {
+	repo.entries[loginName].Active = active
+	return nil
+}
+
+type fakeTokenRepository struct {
+	TokenRepository
+}
+
+func (repo *fakeTokenRepository) RevokeAllAPITokens(ctx context.Context, loginName string) error {
+	return nil
+}
+
+func directoryUsers(n int) []*DirectoryEntry {
+	users := make([]*DirectoryEntry, n)
+	for i := range users {
+		users[i] = &DirectoryEntry{LoginName: fmt.Sprintf("USER%02d", i), Active: true, Source: DirectorySourceLDAP}
+	}
+	return users
+}
+
+func TestDirectorySyncDisableGuard(t *testing.T) {
+	cases := []struct {
+		name         string
+		existing     int
+		fetched      int
+		dryRun       bool
+		wantErr      bool
+		wantDisabled int
+	}{
+		{name: "empty answer disables nobody", existing: 20, fetched: 0, wantErr: true},
+		{name: "empty directory with no users is fine", existing: 0, fetched: 0},
+		{name: "share within the limit is disabled", existing: 20, fetched: 18, wantDisabled: 2},
+		{name: "truncated answer disables nobody", existing: 20, fetched: 15, wantErr: true},
+		{name: "dry run reports the aborted changes", existing: 20, fetched: 15, dryRun: true, wantDisabled: 5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &fakeSyncRepository{entries: map[string]*DirectoryEntry{}}
+			for _, e := range directoryUsers(c.existing) {
+				repo.entries[e.LoginName] = e
+			}
+			source := &fakeDirectorySource{users: directoryUsers(c.fetched)}
+			svc := NewDirectorySyncService(source, repo, nil, &fakeTokenRepository{}, nil, 0.1)
+
+			report, err := svc.Sync(context.Background(), c.dryRun)
+			if c.wantErr {
+				if !res.IsAppErrorEquals(err, DirectorySyncAborted) {
+					t.Fatalf("got %v, want %s", err, DirectorySyncAborted.Code)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.Disabled != c.wantDisabled {
+					t.Fatalf("report has %d disabled, want %d", report.Disabled, c.wantDisabled)
+				}
+				if c.dryRun && len(report.Errors) == 0 {
+					t.Fatal("dry run does not report the abort")
+				}
+			}
+
+			inactive := 0
+			for _, e := range repo.entries {
+				if !e.Active {
+					inactive++
+				}
+			}
+			want := c.wantDisabled
+			if c.wantErr || c.dryRun {
+				want = 0
+			}
+			if inactive != want {
+				t.Fatalf("%d users were disabled, want %d", inactive, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/pkg/errors"
+)
+
+type ExpectedHoursService interface {
+	GetExpectedHours(ctx context.Context, loginName string, month, year int) (*ExpectedHours, error)
+
+	//MissingTimesheets reports every user of the organization whose timesheet is missing or under-filled
+	MissingTimesheets(ctx context.Context, month, year int) (*MissingTimesheetReport, error)
+
+	//MissingTimesheetsBetween is MissingTimesheets for the days from..to, both included
+	MissingTimesheetsBetween(ctx context.Context, from, to time.Time) (*MissingTimesheetReport, error)
+}
+
+type expectedHoursService struct {
+	repo     ExpectedHoursRepository
+	holidays HolidayService
+}
+
+func NewExpectedHoursService(repo ExpectedHoursRepository, holidays HolidayService) ExpectedHoursService {
+	return &expectedHoursService{repo: repo, holidays: holidays}
+}
+
+func (s *expectedHoursService) GetExpectedHours(ctx context.Context, loginName string, month, year int) (*ExpectedHours, error) {
+	if ve := validateMonth(month, year); ve.HasErrors() {
+		return nil, ve
+	}
+
+	from, to := monthRange(month, year)
+	fills, err := s.repo.SelectFills(ctx, strings.ToUpper(loginName), from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(fills) == 0 {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+	eh, err := s.expectedHours(ctx, fills[0], from, to)
+	if err != nil {
+		return nil, err
+	}
+	eh.Month, eh.Year = month, year
+	return eh, nil
+}
+
+func (s *expectedHoursService) MissingTimesheets(ctx context.Context, month, year int) (*MissingTimesheetReport, error) {
+	if ve := validateMonth(month, year); ve.HasErrors() {
+		return nil, ve
+	}
+
+	from, to := monthRange(month, year)
+	report, err := s.MissingTimesheetsBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	report.Month, report.Year = month, year
+	for _, eh := range report.Users {
+		eh.Month, eh.Year = month, year
+	}
+	return report, nil
+}
+
+func (s *expectedHoursService) MissingTimesheetsBetween(ctx context.Context, from, to time.Time) (*MissingTimesheetReport, error) {
+	fills, err := s.repo.SelectFills(ctx, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MissingTimesheetReport{From: from, To: to, Users: []*ExpectedHours{}}
+	for _, fill := range fills {
+		eh, err := s.expectedHours(ctx, fill, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if eh.Status != FillComplete {
+			report.Users = append(report.Users, eh)
+		}
+	}
+	return report, nil
+}
+
+func (s *expectedHoursService) expectedHours(ctx context.Context, fill *MonthFill, from, to time.Time) (*ExpectedHours, error) {
+	holidays, err := s.holidays.HolidaysBetween(ctx, fill.LoginName, fill.Department, from, to)
+	if err != nil {
+		return nil, err
+	}
+	_, working := countWorkingDays(from, to, holidays)
+
+	hours, totals, absenceHours, err := hoursBetween(fill.Timesheets, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	contract := &Contract{WeeklyHours: fill.WeeklyHours, Percentage: fill.Percentage}
+	eh := &ExpectedHours{LoginName: fill.LoginName, Department: fill.Department, From: from, To: to,
+		WorkingDays: working, WeeklyHours: fill.WeeklyHours, Percentage: fill.Percentage,
+		ExpectedHours: roundHours(float64(working) * contract.dailyHours()),
+		FilledHours:   roundHours(hours + absenceHours - totals[AbsenceHoliday])}
+
+	switch {
+	case len(fill.Timesheets) == 0 && eh.ExpectedHours > 0:
+		eh.Status = FillMissing
+	case eh.FilledHours < eh.ExpectedHours:
+		eh.Status = FillUnderFilled
+	default:
+		eh.Status = FillComplete
+	}
+	return eh, nil
+}
+
+//hoursBetween adds up the worked hours and the absences of timesheets on the days from..to, leaving out the days
+//of their periods outside of it
+func hoursBetween(timesheets []*GetAllTimesheets, from, to time.Time) (float64, map[AbsenceType]float64, float64, error) {
+	var hours, absenceHours float64
+	totals := map[AbsenceType]float64{}
+	for _, ts := range timesheets {
+		p := &PayPeriod{Start: ts.PeriodStart, End: ts.PeriodEnd, Frequency: ts.PayFrequency}
+		counts := func(date time.Time) bool {
+			return p.contains(date) && !date.Before(from) && !date.After(to)
+		}
+
+		weeks := []WeekHrs{}
+		if len(ts.WeekHrs) > 0 {
+			if err := json.Unmarshal(ts.WeekHrs, &weeks); err != nil {
+				return 0, nil, 0, err
+			}
+		}
+		for _, w := range weeks {
+			for i, h := range w.days() {
+				if counts(p.slotDate(w.WeekInfo, i+1)) {
+					hours += h
+				}
+			}
+		}
+
+		absences, _, _, err := summarizeAbsences(ts.Absences)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		for _, a := range absences {
+			if counts(p.slotDate(a.WeekInfo, a.Day)) {
+				totals[a.Type] += a.Hours
+				absenceHours += a.Hours
+			}
+		}
+	}
+	return hours, totals, absenceHours, nil
+}
+
+func validateMonth(month, year int) *validate.ValidationError {
+	ve := validate.New()
+	ve.IsNumberInRange("month", month, 1, 12)
+	ve.IsNumberInRange("year", year, 1900, 9999)
+	return ve
+}
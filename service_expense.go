@@ -0,0 +1,253 @@
+package timesheets
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//ExpenseService keeps the expense claims of users. Claims are made by the user for themselves and reviewed by the
+//same managers and admins as their timesheets.
+type ExpenseService interface {
+	//CreateClaim submits a claim for the pay period containing c.PeriodStart
+	CreateClaim(ctx context.Context, claimant *auth.Principal, c *ExpenseClaim) (*ExpenseClaim, error)
+
+	//UpdateClaim replaces the title and lines of a claim that is not approved and submits it again
+	UpdateClaim(ctx context.Context, editor *auth.Principal, loginName, claimID string, c *ExpenseClaim) (*ExpenseClaim, error)
+
+	//ListClaims lists the claims of the pay periods starting from..to, formatted as 2006-01-02. to defaults to today
+	//and from to the 1st of January of its year.
+	ListClaims(ctx context.Context, viewer *auth.Principal, loginName, from, to string) ([]*ExpenseClaim, error)
+
+	GetClaim(ctx context.Context, viewer *auth.Principal, loginName, claimID string) (*ExpenseClaim, error)
+
+	DeleteClaim(ctx context.Context, editor *auth.Principal, loginName, claimID string) error
+
+	//ReviewClaim approves or rejects a submitted claim, reviewer must be an admin or the user's manager
+	ReviewClaim(ctx context.Context, reviewer *auth.Principal, loginName, claimID string, approve bool, review *ExpenseReview) (*ExpenseClaim, error)
+}
+
+type expenseService struct {
+	repo        ExpenseRepository
+	attachments AttachmentRepository
+	periods     PayPeriodService
+	access      TimesheetAccess
+}
+
+func NewExpenseService(repo ExpenseRepository, attachments AttachmentRepository, periods PayPeriodService, access TimesheetAccess) ExpenseService {
+	return &expenseService{repo: repo, attachments: attachments, periods: periods, access: access}
+}
+
+//maxExpenseAmount bounds a single line, larger costs are not claimed as expenses
+const maxExpenseAmount = 1000000
+
+func (s *expenseService) CreateClaim(ctx context.Context, claimant *auth.Principal, c *ExpenseClaim) (*ExpenseClaim, error) {
+	c.LoginName = strings.ToUpper(claimant.LoginName)
+	if c.PeriodStart.IsZero() {
+		ve := validate.New()
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "PeriodStart", Constraint: validate.Required,
+			Message: "Field is required", Args: nil})
+		return nil, ve
+	}
+	period, err := s.periods.PeriodFor(ctx, c.LoginName, dayOf(c.PeriodStart))
+	if err != nil {
+		return nil, err
+	}
+	if err = s.validateClaim(ctx, c, period); err != nil {
+		return nil, err
+	}
+
+	c.ID = uuid.New()
+	c.PeriodStart, c.PeriodEnd = period.Start, period.End
+	c.Status = ExpenseSubmitted
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	if err = s.repo.InsertClaim(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *expenseService) UpdateClaim(ctx context.Context, editor *auth.Principal, loginName, claimID string, c *ExpenseClaim) (*ExpenseClaim, error) {
+	existing, err := s.findClaim(ctx, editor, loginName, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.LoginName != editor.LoginName {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not change a claim of %s", editor.LoginName, existing.LoginName)}
+	}
+	period, err := s.periods.PeriodFor(ctx, existing.LoginName, existing.PeriodStart)
+	if err != nil {
+		return nil, err
+	}
+	c.LoginName = existing.LoginName
+	if err = s.validateClaim(ctx, c, period); err != nil {
+		return nil, err
+	}
+
+	c.ID, c.PeriodStart, c.PeriodEnd, c.CreatedAt = existing.ID, existing.PeriodStart, existing.PeriodEnd, existing.CreatedAt
+	c.Status = ExpenseSubmitted
+	c.UpdatedAt = time.Now()
+	updated, err := s.repo.UpdateClaim(ctx, c, []ExpenseStatus{ExpenseSubmitted, ExpenseRejected})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &res.AppError{ResponseCode: ExpenseClaimNotPending, Cause: errors.Errorf("claim %s is approved", claimID)}
+	}
+	return c, nil
+}
+
+func (s *expenseService) ListClaims(ctx context.Context, viewer *auth.Principal, loginName, from, to string) ([]*ExpenseClaim, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+	if to == "" {
+		to = dayOf(time.Now()).Format(dateLayout)
+	}
+	if toDay, err := time.Parse(dateLayout, to); err == nil && from == "" {
+		from = time.Date(toDay.Year(), time.January, 1, 0, 0, 0, 0, time.UTC).Format(dateLayout)
+	}
+	fromDay, ve := parseDate("from", from)
+	toDay, toErrors := parseDate("to", to)
+	ve.Errors = append(ve.Errors, toErrors.Errors...)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	return s.repo.SelectClaims(ctx, loginName, fromDay, toDay)
+}
+
+func (s *expenseService) GetClaim(ctx context.Context, viewer *auth.Principal, loginName, claimID string) (*ExpenseClaim, error) {
+	return s.findClaim(ctx, viewer, loginName, claimID)
+}
+
+func (s *expenseService) DeleteClaim(ctx context.Context, editor *auth.Principal, loginName, claimID string) error {
+	c, err := s.findClaim(ctx, editor, loginName, claimID)
+	if err != nil {
+		return err
+	}
+	if err = s.access.CheckWrite(ctx, editor, c.LoginName); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteClaim(ctx, c.LoginName, c.ID, []ExpenseStatus{ExpenseSubmitted, ExpenseRejected})
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return &res.AppError{ResponseCode: ExpenseClaimNotPending, Cause: errors.Errorf("claim %s is approved", claimID)}
+	}
+	return nil
+}
+
+func (s *expenseService) ReviewClaim(ctx context.Context, reviewer *auth.Principal, loginName, claimID string, approve bool, review *ExpenseReview) (*ExpenseClaim, error) {
+	c, err := s.findClaim(ctx, reviewer, loginName, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.access.CheckReview(ctx, reviewer, c.LoginName); err != nil {
+		return nil, err
+	}
+	ve := validate.New()
+	ve.IsSizeInRange("Note", review.Note, 0, 1000)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	reviewedAt := time.Now()
+	c.Status = ExpenseRejected
+	if approve {
+		c.Status = ExpenseApproved
+	}
+	c.ReviewedBy, c.ReviewedAt, c.ReviewNote = reviewer.LoginName, &reviewedAt, review.Note
+	reviewed, err := s.repo.ReviewClaim(ctx, c, []ExpenseStatus{ExpenseSubmitted})
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, &res.AppError{ResponseCode: ExpenseClaimNotPending, Cause: errors.Errorf("claim %s is not pending", claimID)}
+	}
+	c.UpdatedAt = reviewedAt
+	return c, nil
+}
+
+//findClaim returns a claim of the user that viewer may see
+func (s *expenseService) findClaim(ctx context.Context, viewer *auth.Principal, loginName, claimID string) (*ExpenseClaim, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(claimID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	c, err := s.repo.SelectClaim(ctx, loginName, id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, &res.AppError{ResponseCode: ExpenseClaimNotFound, Cause: errors.Errorf("claim %s not found", claimID)}
+	}
+	return c, nil
+}
+
+//validateClaim checks the lines of a claim of the period and gives them their ids
+func (s *expenseService) validateClaim(ctx context.Context, c *ExpenseClaim, period *PayPeriod) error {
+	ve := validate.New()
+	ve.IsSizeInRange("Title", c.Title, 1, 200)
+	if len(c.Lines) == 0 || len(c.Lines) > maxExpenseLines {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Lines", Constraint: validate.Size,
+			Message: "Size is not within range", Args: []interface{}{1, maxExpenseLines}})
+	}
+	for i, l := range c.Lines {
+		field := fmt.Sprintf("Lines[%d]", i)
+		l.ID = uuid.New()
+		l.Currency = strings.ToUpper(l.Currency)
+		l.Date = dayOf(l.Date)
+		l.Amount = math.Round(l.Amount*100) / 100
+		ve.IsWithin(field+".Category", string(l.Category), expenseCategories)
+		validateCurrency(ve, field+".Currency", l.Currency)
+		if l.Amount <= 0 || l.Amount > maxExpenseAmount {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: field + ".Amount", Constraint: validate.Range,
+				Message: "Value is not within range", Args: []interface{}{0.01, maxExpenseAmount}})
+		}
+		if !period.contains(l.Date) {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: field + ".Date", Constraint: validate.Range,
+				Message: "Date is not within the pay period", Args: []interface{}{period.Start, period.End}})
+		}
+		ve.IsSizeInRange(field+".Project", l.Project, 0, 100)
+		ve.IsSizeInRange(field+".Description", l.Description, 0, 500)
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+
+	for i, l := range c.Lines {
+		if l.ReceiptID == nil {
+			continue
+		}
+		receipt, err := s.attachments.SelectAttachment(ctx, c.LoginName, *l.ReceiptID)
+		if err != nil {
+			return err
+		}
+		if receipt == nil {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: fmt.Sprintf("Lines[%d].ReceiptID", i), Constraint: validate.Like,
+				Message: "Must be an attachment of the user", Args: nil})
+		}
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+	return nil
+}
+
@@ -0,0 +1,142 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/validate"
+)
+
+type FiscalCalendarService interface {
+	//GetCalendar returns the organization's fiscal calendar, the default one if it has not set up its own
+	GetCalendar(ctx context.Context) (*FiscalCalendar, error)
+
+	SetCalendar(ctx context.Context, c *FiscalCalendar) (*FiscalCalendar, error)
+
+	//Locate places a date, formatted as 2006-01-02, in the fiscal calendar
+	Locate(ctx context.Context, date string) (*FiscalDate, error)
+}
+
+type fiscalCalendarService struct {
+	repo FiscalCalendarRepository
+}
+
+func NewFiscalCalendarService(repo FiscalCalendarRepository) FiscalCalendarService {
+	return &fiscalCalendarService{repo: repo}
+}
+
+func (s *fiscalCalendarService) GetCalendar(ctx context.Context) (*FiscalCalendar, error) {
+	c, err := s.repo.SelectFiscalCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		defaults := defaultFiscalCalendar
+		return &defaults, nil
+	}
+	return c, nil
+}
+
+func (s *fiscalCalendarService) SetCalendar(ctx context.Context, c *FiscalCalendar) (*FiscalCalendar, error) {
+	ve := validate.New()
+	ve.IsNumberInRange("StartMonth", c.StartMonth, 1, 12)
+	ve.IsNumberInRange("StartWeekday", int(c.StartWeekday), int(time.Sunday), int(time.Saturday))
+	ve.IsWithin("Pattern", string(c.Pattern), fiscalPatterns)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	c.UpdatedAt = time.Now()
+	if err := s.repo.UpsertFiscalCalendar(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *fiscalCalendarService) Locate(ctx context.Context, date string) (*FiscalDate, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	c, err := s.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.locate(day), nil
+}
+
+//yearStart is the first day of the fiscal year that starts around the 1st of StartMonth of year
+func (c *FiscalCalendar) yearStart(year int) time.Time {
+	anchor := time.Date(year, time.Month(c.StartMonth), 1, 0, 0, 0, 0, time.UTC)
+	days := (int(c.StartWeekday) - int(anchor.Weekday()) + 7) % 7
+	if days > 3 {
+		days -= 7
+	}
+	return anchor.AddDate(0, 0, days)
+}
+
+//periodWeeks lists the weeks of the twelve periods of a year of yearWeeks weeks
+func (c *FiscalCalendar) periodWeeks(yearWeeks int) []int {
+	quarter := map[FiscalPattern][]int{Pattern445: {4, 4, 5}, Pattern454: {4, 5, 4}, Pattern544: {5, 4, 4}}[c.Pattern]
+	if quarter == nil {
+		quarter = []int{4, 4, 5}
+	}
+
+	weeks := []int{}
+	for q := 0; q < 4; q++ {
+		weeks = append(weeks, quarter...)
+	}
+	weeks[len(weeks)-1] += yearWeeks - 52
+	return weeks
+}
+
+func (c *FiscalCalendar) locate(date time.Time) *FiscalDate {
+	date = dayOf(date)
+	year := date.Year()
+	start := c.yearStart(year)
+	if date.Before(start) {
+		year--
+		start = c.yearStart(year)
+	}
+	next := c.yearStart(year + 1)
+	if !date.Before(next) {
+		year++
+		start, next = next, c.yearStart(year+1)
+	}
+
+	fd := &FiscalDate{Date: date, FiscalYear: year, YearWeeks: daysBetween(start, next) / 7,
+		YearStart: start, YearEnd: next.AddDate(0, 0, -1)}
+	if c.NameByEndYear {
+		fd.FiscalYear = fd.YearEnd.Year()
+	}
+	fd.Week = daysBetween(start, date)/7 + 1
+	fd.WeekStart = start.AddDate(0, 0, (fd.Week-1)*7)
+	fd.WeekEnd = fd.WeekStart.AddDate(0, 0, 6)
+
+	weeks := c.periodWeeks(fd.YearWeeks)
+	before := 0
+	for i, n := range weeks {
+		if fd.Week <= before+n {
+			fd.Period = i + 1
+			fd.PeriodStart = start.AddDate(0, 0, before*7)
+			fd.PeriodEnd = fd.PeriodStart.AddDate(0, 0, n*7-1)
+			break
+		}
+		before += n
+	}
+
+	fd.Quarter = (fd.Period-1)/3 + 1
+	before, length := 0, 0
+	for i, n := range weeks {
+		switch {
+		case i < (fd.Quarter-1)*3:
+			before += n
+		case i < fd.Quarter*3:
+			length += n
+		}
+	}
+	fd.QuarterStart = start.AddDate(0, 0, before*7)
+	fd.QuarterEnd = fd.QuarterStart.AddDate(0, 0, length*7-1)
+	return fd
+}
@@ -0,0 +1,63 @@
+package timesheets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiscalCalendarLocate(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	defaults := defaultFiscalCalendar
+	pattern544 := FiscalCalendar{StartMonth: 1, StartWeekday: time.Monday, Pattern: Pattern544}
+	april := FiscalCalendar{StartMonth: 4, StartWeekday: time.Saturday, Pattern: Pattern445, NameByEndYear: true}
+
+	cases := []struct {
+		name        string
+		calendar    FiscalCalendar
+		date        time.Time
+		year        int
+		yearWeeks   int
+		week        int
+		period      int
+		quarter     int
+		periodStart time.Time
+		periodEnd   time.Time
+	}{
+		{name: "first day of a year starting on January 1st", calendar: defaults, date: date(2024, 1, 1),
+			year: 2024, yearWeeks: 52, week: 1, period: 1, quarter: 1, periodStart: date(2024, 1, 1), periodEnd: date(2024, 1, 28)},
+		{name: "last week of the five week period", calendar: defaults, date: date(2024, 3, 31),
+			year: 2024, yearWeeks: 52, week: 13, period: 3, quarter: 1, periodStart: date(2024, 2, 26), periodEnd: date(2024, 3, 31)},
+		{name: "last day of a 52 week year", calendar: defaults, date: date(2024, 12, 29),
+			year: 2024, yearWeeks: 52, week: 52, period: 12, quarter: 4, periodStart: date(2024, 11, 25), periodEnd: date(2024, 12, 29)},
+		{name: "December days of the next fiscal year", calendar: defaults, date: date(2024, 12, 30),
+			year: 2025, yearWeeks: 52, week: 1, period: 1, quarter: 1, periodStart: date(2024, 12, 30), periodEnd: date(2025, 1, 26)},
+		{name: "53rd week is added to the last period", calendar: defaults, date: date(2027, 1, 3),
+			year: 2026, yearWeeks: 53, week: 53, period: 12, quarter: 4, periodStart: date(2026, 11, 23), periodEnd: date(2027, 1, 3)},
+		{name: "5-4-4 pattern", calendar: pattern544, date: date(2024, 2, 5),
+			year: 2024, yearWeeks: 52, week: 6, period: 2, quarter: 1, periodStart: date(2024, 2, 5), periodEnd: date(2024, 3, 3)},
+		{name: "year named by the calendar year it ends in", calendar: april, date: date(2024, 3, 29),
+			year: 2024, yearWeeks: 52, week: 52, period: 12, quarter: 4, periodStart: date(2024, 2, 24), periodEnd: date(2024, 3, 29)},
+		{name: "start weekday nearest to the 1st of the month", calendar: april, date: date(2024, 3, 30),
+			year: 2025, yearWeeks: 52, week: 1, period: 1, quarter: 1, periodStart: date(2024, 3, 30), periodEnd: date(2024, 4, 26)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fd := c.calendar.locate(c.date)
+			if fd.FiscalYear != c.year || fd.YearWeeks != c.yearWeeks || fd.Week != c.week || fd.Period != c.period ||
+				fd.Quarter != c.quarter {
+				t.Fatalf("got year %d of %d weeks, week %d, period %d, quarter %d, want year %d of %d weeks, week %d, period %d, quarter %d",
+					fd.FiscalYear, fd.YearWeeks, fd.Week, fd.Period, fd.Quarter, c.year, c.yearWeeks, c.week, c.period, c.quarter)
+			}
+			if !fd.PeriodStart.Equal(c.periodStart) || !fd.PeriodEnd.Equal(c.periodEnd) {
+				t.Fatalf("got period %s to %s, want %s to %s", fd.PeriodStart.Format(dateLayout), fd.PeriodEnd.Format(dateLayout),
+					c.periodStart.Format(dateLayout), c.periodEnd.Format(dateLayout))
+			}
+			if c.date.Before(fd.WeekStart) || c.date.After(fd.WeekEnd) || c.date.Before(fd.QuarterStart) || c.date.After(fd.QuarterEnd) {
+				t.Fatalf("%s is outside of its week %s to %s or quarter %s to %s", c.date.Format(dateLayout),
+					fd.WeekStart.Format(dateLayout), fd.WeekEnd.Format(dateLayout), fd.QuarterStart.Format(dateLayout),
+					fd.QuarterEnd.Format(dateLayout))
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	sql "github.com/jmoiron/sqlx/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type HolidayService interface {
+	CreateCalendar(ctx context.Context, c *HolidayCalendar) (*HolidayCalendar, error)
+
+	ListCalendars(ctx context.Context) ([]*HolidayCalendar, error)
+
+	//GetCalendar returns the calendar with its holidays of the given year
+	GetCalendar(ctx context.Context, calendarID string, year int) (*HolidayCalendar, error)
+
+	UpdateCalendar(ctx context.Context, calendarID string, c *HolidayCalendar) (*HolidayCalendar, error)
+
+	DeleteCalendar(ctx context.Context, calendarID string) error
+
+	AddHoliday(ctx context.Context, calendarID string, h *Holiday) (*Holiday, error)
+
+	DeleteHoliday(ctx context.Context, calendarID, holidayID string) error
+
+	//ImportICS adds every day covered by the events of an iCalendar file as a holiday
+	ImportICS(ctx context.Context, calendarID string, r io.Reader) (*ICSImportReport, error)
+
+	AssignCalendar(ctx context.Context, calendarID string, a *CalendarAssignment) error
+
+	UnassignCalendar(ctx context.Context, calendarID string, a *CalendarAssignment) error
+
+	ListAssignments(ctx context.Context, calendarID string) ([]*CalendarAssignment, error)
+
+	//HolidaysFor returns the holidays of the user's calendar that fall within the month
+	HolidaysFor(ctx context.Context, loginName, department string, month, year int) ([]*Holiday, error)
+
+	//HolidaysBetween returns the holidays of the user's calendar from..to, both included
+	HolidaysBetween(ctx context.Context, loginName, department string, from, to time.Time) ([]*Holiday, error)
+
+	GetWorkingDays(ctx context.Context, loginName string, month, year int) (*WorkingDays, error)
+}
+
+type holidayService struct {
+	repo     HolidayRepository
+	userRepo user.TenantUserRepository
+}
+
+func NewHolidayService(repo HolidayRepository, userRepo user.TenantUserRepository) HolidayService {
+	return &holidayService{repo: repo, userRepo: userRepo}
+}
+
+func (s *holidayService) CreateCalendar(ctx context.Context, c *HolidayCalendar) (*HolidayCalendar, error) {
+	if ve := validateCalendar(c); ve.HasErrors() {
+		return nil, ve
+	}
+
+	c.ID = uuid.New()
+	c.Country = strings.ToUpper(c.Country)
+	c.CreatedAt = time.Now()
+	if err := s.repo.InsertCalendar(ctx, c); err != nil {
+		return nil, err
+	}
+
+	//Holidays can be sent along with a new calendar instead of one by one.
+	for _, h := range c.Holidays {
+		if _, err := s.addHoliday(ctx, c.ID, h); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func validateCalendar(c *HolidayCalendar) *validate.ValidationError {
+	ve := validate.New()
+	ve.IsSizeInRange("Name", c.Name, 1, 100)
+	ve.IsSizeInRange("Country", c.Country, 0, 2)
+	return ve
+}
+
+func (s *holidayService) ListCalendars(ctx context.Context) ([]*HolidayCalendar, error) {
+	return s.repo.SelectCalendars(ctx)
+}
+
+func (s *holidayService) GetCalendar(ctx context.Context, calendarID string, year int) (*HolidayCalendar, error) {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if c.Holidays, err = s.repo.SelectHolidays(ctx, c.ID, from, from.AddDate(1, 0, -1)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *holidayService) UpdateCalendar(ctx context.Context, calendarID string, c *HolidayCalendar) (*HolidayCalendar, error) {
+	existing, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	if ve := validateCalendar(c); ve.HasErrors() {
+		return nil, ve
+	}
+
+	existing.Name = c.Name
+	existing.Country = strings.ToUpper(c.Country)
+	if _, err = s.repo.UpdateCalendar(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (s *holidayService) DeleteCalendar(ctx context.Context, calendarID string) error {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.DeleteCalendar(ctx, c.ID)
+	return err
+}
+
+func (s *holidayService) AddHoliday(ctx context.Context, calendarID string, h *Holiday) (*Holiday, error) {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	return s.addHoliday(ctx, c.ID, h)
+}
+
+func (s *holidayService) addHoliday(ctx context.Context, calendarID uuid.UUID, h *Holiday) (*Holiday, error) {
+	ve := validate.New()
+	ve.IsSizeInRange("Name", h.Name, 1, 100)
+	if h.Date.IsZero() {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Date", Constraint: validate.Required,
+			Message: "Field is required"})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	h.ID = uuid.New()
+	h.CalendarID = calendarID
+	h.Date = time.Date(h.Date.Year(), h.Date.Month(), h.Date.Day(), 0, 0, 0, 0, time.UTC)
+	if err := s.repo.UpsertHoliday(ctx, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *holidayService) DeleteHoliday(ctx context.Context, calendarID, holidayID string) error {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(holidayID)
+	if err != nil {
+		return &res.AppError{ResponseCode: HolidayNotFound, Cause: err}
+	}
+
+	found, err := s.repo.DeleteHoliday(ctx, c.ID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &res.AppError{ResponseCode: HolidayNotFound, Cause: errors.New("no such holiday")}
+	}
+	return nil
+}
+
+func (s *holidayService) ImportICS(ctx context.Context, calendarID string, r io.Reader) (*ICSImportReport, error) {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := parseICS(r)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: InvalidICS, Cause: err}
+	}
+
+	report := &ICSImportReport{Skipped: []string{}}
+	for _, e := range events {
+		//Holiday feeds list every year explicitly, a recurring event would only be imported once.
+		if e.Recurring {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s: recurring events are not supported",
+				e.Start.Format("2006-01-02"), e.Summary))
+			continue
+		}
+		if days := daysBetween(e.Start, e.End); days > maxICSEventDays {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s: %d days is longer than the %d an event may cover",
+				e.Start.Format("2006-01-02"), e.Summary, days, maxICSEventDays))
+			continue
+		}
+		for day := e.Start; day.Before(e.End); day = day.AddDate(0, 0, 1) {
+			if _, err = s.addHoliday(ctx, c.ID, &Holiday{Date: day, Name: e.Summary}); err != nil {
+				if _, invalid := err.(*validate.ValidationError); !invalid {
+					return nil, err
+				}
+				report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s: %s", day.Format("2006-01-02"), e.Summary, err))
+				continue
+			}
+			report.Imported++
+		}
+	}
+
+	log.Info().Str("calendar", c.Name).Int("imported", report.Imported).Int("skipped", len(report.Skipped)).Msg("Holidays imported")
+	return report, nil
+}
+
+func (s *holidayService) AssignCalendar(ctx context.Context, calendarID string, a *CalendarAssignment) error {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	a.CalendarID = c.ID
+	a.LoginName = strings.ToUpper(a.LoginName)
+	return s.repo.UpsertAssignment(ctx, a)
+}
+
+func (s *holidayService) UnassignCalendar(ctx context.Context, calendarID string, a *CalendarAssignment) error {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	a.CalendarID = c.ID
+	a.LoginName = strings.ToUpper(a.LoginName)
+	if _, err = s.repo.DeleteAssignment(ctx, a); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *holidayService) ListAssignments(ctx context.Context, calendarID string) ([]*CalendarAssignment, error) {
+	c, err := s.findCalendar(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SelectAssignments(ctx, c.ID)
+}
+
+func (s *holidayService) HolidaysFor(ctx context.Context, loginName, department string, month, year int) ([]*Holiday, error) {
+	from, to := monthRange(month, year)
+	return s.HolidaysBetween(ctx, loginName, department, from, to)
+}
+
+func (s *holidayService) HolidaysBetween(ctx context.Context, loginName, department string, from, to time.Time) ([]*Holiday, error) {
+	calendarID, err := s.repo.SelectAssignedCalendarID(ctx, loginName, department)
+	if err != nil {
+		return nil, err
+	}
+	if calendarID == uuid.Nil {
+		return []*Holiday{}, nil
+	}
+	return s.repo.SelectHolidays(ctx, calendarID, from, to)
+}
+
+func (s *holidayService) GetWorkingDays(ctx context.Context, loginName string, month, year int) (*WorkingDays, error) {
+	ve := validate.New()
+	ve.IsNumberInRange("month", month, 1, 12)
+	ve.IsNumberInRange("year", year, 1900, 9999)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	u, err := s.userRepo.SelectUserByLoginName(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+
+	holidays, err := s.HolidaysFor(ctx, u.LoginName, u.Department, month, year)
+	if err != nil {
+		return nil, err
+	}
+
+	wd := &WorkingDays{LoginName: u.LoginName, Month: month, Year: year, Holidays: holidays}
+	from, to := monthRange(month, year)
+	wd.Weekdays, wd.WorkingDays = countWorkingDays(from, to, holidays)
+	return wd, nil
+}
+
+//countWorkingDays counts the weekdays from..to, and those of them that are not one of the holidays of the range
+func countWorkingDays(from, to time.Time, holidays []*Holiday) (weekdays, working int) {
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if _, _, weekday := weekSlot(day); weekday {
+			weekdays++
+		}
+	}
+	working = weekdays
+	for _, h := range holidays {
+		if _, _, weekday := weekSlot(h.Date); weekday {
+			working--
+		}
+	}
+	return weekdays, working
+}
+
+func (s *holidayService) findCalendar(ctx context.Context, calendarID string) (*HolidayCalendar, error) {
+	id, err := uuid.Parse(calendarID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: HolidayCalendarNotFound, Cause: err}
+	}
+
+	c, err := s.repo.SelectCalendar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, &res.AppError{ResponseCode: HolidayCalendarNotFound, Cause: errors.New("no such holiday calendar")}
+	}
+	return c, nil
+}
+
+//weekSlot addresses a date the way WeekHrs does: weeks of the month start on Monday, the first one being the
+//week the 1st falls in, and Day1..Day5 are Monday to Friday. Weekends have no slot.
+func weekSlot(date time.Time) (int, int, bool) {
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(first.Weekday()) + 6) % 7
+	weekday := (int(date.Weekday()) + 6) % 7
+	if weekday > 4 {
+		return 0, 0, false
+	}
+	return (date.Day()-1+offset)/7 + 1, weekday + 1, true
+}
+
+//addHolidayAbsences pre-fills a Holiday absence for each holiday of the period on a weekday that has no absence yet
+func addHolidayAbsences(ts *Timesheet, period *PayPeriod, holidays []*Holiday) error {
+	if len(holidays) == 0 {
+		return nil
+	}
+
+	absences, _, _, err := summarizeAbsences(ts.Absences)
+	if err != nil {
+		return &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+
+	taken := map[[2]int]bool{}
+	for _, a := range absences {
+		taken[[2]int{a.WeekInfo, a.Day}] = true
+	}
+	for _, h := range holidays {
+		week, day, weekday := period.slotOf(h.Date)
+		if !weekday || !period.contains(h.Date) || taken[[2]int{week, day}] {
+			continue
+		}
+		taken[[2]int{week, day}] = true
+		absences = append(absences, AbsenceEntry{WeekInfo: week, Day: day, Type: AbsenceHoliday, Hours: holidayHours, Note: h.Name})
+	}
+
+	raw, err := json.Marshal(absences)
+	if err != nil {
+		return err
+	}
+	ts.Absences = sql.JSONText(raw)
+	return nil
+}
@@ -0,0 +1,630 @@
+package timesheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type LeaveService interface {
+	CreatePolicy(ctx context.Context, p *LeavePolicy) (*LeavePolicy, error)
+
+	ListPolicies(ctx context.Context) ([]*LeavePolicy, error)
+
+	DeletePolicy(ctx context.Context, policyID string) error
+
+	//GetBalances returns the balance of every policy that applies to the user
+	GetBalances(ctx context.Context, loginName string) ([]*LeaveBalance, error)
+
+	RequestLeave(ctx context.Context, loginName string, lr *LeaveRequest) (*LeaveRequest, error)
+
+	ListRequests(ctx context.Context, loginName string) ([]*LeaveRequest, error)
+
+	//ListPendingApprovals lists the pending requests of the approver's direct reports, or of everybody for an admin
+	ListPendingApprovals(ctx context.Context, approver *auth.Principal) ([]*LeaveRequest, error)
+
+	DecideRequest(ctx context.Context, approver *auth.Principal, requestID string, approve bool, decision *LeaveDecision) (*LeaveRequest, error)
+
+	CancelRequest(ctx context.Context, loginName, requestID string) (*LeaveRequest, error)
+
+	//CanView tells whether viewer may see the leave of loginName: the user, their manager or an admin
+	CanView(ctx context.Context, viewer *auth.Principal, loginName string) (bool, error)
+
+	//CheckTimesheetAbsences rejects policy backed absences that no approved request covers
+	CheckTimesheetAbsences(ctx context.Context, loginName string, period *PayPeriod, absences []AbsenceEntry) error
+
+	//TimesheetUsage is what the absences of a timesheet deduct from the balances, replacing an earlier deduction
+	//of the period. Without absences it gives back what the period deducted, as when the timesheet is deleted.
+	TimesheetUsage(ctx context.Context, loginName string, period *PayPeriod, absences []AbsenceEntry) (*LeaveUsage, error)
+
+	//RunAccruals credits every user of the organization in ctx up to asOf. It is idempotent.
+	RunAccruals(ctx context.Context, asOf time.Time) (int, error)
+
+	//ApprovedLeaveDays lists the days from..to covered by approved leave requests of the user
+	ApprovedLeaveDays(ctx context.Context, loginName string, from, to time.Time) (map[time.Time]bool, error)
+}
+
+type leaveService struct {
+	repo     LeaveRepository
+	userRepo user.TenantUserRepository
+	dirRepo  user.DirectoryRepository
+}
+
+func NewLeaveService(repo LeaveRepository, userRepo user.TenantUserRepository, dirRepo user.DirectoryRepository) LeaveService {
+	return &leaveService{repo: repo, userRepo: userRepo, dirRepo: dirRepo}
+}
+
+var accrualMethods = []string{string(AccrualPerPeriod), string(AccrualAnnualGrant)}
+
+const maxDirectReports = 1000
+
+func (s *leaveService) CreatePolicy(ctx context.Context, p *LeavePolicy) (*LeavePolicy, error) {
+	ve := validate.New()
+	ve.IsSizeInRange("Name", p.Name, 1, 100)
+	ve.IsWithin("AbsenceType", string(p.AbsenceType), absenceTypes)
+	ve.IsWithin("AccrualMethod", string(p.AccrualMethod), accrualMethods)
+	if p.AccrualHours < 0 {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "AccrualHours", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, nil}})
+	}
+	if p.CarryOverCap != nil && *p.CarryOverCap < 0 {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "CarryOverCap", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, nil}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now()
+	if err := s.repo.InsertLeavePolicy(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *leaveService) ListPolicies(ctx context.Context) ([]*LeavePolicy, error) {
+	return s.repo.SelectLeavePolicies(ctx)
+}
+
+func (s *leaveService) DeletePolicy(ctx context.Context, policyID string) error {
+	id, err := uuid.Parse(policyID)
+	if err != nil {
+		return &res.AppError{ResponseCode: LeavePolicyNotFound, Cause: err}
+	}
+
+	found, err := s.repo.DeleteLeavePolicy(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &res.AppError{ResponseCode: LeavePolicyNotFound, Cause: errors.New("no such leave policy")}
+	}
+	return nil
+}
+
+func (s *leaveService) GetBalances(ctx context.Context, loginName string) ([]*LeaveBalance, error) {
+	u, err := s.findUser(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []*LeaveBalance{}
+	for _, t := range absenceTypes {
+		p, err := s.repo.SelectLeavePolicy(ctx, AbsenceType(t), u.Department)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			continue
+		}
+
+		b, err := s.balanceOf(ctx, u.LoginName, p)
+		if err != nil {
+			return nil, err
+		}
+		if b.Ledger, err = s.repo.SelectLedger(ctx, u.LoginName, p.ID); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, nil
+}
+
+func (s *leaveService) balanceOf(ctx context.Context, loginName string, p *LeavePolicy) (*LeaveBalance, error) {
+	//Entries are dated, the far future includes grants for the current year that are dated ahead of today.
+	balance, err := s.repo.SelectBalance(ctx, loginName, p.ID, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.repo.SelectPendingLeaveHours(ctx, loginName, p.ID, p.AbsenceType)
+	if err != nil {
+		return nil, err
+	}
+	return &LeaveBalance{
+		PolicyID:     p.ID,
+		PolicyName:   p.Name,
+		AbsenceType:  p.AbsenceType,
+		Balance:      balance,
+		PendingHours: pending,
+		Available:    balance - pending,
+	}, nil
+}
+
+func (s *leaveService) RequestLeave(ctx context.Context, loginName string, lr *LeaveRequest) (*LeaveRequest, error) {
+	u, err := s.findUser(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+
+	ve := validate.New()
+	ve.IsWithin("AbsenceType", string(lr.AbsenceType), absenceTypes)
+	ve.IsSizeInRange("Comment", lr.Comment, 0, 500)
+	if lr.StartDate.IsZero() || lr.EndDate.Before(lr.StartDate) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "EndDate", Constraint: validate.Range,
+			Message: "EndDate must not be before StartDate", Args: []interface{}{lr.StartDate}})
+	}
+	if lr.Hours <= 0 {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Hours", Constraint: validate.Range,
+			Message: "Value is not within range", Args: []interface{}{0, nil}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	p, err := s.repo.SelectLeavePolicy(ctx, lr.AbsenceType, u.Department)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		b, err := s.balanceOf(ctx, u.LoginName, p)
+		if err != nil {
+			return nil, err
+		}
+		if b.Available < lr.Hours {
+			return nil, &res.AppError{ResponseCode: InsufficientLeaveBalance,
+				Cause: errors.Errorf("requested %.2f hours but only %.2f are available", lr.Hours, b.Available)}
+		}
+	}
+
+	lr.ID = uuid.New()
+	lr.LoginName = u.LoginName
+	lr.Status = LeaveRequestPending
+	lr.ApproverLoginName = ""
+	lr.DecisionComment = ""
+	lr.DecidedAt = nil
+	lr.CreatedAt = time.Now()
+	if err = s.repo.InsertLeaveRequest(ctx, lr); err != nil {
+		return nil, err
+	}
+	log.Info().Str("loginName", lr.LoginName).Str("type", string(lr.AbsenceType)).Msg("Leave requested")
+	return lr, nil
+}
+
+func (s *leaveService) ListRequests(ctx context.Context, loginName string) ([]*LeaveRequest, error) {
+	return s.repo.SelectLeaveRequests(ctx, []string{strings.ToUpper(loginName)}, "")
+}
+
+func (s *leaveService) ListPendingApprovals(ctx context.Context, approver *auth.Principal) ([]*LeaveRequest, error) {
+	if approver.Admin {
+		return s.repo.SelectLeaveRequests(ctx, nil, LeaveRequestPending)
+	}
+
+	reports, _, err := s.dirRepo.SelectDirectoryEntries(ctx, user.DirectoryFilter{ManagerLoginName: approver.LoginName}, 0, maxDirectReports)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return []*LeaveRequest{}, nil
+	}
+
+	logins := make([]string, 0, len(reports))
+	for _, r := range reports {
+		logins = append(logins, r.LoginName)
+	}
+	return s.repo.SelectLeaveRequests(ctx, logins, LeaveRequestPending)
+}
+
+func (s *leaveService) DecideRequest(ctx context.Context, approver *auth.Principal, requestID string, approve bool, decision *LeaveDecision) (*LeaveRequest, error) {
+	lr, err := s.findRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if lr.LoginName == approver.LoginName {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.New("leave can not be decided by the requester")}
+	}
+	if !approver.Admin {
+		isManager, err := s.isManagerOf(ctx, approver.LoginName, lr.LoginName)
+		if err != nil {
+			return nil, err
+		}
+		if !isManager {
+			return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.New("approver is not the manager of the requester")}
+		}
+	}
+
+	now := time.Now()
+	lr.Status = LeaveRequestRejected
+	if approve {
+		lr.Status = LeaveRequestApproved
+	}
+	lr.ApproverLoginName = approver.LoginName
+	lr.DecidedAt = &now
+	if decision != nil {
+		lr.DecisionComment = decision.Comment
+	}
+
+	updated, err := s.repo.UpdateLeaveRequestStatus(ctx, lr, LeaveRequestPending)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &res.AppError{ResponseCode: LeaveRequestNotPending, Cause: errors.New("leave request is not pending")}
+	}
+	log.Info().Str("loginName", lr.LoginName).Str("approver", approver.LoginName).Str("status", string(lr.Status)).Msg("Leave decided")
+	return lr, nil
+}
+
+func (s *leaveService) CancelRequest(ctx context.Context, loginName, requestID string) (*LeaveRequest, error) {
+	lr, err := s.findRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if lr.LoginName != strings.ToUpper(loginName) {
+		return nil, &res.AppError{ResponseCode: LeaveRequestNotFound, Cause: errors.New("leave request belongs to another user")}
+	}
+
+	//Approved leave can still be cancelled as long as no timesheet has used it yet.
+	expected := lr.Status
+	if expected != LeaveRequestPending && expected != LeaveRequestApproved {
+		return nil, &res.AppError{ResponseCode: LeaveRequestNotPending, Cause: errors.New("leave request can no longer be cancelled")}
+	}
+
+	if expected == LeaveRequestApproved {
+		used, err := s.isRecorded(ctx, lr)
+		if err != nil {
+			return nil, err
+		}
+		if used {
+			return nil, &res.AppError{ResponseCode: LeaveAlreadyUsed,
+				Cause: errors.Errorf("%s leave from %s to %s is on a timesheet", lr.AbsenceType, lr.StartDate.Format(dateLayout), lr.EndDate.Format(dateLayout))}
+		}
+	}
+
+	lr.Status = LeaveRequestCancelled
+	updated, err := s.repo.UpdateLeaveRequestStatus(ctx, lr, expected)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &res.AppError{ResponseCode: LeaveRequestNotPending, Cause: errors.New("leave request changed concurrently")}
+	}
+	return lr, nil
+}
+
+//isRecorded tells whether a timesheet has an absence of the request's type on one of its days
+func (s *leaveService) isRecorded(ctx context.Context, lr *LeaveRequest) (bool, error) {
+	from, to := dayOf(lr.StartDate), dayOf(lr.EndDate)
+	recorded, err := s.repo.SelectRecordedAbsences(ctx, lr.LoginName, from, to)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range recorded {
+		absences, _, _, err := summarizeAbsences(r.Absences)
+		if err != nil {
+			return false, &res.AppError{ResponseCode: res.DatabaseError, Cause: err}
+		}
+		period := &PayPeriod{Start: r.PeriodStart}
+		for _, a := range absences {
+			date := period.slotDate(a.WeekInfo, a.Day)
+			if a.Type == lr.AbsenceType && a.Hours > 0 && !date.Before(from) && !date.After(to) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *leaveService) findRequest(ctx context.Context, requestID string) (*LeaveRequest, error) {
+	id, err := uuid.Parse(requestID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: LeaveRequestNotFound, Cause: err}
+	}
+
+	lr, err := s.repo.SelectLeaveRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if lr == nil {
+		return nil, &res.AppError{ResponseCode: LeaveRequestNotFound, Cause: errors.New("no such leave request")}
+	}
+	return lr, nil
+}
+
+func (s *leaveService) findUser(ctx context.Context, loginName string) (*user.User, error) {
+	u, err := s.userRepo.SelectUserByLoginName(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+	return u, nil
+}
+
+func (s *leaveService) isManagerOf(ctx context.Context, manager, loginName string) (bool, error) {
+	e, err := s.dirRepo.SelectDirectoryEntryByLoginName(ctx, loginName)
+	if err != nil {
+		return false, err
+	}
+	return e != nil && e.ManagerLoginName != "" && e.ManagerLoginName == manager, nil
+}
+
+func (s *leaveService) CanView(ctx context.Context, viewer *auth.Principal, loginName string) (bool, error) {
+	loginName = strings.ToUpper(loginName)
+	if viewer.Admin || viewer.LoginName == loginName {
+		return true, nil
+	}
+	return s.isManagerOf(ctx, viewer.LoginName, loginName)
+}
+
+func (s *leaveService) CheckTimesheetAbsences(ctx context.Context, loginName string, period *PayPeriod, absences []AbsenceEntry) error {
+	u, err := s.findUser(ctx, loginName)
+	if err != nil {
+		return err
+	}
+
+	var requests []*LeaveRequest
+	for t, hours := range absenceHoursByType(absences) {
+		if hours == 0 {
+			continue
+		}
+		p, err := s.repo.SelectLeavePolicy(ctx, t, u.Department)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			continue
+		}
+
+		if requests == nil {
+			if requests, err = s.repo.SelectLeaveRequests(ctx, []string{u.LoginName}, LeaveRequestApproved); err != nil {
+				return err
+			}
+		}
+		if err = checkApprovedLeave(period, t, absences, requests); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//checkApprovedLeave requires every absence of type t to fall on a day of an approved request and the hours booked
+//against a request in the period to stay within its share of the approved hours
+func checkApprovedLeave(period *PayPeriod, t AbsenceType, absences []AbsenceEntry, requests []*LeaveRequest) error {
+	booked := map[*LeaveRequest]float64{}
+	for _, a := range absences {
+		if a.Type != t || a.Hours == 0 {
+			continue
+		}
+		date := period.slotDate(a.WeekInfo, a.Day)
+		var covering *LeaveRequest
+		for _, lr := range requests {
+			if lr.AbsenceType == t && !date.Before(dayOf(lr.StartDate)) && !date.After(dayOf(lr.EndDate)) {
+				covering = lr
+				break
+			}
+		}
+		if covering == nil {
+			return &res.AppError{ResponseCode: LeaveNotApproved,
+				Cause: errors.Errorf("no approved %s leave on %s", t, date.Format(dateLayout))}
+		}
+		booked[covering] += a.Hours
+	}
+
+	for lr, hours := range booked {
+		approved := lr.Hours * leaveShare(lr, period)
+		if hours > approved+0.01 {
+			return &res.AppError{ResponseCode: LeaveNotApproved,
+				Cause: errors.Errorf("%.2f hours of %s leave booked from %s to %s but only %.2f are approved in the period",
+					hours, t, lr.StartDate.Format(dateLayout), lr.EndDate.Format(dateLayout), approved)}
+		}
+	}
+	return nil
+}
+
+//leaveShare is the part of a request that falls into the period, counted in working days unless the request has none
+func leaveShare(lr *LeaveRequest, period *PayPeriod) float64 {
+	total, inPeriod, days, daysInPeriod := 0, 0, 0, 0
+	for date := dayOf(lr.StartDate); !date.After(dayOf(lr.EndDate)); date = date.AddDate(0, 0, 1) {
+		weekday := date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+		days++
+		if weekday {
+			total++
+		}
+		if period.contains(date) {
+			daysInPeriod++
+			if weekday {
+				inPeriod++
+			}
+		}
+	}
+	if total == 0 {
+		return float64(daysInPeriod) / float64(days)
+	}
+	return float64(inPeriod) / float64(total)
+}
+
+func (s *leaveService) TimesheetUsage(ctx context.Context, loginName string, period *PayPeriod, absences []AbsenceEntry) (*LeaveUsage, error) {
+	u, err := s.findUser(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &LeaveUsage{LoginName: u.LoginName, Reference: period.reference()}
+	hoursByType := absenceHoursByType(absences)
+	for _, t := range absenceTypes {
+		p, err := s.repo.SelectLeavePolicy(ctx, AbsenceType(t), u.Department)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			continue
+		}
+
+		hours := hoursByType[AbsenceType(t)]
+		if hours == 0 {
+			//The absence may have been removed by an update of the timesheet.
+			usage.Cleared = append(usage.Cleared, p.ID)
+			continue
+		}
+		usage.Entries = append(usage.Entries, &LedgerEntry{
+			ID:            uuid.New(),
+			LoginName:     u.LoginName,
+			PolicyID:      p.ID,
+			AbsenceType:   p.AbsenceType,
+			EntryType:     LedgerUsage,
+			Hours:         -hours,
+			EffectiveDate: period.Start,
+			Reference:     usage.Reference,
+			CreatedAt:     time.Now(),
+		})
+	}
+	return usage, nil
+}
+
+func (s *leaveService) RunAccruals(ctx context.Context, asOf time.Time) (int, error) {
+	policies, err := s.repo.SelectLeavePolicies(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(policies) == 0 {
+		return 0, nil
+	}
+
+	users, err := s.repo.SelectLeaveUsers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	credited := 0
+	for _, u := range users {
+		for _, p := range policies {
+			//Only the most specific policy of a type applies to a user.
+			effective, err := s.repo.SelectLeavePolicy(ctx, p.AbsenceType, u.Department)
+			if err != nil {
+				return credited, err
+			}
+			if effective == nil || effective.ID != p.ID {
+				continue
+			}
+
+			n, err := s.accrue(ctx, u.LoginName, p, asOf)
+			if err != nil {
+				log.Error().Err(err).Str("loginName", u.LoginName).Str("policy", p.Name).Msg("Leave accrual failed")
+				return credited, err
+			}
+			credited += n
+		}
+	}
+	return credited, nil
+}
+
+//accrue writes the carry-over forfeit and the accruals of asOf's year that are due for one user and returns how many
+//entries were due, including those already on the ledger
+func (s *leaveService) accrue(ctx context.Context, loginName string, p *LeavePolicy, asOf time.Time) (int, error) {
+	year := asOf.Year()
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*LedgerEntry{}
+
+	if p.CarryOverCap != nil && p.CreatedAt.Before(yearStart) {
+		carried, err := s.repo.SelectBalance(ctx, loginName, p.ID, yearStart)
+		if err != nil {
+			return 0, err
+		}
+		if carried > *p.CarryOverCap {
+			entries = append(entries, &LedgerEntry{EntryType: LedgerForfeit, Hours: *p.CarryOverCap - carried,
+				EffectiveDate: yearStart, Reference: fmt.Sprintf("carryover:%d", year)})
+		}
+	}
+
+	//Accruals start with the period the policy was created in, missed periods of the year are caught up.
+	start := time.Date(p.CreatedAt.Year(), p.CreatedAt.Month(), 1, 0, 0, 0, 0, time.UTC)
+	switch p.AccrualMethod {
+	case AccrualAnnualGrant:
+		if start.Year() <= year {
+			effective := yearStart
+			if start.After(yearStart) {
+				effective = start
+			}
+			entries = append(entries, &LedgerEntry{EntryType: LedgerAccrual, Hours: p.AccrualHours,
+				EffectiveDate: effective, Reference: fmt.Sprintf("grant:%d", year)})
+		}
+	case AccrualPerPeriod:
+		for m := time.January; m <= asOf.Month(); m++ {
+			period := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+			if period.Before(start) {
+				continue
+			}
+			entries = append(entries, &LedgerEntry{EntryType: LedgerAccrual, Hours: p.AccrualHours,
+				EffectiveDate: period, Reference: "accrual:" + period.Format("2006-01")})
+		}
+	}
+
+	for _, e := range entries {
+		e.ID = uuid.New()
+		e.LoginName = loginName
+		e.PolicyID = p.ID
+		e.AbsenceType = p.AbsenceType
+		e.CreatedAt = time.Now()
+		if err := s.repo.InsertLedgerEntryIfAbsent(ctx, e); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+func absenceHoursByType(absences []AbsenceEntry) map[AbsenceType]float64 {
+	hours := map[AbsenceType]float64{}
+	for _, a := range absences {
+		hours[a.Type] += a.Hours
+	}
+	return hours
+}
+
+func monthRange(month, year int) (time.Time, time.Time) {
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, -1)
+}
+
+func timesheetReference(month, year int) string {
+	return fmt.Sprintf("timesheet:%d-%02d", year, month)
+}
+
+func (s *leaveService) ApprovedLeaveDays(ctx context.Context, loginName string, from, to time.Time) (map[time.Time]bool, error) {
+	requests, err := s.repo.SelectLeaveRequests(ctx, []string{strings.ToUpper(loginName)}, LeaveRequestApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	days := map[time.Time]bool{}
+	for _, lr := range requests {
+		for date := dayOf(lr.StartDate); !date.After(dayOf(lr.EndDate)); date = date.AddDate(0, 0, 1) {
+			if !date.Before(from) && !date.After(to) {
+				days[date] = true
+			}
+		}
+	}
+	return days, nil
+}
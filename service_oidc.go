@@ -0,0 +1,391 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type OIDCService interface {
+	//Enabled reports whether an identity provider is configured
+	Enabled() bool
+
+	//BeginLogin creates the state to keep in the browser and the provider url to redirect to
+	BeginLogin(ctx context.Context) (*OIDCLoginState, string, error)
+
+	//CompleteLogin exchanges the authorization code, verifies the ID token and returns the matching user,
+	//provisioning one when AutoProvision is on.
+	CompleteLogin(ctx context.Context, state *OIDCLoginState, code string) (*User, error)
+}
+
+type oidcService struct {
+	cfg         OIDCConfig
+	client      *http.Client
+	userRepo    TenantUserRepository
+	identities  OIDCIdentityRepository
+	directory   DirectoryRepository
+	provisioner *accountProvisioner
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+//NewOIDCService returns the login flow for the configured provider. client may be nil to use a default client,
+//which lets tests point the service at a local mock provider.
+func NewOIDCService(cfg OIDCConfig, client *http.Client, userRepo TenantUserRepository, identities OIDCIdentityRepository,
+	directory DirectoryRepository, userSvc Service, orgRepo OrganizationRepository) OIDCService {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	return &oidcService{cfg: cfg, client: client, userRepo: userRepo, identities: identities, directory: directory,
+		provisioner: &accountProvisioner{userSvc: userSvc, orgRepo: orgRepo}}
+}
+
+func (s *oidcService) Enabled() bool {
+	return s.cfg.IssuerURL != "" && s.cfg.ClientID != ""
+}
+
+func (s *oidcService) BeginLogin(ctx context.Context) (*OIDCLoginState, string, error) {
+	if !s.Enabled() {
+		return nil, "", &res.AppError{ResponseCode: OIDCDisabled, Cause: errors.New("oidc issuer is not configured")}
+	}
+
+	d, err := s.getDiscovery(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	state := &OIDCLoginState{State: randomURLString(), Nonce: randomURLString(), CodeVerifier: randomURLString()}
+	challenge := sha256.Sum256([]byte(state.CodeVerifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", s.cfg.ClientID)
+	q.Set("redirect_uri", s.cfg.RedirectURL)
+	q.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	q.Set("state", state.State)
+	q.Set("nonce", state.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return state, d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+func (s *oidcService) CompleteLogin(ctx context.Context, state *OIDCLoginState, code string) (*User, error) {
+	if !s.Enabled() {
+		return nil, &res.AppError{ResponseCode: OIDCDisabled, Cause: errors.New("oidc issuer is not configured")}
+	}
+
+	d, err := s.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.cfg.RedirectURL)
+	form.Set("client_id", s.cfg.ClientID)
+	form.Set("code_verifier", state.CodeVerifier)
+	if s.cfg.ClientSecret != "" {
+		form.Set("client_secret", s.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed, Cause: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	tr := &oidcTokenResponse{}
+	if err = s.doJSON(req, tr); err != nil {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed, Cause: err}
+	}
+	if tr.Error != "" || tr.IDToken == "" {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed,
+			Cause: fmt.Errorf("token endpoint returned no id_token: %s %s", tr.Error, tr.ErrorDescription)}
+	}
+
+	claims, err := s.verifyIDToken(ctx, d, tr.IDToken, state.Nonce)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed, Cause: err}
+	}
+
+	return s.resolveUser(ctx, claims)
+}
+
+//resolveUser returns the account of the identity in claims. An identity seen for the first time is linked to the
+//account with its verified email, to the one named by UsernameClaim if that claim is trusted, or else to a newly
+//provisioned account. It is never linked to an existing account just because that account has the claimed name.
+func (s *oidcService) resolveUser(ctx context.Context, claims jwt.MapClaims) (*User, error) {
+	issuer, subject := claimString(claims, "iss"), claimString(claims, "sub")
+	if subject == "" {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed, Cause: errors.New("id token has no sub claim")}
+	}
+
+	identity, err := s.identities.SelectIdentity(ctx, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		u, err := s.selectUser(ctx, identity.LoginName)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, &res.AppError{ResponseCode: OIDCUserNotProvisioned,
+				Cause: fmt.Errorf("user %s linked to %s of %s no longer exists", identity.LoginName, subject, issuer)}
+		}
+		return u, nil
+	}
+
+	u, err := s.linkableUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		if u, err = s.provision(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = s.identities.InsertIdentity(ctx, &OIDCIdentity{Issuer: issuer, Subject: subject, LoginName: u.LoginName,
+		CreatedAt: time.Now()}); err != nil {
+		return nil, err
+	}
+	log.Info().Str("loginName", u.LoginName).Str("issuer", issuer).Msg("Linked single sign-on identity")
+	return u, nil
+}
+
+//linkableUser is the existing account a new identity belongs to, nil if there is none
+func (s *oidcService) linkableUser(ctx context.Context, claims jwt.MapClaims) (*User, error) {
+	if email := claimString(claims, "email"); email != "" && emailVerified(claims) {
+		entries, _, err := s.directory.SelectDirectoryEntries(ctx, DirectoryFilter{Email: email}, 0, 2)
+		if err != nil {
+			return nil, err
+		}
+		//An email shared by several accounts does not tell which one is meant
+		if len(entries) == 1 {
+			return s.selectUser(ctx, entries[0].LoginName)
+		}
+	}
+	if s.cfg.TrustUsernameClaim {
+		if loginName := strings.ToUpper(claimString(claims, s.cfg.UsernameClaim)); loginName != "" {
+			return s.selectUser(ctx, loginName)
+		}
+	}
+	return nil, nil
+}
+
+//provision creates the account of a new identity named by UsernameClaim
+func (s *oidcService) provision(ctx context.Context, claims jwt.MapClaims) (*User, error) {
+	loginName := strings.ToUpper(claimString(claims, s.cfg.UsernameClaim))
+	if !s.cfg.AutoProvision {
+		return nil, &res.AppError{ResponseCode: OIDCUserNotProvisioned,
+			Cause: fmt.Errorf("no account is linked to %s and auto provisioning is off", claimString(claims, "sub"))}
+	}
+	if loginName == "" {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed,
+			Cause: fmt.Errorf("id token has no %s claim", s.cfg.UsernameClaim)}
+	}
+
+	u := &User{
+		LoginName:  loginName,
+		Department: claimString(claims, s.cfg.DepartmentClaim),
+		JobTitle:   claimString(claims, s.cfg.JobTitleClaim),
+	}
+	created, err := s.provisioner.ensureUser(ctx, u)
+	if err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Just-in-time provisioning failed")
+		return nil, err
+	}
+	if !created {
+		return nil, &res.AppError{ResponseCode: OIDCUserNotProvisioned,
+			Cause: fmt.Errorf("user %s exists and is not linked to %s", loginName, claimString(claims, "sub"))}
+	}
+
+	log.Info().Str("loginName", loginName).Msg("Provisioned user from single sign-on")
+	return u, nil
+}
+
+func (s *oidcService) selectUser(ctx context.Context, loginName string) (*User, error) {
+	u, err := s.userRepo.SelectUserByLoginName(ctx, loginName)
+	if err != nil && !res.IsAppErrorEquals(err, res.RecordNotFound) {
+		return nil, err
+	}
+	if u == nil || u.LoginName == "" {
+		return nil, nil
+	}
+	return u, nil
+}
+
+func (s *oidcService) verifyIDToken(ctx context.Context, d *oidcDiscovery, raw string, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return s.getKey(ctx, d, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid id token")
+	}
+
+	if !claims.VerifyIssuer(d.Issuer, true) {
+		return nil, errors.New("id token issuer mismatch")
+	}
+	if !audienceContains(claims["aud"], s.cfg.ClientID) {
+		return nil, errors.New("id token audience mismatch")
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (s *oidcService) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.discovery != nil {
+		return s.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(s.cfg.IssuerURL, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed, Cause: err}
+	}
+
+	d := &oidcDiscovery{}
+	if err = s.doJSON(req, d); err != nil {
+		return nil, &res.AppError{ResponseCode: OIDCLoginFailed, Cause: errors.Wrap(err, "oidc discovery failed")}
+	}
+	if d.Issuer == "" {
+		d.Issuer = s.cfg.IssuerURL
+	}
+	s.discovery = d
+	return d, nil
+}
+
+//getKey returns the signing key with the given id, refreshing the key set once when the id is unknown
+//so that provider key rotation is picked up without a restart.
+func (s *oidcService) getKey(ctx context.Context, d *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	set := &jsonWebKeySet{}
+	if err = s.doJSON(req, set); err != nil {
+		return nil, errors.Wrap(err, "unable to fetch jwks")
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	s.keys = keys
+
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no signing key with kid %q", kid)
+}
+
+func (s *oidcService) doJSON(req *http.Request, v interface{}) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s %s returned %d", req.Method, req.URL, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	if name == "" {
+		return ""
+	}
+	v, _ := claims[name].(string)
+	return v
+}
+
+//emailVerified reads email_verified, which some providers send as a string
+func emailVerified(claims jwt.MapClaims) bool {
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func randomURLString() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
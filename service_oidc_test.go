@@ -0,0 +1,200 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+//mockProvider is an OpenID Connect provider that answers every code with an ID token of claims
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	nonce  string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{Kid: "test", Kty: "RSA", Alg: "RS256", Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes())}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{"iss": p.server.URL, "aud": "timesheet", "nonce": p.nonce,
+			"exp": time.Now().Add(time.Minute).Unix()}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(p.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(oidcTokenResponse{IDToken: signed, TokenType: "Bearer"})
+	})
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+type fakeUserRepository struct {
+	Repository
+	users map[string]*User
+}
+
+func (repo *fakeUserRepository) SelectUserByLoginName(ctx context.Context, loginName string) (*User, error) {
+	return repo.users[loginName], nil
+}
+
+//fakeOrganizationRepository knows the organization of every user by login name
+type fakeOrganizationRepository struct {
+	OrganizationRepository
+	orgs map[string]uuid.UUID
+}
+
+func (repo *fakeOrganizationRepository) SelectOrganizationIDByLoginName(ctx context.Context, loginName string) (uuid.UUID, error) {
+	return repo.orgs[loginName], nil
+}
+
+type fakeIdentityRepository struct {
+	identities map[string]*OIDCIdentity
+}
+
+func (repo *fakeIdentityRepository) SelectIdentity(ctx context.Context, issuer, subject string) (*OIDCIdentity, error) {
+	return repo.identities[issuer+" "+subject], nil
+}
+
+func (repo *fakeIdentityRepository) InsertIdentity(ctx context.Context, i *OIDCIdentity) error {
+	repo.identities[i.Issuer+" "+i.Subject] = i
+	return nil
+}
+
+type fakeDirectoryRepository struct {
+	DirectoryRepository
+	entries []*DirectoryEntry
+}
+
+func (repo *fakeDirectoryRepository) SelectDirectoryEntries(ctx context.Context, filter DirectoryFilter, offset, limit int) ([]*DirectoryEntry, int, error) {
+	found := []*DirectoryEntry{}
+	for _, e := range repo.entries {
+		if strings.EqualFold(e.Email, filter.Email) {
+			found = append(found, e)
+		}
+	}
+	return found, len(found), nil
+}
+
+func TestOIDCResolveUser(t *testing.T) {
+	provider := newMockProvider(t)
+	defer provider.server.Close()
+
+	cases := []struct {
+		name          string
+		claims        jwt.MapClaims
+		trustUsername bool
+		autoProvision bool
+		linked        map[string]string
+		want          string
+		wantErr       *res.ResponseCode
+	}{
+		{name: "username of an existing account does not link",
+			claims:  jwt.MapClaims{"sub": "s-1", "preferred_username": "alice"},
+			wantErr: OIDCUserNotProvisioned},
+		{name: "username of an existing account does not link with auto provisioning",
+			claims:        jwt.MapClaims{"sub": "s-1", "preferred_username": "alice"},
+			autoProvision: true, wantErr: OIDCUserNotProvisioned},
+		{name: "username of another organization is not provisioned",
+			claims:        jwt.MapClaims{"sub": "s-1", "preferred_username": "carol"},
+			autoProvision: true, wantErr: UserAlreadyExists},
+		{name: "trusted username links",
+			claims:        jwt.MapClaims{"sub": "s-1", "preferred_username": "alice"},
+			trustUsername: true, want: "ALICE"},
+		{name: "verified email links",
+			claims: jwt.MapClaims{"sub": "s-1", "preferred_username": "mallory", "email": "Alice@example.com", "email_verified": true},
+			want:   "ALICE"},
+		{name: "verified email sent as a string links",
+			claims: jwt.MapClaims{"sub": "s-1", "email": "alice@example.com", "email_verified": "true"},
+			want:   "ALICE"},
+		{name: "unverified email does not link",
+			claims:  jwt.MapClaims{"sub": "s-1", "email": "alice@example.com", "email_verified": false},
+			wantErr: OIDCUserNotProvisioned},
+		{name: "linked subject wins over a changed username",
+			claims: jwt.MapClaims{"sub": "s-2", "preferred_username": "alice"},
+			linked: map[string]string{"s-2": "BOB"}, want: "BOB"},
+		{name: "token without subject fails",
+			claims:  jwt.MapClaims{"preferred_username": "alice"},
+			wantErr: OIDCLoginFailed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			identities := &fakeIdentityRepository{identities: map[string]*OIDCIdentity{}}
+			for subject, loginName := range c.linked {
+				identities.identities[provider.server.URL+" "+subject] = &OIDCIdentity{Issuer: provider.server.URL,
+					Subject: subject, LoginName: loginName}
+			}
+			users := &fakeUserRepository{users: map[string]*User{"ALICE": {LoginName: "ALICE"}, "BOB": {LoginName: "BOB"}}}
+			directory := &fakeDirectoryRepository{entries: []*DirectoryEntry{{LoginName: "ALICE", Email: "alice@example.com"}}}
+			orgs := &fakeOrganizationRepository{orgs: map[string]uuid.UUID{"ALICE": DefaultOrganizationID,
+				"BOB": DefaultOrganizationID, "CAROL": uuid.New()}}
+			svc := NewOIDCService(OIDCConfig{IssuerURL: provider.server.URL, ClientID: "timesheet",
+				TrustUsernameClaim: c.trustUsername, AutoProvision: c.autoProvision},
+				provider.server.Client(), users, identities, directory, nil, orgs)
+			ctx := auth.WithTenant(context.Background(), DefaultOrganizationID)
+
+			state, _, err := svc.BeginLogin(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			provider.nonce, provider.claims = state.Nonce, c.claims
+
+			u, err := svc.CompleteLogin(ctx, state, "code")
+			if c.wantErr != nil {
+				if !res.IsAppErrorEquals(err, c.wantErr) {
+					t.Fatalf("got %v, want %s", err, c.wantErr.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.LoginName != c.want {
+				t.Fatalf("logged in as %s, want %s", u.LoginName, c.want)
+			}
+			subject := claimString(c.claims, "sub")
+			if linked := identities.identities[provider.server.URL+" "+subject]; linked == nil || linked.LoginName != c.want {
+				t.Fatalf("identity %s is not linked to %s", subject, c.want)
+			}
+		})
+	}
+}
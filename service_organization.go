@@ -0,0 +1,88 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, org *Organization) (*Organization, error)
+
+	ListOrganizations(ctx context.Context) ([]*Organization, error)
+
+	//ResolveSlug returns the id of the organization configured for a provisioning client or job
+	ResolveSlug(ctx context.Context, slug string) (uuid.UUID, error)
+
+	//TenantOf returns the organization a user belongs to
+	TenantOf(ctx context.Context, loginName string) (uuid.UUID, error)
+}
+
+type organizationService struct {
+	repo OrganizationRepository
+}
+
+func NewOrganizationService(repo OrganizationRepository) OrganizationService {
+	return &organizationService{repo: repo}
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+func (s *organizationService) CreateOrganization(ctx context.Context, org *Organization) (*Organization, error) {
+	org.Slug = strings.ToLower(strings.TrimSpace(org.Slug))
+	org.Name = strings.TrimSpace(org.Name)
+
+	ve := validate.New()
+	ve.IsSizeInRange("Slug", org.Slug, 1, 50)
+	ve.IsSizeInRange("Name", org.Name, 1, 100)
+	if !slugPattern.MatchString(org.Slug) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Slug", Constraint: validate.Like,
+			Message: "Field must match regex", Args: []interface{}{slugPattern.String()}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	org.ID = uuid.New()
+	org.CreatedAt = time.Now().UTC()
+	if err := s.repo.InsertOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("slug", org.Slug).Msg("Created organization")
+	return org, nil
+}
+
+func (s *organizationService) ListOrganizations(ctx context.Context) ([]*Organization, error) {
+	return s.repo.SelectOrganizations(ctx)
+}
+
+func (s *organizationService) ResolveSlug(ctx context.Context, slug string) (uuid.UUID, error) {
+	org, err := s.repo.SelectOrganizationBySlug(ctx, strings.ToLower(slug))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if org == nil {
+		return uuid.Nil, &res.AppError{ResponseCode: OrganizationNotFound, Cause: fmt.Errorf("no organization %q", slug)}
+	}
+	return org.ID, nil
+}
+
+func (s *organizationService) TenantOf(ctx context.Context, loginName string) (uuid.UUID, error) {
+	orgID, err := s.repo.SelectOrganizationIDByLoginName(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if orgID == uuid.Nil {
+		return uuid.Nil, &res.AppError{ResponseCode: res.Unauthorized, Cause: fmt.Errorf("user %s does not exist", loginName)}
+	}
+	return orgID, nil
+}
@@ -0,0 +1,90 @@
+package timesheets
+
+import (
+	"context"
+	"time"
+
+	"timesheet/commons/events"
+
+	"github.com/rs/zerolog/log"
+)
+
+type OutboxRelay interface {
+	//Relay publishes the pending events of the organization in ctx to every sink and returns how many
+	//left the outbox. An event stays until all sinks took it, so a sink may see it more than once.
+	Relay(ctx context.Context, now time.Time) (int, error)
+}
+
+type outboxRelay struct {
+	repo  OutboxRepository
+	sinks []events.Sink
+	cfg   OutboxConfig
+}
+
+func NewOutboxRelay(repo OutboxRepository, sinks []events.Sink, cfg OutboxConfig) OutboxRelay {
+	return &outboxRelay{repo: repo, sinks: sinks, cfg: cfg}
+}
+
+const (
+	//outboxBatch is how many events one Relay claims
+	outboxBatch = 100
+	//outboxLease is how long a claimed event is left to one relay before another may pick it up
+	outboxLease = time.Minute
+	//maxOutboxRetry caps the wait between two attempts
+	maxOutboxRetry = time.Hour
+)
+
+func (r *outboxRelay) Relay(ctx context.Context, now time.Time) (int, error) {
+	entries, err := r.repo.ClaimOutbox(ctx, now, now.Add(outboxLease), outboxBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, e := range entries {
+		msg := &events.Message{Key: e.ID.String(), Type: string(e.EventType), Payload: e.Payload, OccurredAt: e.CreatedAt}
+		if err = r.publish(ctx, msg); err != nil {
+			e.Attempts++
+			e.LastError = err.Error()
+			e.AvailableAt = time.Now().Add(r.retryAfter(e.Attempts))
+			log.Warn().Err(err).Str("event", msg.Key).Int("attempts", e.Attempts).Msg("Outbox event not published")
+			if err = r.repo.MarkFailed(ctx, e); err != nil {
+				return published, err
+			}
+			continue
+		}
+		if err = r.repo.MarkPublished(ctx, e.ID, time.Now()); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	if r.cfg.Retention > 0 {
+		if _, err = r.repo.PurgePublished(ctx, now.Add(-r.cfg.Retention)); err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+func (r *outboxRelay) publish(ctx context.Context, msg *events.Message) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, msg); err != nil {
+			log.Error().Err(err).Str("sink", sink.Name()).Str("event", msg.Key).Msg("Sink failed to publish the event")
+			return err
+		}
+	}
+	return nil
+}
+
+//retryAfter doubles RetryBase with every failed attempt, up to maxOutboxRetry
+func (r *outboxRelay) retryAfter(attempts int) time.Duration {
+	retry := r.cfg.RetryBase
+	for i := 1; i < attempts && retry < maxOutboxRetry; i++ {
+		retry *= 2
+	}
+	if retry > maxOutboxRetry {
+		return maxOutboxRetry
+	}
+	return retry
+}
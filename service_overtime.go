@@ -0,0 +1,259 @@
+package timesheets
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type OvertimeService interface {
+	CreateRule(ctx context.Context, rule *OvertimeRule) (*OvertimeRule, error)
+
+	UpdateRule(ctx context.Context, ruleID string, rule *OvertimeRule) (*OvertimeRule, error)
+
+	DeleteRule(ctx context.Context, ruleID string) error
+
+	ListRules(ctx context.Context) ([]*OvertimeRule, error)
+
+	//Breakdown applies the rule of the user's department and contract to the hours of a pay period. earlier holds the
+	//hours per day of the previous period in the calendar week the period starts in, they count towards its weekly threshold.
+	Breakdown(ctx context.Context, loginName, department string, period *PayPeriod, weeks []WeekHrs, earlier map[time.Time]float64) (*HoursBreakdown, error)
+}
+
+type overtimeService struct {
+	repo         OvertimeRepository
+	contractRepo ContractRepository
+	holidays     HolidayService
+}
+
+func NewOvertimeService(repo OvertimeRepository, contractRepo ContractRepository, holidays HolidayService) OvertimeService {
+	return &overtimeService{repo: repo, contractRepo: contractRepo, holidays: holidays}
+}
+
+func (s *overtimeService) CreateRule(ctx context.Context, rule *OvertimeRule) (*OvertimeRule, error) {
+	if ve := validateOvertimeRule(rule); ve.HasErrors() {
+		return nil, ve
+	}
+
+	rule.ID = uuid.New()
+	rule.CreatedAt = time.Now()
+	if err := s.repo.InsertOvertimeRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *overtimeService) UpdateRule(ctx context.Context, ruleID string, rule *OvertimeRule) (*OvertimeRule, error) {
+	id, err := uuid.Parse(ruleID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: OvertimeRuleNotFound, Cause: err}
+	}
+	if ve := validateOvertimeRule(rule); ve.HasErrors() {
+		return nil, ve
+	}
+
+	rule.ID = id
+	found, err := s.repo.UpdateOvertimeRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, &res.AppError{ResponseCode: OvertimeRuleNotFound, Cause: errors.New("no such overtime rule")}
+	}
+	return rule, nil
+}
+
+//validateOvertimeRule also fills in the default multipliers
+func validateOvertimeRule(rule *OvertimeRule) *validate.ValidationError {
+	if rule.OvertimeMultiplier == 0 {
+		rule.OvertimeMultiplier = defaultOvertimeMultiplier
+	}
+	if rule.DoubleTimeMultiplier == 0 {
+		rule.DoubleTimeMultiplier = defaultDoubleTimeMultiplier
+	}
+
+	ve := validate.New()
+	ve.IsSizeInRange("Name", rule.Name, 1, 100)
+	ve.IsSizeInRange("Department", rule.Department, 0, 100)
+	ve.IsSizeInRange("ContractType", rule.ContractType, 0, 50)
+	for field, hours := range map[string]float64{"DailyOvertimeAfter": rule.DailyOvertimeAfter,
+		"DailyDoubleTimeAfter": rule.DailyDoubleTimeAfter, "WeeklyOvertimeAfter": rule.WeeklyOvertimeAfter} {
+		if hours < 0 {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: field, Constraint: validate.Range,
+				Message: "Value is not within range", Args: []interface{}{0, nil}})
+		}
+	}
+	if rule.DailyDoubleTimeAfter > 0 && rule.DailyDoubleTimeAfter < rule.DailyOvertimeAfter {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "DailyDoubleTimeAfter", Constraint: validate.Range,
+			Message: "Must not be below DailyOvertimeAfter", Args: []interface{}{rule.DailyOvertimeAfter, nil}})
+	}
+	for field, m := range map[string]float64{"WeekendMultiplier": rule.WeekendMultiplier, "HolidayMultiplier": rule.HolidayMultiplier,
+		"OvertimeMultiplier": rule.OvertimeMultiplier, "DoubleTimeMultiplier": rule.DoubleTimeMultiplier} {
+		if m < 0 || m > 5 {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: field, Constraint: validate.Range,
+				Message: "Value is not within range", Args: []interface{}{0, 5}})
+		}
+	}
+	return ve
+}
+
+func (s *overtimeService) DeleteRule(ctx context.Context, ruleID string) error {
+	id, err := uuid.Parse(ruleID)
+	if err != nil {
+		return &res.AppError{ResponseCode: OvertimeRuleNotFound, Cause: err}
+	}
+
+	found, err := s.repo.DeleteOvertimeRule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &res.AppError{ResponseCode: OvertimeRuleNotFound, Cause: errors.New("no such overtime rule")}
+	}
+	return nil
+}
+
+func (s *overtimeService) ListRules(ctx context.Context) ([]*OvertimeRule, error) {
+	return s.repo.SelectOvertimeRules(ctx)
+}
+
+func (s *overtimeService) Breakdown(ctx context.Context, loginName, department string, period *PayPeriod, weeks []WeekHrs, earlier map[time.Time]float64) (*HoursBreakdown, error) {
+	contractType := ""
+	contract, err := s.contractRepo.SelectContract(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if contract != nil {
+		contractType = contract.ContractType
+	}
+
+	rule, err := s.repo.SelectApplicableRule(ctx, department, contractType)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := s.holidays.HolidaysBetween(ctx, loginName, department, weekStart(period.Start), period.End)
+	if err != nil {
+		return nil, err
+	}
+	holidayDays := map[time.Time]bool{}
+	for _, h := range holidays {
+		holidayDays[dayOf(h.Date)] = true
+	}
+
+	return splitHours(rule, weeks, period, holidayDays, earlier), nil
+}
+
+//splitHours is the rules engine. Each day is classified on its own first: weekend and holiday hours by their
+//multiplier, other days by the daily thresholds. The weekly threshold then moves the regular hours of a calendar
+//week that exceed it into overtime, counting the regular hours of the week's earlier days in the previous period.
+//Without a rule every hour is regular.
+func splitHours(rule *OvertimeRule, weeks []WeekHrs, period *PayPeriod, holidays map[time.Time]bool, earlier map[time.Time]float64) *HoursBreakdown {
+	b := &HoursBreakdown{}
+	if rule == nil {
+		for _, w := range weeks {
+			b.RegularHours += w.total()
+		}
+		b.PayableHours = b.RegularHours
+		return b
+	}
+
+	//Regular hours per calendar week, keyed by its Monday, before the period and within it
+	carried, regular := map[time.Time]float64{}, map[time.Time]float64{}
+	first := weekStart(period.Start)
+	for date, hours := range earlier {
+		if !date.Before(first) && date.Before(period.Start) {
+			carried[first] += splitDay(rule, date, hours, holidays[date]).regular
+		}
+	}
+
+	for _, w := range weeks {
+		for day, hours := range w.days() {
+			if hours <= 0 {
+				continue
+			}
+			date := period.slotDate(w.WeekInfo, day+1)
+			split := splitDay(rule, date, hours, holidays[date])
+			regular[weekStart(date)] += split.regular
+			b.OvertimeHours += split.overtime
+			b.DoubleTimeHours += split.double
+			b.PayableHours += split.payable
+		}
+	}
+
+	for week, hours := range regular {
+		if rule.WeeklyOvertimeAfter > 0 && carried[week]+hours > rule.WeeklyOvertimeAfter {
+			excess := math.Min(carried[week]+hours-rule.WeeklyOvertimeAfter, hours)
+			hours -= excess
+			b.OvertimeHours += excess
+			b.PayableHours += excess * rule.OvertimeMultiplier
+		}
+		b.RegularHours += hours
+		b.PayableHours += hours
+	}
+
+	b.RegularHours = roundHours(b.RegularHours)
+	b.OvertimeHours = roundHours(b.OvertimeHours)
+	b.DoubleTimeHours = roundHours(b.DoubleTimeHours)
+	b.PayableHours = roundHours(b.PayableHours)
+	return b
+}
+
+//daySplit is how the hours of one day divide before the weekly threshold. payable weighs the premium hours only,
+//regular hours are added once the week is known.
+type daySplit struct {
+	regular, overtime, double, payable float64
+}
+
+func splitDay(rule *OvertimeRule, date time.Time, hours float64, holiday bool) daySplit {
+	premium := func(multiplier float64) daySplit {
+		if multiplier >= rule.DoubleTimeMultiplier {
+			return daySplit{double: hours, payable: hours * multiplier}
+		}
+		return daySplit{overtime: hours, payable: hours * multiplier}
+	}
+	if holiday && rule.HolidayMultiplier > 1 {
+		return premium(rule.HolidayMultiplier)
+	}
+	weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+	if weekend && rule.WeekendMultiplier > 1 {
+		return premium(rule.WeekendMultiplier)
+	}
+
+	split := daySplit{regular: hours}
+	if rule.DailyOvertimeAfter > 0 && hours > rule.DailyOvertimeAfter {
+		split.regular = rule.DailyOvertimeAfter
+	}
+	//Without an overtime threshold the hours up to double time stay regular.
+	if rule.DailyDoubleTimeAfter > 0 && hours > rule.DailyDoubleTimeAfter {
+		split.double = hours - rule.DailyDoubleTimeAfter
+		split.regular = math.Min(split.regular, rule.DailyDoubleTimeAfter)
+	}
+	split.overtime = hours - split.regular - split.double
+	split.payable = split.overtime*rule.OvertimeMultiplier + split.double*rule.DoubleTimeMultiplier
+	return split
+}
+
+//weekStart is the Monday of date's calendar week
+func weekStart(date time.Time) time.Time {
+	day := dayOf(date)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+//slotDate is the reverse of weekSlot, ok is false for a slot outside the month
+func slotDate(month, year, week, day int) (time.Time, bool) {
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(first.Weekday()) + 6) % 7
+	date := first.AddDate(0, 0, (week-1)*7+(day-1)-offset)
+	return date, date.Month() == first.Month()
+}
+
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
@@ -0,0 +1,78 @@
+package timesheets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitHours(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	//January 2026 starts on a Thursday, week 1 runs from Monday December 29 to Sunday January 4
+	period := &PayPeriod{Start: date(2026, 1, 1), End: date(2026, 1, 31), Frequency: PayMonthly}
+	rule := func(r OvertimeRule) *OvertimeRule {
+		r.OvertimeMultiplier, r.DoubleTimeMultiplier = defaultOvertimeMultiplier, defaultDoubleTimeMultiplier
+		return &r
+	}
+
+	cases := []struct {
+		name     string
+		rule     *OvertimeRule
+		weeks    []WeekHrs
+		holidays map[time.Time]bool
+		earlier  map[time.Time]float64
+		want     HoursBreakdown
+	}{
+		{name: "without a rule every hour is regular",
+			weeks: []WeekHrs{{WeekInfo: 2, Day1: 10, Day2: 10, Day3: 10, Day4: 10, Day5: 10, Day6: 4}},
+			want:  HoursBreakdown{RegularHours: 54, PayableHours: 54}},
+		{name: "daily overtime and double time",
+			rule:  rule(OvertimeRule{DailyOvertimeAfter: 8, DailyDoubleTimeAfter: 12}),
+			weeks: []WeekHrs{{WeekInfo: 2, Day2: 13}},
+			want:  HoursBreakdown{RegularHours: 8, OvertimeHours: 4, DoubleTimeHours: 1, PayableHours: 16}},
+		{name: "double time without an overtime threshold keeps the rest regular",
+			rule:  rule(OvertimeRule{DailyDoubleTimeAfter: 12}),
+			weeks: []WeekHrs{{WeekInfo: 2, Day2: 14}},
+			want:  HoursBreakdown{RegularHours: 12, DoubleTimeHours: 2, PayableHours: 16}},
+		{name: "weekend hours take the weekend multiplier",
+			rule:  rule(OvertimeRule{WeekendMultiplier: 1.5}),
+			weeks: []WeekHrs{{WeekInfo: 2, Day6: 5}},
+			want:  HoursBreakdown{OvertimeHours: 5, PayableHours: 7.5}},
+		{name: "holiday hours reaching the double time multiplier are double time",
+			rule:     rule(OvertimeRule{HolidayMultiplier: 2}),
+			weeks:    []WeekHrs{{WeekInfo: 1, Day4: 8}},
+			holidays: map[time.Time]bool{date(2026, 1, 1): true},
+			want:     HoursBreakdown{DoubleTimeHours: 8, PayableHours: 16}},
+		{name: "weekly threshold",
+			rule:  rule(OvertimeRule{WeeklyOvertimeAfter: 40}),
+			weeks: []WeekHrs{{WeekInfo: 2, Day1: 9, Day2: 9, Day3: 9, Day4: 9, Day5: 9}},
+			want:  HoursBreakdown{RegularHours: 40, OvertimeHours: 5, PayableHours: 47.5}},
+		{name: "weekly threshold counts the days of the week in the previous period",
+			rule:    rule(OvertimeRule{WeeklyOvertimeAfter: 40}),
+			weeks:   []WeekHrs{{WeekInfo: 1, Day4: 10, Day5: 10}},
+			earlier: map[time.Time]float64{date(2025, 12, 29): 8, date(2025, 12, 30): 8, date(2025, 12, 31): 8},
+			want:    HoursBreakdown{RegularHours: 16, OvertimeHours: 4, PayableHours: 22}},
+		{name: "overtime of the previous period is not counted again",
+			rule:    rule(OvertimeRule{WeeklyOvertimeAfter: 40}),
+			weeks:   []WeekHrs{{WeekInfo: 1, Day4: 8}},
+			earlier: map[time.Time]float64{date(2025, 12, 29): 15, date(2025, 12, 30): 15, date(2025, 12, 31): 15},
+			want:    HoursBreakdown{OvertimeHours: 8, PayableHours: 12}},
+		{name: "days before the first week do not count",
+			rule:    rule(OvertimeRule{WeeklyOvertimeAfter: 40}),
+			weeks:   []WeekHrs{{WeekInfo: 1, Day4: 8}},
+			earlier: map[time.Time]float64{date(2025, 12, 26): 40},
+			want:    HoursBreakdown{RegularHours: 8, PayableHours: 8}},
+		{name: "daily overtime is not counted towards the weekly threshold",
+			rule:  rule(OvertimeRule{DailyOvertimeAfter: 8, WeeklyOvertimeAfter: 40}),
+			weeks: []WeekHrs{{WeekInfo: 2, Day1: 10, Day2: 10, Day3: 10, Day4: 10, Day5: 10}},
+			want:  HoursBreakdown{RegularHours: 40, OvertimeHours: 10, PayableHours: 55}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := splitHours(c.rule, c.weeks, period, c.holidays, c.earlier)
+			if *got != c.want {
+				t.Fatalf("got %+v, want %+v", *got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type PayPeriodService interface {
+	//SetDefinition creates the definition of a department, or replaces it. Timesheets that exist already keep
+	//the period they were created for.
+	SetDefinition(ctx context.Context, d *PayPeriodDefinition) (*PayPeriodDefinition, error)
+
+	ListDefinitions(ctx context.Context) ([]*PayPeriodDefinition, error)
+
+	DeleteDefinition(ctx context.Context, definitionID string) error
+
+	//PeriodFor returns the user's period that contains date: the one of their timesheet covering the date if
+	//there is such a timesheet, otherwise the one their definition gives
+	PeriodFor(ctx context.Context, loginName string, date time.Time) (*PayPeriod, error)
+
+	//OpenPeriod returns the period a new timesheet containing date covers, it fails when a timesheet of the
+	//user overlaps that period
+	OpenPeriod(ctx context.Context, loginName string, date time.Time) (*PayPeriod, error)
+
+	//ResolvePeriod is PeriodFor for a date formatted as 2006-01-02
+	ResolvePeriod(ctx context.Context, loginName, date string) (*PayPeriod, error)
+
+	//MonthPeriod is the period of the month routes. It fails with PayPeriodNotMonthly unless the period containing
+	//the 1st is the whole month, rather than picking a period that leaves days of the month out.
+	MonthPeriod(ctx context.Context, loginName string, month, year int) (*PayPeriod, error)
+}
+
+type payPeriodService struct {
+	repo     PayPeriodRepository
+	userRepo user.TenantUserRepository
+}
+
+func NewPayPeriodService(repo PayPeriodRepository, userRepo user.TenantUserRepository) PayPeriodService {
+	return &payPeriodService{repo: repo, userRepo: userRepo}
+}
+
+//dateLayout is how dates are written in URLs
+const dateLayout = "2006-01-02"
+
+func (s *payPeriodService) SetDefinition(ctx context.Context, d *PayPeriodDefinition) (*PayPeriodDefinition, error) {
+	ve := validate.New()
+	ve.IsSizeInRange("Name", d.Name, 1, 100)
+	ve.IsWithin("Frequency", string(d.Frequency), payFrequencies)
+	ve.IsSizeInRange("Department", d.Department, 0, 100)
+	if (d.Frequency == PayWeekly || d.Frequency == PayBiWeekly) && d.AnchorDate.IsZero() {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "AnchorDate", Constraint: validate.Required,
+			Message: "Weekly and bi-weekly periods need the start of one of them", Args: nil})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	d.AnchorDate = dayOf(d.AnchorDate)
+	d.ID = uuid.New()
+	d.CreatedAt = time.Now()
+	if err := s.repo.UpsertDefinition(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *payPeriodService) ListDefinitions(ctx context.Context) ([]*PayPeriodDefinition, error) {
+	return s.repo.SelectDefinitions(ctx)
+}
+
+func (s *payPeriodService) DeleteDefinition(ctx context.Context, definitionID string) error {
+	id, err := uuid.Parse(definitionID)
+	if err != nil {
+		return &res.AppError{ResponseCode: PayPeriodDefinitionNotFound, Cause: err}
+	}
+
+	found, err := s.repo.DeleteDefinition(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &res.AppError{ResponseCode: PayPeriodDefinitionNotFound, Cause: errors.New("no such pay period definition")}
+	}
+	return nil
+}
+
+func (s *payPeriodService) PeriodFor(ctx context.Context, loginName string, date time.Time) (*PayPeriod, error) {
+	loginName, date = strings.ToUpper(loginName), dayOf(date)
+	stored, err := s.repo.SelectTimesheetPeriods(ctx, loginName, date, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) > 0 {
+		return stored[0], nil
+	}
+	return s.definedPeriod(ctx, loginName, date)
+}
+
+func (s *payPeriodService) OpenPeriod(ctx context.Context, loginName string, date time.Time) (*PayPeriod, error) {
+	loginName = strings.ToUpper(loginName)
+	p, err := s.definedPeriod(ctx, loginName, dayOf(date))
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.SelectTimesheetPeriods(ctx, loginName, p.Start, p.End)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) > 0 {
+		return nil, &res.AppError{ResponseCode: PayPeriodOverlaps, Cause: errors.Errorf("period %s to %s of %s overlaps the timesheet from %s",
+			p.Start.Format(dateLayout), p.End.Format(dateLayout), loginName, stored[0].Start.Format(dateLayout))}
+	}
+	return p, nil
+}
+
+func (s *payPeriodService) ResolvePeriod(ctx context.Context, loginName, date string) (*PayPeriod, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	return s.PeriodFor(ctx, loginName, day)
+}
+
+func (s *payPeriodService) MonthPeriod(ctx context.Context, loginName string, month, year int) (*PayPeriod, error) {
+	if ve := validateMonth(month, year); ve.HasErrors() {
+		return nil, ve
+	}
+	from, _ := monthRange(month, year)
+	p, err := s.PeriodFor(ctx, loginName, from)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkMonthPeriod(p, month, year); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func checkMonthPeriod(p *PayPeriod, month, year int) error {
+	if !p.isMonth(month, year) {
+		return &res.AppError{ResponseCode: PayPeriodNotMonthly, Cause: errors.Errorf("the period of %d/%d runs from %s to %s",
+			month, year, p.Start.Format(dateLayout), p.End.Format(dateLayout))}
+	}
+	return nil
+}
+
+//definedPeriod is the period the user's definition gives for date
+func (s *payPeriodService) definedPeriod(ctx context.Context, loginName string, date time.Time) (*PayPeriod, error) {
+	u, err := s.userRepo.SelectUserByLoginName(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+
+	d, err := s.repo.SelectApplicableDefinition(ctx, u.Department)
+	if err != nil {
+		return nil, err
+	}
+	return periodOf(d, date), nil
+}
+
+//periodOf cuts time as d says and returns the period containing date, the month without a definition
+func periodOf(d *PayPeriodDefinition, date time.Time) *PayPeriod {
+	if d == nil {
+		return monthPeriod(int(date.Month()), date.Year())
+	}
+
+	switch d.Frequency {
+	case PayWeekly, PayBiWeekly:
+		length := 7
+		if d.Frequency == PayBiWeekly {
+			length = 14
+		}
+		days := daysBetween(d.AnchorDate, date)
+		n := days / length
+		if days%length < 0 {
+			n--
+		}
+		start := d.AnchorDate.AddDate(0, 0, n*length)
+		return &PayPeriod{Start: start, End: start.AddDate(0, 0, length-1), Frequency: d.Frequency}
+	case PaySemiMonthly:
+		from, to := monthRange(int(date.Month()), date.Year())
+		if date.Day() <= 15 {
+			return &PayPeriod{Start: from, End: from.AddDate(0, 0, 14), Frequency: d.Frequency}
+		}
+		return &PayPeriod{Start: from.AddDate(0, 0, 15), End: to, Frequency: d.Frequency}
+	default:
+		return monthPeriod(int(date.Month()), date.Year())
+	}
+}
+
+//dayOf drops the time of day, days are kept as midnight UTC like the dates read from the database
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func parseDate(field, value string) (time.Time, *validate.ValidationError) {
+	ve := validate.New()
+	day, err := time.Parse(dateLayout, value)
+	if err != nil {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: field, Constraint: validate.Like,
+			Message: "Must be a date formatted as 2006-01-02", Args: nil})
+	}
+	return day, ve
+}
@@ -0,0 +1,96 @@
+package timesheets
+
+import (
+	"testing"
+	"time"
+
+	sql "github.com/jmoiron/sqlx/types"
+)
+
+func TestPeriodOf(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	//Monday May 6 2024 starts the weekly and bi-weekly periods
+	weekly := &PayPeriodDefinition{Frequency: PayWeekly, AnchorDate: date(2024, 5, 6)}
+	biWeekly := &PayPeriodDefinition{Frequency: PayBiWeekly, AnchorDate: date(2024, 5, 6)}
+	semiMonthly := &PayPeriodDefinition{Frequency: PaySemiMonthly}
+
+	cases := []struct {
+		name       string
+		definition *PayPeriodDefinition
+		date       time.Time
+		wantStart  time.Time
+		wantEnd    time.Time
+	}{
+		{name: "no definition is the month", date: date(2024, 2, 10),
+			wantStart: date(2024, 2, 1), wantEnd: date(2024, 2, 29)},
+		{name: "monthly", definition: &PayPeriodDefinition{Frequency: PayMonthly}, date: date(2024, 12, 31),
+			wantStart: date(2024, 12, 1), wantEnd: date(2024, 12, 31)},
+		{name: "weekly on the anchor", definition: weekly, date: date(2024, 5, 6),
+			wantStart: date(2024, 5, 6), wantEnd: date(2024, 5, 12)},
+		{name: "weekly after the anchor", definition: weekly, date: date(2024, 5, 26),
+			wantStart: date(2024, 5, 20), wantEnd: date(2024, 5, 26)},
+		{name: "weekly before the anchor", definition: weekly, date: date(2024, 5, 1),
+			wantStart: date(2024, 4, 29), wantEnd: date(2024, 5, 5)},
+		{name: "bi-weekly across the month", definition: biWeekly, date: date(2024, 6, 1),
+			wantStart: date(2024, 5, 20), wantEnd: date(2024, 6, 2)},
+		{name: "bi-weekly the day before the anchor", definition: biWeekly, date: date(2024, 5, 5),
+			wantStart: date(2024, 4, 22), wantEnd: date(2024, 5, 5)},
+		{name: "semi-monthly first half", definition: semiMonthly, date: date(2024, 2, 15),
+			wantStart: date(2024, 2, 1), wantEnd: date(2024, 2, 15)},
+		{name: "semi-monthly second half", definition: semiMonthly, date: date(2024, 2, 16),
+			wantStart: date(2024, 2, 16), wantEnd: date(2024, 2, 29)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := periodOf(c.definition, c.date)
+			if !p.Start.Equal(c.wantStart) || !p.End.Equal(c.wantEnd) {
+				t.Fatalf("got %s to %s, want %s to %s", p.Start.Format(dateLayout), p.End.Format(dateLayout),
+					c.wantStart.Format(dateLayout), c.wantEnd.Format(dateLayout))
+			}
+			if !p.contains(c.date) {
+				t.Fatalf("period does not contain %s", c.date.Format(dateLayout))
+			}
+		})
+	}
+}
+
+func TestHoursBetween(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	//April 29 to May 12 2024: week 5 of April runs into May, May 6 is in week 6
+	acrossMonths := &GetAllTimesheets{PeriodStart: date(2024, 4, 29), PeriodEnd: date(2024, 5, 12), PayFrequency: PayBiWeekly,
+		WeekHrs:  sql.JSONText(`[{"WeekInfo": 5, "Day1": 8, "Day2": 8, "Day3": 8, "Day4": 8, "Day5": 8}, {"WeekInfo": 6, "Day1": 6}]`),
+		Absences: sql.JSONText(`[{"WeekInfo": 5, "Day": 2, "Type": "Vacation", "Hours": 2}, {"WeekInfo": 6, "Day": 2, "Type": "Sick", "Hours": 8}]`)}
+	//May 13 to May 26, weeks counted from May
+	inMonth := &GetAllTimesheets{PeriodStart: date(2024, 5, 13), PeriodEnd: date(2024, 5, 26), PayFrequency: PayBiWeekly,
+		WeekHrs: sql.JSONText(`[{"WeekInfo": 3, "Day1": 7}]`)}
+
+	cases := []struct {
+		name             string
+		timesheets       []*GetAllTimesheets
+		from             time.Time
+		to               time.Time
+		wantHours        float64
+		wantAbsenceHours float64
+	}{
+		{name: "no timesheets", from: date(2024, 5, 1), to: date(2024, 5, 31)},
+		{name: "only the days in May of a period starting in April", timesheets: []*GetAllTimesheets{acrossMonths},
+			from: date(2024, 5, 1), to: date(2024, 5, 31), wantHours: 30, wantAbsenceHours: 8},
+		{name: "only the days in April of the same period", timesheets: []*GetAllTimesheets{acrossMonths},
+			from: date(2024, 4, 1), to: date(2024, 4, 30), wantHours: 16, wantAbsenceHours: 2},
+		{name: "periods are added up", timesheets: []*GetAllTimesheets{acrossMonths, inMonth},
+			from: date(2024, 5, 1), to: date(2024, 5, 31), wantHours: 37, wantAbsenceHours: 8},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hours, _, absenceHours, err := hoursBetween(c.timesheets, c.from, c.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hours != c.wantHours || absenceHours != c.wantAbsenceHours {
+				t.Fatalf("got %v hours and %v absence hours, want %v and %v", hours, absenceHours, c.wantHours, c.wantAbsenceHours)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+)
+
+//accountProvisioner creates local accounts for users that authenticate or are managed elsewhere (OIDC, SCIM, LDAP)
+type accountProvisioner struct {
+	userSvc Service
+	orgRepo OrganizationRepository
+}
+
+//ensureUser makes sure u.LoginName exists in the organization of ctx. An account created locally before
+//provisioning was switched on is adopted rather than duplicated. Login names are unique across organizations, so
+//the name of an account of another organization is refused with UserAlreadyExists. The created account can only
+//be used through its identity source, so its local password is random and never disclosed.
+func (p *accountProvisioner) ensureUser(ctx context.Context, u *User) (bool, error) {
+	tenant, err := auth.TenantFromContext(ctx)
+	if err != nil {
+		return false, &res.AppError{ResponseCode: res.Forbidden, Cause: err}
+	}
+	orgID, err := p.orgRepo.SelectOrganizationIDByLoginName(ctx, u.LoginName)
+	if err != nil {
+		return false, err
+	}
+	if orgID == tenant {
+		return false, nil
+	}
+	if orgID != uuid.Nil {
+		return false, &res.AppError{ResponseCode: UserAlreadyExists,
+			Cause: fmt.Errorf("user %s belongs to another organization", u.LoginName)}
+	}
+
+	u.Password = randomURLString() + "aA1!"
+	if _, err = p.userSvc.CreateUser(ctx, u); err != nil {
+		return false, err
+	}
+	u.Password = ""
+
+	//New users land in the default organization; move them into the one being provisioned.
+	if err = p.orgRepo.UpdateUserOrganization(ctx, u.LoginName); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,394 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type PunchService interface {
+	//Punch records a punch of the user at the current server time and rolls the work day up into the timesheet.
+	//Only Kind and TimeZone of p are used, an empty TimeZone is the zone of the user.
+	Punch(ctx context.Context, loginName string, p *Punch) (*PunchDay, error)
+
+	//GetDay returns the user's work day of date, formatted as 2006-01-02, with its times in the zone timeZone, the
+	//user's zone if empty
+	GetDay(ctx context.Context, viewer *auth.Principal, loginName, date, timeZone string) (*PunchDay, error)
+
+	//MissingPunches lists the work days of date with missing punches of the approver's direct reports, of everybody
+	//for admins. Times are given in the zone timeZone, the approver's zone if empty.
+	MissingPunches(ctx context.Context, approver *auth.Principal, date, timeZone string) ([]*PunchDay, error)
+
+	//DaysWithIssues lists the work days of date with missing punches in the whole organization
+	DaysWithIssues(ctx context.Context, date time.Time) ([]*PunchDay, error)
+
+	//AddCorrection, CorrectPunch and VoidPunch fix the punches of a user, the reviewer must be an admin or the
+	//user's manager. The affected work days are rolled up again.
+	AddCorrection(ctx context.Context, reviewer *auth.Principal, loginName string, c *PunchCorrection) (*PunchDay, error)
+
+	CorrectPunch(ctx context.Context, reviewer *auth.Principal, loginName, punchID string, c *PunchCorrection) (*PunchDay, error)
+
+	VoidPunch(ctx context.Context, reviewer *auth.Principal, loginName, punchID, reason string) (*PunchDay, error)
+}
+
+type punchService struct {
+	repo       PunchRepository
+	timesheets Service
+	access     TimesheetAccess
+	dirRepo    user.DirectoryRepository
+	zones      TimeZoneService
+}
+
+func NewPunchService(repo PunchRepository, timesheets Service, access TimesheetAccess, dirRepo user.DirectoryRepository,
+	zones TimeZoneService) PunchService {
+	return &punchService{repo: repo, timesheets: timesheets, access: access, dirRepo: dirRepo, zones: zones}
+}
+
+func (s *punchService) Punch(ctx context.Context, loginName string, p *Punch) (*PunchDay, error) {
+	ve := validate.New()
+	ve.IsWithin("Kind", string(p.Kind), punchKinds)
+	validateTimeZone(ve, "TimeZone", p.TimeZone)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	now := time.Now()
+	p.ID = uuid.New()
+	p.LoginName = strings.ToUpper(loginName)
+	p.PunchedAt, p.CreatedAt, p.UpdatedAt = now, now, now
+	p.CorrectedBy, p.Reason, p.Voided = "", "", false
+	if err := s.assignWorkDate(ctx, p); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.InsertPunch(ctx, p); err != nil {
+		return nil, err
+	}
+	return s.rollup(ctx, p.LoginName, p.WorkDate)
+}
+
+func (s *punchService) GetDay(ctx context.Context, viewer *auth.Principal, loginName, date, timeZone string) (*PunchDay, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+
+	loc, err := s.zones.ReportLocation(ctx, loginName, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	punches, err := s.repo.SelectPunches(ctx, []string{loginName}, day)
+	if err != nil {
+		return nil, err
+	}
+	return summarizePunches(loginName, day, punches, time.Now()).in(loc), nil
+}
+
+func (s *punchService) MissingPunches(ctx context.Context, approver *auth.Principal, date, timeZone string) ([]*PunchDay, error) {
+	day, ve := parseDate("date", date)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	loc, err := s.zones.ReportLocation(ctx, approver.LoginName, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	if approver.Admin {
+		days, err := s.DaysWithIssues(ctx, day)
+		return punchDaysIn(days, loc), err
+	}
+
+	reports, _, err := s.dirRepo.SelectDirectoryEntries(ctx, user.DirectoryFilter{ManagerLoginName: approver.LoginName}, 0, maxDirectReports)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return []*PunchDay{}, nil
+	}
+	logins := make([]string, 0, len(reports))
+	for _, r := range reports {
+		logins = append(logins, r.LoginName)
+	}
+	days, err := s.daysWithIssues(ctx, logins, day)
+	return punchDaysIn(days, loc), err
+}
+
+func (s *punchService) DaysWithIssues(ctx context.Context, date time.Time) ([]*PunchDay, error) {
+	return s.daysWithIssues(ctx, nil, dayOf(date))
+}
+
+func (s *punchService) daysWithIssues(ctx context.Context, loginNames []string, date time.Time) ([]*PunchDay, error) {
+	punches, err := s.repo.SelectPunches(ctx, loginNames, date)
+	if err != nil {
+		return nil, err
+	}
+
+	days := []*PunchDay{}
+	now := time.Now()
+	for from := 0; from < len(punches); {
+		to := from
+		for to < len(punches) && punches[to].LoginName == punches[from].LoginName {
+			to++
+		}
+		if day := summarizePunches(punches[from].LoginName, date, punches[from:to], now); len(day.Issues) > 0 {
+			days = append(days, day)
+		}
+		from = to
+	}
+	return days, nil
+}
+
+func (s *punchService) AddCorrection(ctx context.Context, reviewer *auth.Principal, loginName string, c *PunchCorrection) (*PunchDay, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckReview(ctx, reviewer, loginName); err != nil {
+		return nil, err
+	}
+	if ve := validateCorrection(c); ve.HasErrors() {
+		return nil, ve
+	}
+
+	now := time.Now()
+	p := &Punch{ID: uuid.New(), LoginName: loginName, Kind: c.Kind, PunchedAt: c.PunchedAt.UTC(), TimeZone: c.TimeZone,
+		CorrectedBy: reviewer.LoginName, Reason: c.Reason, CreatedAt: now, UpdatedAt: now}
+	if err := s.assignWorkDate(ctx, p); err != nil {
+		return nil, err
+	}
+	if err := s.repo.InsertPunch(ctx, p); err != nil {
+		return nil, err
+	}
+	return s.rollup(ctx, loginName, p.WorkDate)
+}
+
+func (s *punchService) CorrectPunch(ctx context.Context, reviewer *auth.Principal, loginName, punchID string, c *PunchCorrection) (*PunchDay, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckReview(ctx, reviewer, loginName); err != nil {
+		return nil, err
+	}
+	if ve := validateCorrection(c); ve.HasErrors() {
+		return nil, ve
+	}
+	p, err := s.findPunch(ctx, loginName, punchID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := p.WorkDate
+	p.Kind, p.PunchedAt, p.TimeZone = c.Kind, c.PunchedAt.UTC(), c.TimeZone
+	p.CorrectedBy, p.Reason, p.UpdatedAt = reviewer.LoginName, c.Reason, time.Now()
+	if err = s.assignWorkDate(ctx, p); err != nil {
+		return nil, err
+	}
+	if err = s.repo.UpdatePunch(ctx, p); err != nil {
+		return nil, err
+	}
+
+	if !previous.Equal(p.WorkDate) {
+		if _, err = s.rollup(ctx, loginName, previous); err != nil {
+			return nil, err
+		}
+	}
+	return s.rollup(ctx, loginName, p.WorkDate)
+}
+
+func (s *punchService) VoidPunch(ctx context.Context, reviewer *auth.Principal, loginName, punchID, reason string) (*PunchDay, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckReview(ctx, reviewer, loginName); err != nil {
+		return nil, err
+	}
+	ve := validate.New()
+	ve.IsSizeInRange("reason", reason, 1, 500)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	p, err := s.findPunch(ctx, loginName, punchID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Voided, p.CorrectedBy, p.Reason, p.UpdatedAt = true, reviewer.LoginName, reason, time.Now()
+	if err = s.repo.UpdatePunch(ctx, p); err != nil {
+		return nil, err
+	}
+	return s.rollup(ctx, loginName, p.WorkDate)
+}
+
+//rollup summarizes the user's work day and books the change of its worked hours on the timesheet. A failing rollup
+//does not undo the punch, it is reported as an issue of the day and retried with the next punch or correction.
+func (s *punchService) rollup(ctx context.Context, loginName string, date time.Time) (*PunchDay, error) {
+	punches, err := s.repo.SelectPunches(ctx, []string{loginName}, date)
+	if err != nil {
+		return nil, err
+	}
+	day := summarizePunches(loginName, date, punches, time.Now())
+
+	if err = s.timesheets.RollupDayHours(ctx, loginName, date, day.WorkedHours); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Str("date", date.Format(dateLayout)).Msg("Error while rolling punches up into the timesheet")
+		day.Issues = append(day.Issues, PunchIssue{Kind: TimesheetNotUpdated, At: time.Now()})
+	}
+	return day, nil
+}
+
+//assignWorkDate puts p on the work day of the user's open shift, or on its local date if it starts a new one. Without
+//a TimeZone the punch is taken to be in the zone of the user.
+func (s *punchService) assignWorkDate(ctx context.Context, p *Punch) error {
+	if p.TimeZone == "" {
+		setting, err := s.zones.GetTimeZone(ctx, p.LoginName)
+		if err != nil {
+			return err
+		}
+		p.TimeZone = setting.TimeZone
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return err
+	}
+	p.WorkDate = localDate(p.PunchedAt, loc)
+	if p.Kind == PunchIn {
+		return nil
+	}
+
+	last, err := s.repo.SelectLastPunch(ctx, p.LoginName, p.PunchedAt)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.ID != p.ID && last.Kind != PunchOut && p.PunchedAt.Sub(last.PunchedAt) <= maxShiftLength {
+		p.WorkDate = last.WorkDate
+	}
+	return nil
+}
+
+
+func (s *punchService) findPunch(ctx context.Context, loginName, punchID string) (*Punch, error) {
+	id, err := uuid.Parse(punchID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	p, err := s.repo.SelectPunch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.LoginName != loginName {
+		return nil, &res.AppError{ResponseCode: PunchNotFound, Cause: errors.Errorf("punch %s of %s not found", punchID, loginName)}
+	}
+	return p, nil
+}
+
+func validateCorrection(c *PunchCorrection) *validate.ValidationError {
+	ve := validate.New()
+	ve.IsWithin("Kind", string(c.Kind), punchKinds)
+	validateTimeZone(ve, "TimeZone", c.TimeZone)
+	ve.IsSizeInRange("Reason", c.Reason, 1, 500)
+	if c.PunchedAt.IsZero() || c.PunchedAt.After(time.Now()) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "PunchedAt", Constraint: validate.Range,
+			Message: "Must be given and not in the future", Args: []interface{}{}})
+	}
+	return ve
+}
+
+//validateTimeZone accepts an IANA time zone name such as Europe/Berlin, or empty
+func validateTimeZone(ve *validate.ValidationError, field, timeZone string) {
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: field, Constraint: validate.Like,
+			Message: "Is not a known time zone", Args: []interface{}{timeZone}})
+	}
+}
+
+//summarizePunches walks the punches in order and adds up the completed stretches of work and breaks. A punch that
+//does not follow from the one before is reported as an issue and the stretch before it is not counted.
+func summarizePunches(loginName string, date time.Time, punches []*Punch, now time.Time) *PunchDay {
+	day := &PunchDay{LoginName: loginName, Date: date, Punches: punches, Issues: []PunchIssue{}}
+	issue := func(kind PunchIssueKind, p *Punch) {
+		day.Issues = append(day.Issues, PunchIssue{Kind: kind, PunchID: p.ID, At: p.PunchedAt})
+	}
+
+	var worked, breaks time.Duration
+	var since time.Time
+	var last *Punch
+	state := PunchOut
+	for _, p := range punches {
+		if p.Voided {
+			continue
+		}
+		switch p.Kind {
+		case PunchIn:
+			if state != PunchOut {
+				issue(MissingPunchOut, p)
+			}
+			state = PunchIn
+		case PunchBreakStart:
+			switch state {
+			case PunchIn:
+				worked += p.PunchedAt.Sub(since)
+			case PunchBreakStart:
+				issue(MissingBreakEnd, p)
+			default:
+				issue(MissingPunchIn, p)
+			}
+			state = PunchBreakStart
+		case PunchBreakEnd:
+			switch state {
+			case PunchBreakStart:
+				breaks += p.PunchedAt.Sub(since)
+			case PunchIn:
+				issue(MissingBreakStart, p)
+			default:
+				issue(MissingPunchIn, p)
+			}
+			state = PunchIn
+		case PunchOut:
+			switch state {
+			case PunchIn:
+				worked += p.PunchedAt.Sub(since)
+			case PunchBreakStart:
+				issue(MissingBreakEnd, p)
+			default:
+				issue(MissingPunchIn, p)
+			}
+			state = PunchOut
+		}
+		since, last = p.PunchedAt, p
+	}
+
+	if state != PunchOut {
+		if now.Sub(last.PunchedAt) > maxShiftLength {
+			issue(MissingPunchOut, last)
+		} else {
+			day.Open = true
+		}
+	}
+	day.WorkedHours = roundHours(worked.Hours())
+	day.BreakHours = roundHours(breaks.Hours())
+	return day
+}
+
+//in gives the times of the day in loc
+func (d *PunchDay) in(loc *time.Location) *PunchDay {
+	for _, p := range d.Punches {
+		p.PunchedAt, p.CreatedAt, p.UpdatedAt = p.PunchedAt.In(loc), p.CreatedAt.In(loc), p.UpdatedAt.In(loc)
+	}
+	for i := range d.Issues {
+		d.Issues[i].At = d.Issues[i].At.In(loc)
+	}
+	return d
+}
+
+func punchDaysIn(days []*PunchDay, loc *time.Location) []*PunchDay {
+	for _, d := range days {
+		d.in(loc)
+	}
+	return days
+}
@@ -0,0 +1,75 @@
+package timesheets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizePunches(t *testing.T) {
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	punch := func(kind PunchKind, hour, minute int) *Punch { return &Punch{Kind: kind, PunchedAt: at(hour, minute)} }
+	voided := func(p *Punch) *Punch {
+		p.Voided = true
+		return p
+	}
+
+	cases := []struct {
+		name       string
+		punches    []*Punch
+		now        time.Time
+		wantWorked float64
+		wantBreaks float64
+		wantOpen   bool
+		wantIssues []PunchIssueKind
+	}{
+		{name: "no punches", now: at(12, 0)},
+		{name: "shift with a break",
+			punches: []*Punch{punch(PunchIn, 8, 0), punch(PunchBreakStart, 12, 0), punch(PunchBreakEnd, 12, 30), punch(PunchOut, 16, 30)},
+			now:     at(18, 0), wantWorked: 8, wantBreaks: 0.5},
+		{name: "open shift counts the completed stretches",
+			punches: []*Punch{punch(PunchIn, 8, 0), punch(PunchBreakStart, 12, 0), punch(PunchBreakEnd, 12, 30)},
+			now:     at(14, 0), wantWorked: 4, wantBreaks: 0.5, wantOpen: true},
+		{name: "shift left open too long misses its out",
+			punches: []*Punch{punch(PunchIn, 6, 0)},
+			now:     at(23, 0), wantIssues: []PunchIssueKind{MissingPunchOut}},
+		{name: "voided punches are not counted",
+			punches: []*Punch{punch(PunchIn, 8, 0), voided(punch(PunchOut, 9, 0)), punch(PunchOut, 12, 0)},
+			now:     at(18, 0), wantWorked: 4},
+		{name: "all punches voided",
+			punches: []*Punch{voided(punch(PunchIn, 8, 0)), voided(punch(PunchOut, 12, 0))},
+			now:     at(18, 0)},
+		{name: "second in misses the out before it",
+			punches: []*Punch{punch(PunchIn, 8, 0), punch(PunchIn, 13, 0), punch(PunchOut, 15, 0)},
+			now:     at(18, 0), wantWorked: 2, wantIssues: []PunchIssueKind{MissingPunchOut}},
+		{name: "out without in",
+			punches: []*Punch{punch(PunchOut, 12, 0)},
+			now:     at(18, 0), wantIssues: []PunchIssueKind{MissingPunchIn}},
+		{name: "out during a break misses the break end",
+			punches: []*Punch{punch(PunchIn, 8, 0), punch(PunchBreakStart, 10, 0), punch(PunchOut, 16, 0)},
+			now:     at(18, 0), wantWorked: 2, wantIssues: []PunchIssueKind{MissingBreakEnd}},
+		{name: "break end without break start",
+			punches: []*Punch{punch(PunchIn, 8, 0), punch(PunchBreakEnd, 10, 0), punch(PunchOut, 12, 0)},
+			now:     at(18, 0), wantWorked: 2, wantIssues: []PunchIssueKind{MissingBreakStart}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			day := summarizePunches("ALICE", date, c.punches, c.now)
+			if day.WorkedHours != c.wantWorked || day.BreakHours != c.wantBreaks || day.Open != c.wantOpen {
+				t.Fatalf("got %v worked, %v break hours, open %t, want %v, %v, %t", day.WorkedHours, day.BreakHours, day.Open,
+					c.wantWorked, c.wantBreaks, c.wantOpen)
+			}
+			if len(day.Issues) != len(c.wantIssues) {
+				t.Fatalf("got issues %v, want %v", day.Issues, c.wantIssues)
+			}
+			for i, kind := range c.wantIssues {
+				if day.Issues[i].Kind != kind {
+					t.Fatalf("got issues %v, want %v", day.Issues, c.wantIssues)
+				}
+			}
+		})
+	}
+}
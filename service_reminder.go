@@ -0,0 +1,200 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/mailer"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type ReminderService interface {
+	GetPreferences(ctx context.Context, viewer *auth.Principal, loginName string) (*ReminderPreferences, error)
+
+	//SetPreferences may be called by the user or an admin
+	SetPreferences(ctx context.Context, viewer *auth.Principal, loginName string, p *ReminderPreferences) (*ReminderPreferences, error)
+
+	//SendReminders sends what is due at now in the organization of ctx, at most one reminder of a kind
+	//per user and day. It returns how many were sent.
+	SendReminders(ctx context.Context, now time.Time) (int, error)
+}
+
+type reminderService struct {
+	repo     ReminderRepository
+	userRepo user.TenantUserRepository
+	expected ExpectedHoursService
+	mailer   mailer.Mailer
+	cfg      ReminderConfig
+}
+
+func NewReminderService(repo ReminderRepository, userRepo user.TenantUserRepository, expected ExpectedHoursService,
+	m mailer.Mailer, cfg ReminderConfig) ReminderService {
+	return &reminderService{repo: repo, userRepo: userRepo, expected: expected, mailer: m, cfg: cfg}
+}
+
+func (s *reminderService) GetPreferences(ctx context.Context, viewer *auth.Principal, loginName string) (*ReminderPreferences, error) {
+	loginName = strings.ToUpper(loginName)
+	if !viewer.Admin && viewer.LoginName != loginName {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.New("only the user or an admin may see reminder preferences")}
+	}
+
+	p, err := s.repo.SelectPreferences(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return &ReminderPreferences{LoginName: loginName, Locale: defaultLocale}, nil
+	}
+	return p, nil
+}
+
+func (s *reminderService) SetPreferences(ctx context.Context, viewer *auth.Principal, loginName string, p *ReminderPreferences) (*ReminderPreferences, error) {
+	loginName = strings.ToUpper(loginName)
+	if !viewer.Admin && viewer.LoginName != loginName {
+		return nil, &res.AppError{ResponseCode: res.Forbidden, Cause: errors.New("only the user or an admin may change reminder preferences")}
+	}
+
+	if p.Locale == "" {
+		p.Locale = defaultLocale
+	}
+	ve := validate.New()
+	ve.IsWithin("Locale", p.Locale, reminderLocales())
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	u, err := s.userRepo.SelectUserByLoginName(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+
+	p.LoginName = u.LoginName
+	p.UpdatedAt = time.Now()
+	if err = s.repo.UpsertPreferences(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//pendingReminder is a reminder that is due, before the recipient's address and preferences are known
+type pendingReminder struct {
+	loginName string
+	kind      ReminderKind
+	data      reminderData
+	//reference tells reminders of the same kind apart, e.g. the rejected timesheets of several periods
+	reference string
+}
+
+func (s *reminderService) SendReminders(ctx context.Context, now time.Time) (int, error) {
+	due := []pendingReminder{}
+
+	//Missing timesheets of the current month, in the last days before it ends
+	deadline := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location())
+	if !now.Before(deadline.AddDate(0, 0, -s.cfg.DaysBeforeDeadline)) {
+		report, err := s.expected.MissingTimesheets(ctx, int(now.Month()), now.Year())
+		if err != nil {
+			return 0, err
+		}
+		for _, u := range report.Users {
+			if u.Status == FillMissing {
+				due = append(due, pendingReminder{loginName: u.LoginName, kind: ReminderMissingTimesheet,
+					data: reminderData{Month: report.Month, Year: report.Year, Deadline: deadline.Format("2006-01-02")}})
+			}
+		}
+	}
+
+	pending, err := s.repo.SelectPendingApprovals(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range pending {
+		due = append(due, pendingReminder{loginName: p.LoginName, kind: ReminderPendingApprovals,
+			data: reminderData{PendingTimesheets: p.PendingTimesheets, PendingLeave: p.PendingLeave}})
+	}
+
+	rejected, err := s.repo.SelectRejectedTimesheets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range rejected {
+		p := &PayPeriod{Start: t.PeriodStart, End: t.PeriodEnd, Frequency: t.PayFrequency}
+		due = append(due, pendingReminder{loginName: t.LoginName, kind: ReminderRejectedTimesheet,
+			data: reminderData{Month: p.Month(), Year: p.Year(), Period: p.name()}, reference: p.reference()})
+	}
+
+	return s.send(ctx, now, due)
+}
+
+func (s *reminderService) send(ctx context.Context, now time.Time, due []pendingReminder) (int, error) {
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	loginNames := []string{}
+	for _, d := range due {
+		loginNames = append(loginNames, d.loginName)
+	}
+	recipients, err := s.repo.SelectRecipients(ctx, loginNames)
+	if err != nil {
+		return 0, err
+	}
+	byLoginName := map[string]*ReminderRecipient{}
+	for _, r := range recipients {
+		byLoginName[r.LoginName] = r
+	}
+
+	sent := 0
+	reference := now.Format("2006-01-02")
+	for _, d := range due {
+		r := byLoginName[d.loginName]
+		if r == nil || r.OptOut {
+			continue
+		}
+		//A user with rejected timesheets of several periods gets one reminder a day for each of them
+		ref := reference
+		if d.reference != "" {
+			ref = d.reference + "@" + reference
+		}
+		reminded, err := s.repo.WasReminded(ctx, r.LoginName, d.kind, ref)
+		if err != nil {
+			return sent, err
+		}
+		if reminded {
+			continue
+		}
+
+		templates, ok := reminderTemplates[r.Locale]
+		if !ok {
+			templates = reminderTemplates[defaultLocale]
+		}
+		d.data.Name = r.GivenName
+		if d.data.Name == "" {
+			d.data.Name = r.LoginName
+		}
+		subject, body, err := templates[d.kind].render(d.data)
+		if err != nil {
+			return sent, err
+		}
+
+		//A failing address must not hold up the reminders of everybody else
+		if err = s.mailer.Send(ctx, &mailer.Message{To: []string{r.Email}, Subject: subject, Body: body}); err != nil {
+			log.Error().Err(err).Str("loginName", r.LoginName).Str("kind", string(d.kind)).Msg("Reminder could not be sent")
+			continue
+		}
+		if err = s.repo.InsertReminderSent(ctx, r.LoginName, d.kind, ref); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,495 @@
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type ReportService interface {
+	//HoursReport adds up the worked and absence hours of every day from..to by the fiscal unit groupBy, per user.
+	//Dates are formatted as 2006-01-02, loginName limits the report to one user when not empty. to defaults to today
+	//in the zone timeZone, the zone of the user or else of the organization if empty, from to the start of its fiscal year.
+	HoursReport(ctx context.Context, loginName, from, to, groupBy, timeZone string) (*HoursReport, error)
+
+	//PayrollExport gives the timesheets and approved expenses of the pay periods containing date, formatted as 2006-01-02,
+	//or with groupBy of the pay periods overlapping the fiscal unit containing date. The expenses are also converted to
+	//currency when it is not empty.
+	PayrollExport(ctx context.Context, date, groupBy, currency string) (*PayrollExport, error)
+
+	//ExpenseExport lists the approved expenses dated from..to, of one project if project is not empty, converted to
+	//currency when it is not empty. With groupBy the lines are also added up per fiscal unit.
+	ExpenseExport(ctx context.Context, from, to, project, groupBy, currency string) (*ExpenseExport, error)
+
+	//MissingTimesheets reports the users whose timesheets leave the fiscal unit of groupBy containing date missing
+	//or under-filled
+	MissingTimesheets(ctx context.Context, date, groupBy string) (*MissingTimesheetReport, error)
+
+	//ComplianceReport lists the compliance findings dated in the fiscal unit of groupBy containing date, of one user
+	//if loginName is not empty
+	ComplianceReport(ctx context.Context, loginName, date, groupBy string) (*ComplianceReport, error)
+
+	//Invoice bills project for the days from..to, formatted as 2006-01-02, in currency. It fails with
+	//BillingRateMissing or ExchangeRateMissing rather than leave out time or an expense it can not price.
+	Invoice(ctx context.Context, project, from, to, currency string) (*Invoice, error)
+}
+
+type reportService struct {
+	repo       ReportRepository
+	fiscal     FiscalCalendarService
+	zones      TimeZoneService
+	expenses   ExpenseRepository
+	rates      ExchangeRateService
+	expected   ExpectedHoursService
+	compliance ComplianceService
+	billing    BillingRateService
+	entries    TimerRepository
+}
+
+func NewReportService(repo ReportRepository, fiscal FiscalCalendarService, zones TimeZoneService, expenses ExpenseRepository,
+	rates ExchangeRateService, expected ExpectedHoursService, compliance ComplianceService, billing BillingRateService,
+	entries TimerRepository) ReportService {
+	return &reportService{repo: repo, fiscal: fiscal, zones: zones, expenses: expenses, rates: rates, expected: expected,
+		compliance: compliance, billing: billing, entries: entries}
+}
+
+func (s *reportService) HoursReport(ctx context.Context, loginName, from, to, groupBy, timeZone string) (*HoursReport, error) {
+	loc, err := s.zones.ReportLocation(ctx, loginName, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := s.fiscal.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if to == "" {
+		to = localDate(time.Now(), loc).Format(dateLayout)
+	}
+	if toDay, err := time.Parse(dateLayout, to); err == nil && from == "" {
+		from = calendar.locate(toDay).YearStart.Format(dateLayout)
+	}
+
+	fromDay, ve := parseDate("from", from)
+	toDay, toErrors := parseDate("to", to)
+	ve.Errors = append(ve.Errors, toErrors.Errors...)
+	if groupBy == "" {
+		groupBy = string(GroupByFiscalPeriod)
+	}
+	ve.IsWithin("groupBy", groupBy, reportGroupings)
+	if !ve.HasErrors() && (toDay.Before(fromDay) || daysBetween(fromDay, toDay) > maxReportDays) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "to", Constraint: validate.Range,
+			Message: "Must not be before from and at most three years after it", Args: []interface{}{from, maxReportDays}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	sheets, err := s.repo.SelectTimesheetsBetween(ctx, strings.ToUpper(loginName), fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &HoursReport{GroupBy: ReportGrouping(groupBy), From: fromDay, To: toDay, TimeZone: loc.String(), Rows: []*ReportedHours{}}
+	rows := map[string]*ReportedHours{}
+	add := func(loginName string, date time.Time, worked, absent float64) {
+		if date.Before(fromDay) || date.After(toDay) {
+			return
+		}
+		unit := reportUnit(calendar.locate(date), report.GroupBy)
+		key := loginName + "/" + unit.Start.Format(dateLayout)
+		row := rows[key]
+		if row == nil {
+			row = &ReportedHours{LoginName: loginName, FiscalUnit: *unit}
+			rows[key] = row
+			report.Rows = append(report.Rows, row)
+		}
+		row.WorkedHours += worked
+		row.AbsenceHours += absent
+	}
+
+	for _, ts := range sheets {
+		timesheetDays(ts, func(date time.Time, worked, absent float64) {
+			add(ts.LoginName, date, worked, absent)
+		})
+	}
+
+	for _, row := range report.Rows {
+		row.WorkedHours = roundHours(row.WorkedHours)
+		row.AbsenceHours = roundHours(row.AbsenceHours)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.LoginName != b.LoginName {
+			return a.LoginName < b.LoginName
+		}
+		return a.Start.Before(b.Start)
+	})
+	return report, nil
+}
+
+func (s *reportService) PayrollExport(ctx context.Context, date, groupBy, currency string) (*PayrollExport, error) {
+	day, ve := parseDate("date", date)
+	if groupBy != "" {
+		ve.IsWithin("groupBy", groupBy, reportGroupings)
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	converter, err := s.converter(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &PayrollExport{Date: day, GroupBy: ReportGrouping(groupBy), Rows: []*PayrollRow{}}
+	from, to := day, day
+	if groupBy != "" {
+		if export.FiscalUnit, err = s.fiscalUnit(ctx, day, export.GroupBy); err != nil {
+			return nil, err
+		}
+		from, to = export.FiscalUnit.Start, export.FiscalUnit.End
+	}
+	sheets, err := s.repo.SelectTimesheetsBetween(ctx, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.expenses.SelectApprovedClaims(ctx, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if converter != nil {
+		export.Currency = converter.Currency
+	}
+	rows := map[string]*PayrollRow{}
+	rowOf := func(loginName string, start, end time.Time) *PayrollRow {
+		key := loginName + "/" + start.Format(dateLayout)
+		if rows[key] == nil {
+			rows[key] = &PayrollRow{LoginName: loginName, PeriodStart: start, PeriodEnd: end, Expenses: map[string]float64{}}
+			export.Rows = append(export.Rows, rows[key])
+		}
+		return rows[key]
+	}
+	for _, ts := range sheets {
+		row := rowOf(ts.LoginName, ts.PeriodStart, ts.PeriodEnd)
+		row.Status, row.AbsenceHours, row.HoursBreakdown = ts.Status, ts.AbsenceHours, ts.HoursBreakdown
+	}
+	for _, c := range claims {
+		row := rowOf(c.LoginName, c.PeriodStart, c.PeriodEnd)
+		for _, l := range c.Lines {
+			row.Expenses[l.Currency] = math.Round((row.Expenses[l.Currency]+l.Amount)*100) / 100
+			if converter == nil {
+				continue
+			}
+			converted, err := converter.Convert(l.Amount, l.Currency, l.Date)
+			if err != nil {
+				return nil, err
+			}
+			if row.ConvertedExpenses == nil {
+				row.ConvertedExpenses = new(float64)
+			}
+			*row.ConvertedExpenses = math.Round((*row.ConvertedExpenses+converted.Amount)*100) / 100
+			row.Conversions = addConversion(row.Conversions, converted.Conversion)
+		}
+	}
+	if converter != nil {
+		for _, row := range export.Rows {
+			if row.ConvertedExpenses == nil {
+				row.ConvertedExpenses = new(float64)
+			}
+		}
+	}
+
+	sort.Slice(export.Rows, func(i, j int) bool {
+		a, b := export.Rows[i], export.Rows[j]
+		if a.LoginName != b.LoginName {
+			return a.LoginName < b.LoginName
+		}
+		return a.PeriodStart.Before(b.PeriodStart)
+	})
+	return export, nil
+}
+
+func (s *reportService) ExpenseExport(ctx context.Context, from, to, project, groupBy, currency string) (*ExpenseExport, error) {
+	fromDay, ve := parseDate("from", from)
+	toDay, toErrors := parseDate("to", to)
+	ve.Errors = append(ve.Errors, toErrors.Errors...)
+	if groupBy != "" {
+		ve.IsWithin("groupBy", groupBy, reportGroupings)
+	}
+	if !ve.HasErrors() && (toDay.Before(fromDay) || daysBetween(fromDay, toDay) > maxReportDays) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "to", Constraint: validate.Range,
+			Message: "Must not be before from and at most three years after it", Args: []interface{}{from, maxReportDays}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	converter, err := s.converter(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.expenses.SelectApprovedClaims(ctx, "", fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	export := &ExpenseExport{From: fromDay, To: toDay, GroupBy: ReportGrouping(groupBy), Lines: []*ExportedExpense{}}
+	if converter != nil {
+		export.Currency, export.Total = converter.Currency, new(float64)
+	}
+	var calendar *FiscalCalendar
+	if groupBy != "" {
+		if calendar, err = s.fiscal.GetCalendar(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range claims {
+		for _, l := range c.Lines {
+			if l.Date.Before(fromDay) || l.Date.After(toDay) || (project != "" && !strings.EqualFold(l.Project, project)) {
+				continue
+			}
+			line := &ExportedExpense{LoginName: c.LoginName, ClaimID: c.ID, ExpenseLine: l}
+			if converter != nil {
+				if line.Converted, err = converter.Convert(l.Amount, l.Currency, l.Date); err != nil {
+					return nil, err
+				}
+				*export.Total = math.Round((*export.Total+line.Converted.Amount)*100) / 100
+			}
+			export.Lines = append(export.Lines, line)
+		}
+	}
+	sort.SliceStable(export.Lines, func(i, j int) bool {
+		a, b := export.Lines[i], export.Lines[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Date.Before(b.Date)
+	})
+
+	if calendar != nil {
+		units := map[time.Time]*ExpenseUnit{}
+		for _, l := range export.Lines {
+			unit := reportUnit(calendar.locate(l.Date), export.GroupBy)
+			u := units[unit.Start]
+			if u == nil {
+				u = &ExpenseUnit{FiscalUnit: *unit, Expenses: map[string]float64{}}
+				if converter != nil {
+					u.Total = new(float64)
+				}
+				units[unit.Start] = u
+				export.Units = append(export.Units, u)
+			}
+			u.Expenses[l.Currency] = math.Round((u.Expenses[l.Currency]+l.Amount)*100) / 100
+			if l.Converted != nil {
+				*u.Total = math.Round((*u.Total+l.Converted.Amount)*100) / 100
+			}
+		}
+		sort.Slice(export.Units, func(i, j int) bool { return export.Units[i].Start.Before(export.Units[j].Start) })
+	}
+	return export, nil
+}
+
+func (s *reportService) MissingTimesheets(ctx context.Context, date, groupBy string) (*MissingTimesheetReport, error) {
+	unit, grouping, err := s.parseUnit(ctx, date, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.expected.MissingTimesheetsBetween(ctx, unit.Start, unit.End)
+	if err != nil {
+		return nil, err
+	}
+	report.GroupBy, report.FiscalUnit = grouping, unit
+	return report, nil
+}
+
+func (s *reportService) ComplianceReport(ctx context.Context, loginName, date, groupBy string) (*ComplianceReport, error) {
+	unit, grouping, err := s.parseUnit(ctx, date, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := s.compliance.ReportBetween(ctx, loginName, unit.Start, unit.End)
+	if err != nil {
+		return nil, err
+	}
+	return &ComplianceReport{GroupBy: grouping, FiscalUnit: unit, Findings: findings}, nil
+}
+
+func (s *reportService) Invoice(ctx context.Context, project, from, to, currency string) (*Invoice, error) {
+	project = strings.TrimSpace(project)
+	fromDay, ve := parseDate("from", from)
+	toDay, toErrors := parseDate("to", to)
+	ve.Errors = append(ve.Errors, toErrors.Errors...)
+	ve.IsSizeInRange("project", project, 1, 100)
+	if !ve.HasErrors() && (toDay.Before(fromDay) || daysBetween(fromDay, toDay) > maxReportDays) {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "to", Constraint: validate.Range,
+			Message: "Must not be before from and at most three years after it", Args: []interface{}{from, maxReportDays}})
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	converter, err := s.rates.Converter(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := s.billing.Rates(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.entries.SelectProjectEntries(ctx, project, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.expenses.SelectApprovedClaims(ctx, "", fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &Invoice{Project: project, From: fromDay, To: toDay, Currency: converter.Currency, Lines: []*InvoiceLine{},
+		Conversions: []*Conversion{}}
+	add := func(l *InvoiceLine) error {
+		converted, err := converter.Convert(l.Amount, l.Currency, l.Date)
+		if err != nil {
+			return err
+		}
+		l.Converted = converted
+		invoice.Total = math.Round((invoice.Total+converted.Amount)*100) / 100
+		invoice.Conversions = addConversion(invoice.Conversions, converted.Conversion)
+		invoice.Lines = append(invoice.Lines, l)
+		return nil
+	}
+
+	for _, e := range entries {
+		rate := rates.rateOn(e.LoginName, e.WorkDate)
+		if rate == nil {
+			return nil, &res.AppError{ResponseCode: BillingRateMissing,
+				Cause: errors.Errorf("no billing rate of %s for %s on %s", project, e.LoginName, e.WorkDate.Format(dateLayout))}
+		}
+		invoice.Hours += e.Hours
+		if err = add(&InvoiceLine{Kind: InvoiceTime, LoginName: e.LoginName, Date: e.WorkDate, EntryID: &e.ID, Task: e.Task,
+			Description: e.Description, Hours: e.Hours, HourlyRate: rate.HourlyRate, RateID: &rate.ID,
+			Amount: math.Round(e.Hours*rate.HourlyRate*100) / 100, Currency: rate.Currency}); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range claims {
+		for _, l := range c.Lines {
+			if l.Date.Before(fromDay) || l.Date.After(toDay) || !strings.EqualFold(l.Project, project) {
+				continue
+			}
+			if err = add(&InvoiceLine{Kind: InvoiceExpense, LoginName: c.LoginName, Date: l.Date, ClaimID: &c.ID,
+				ExpenseID: &l.ID, Description: l.Description, Amount: l.Amount, Currency: l.Currency}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	invoice.Hours = roundHours(invoice.Hours)
+
+	sort.SliceStable(invoice.Lines, func(i, j int) bool {
+		a, b := invoice.Lines[i], invoice.Lines[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Kind > b.Kind
+	})
+	return invoice, nil
+}
+
+//parseUnit is the fiscal unit of groupBy, by default the fiscal period, containing date formatted as 2006-01-02
+func (s *reportService) parseUnit(ctx context.Context, date, groupBy string) (*FiscalUnit, ReportGrouping, error) {
+	day, ve := parseDate("date", date)
+	if groupBy == "" {
+		groupBy = string(GroupByFiscalPeriod)
+	}
+	ve.IsWithin("groupBy", groupBy, reportGroupings)
+	if ve.HasErrors() {
+		return nil, "", ve
+	}
+	unit, err := s.fiscalUnit(ctx, day, ReportGrouping(groupBy))
+	if err != nil {
+		return nil, "", err
+	}
+	return unit, ReportGrouping(groupBy), nil
+}
+
+//fiscalUnit is the unit of groupBy containing day in the organization's fiscal calendar
+func (s *reportService) fiscalUnit(ctx context.Context, day time.Time, groupBy ReportGrouping) (*FiscalUnit, error) {
+	calendar, err := s.fiscal.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return reportUnit(calendar.locate(day), groupBy), nil
+}
+
+//converter is nil when a report is not converted to a reporting currency
+func (s *reportService) converter(ctx context.Context, currency string) (*CurrencyConverter, error) {
+	if currency == "" {
+		return nil, nil
+	}
+	return s.rates.Converter(ctx, currency)
+}
+
+//addConversion adds c to the conversions of a report once per stored rate
+func addConversion(conversions []*Conversion, c *Conversion) []*Conversion {
+	for _, used := range conversions {
+		if used.From == c.From && used.EffectiveFrom.Equal(c.EffectiveFrom) && used.Inverted == c.Inverted {
+			return conversions
+		}
+	}
+	return append(conversions, c)
+}
+
+//reportUnit is the unit a day of fd is added to when grouping by groupBy
+func reportUnit(fd *FiscalDate, groupBy ReportGrouping) *FiscalUnit {
+	unit := &FiscalUnit{FiscalYear: fd.FiscalYear}
+	switch groupBy {
+	case GroupByFiscalWeek:
+		unit.Quarter, unit.Period, unit.Week, unit.Start, unit.End = fd.Quarter, fd.Period, fd.Week, fd.WeekStart, fd.WeekEnd
+	case GroupByFiscalPeriod:
+		unit.Quarter, unit.Period, unit.Start, unit.End = fd.Quarter, fd.Period, fd.PeriodStart, fd.PeriodEnd
+	case GroupByFiscalQuarter:
+		unit.Quarter, unit.Start, unit.End = fd.Quarter, fd.QuarterStart, fd.QuarterEnd
+	default:
+		unit.Start, unit.End = fd.YearStart, fd.YearEnd
+	}
+	return unit
+}
+
+//timesheetDays calls fn with the worked and absence hours of every day of the timesheet's period that has any
+func timesheetDays(ts *GetAllTimesheets, fn func(date time.Time, worked, absent float64)) {
+	period := &PayPeriod{Start: ts.PeriodStart, End: ts.PeriodEnd, Frequency: ts.PayFrequency}
+
+	weeks := []WeekHrs{}
+	if len(ts.WeekHrs) > 0 {
+		if err := json.Unmarshal(ts.WeekHrs, &weeks); err != nil {
+			log.Error().Err(err).Str("loginName", ts.LoginName).Msg("Error while unmarshalling week hrs json")
+		}
+	}
+	for _, w := range weeks {
+		for day, hours := range w.days() {
+			date := period.slotDate(w.WeekInfo, day+1)
+			if hours != 0 && period.contains(date) {
+				fn(date, hours, 0)
+			}
+		}
+	}
+
+	absences, _, _, err := summarizeAbsences(ts.Absences)
+	if err != nil {
+		log.Error().Err(err).Str("loginName", ts.LoginName).Msg("Error while unmarshalling absence json")
+	}
+	for _, a := range absences {
+		if date := period.slotDate(a.WeekInfo, a.Day); period.contains(date) {
+			fn(date, 0, a.Hours)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"timesheet/commons/res"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//ProvisioningService manages users on behalf of the HR system through SCIM 2.0
+type ProvisioningService interface {
+	CreateSCIMUser(ctx context.Context, su *SCIMUser) (*SCIMUser, error)
+
+	GetSCIMUser(ctx context.Context, id string) (*SCIMUser, error)
+
+	ListSCIMUsers(ctx context.Context, filter string, startIndex, count int) (*SCIMListResponse, error)
+
+	PatchSCIMUser(ctx context.Context, id string, patch *SCIMPatchRequest) (*SCIMUser, error)
+
+	DeactivateSCIMUser(ctx context.Context, id string) error
+
+	//IsActive reports whether the user may sign in. Users not managed by a directory are always active.
+	IsActive(ctx context.Context, loginName string) (bool, error)
+}
+
+type provisioningService struct {
+	dirRepo     DirectoryRepository
+	tokenRepo   TokenRepository
+	provisioner *accountProvisioner
+}
+
+func NewProvisioningService(dirRepo DirectoryRepository, userSvc Service, tokenRepo TokenRepository, orgRepo OrganizationRepository) ProvisioningService {
+	return &provisioningService{dirRepo: dirRepo, tokenRepo: tokenRepo,
+		provisioner: &accountProvisioner{userSvc: userSvc, orgRepo: orgRepo}}
+}
+
+func (s *provisioningService) CreateSCIMUser(ctx context.Context, su *SCIMUser) (*SCIMUser, error) {
+	loginName := strings.ToUpper(strings.TrimSpace(su.UserName))
+	if loginName == "" {
+		return nil, &res.AppError{ResponseCode: SCIMInvalidValue, Cause: errors.New("userName is required")}
+	}
+
+	existing, err := s.dirRepo.SelectDirectoryEntryByLoginName(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, &res.AppError{ResponseCode: UserAlreadyExists, Cause: fmt.Errorf("user %s is already provisioned", loginName)}
+	}
+
+	now := time.Now().UTC()
+	e := &DirectoryEntry{
+		ID:        uuid.New(),
+		LoginName: loginName,
+		Active:    true,
+		Source:    DirectorySourceSCIM,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = s.applySCIMUser(ctx, e, su); err != nil {
+		return nil, err
+	}
+
+	if _, err = s.provisioner.ensureUser(ctx, &User{LoginName: loginName, Department: e.Department, JobTitle: e.JobTitle}); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Unable to create provisioned user")
+		return nil, err
+	}
+
+	if err = s.dirRepo.InsertDirectoryEntry(ctx, e); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("loginName", loginName).Msg("Provisioned user through SCIM")
+	return s.toSCIMUser(ctx, e)
+}
+
+func (s *provisioningService) GetSCIMUser(ctx context.Context, id string) (*SCIMUser, error) {
+	e, err := s.getEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toSCIMUser(ctx, e)
+}
+
+func (s *provisioningService) ListSCIMUsers(ctx context.Context, filter string, startIndex, count int) (*SCIMListResponse, error) {
+	f, err := parseSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count <= 0 {
+		count = scimDefaultCount
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+
+	entries, total, err := s.dirRepo.SelectDirectoryEntries(ctx, f, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &SCIMListResponse{
+		Schemas:      []string{SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(entries),
+		Resources:    []*SCIMUser{},
+	}
+	for _, e := range entries {
+		su, err := s.toSCIMUser(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		list.Resources = append(list.Resources, su)
+	}
+	return list, nil
+}
+
+func (s *provisioningService) PatchSCIMUser(ctx context.Context, id string, patch *SCIMPatchRequest) (*SCIMUser, error) {
+	e, err := s.getEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	wasActive := e.Active
+
+	for _, op := range patch.Operations {
+		if err = s.applyPatchOperation(ctx, e, op); err != nil {
+			return nil, err
+		}
+	}
+
+	e.UpdatedAt = time.Now().UTC()
+	if err = s.dirRepo.UpdateDirectoryEntry(ctx, e); err != nil {
+		return nil, err
+	}
+	if wasActive && !e.Active {
+		s.revokeAccess(ctx, e.LoginName)
+	}
+	return s.toSCIMUser(ctx, e)
+}
+
+//DeactivateSCIMUser handles DELETE. Timesheets reference the login name, so users are never removed, only disabled.
+func (s *provisioningService) DeactivateSCIMUser(ctx context.Context, id string) error {
+	e, err := s.getEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = s.dirRepo.UpdateDirectoryEntryActive(ctx, e.LoginName, false); err != nil {
+		return err
+	}
+	s.revokeAccess(ctx, e.LoginName)
+
+	log.Info().Str("loginName", e.LoginName).Msg("Deactivated user through SCIM")
+	return nil
+}
+
+func (s *provisioningService) IsActive(ctx context.Context, loginName string) (bool, error) {
+	e, err := s.dirRepo.SelectDirectoryEntryByLoginName(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return false, err
+	}
+	return e == nil || e.Active, nil
+}
+
+func (s *provisioningService) revokeAccess(ctx context.Context, loginName string) {
+	if err := s.tokenRepo.RevokeAllAPITokens(ctx, loginName); err != nil {
+		log.Error().Err(err).Str("loginName", loginName).Msg("Unable to revoke api tokens of deactivated user")
+	}
+}
+
+func (s *provisioningService) getEntry(ctx context.Context, id string) (*DirectoryEntry, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: DirectoryEntryNotFound, Cause: err}
+	}
+	e, err := s.dirRepo.SelectDirectoryEntryByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, &res.AppError{ResponseCode: DirectoryEntryNotFound, Cause: fmt.Errorf("no user with id %s", id)}
+	}
+	return e, nil
+}
+
+//applySCIMUser copies the writable attributes of a full SCIM resource onto the entry
+func (s *provisioningService) applySCIMUser(ctx context.Context, e *DirectoryEntry, su *SCIMUser) error {
+	e.ExternalID = su.ExternalID
+	if su.Name != nil {
+		e.GivenName = su.Name.GivenName
+		e.FamilyName = su.Name.FamilyName
+	}
+	e.Email = primaryEmail(su.Emails)
+	e.JobTitle = su.Title
+	if su.Active != nil {
+		e.Active = *su.Active
+	}
+	if su.Enterprise != nil {
+		e.Department = su.Enterprise.Department
+		if su.Enterprise.Manager != nil {
+			return s.setManager(ctx, e, su.Enterprise.Manager.Value)
+		}
+	}
+	return nil
+}
+
+func (s *provisioningService) applyPatchOperation(ctx context.Context, e *DirectoryEntry, op SCIMPatchOperation) error {
+	opName := strings.ToLower(op.Op)
+	if opName != "add" && opName != "replace" && opName != "remove" {
+		return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: fmt.Errorf("unsupported op %s", op.Op)}
+	}
+
+	//Without a path the value is an object of attribute/value pairs (sent by Azure AD among others).
+	if op.Path == "" {
+		values := map[string]json.RawMessage{}
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+		}
+		for path, value := range values {
+			if err := s.applyPatchOperation(ctx, e, SCIMPatchOperation{Op: op.Op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path := strings.ToLower(op.Path)
+	if strings.HasPrefix(path, strings.ToLower(SCIMSchemaEnterpriseUser)+":") {
+		path = "enterprise." + strings.TrimPrefix(path, strings.ToLower(SCIMSchemaEnterpriseUser)+":")
+	} else if path == strings.ToLower(SCIMSchemaEnterpriseUser) {
+		ent := &SCIMEnterpriseUser{}
+		if err := json.Unmarshal(op.Value, ent); err != nil {
+			return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+		}
+		e.Department = ent.Department
+		if ent.Manager != nil {
+			return s.setManager(ctx, e, ent.Manager.Value)
+		}
+		return nil
+	}
+
+	//value is only decoded by the attributes that hold a plain string
+	value := ""
+	if opName != "remove" && len(op.Value) > 0 && op.Value[0] == '"' {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+		}
+	}
+
+	switch {
+	case path == "active":
+		active, err := patchBool(op.Value)
+		if err != nil {
+			return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+		}
+		e.Active = active
+	case path == "externalid":
+		e.ExternalID = value
+	case path == "name":
+		name := &SCIMName{}
+		if opName != "remove" {
+			if err := json.Unmarshal(op.Value, name); err != nil {
+				return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+			}
+		}
+		e.GivenName, e.FamilyName = name.GivenName, name.FamilyName
+	case path == "name.givenname":
+		e.GivenName = value
+	case path == "name.familyname":
+		e.FamilyName = value
+	case path == "title":
+		e.JobTitle = value
+	case path == "enterprise.department":
+		e.Department = value
+	case path == "enterprise.manager":
+		managerID := ""
+		if opName != "remove" {
+			m := &SCIMManager{}
+			if err := json.Unmarshal(op.Value, m); err != nil {
+				if err = json.Unmarshal(op.Value, &managerID); err != nil {
+					return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+				}
+			} else {
+				managerID = m.Value
+			}
+		}
+		return s.setManager(ctx, e, managerID)
+	case strings.HasPrefix(path, "emails"):
+		if opName == "remove" {
+			e.Email = ""
+		} else if strings.HasSuffix(path, ".value") {
+			e.Email = value
+		} else {
+			emails := []SCIMEmail{}
+			if err := json.Unmarshal(op.Value, &emails); err != nil {
+				return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: err}
+			}
+			e.Email = primaryEmail(emails)
+		}
+	default:
+		return &res.AppError{ResponseCode: SCIMInvalidPath, Cause: fmt.Errorf("unsupported path %s", op.Path)}
+	}
+	return nil
+}
+
+func (s *provisioningService) setManager(ctx context.Context, e *DirectoryEntry, managerID string) error {
+	if managerID == "" {
+		e.ManagerLoginName = ""
+		return nil
+	}
+	manager, err := s.getEntry(ctx, managerID)
+	if err != nil {
+		return &res.AppError{ResponseCode: SCIMInvalidValue, Cause: errors.Wrapf(err, "unknown manager %s", managerID)}
+	}
+	e.ManagerLoginName = manager.LoginName
+	return nil
+}
+
+func (s *provisioningService) toSCIMUser(ctx context.Context, e *DirectoryEntry) (*SCIMUser, error) {
+	active := e.Active
+	su := &SCIMUser{
+		Schemas:    []string{SCIMSchemaUser, SCIMSchemaEnterpriseUser},
+		ID:         e.ID.String(),
+		ExternalID: e.ExternalID,
+		UserName:   e.LoginName,
+		Name:       &SCIMName{GivenName: e.GivenName, FamilyName: e.FamilyName},
+		Title:      e.JobTitle,
+		Active:     &active,
+		Enterprise: &SCIMEnterpriseUser{Department: e.Department},
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      e.CreatedAt,
+			LastModified: e.UpdatedAt,
+			Location:     "/scim/v2/Users/" + e.ID.String(),
+		},
+	}
+	if e.Email != "" {
+		su.Emails = []SCIMEmail{{Value: e.Email, Type: "work", Primary: true}}
+	}
+	if e.ManagerLoginName != "" {
+		manager, err := s.dirRepo.SelectDirectoryEntryByLoginName(ctx, e.ManagerLoginName)
+		if err != nil {
+			return nil, err
+		}
+		if manager != nil {
+			su.Enterprise.Manager = &SCIMManager{Value: manager.ID.String(), DisplayName: manager.LoginName}
+		}
+	}
+	return su, nil
+}
+
+func primaryEmail(emails []SCIMEmail) string {
+	for _, m := range emails {
+		if m.Primary {
+			return m.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+//patchBool accepts both true and "True", the latter being sent by some identity providers
+func patchBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return false, err
+	}
+	return strings.EqualFold(str, "true"), nil
+}
+
+//scimFilterExpr matches one leading `attr eq value` term, optionally followed by "and"
+var scimFilterExpr = regexp.MustCompile(`(?i)^\s*([a-z0-9.:]+)\s+eq\s+("(?:[^"\\]|\\.)*"|true|false)\s*(and\s+|$)`)
+
+//parseSCIMFilter supports equality filters joined by "and", which covers what provisioning clients send.
+func parseSCIMFilter(filter string) (DirectoryFilter, error) {
+	f := DirectoryFilter{}
+	if strings.TrimSpace(filter) == "" {
+		return f, nil
+	}
+
+	for rest := filter; strings.TrimSpace(rest) != ""; {
+		m := scimFilterExpr.FindStringSubmatch(rest)
+		if m == nil {
+			return f, &res.AppError{ResponseCode: SCIMInvalidFilter, Cause: fmt.Errorf("unsupported filter %q", rest)}
+		}
+		rest = rest[len(m[0]):]
+
+		attr := strings.ToLower(m[1])
+		value := m[2]
+		if strings.HasPrefix(value, `"`) {
+			if err := json.Unmarshal([]byte(value), &value); err != nil {
+				return f, &res.AppError{ResponseCode: SCIMInvalidFilter, Cause: err}
+			}
+		}
+
+		switch attr {
+		case "username":
+			f.LoginName = value
+		case "externalid":
+			f.ExternalID = value
+		case "emails", "emails.value":
+			f.Email = value
+		case "title":
+			f.JobTitle = value
+		case "department", strings.ToLower(SCIMSchemaEnterpriseUser) + ":department":
+			f.Department = value
+		case "active":
+			active := strings.EqualFold(value, "true")
+			f.Active = &active
+		default:
+			return f, &res.AppError{ResponseCode: SCIMInvalidFilter, Cause: fmt.Errorf("unsupported filter attribute %s", m[1])}
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,129 @@
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+//EventStream hands the events announced on eventChannel to the live subscribers of this instance. Every
+//instance listens on its own, so a change made through any instance reaches the subscribers of all of them.
+type EventStream interface {
+	//Listen keeps a connection of the pool listening until ctx is done, reconnecting when it is lost
+	Listen(ctx context.Context)
+
+	//Subscribe returns the events viewer may see: all events of the organization for admins, otherwise
+	//those of the viewer and of their direct reports. The channel is closed once ctx is done.
+	Subscribe(ctx context.Context, viewer *auth.Principal) (<-chan *Event, error)
+}
+
+type eventStream struct {
+	db      *pgxpool.Pool
+	dirRepo user.DirectoryRepository
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	orgID uuid.UUID
+	//loginNames are the users whose events may be seen, nil for all
+	loginNames map[string]bool
+	events     chan *Event
+}
+
+//subscriberBuffer is how many events a slow subscriber may fall behind before events are dropped for it
+const subscriberBuffer = 64
+
+func NewEventStream(db *pgxpool.Pool, dirRepo user.DirectoryRepository) EventStream {
+	return &eventStream{db: db, dirRepo: dirRepo, subscribers: map[*subscriber]struct{}{}}
+}
+
+func (s *eventStream) Listen(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := s.listen(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Event stream lost its connection, reconnecting")
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
+
+func (s *eventStream) listen(ctx context.Context) error {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "listen "+eventChannel); err != nil {
+		return err
+	}
+	log.Info().Str("channel", eventChannel).Msg("Event stream listening")
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		n := &eventNotification{}
+		if err = json.Unmarshal([]byte(notification.Payload), n); err != nil {
+			log.Error().Err(err).Msg("Unable to parse event notification")
+			continue
+		}
+		s.dispatch(n)
+	}
+}
+
+func (s *eventStream) dispatch(n *eventNotification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		if sub.orgID != n.OrgID || (sub.loginNames != nil && !sub.loginNames[n.Event.LoginName]) {
+			continue
+		}
+		select {
+		case sub.events <- n.Event:
+		default:
+			log.Warn().Str("event", n.Event.ID.String()).Msg("Event stream subscriber is too slow, event dropped")
+		}
+	}
+}
+
+func (s *eventStream) Subscribe(ctx context.Context, viewer *auth.Principal) (<-chan *Event, error) {
+	sub := &subscriber{orgID: viewer.OrgID, events: make(chan *Event, subscriberBuffer)}
+	if !viewer.Admin {
+		sub.loginNames = map[string]bool{strings.ToUpper(viewer.LoginName): true}
+		reports, _, err := s.dirRepo.SelectDirectoryEntries(ctx, user.DirectoryFilter{ManagerLoginName: viewer.LoginName}, 0, maxDirectReports)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range reports {
+			sub.loginNames[r.LoginName] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+		close(sub.events)
+	}()
+	return sub.events, nil
+}
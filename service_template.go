@@ -0,0 +1,335 @@
+package timesheets
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+	"timesheet/user"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type TemplateService interface {
+	CreateTemplate(ctx context.Context, loginName string, t *TimesheetTemplate) (*TimesheetTemplate, error)
+
+	UpdateTemplate(ctx context.Context, loginName, templateID string, t *TimesheetTemplate) (*TimesheetTemplate, error)
+
+	ListTemplates(ctx context.Context, loginName string) ([]*TimesheetTemplate, error)
+
+	DeleteTemplate(ctx context.Context, loginName, templateID string) error
+
+	//Prefill creates the caller's timesheet of a pay period through CreateTimesheet with the hours of a template or of
+	//the previous week or month and returns it. A template also gives a time entry for the project and task of each
+	//of its lines on every filled day.
+	Prefill(ctx context.Context, caller *auth.Principal, req *PrefillRequest) (*Timesheet, error)
+}
+
+type templateService struct {
+	repo       TemplateRepository
+	timesheets Service
+	periods    PayPeriodService
+	reports    ReportRepository
+	holidays   HolidayService
+	leave      LeaveService
+	zones      TimeZoneService
+	userRepo   user.TenantUserRepository
+}
+
+func NewTemplateService(repo TemplateRepository, timesheets Service, periods PayPeriodService, reports ReportRepository,
+	holidays HolidayService, leave LeaveService, zones TimeZoneService, userRepo user.TenantUserRepository) TemplateService {
+	return &templateService{repo: repo, timesheets: timesheets, periods: periods, reports: reports, holidays: holidays,
+		leave: leave, zones: zones, userRepo: userRepo}
+}
+
+func (s *templateService) CreateTemplate(ctx context.Context, loginName string, t *TimesheetTemplate) (*TimesheetTemplate, error) {
+	if ve := validateTemplate(t); ve.HasErrors() {
+		return nil, ve
+	}
+
+	t.ID = uuid.New()
+	t.LoginName = strings.ToUpper(loginName)
+	t.CreatedAt = time.Now()
+	if err := s.repo.InsertTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *templateService) UpdateTemplate(ctx context.Context, loginName, templateID string, t *TimesheetTemplate) (*TimesheetTemplate, error) {
+	existing, err := s.findTemplate(ctx, loginName, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if ve := validateTemplate(t); ve.HasErrors() {
+		return nil, ve
+	}
+
+	t.ID, t.LoginName, t.CreatedAt = existing.ID, existing.LoginName, existing.CreatedAt
+	updated, err := s.repo.UpdateTemplate(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &res.AppError{ResponseCode: TemplateNotFound, Cause: errors.Errorf("template %s not found", templateID)}
+	}
+	return t, nil
+}
+
+func (s *templateService) ListTemplates(ctx context.Context, loginName string) ([]*TimesheetTemplate, error) {
+	return s.repo.SelectTemplates(ctx, strings.ToUpper(loginName))
+}
+
+func (s *templateService) DeleteTemplate(ctx context.Context, loginName, templateID string) error {
+	t, err := s.findTemplate(ctx, loginName, templateID)
+	if err != nil {
+		return err
+	}
+	if _, err = s.repo.DeleteTemplate(ctx, t.LoginName, t.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *templateService) Prefill(ctx context.Context, caller *auth.Principal, req *PrefillRequest) (*Timesheet, error) {
+	loginName := strings.ToUpper(caller.LoginName)
+	ve := validate.New()
+	ve.IsWithin("Source", string(req.Source), prefillSources)
+	date := req.PeriodStart
+	if date.IsZero() {
+		if monthErrors := validateMonth(req.Month, req.Year); monthErrors.HasErrors() {
+			ve.Errors = append(ve.Errors, monthErrors.Errors...)
+		} else {
+			date, _ = monthRange(req.Month, req.Year)
+		}
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	u, err := s.userRepo.SelectUserByLoginName(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, &res.AppError{ResponseCode: res.RecordNotFound, Cause: errors.Errorf("user %s not found", loginName)}
+	}
+	period, err := s.periods.OpenPeriod(ctx, loginName, date)
+	if err != nil {
+		return nil, err
+	}
+	if req.PeriodStart.IsZero() {
+		if err = checkMonthPeriod(period, req.Month, req.Year); err != nil {
+			return nil, err
+		}
+	}
+
+	var hoursOf func(date time.Time) float64
+	var lines []TemplateLine
+	switch req.Source {
+	case PrefillFromTemplate:
+		if lines, err = s.templateLines(ctx, loginName, req.TemplateID); err != nil {
+			return nil, err
+		}
+		hoursOf = func(date time.Time) float64 {
+			hours := 0.0
+			for _, l := range lines {
+				hours += l.days()[weekdayIndex(date)]
+			}
+			return roundHours(hours)
+		}
+	case PrefillFromPreviousWeek:
+		weekStart := period.Start.AddDate(0, 0, -weekdayIndex(period.Start)-7)
+		source, err := s.dailyHours(ctx, loginName, weekStart, weekStart.AddDate(0, 0, 6))
+		if err != nil {
+			return nil, err
+		}
+		hoursOf = func(date time.Time) float64 {
+			return source[weekStart.AddDate(0, 0, weekdayIndex(date))]
+		}
+	case PrefillFromPreviousMonth:
+		previous, err := s.periods.PeriodFor(ctx, loginName, period.Start.AddDate(0, 0, -1))
+		if err != nil {
+			return nil, err
+		}
+		source, err := s.dailyHours(ctx, loginName, previous.Start, previous.End)
+		if err != nil {
+			return nil, err
+		}
+		hoursOf = func(date time.Time) float64 {
+			return source[sameWeekdayIn(previous, daysBetween(period.Start, date)/7, date.Weekday())]
+		}
+	}
+
+	//Holidays are added as absences by CreateTimesheet, days of approved leave stay empty
+	skip, err := s.leave.ApprovedLeaveDays(ctx, loginName, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := s.holidays.HolidaysBetween(ctx, loginName, u.Department, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holidays {
+		skip[dayOf(h.Date)] = true
+	}
+
+	loc, err := s.location(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+
+	weeks := map[int]*WeekHrs{}
+	entries := []*TimeEntry{}
+	for date := period.Start; !date.After(period.End); date = date.AddDate(0, 0, 1) {
+		hours := hoursOf(date)
+		if skip[date] || hours == 0 {
+			continue
+		}
+		week, day := period.hoursSlot(date)
+		if weeks[week] == nil {
+			weeks[week] = &WeekHrs{WeekInfo: week}
+		}
+		weeks[week].setDay(day, hours)
+		entries = append(entries, templateEntries(loginName, period, lines, date, loc)...)
+	}
+	weekHrs := []WeekHrs{}
+	for _, w := range weeks {
+		weekHrs = append(weekHrs, *w)
+	}
+	sort.Slice(weekHrs, func(i, j int) bool { return weekHrs[i].WeekInfo < weekHrs[j].WeekInfo })
+	raw, _ := json.Marshal(weekHrs)
+
+	ts := &Timesheet{LoginName: loginName, PeriodStart: period.Start, Info: req.Info, WeekHrs: raw}
+	if _, err = s.timesheets.CreateTimesheetWithEntries(ctx, caller, ts, entries); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (s *templateService) templateLines(ctx context.Context, loginName string, templateID uuid.UUID) ([]TemplateLine, error) {
+	t, err := s.findTemplate(ctx, loginName, templateID.String())
+	if err != nil {
+		return nil, err
+	}
+	lines := []TemplateLine{}
+	if err = json.Unmarshal(t.Lines, &lines); err != nil {
+		return nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	return lines, nil
+}
+
+//location is the zone of the user, template entries start at midnight of their day in it
+func (s *templateService) location(ctx context.Context, loginName string) (*time.Location, error) {
+	setting, err := s.zones.GetTimeZone(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(setting.TimeZone)
+}
+
+//templateEntries are the time entries of the template lines with hours on the weekday of date. They are not timed,
+//each starts at midnight of date in loc and lasts its hours.
+func templateEntries(loginName string, period *PayPeriod, lines []TemplateLine, date time.Time, loc *time.Location) []*TimeEntry {
+	entries := []*TimeEntry{}
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	for _, l := range lines {
+		hours := roundHours(l.days()[weekdayIndex(date)])
+		if hours == 0 {
+			continue
+		}
+		entries = append(entries, &TimeEntry{ID: uuid.New(), LoginName: loginName, Project: l.Project, Task: l.Task,
+			StartedAt: start, EndedAt: start.Add(time.Duration(hours * float64(time.Hour))), Hours: hours, WorkDate: date,
+			PeriodStart: period.Start, Source: TimeEntryTemplate, Booked: true, CreatedAt: time.Now()})
+	}
+	return entries
+}
+
+//dailyHours returns the hours worked per day from..to on the user's timesheets
+func (s *templateService) dailyHours(ctx context.Context, loginName string, from, to time.Time) (map[time.Time]float64, error) {
+	sheets, err := s.reports.SelectTimesheetsBetween(ctx, loginName, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	hours := map[time.Time]float64{}
+	for _, ts := range sheets {
+		timesheetDays(ts, func(date time.Time, worked, _ float64) {
+			if !date.Before(from) && !date.After(to) {
+				hours[date] += worked
+			}
+		})
+	}
+	return hours, nil
+}
+
+func (s *templateService) findTemplate(ctx context.Context, loginName, templateID string) (*TimesheetTemplate, error) {
+	id, err := uuid.Parse(templateID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: res.BadRequest, Cause: err}
+	}
+	t, err := s.repo.SelectTemplate(ctx, strings.ToUpper(loginName), id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, &res.AppError{ResponseCode: TemplateNotFound, Cause: errors.Errorf("template %s not found", templateID)}
+	}
+	return t, nil
+}
+
+func validateTemplate(t *TimesheetTemplate) *validate.ValidationError {
+	ve := validate.New()
+	ve.IsSizeInRange("Name", t.Name, 1, 100)
+
+	lines := []TemplateLine{}
+	if err := json.Unmarshal(t.Lines, &lines); err != nil {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Lines", Constraint: validate.Like,
+			Message: "Must be a list of template lines", Args: []interface{}{}})
+		return ve
+	}
+	if len(lines) == 0 || len(lines) > maxTemplateLines {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "Lines", Constraint: validate.Size,
+			Message: "Size is not within range", Args: []interface{}{1, maxTemplateLines}})
+	}
+	perWeekday := make([]float64, 7)
+	for _, l := range lines {
+		ve.IsSizeInRange("Lines.Project", l.Project, 1, 100)
+		ve.IsSizeInRange("Lines.Task", l.Task, 0, 100)
+		for i, hours := range l.days() {
+			perWeekday[i] += hours
+			if hours < 0 {
+				ve.Errors = append(ve.Errors, validate.FieldError{Field: "Lines.Day", Constraint: validate.Range,
+					Message: "Value is not within range", Args: []interface{}{0, 24}})
+			}
+		}
+	}
+	for _, hours := range perWeekday {
+		if hours > 24 {
+			ve.Errors = append(ve.Errors, validate.FieldError{Field: "Lines", Constraint: validate.Range,
+				Message: "The hours of a weekday add up to more than 24", Args: []interface{}{0, 24}})
+			break
+		}
+	}
+	return ve
+}
+
+//weekdayIndex counts the days of the week from Monday, 0, to Sunday, 6
+func weekdayIndex(date time.Time) int {
+	return (int(date.Weekday()) + 6) % 7
+}
+
+//sameWeekdayIn is the n-th weekday of the period, or its last one when it has fewer
+func sameWeekdayIn(period *PayPeriod, n int, weekday time.Weekday) time.Time {
+	date := period.Start.AddDate(0, 0, (int(weekday)-int(period.Start.Weekday())+7)%7+7*n)
+	for date.After(period.End) && !date.AddDate(0, 0, -7).Before(period.Start) {
+		date = date.AddDate(0, 0, -7)
+	}
+	return date
+}
@@ -0,0 +1,243 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type TimerService interface {
+	//Start starts a timer for the user, who must not have one running
+	Start(ctx context.Context, loginName string, t *Timer) (*Timer, error)
+
+	//Stop stops the user's timer and books its time on the timesheet. An entry the timesheet does not take is kept
+	//unbooked for BookPending.
+	Stop(ctx context.Context, loginName string) (*TimeEntry, error)
+
+	//Switch stops the running timer, if any, and starts t at the same instant
+	Switch(ctx context.Context, loginName string, t *Timer) (*TimerSwitch, error)
+
+	//Active returns the user's running timer, nil if there is none
+	Active(ctx context.Context, loginName string) (*Timer, error)
+
+	//ListEntries lists the time entries worked from..to, formatted as 2006-01-02, with their times in the zone
+	//timeZone, the user's zone if empty. Open to the user, their manager and admins.
+	ListEntries(ctx context.Context, viewer *auth.Principal, loginName, from, to, timeZone string) ([]*TimeEntry, error)
+
+	//StopForgotten stops the timers that ran longer than the maximum duration and returns how many it stopped
+	StopForgotten(ctx context.Context, now time.Time) (int, error)
+
+	//BookPending books the unbooked time entries again and returns how many it booked. Entries of approved
+	//timesheets stay unbooked until the timesheet is rejected.
+	BookPending(ctx context.Context) (int, error)
+}
+
+type timerService struct {
+	repo       TimerRepository
+	timesheets Service
+	periods    PayPeriodService
+	access     TimesheetAccess
+	zones      TimeZoneService
+	config     TimerConfig
+}
+
+func NewTimerService(repo TimerRepository, timesheets Service, periods PayPeriodService, access TimesheetAccess,
+	zones TimeZoneService, config TimerConfig) TimerService {
+	return &timerService{repo: repo, timesheets: timesheets, periods: periods, access: access, zones: zones, config: config}
+}
+
+func (s *timerService) Start(ctx context.Context, loginName string, t *Timer) (*Timer, error) {
+	if ve := validateTimer(t); ve.HasErrors() {
+		return nil, ve
+	}
+
+	if err := s.newTimer(ctx, loginName, t, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.InsertTimer(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *timerService) Stop(ctx context.Context, loginName string) (*TimeEntry, error) {
+	t, err := s.repo.SelectTimer(ctx, strings.ToUpper(loginName))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, &res.AppError{ResponseCode: NoTimerRunning, Cause: errors.Errorf("%s has no running timer", loginName)}
+	}
+	return s.stop(ctx, t, time.Now(), TimeEntryTimer, nil)
+}
+
+func (s *timerService) Switch(ctx context.Context, loginName string, t *Timer) (*TimerSwitch, error) {
+	if ve := validateTimer(t); ve.HasErrors() {
+		return nil, ve
+	}
+
+	now := time.Now()
+	if err := s.newTimer(ctx, loginName, t, now); err != nil {
+		return nil, err
+	}
+	running, err := s.repo.SelectTimer(ctx, t.LoginName)
+	if err != nil {
+		return nil, err
+	}
+	if running == nil {
+		if err = s.repo.InsertTimer(ctx, t); err != nil {
+			return nil, err
+		}
+		return &TimerSwitch{Started: t}, nil
+	}
+
+	e, err := s.stop(ctx, running, now, TimeEntryTimer, t)
+	if err != nil {
+		return nil, err
+	}
+	return &TimerSwitch{Stopped: e, Started: t}, nil
+}
+
+func (s *timerService) Active(ctx context.Context, loginName string) (*Timer, error) {
+	return s.repo.SelectTimer(ctx, strings.ToUpper(loginName))
+}
+
+func (s *timerService) ListEntries(ctx context.Context, viewer *auth.Principal, loginName, from, to, timeZone string) ([]*TimeEntry, error) {
+	fromDay, ve := parseDate("from", from)
+	toDay, toErrors := parseDate("to", to)
+	ve.Errors = append(ve.Errors, toErrors.Errors...)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	loginName = strings.ToUpper(loginName)
+	if err := s.access.CheckView(ctx, viewer, loginName); err != nil {
+		return nil, err
+	}
+	loc, err := s.zones.ReportLocation(ctx, loginName, timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.SelectTimeEntries(ctx, loginName, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		e.StartedAt, e.EndedAt, e.CreatedAt = e.StartedAt.In(loc), e.EndedAt.In(loc), e.CreatedAt.In(loc)
+	}
+	return entries, nil
+}
+
+func (s *timerService) StopForgotten(ctx context.Context, now time.Time) (int, error) {
+	timers, err := s.repo.SelectTimersStartedBefore(ctx, now.Add(-s.config.MaxDuration))
+	if err != nil {
+		return 0, err
+	}
+
+	stopped := 0
+	for _, t := range timers {
+		if _, err = s.stop(ctx, t, now, TimeEntryAutoStopped, nil); err != nil {
+			if res.IsAppErrorEquals(err, NoTimerRunning) {
+				continue
+			}
+			return stopped, err
+		}
+		stopped++
+	}
+	return stopped, nil
+}
+
+func (s *timerService) BookPending(ctx context.Context) (int, error) {
+	entries, err := s.repo.SelectUnbookedEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	booked := 0
+	for _, e := range entries {
+		if err = s.timesheets.BookTimeEntry(ctx, e); err != nil {
+			if !res.IsAppErrorEquals(err, TimesheetNotPending) {
+				log.Error().Err(err).Str("loginName", e.LoginName).Str("entry", e.ID.String()).Msg("Error while booking the time entry on the timesheet")
+			}
+			continue
+		}
+		booked++
+	}
+	return booked, nil
+}
+
+//stop turns t into a time entry ending at endedAt, cut to the maximum duration, starts next in the same transaction
+//and books the hours on the timesheet. The entry is kept unbooked if the timesheet can not be changed, e.g. when it
+//is approved, and BookPending books it later.
+func (s *timerService) stop(ctx context.Context, t *Timer, endedAt time.Time, source TimeEntrySource, next *Timer) (*TimeEntry, error) {
+	e := &TimeEntry{ID: uuid.New(), LoginName: t.LoginName, Project: t.Project, Task: t.Task, Description: t.Description,
+		StartedAt: t.StartedAt, EndedAt: endedAt, Source: source, CreatedAt: time.Now()}
+	if s.config.MaxDuration > 0 && e.EndedAt.Sub(e.StartedAt) > s.config.MaxDuration {
+		e.EndedAt, e.Capped = e.StartedAt.Add(s.config.MaxDuration), true
+	}
+	e.Hours = roundHours(e.EndedAt.Sub(e.StartedAt).Hours())
+	//There is nothing to book of an entry shorter than the rounding
+	e.Booked = e.Hours == 0
+
+	loc, err := time.LoadLocation(t.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	e.WorkDate = localDate(e.StartedAt, loc)
+	period, err := s.periods.PeriodFor(ctx, e.LoginName, e.WorkDate)
+	if err != nil {
+		return nil, err
+	}
+	e.PeriodStart = period.Start
+
+	stopped, err := s.repo.StopTimer(ctx, t.ID, e, next)
+	if err != nil {
+		return nil, err
+	}
+	if !stopped {
+		return nil, &res.AppError{ResponseCode: NoTimerRunning, Cause: errors.Errorf("timer of %s was already stopped", t.LoginName)}
+	}
+
+	if e.Booked {
+		return e, nil
+	}
+	if err = s.timesheets.BookTimeEntry(ctx, e); err != nil {
+		log.Warn().Err(err).Str("loginName", e.LoginName).Str("entry", e.ID.String()).Msg("Time entry is kept to be booked on the timesheet later")
+		return e, nil
+	}
+	e.Booked = true
+	return e, nil
+}
+
+//newTimer starts t now, in the zone of the user unless it names one
+func (s *timerService) newTimer(ctx context.Context, loginName string, t *Timer, now time.Time) error {
+	t.ID = uuid.New()
+	t.LoginName = strings.ToUpper(loginName)
+	t.StartedAt = now
+	if t.TimeZone == "" {
+		setting, err := s.zones.GetTimeZone(ctx, t.LoginName)
+		if err != nil {
+			return err
+		}
+		t.TimeZone = setting.TimeZone
+	}
+	return nil
+}
+
+func validateTimer(t *Timer) *validate.ValidationError {
+	ve := validate.New()
+	ve.IsSizeInRange("Project", t.Project, 1, 100)
+	ve.IsSizeInRange("Task", t.Task, 0, 100)
+	ve.IsSizeInRange("Description", t.Description, 0, 500)
+	validateTimeZone(ve, "TimeZone", t.TimeZone)
+	return ve
+}
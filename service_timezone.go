@@ -0,0 +1,119 @@
+package timesheets
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/pkg/errors"
+)
+
+type TimeZoneService interface {
+	//GetTimeZone returns the zone the user's days are counted in, the organization's for an empty loginName
+	GetTimeZone(ctx context.Context, loginName string) (*TimeZoneSetting, error)
+
+	//SetTimeZone sets the zone of a user, open to the user and admins, or of the organization for an empty
+	//loginName, open to admins
+	SetTimeZone(ctx context.Context, editor *auth.Principal, loginName string, s *TimeZoneSetting) (*TimeZoneSetting, error)
+
+	//ClearTimeZone lets the user follow the zone of the organization again
+	ClearTimeZone(ctx context.Context, editor *auth.Principal, loginName string) error
+
+	//Location is the loaded zone of GetTimeZone
+	Location(ctx context.Context, loginName string) (*time.Location, error)
+
+	//ReportLocation is the zone a report is given in: timeZone if it is not empty, else the zone of loginName
+	ReportLocation(ctx context.Context, loginName, timeZone string) (*time.Location, error)
+}
+
+type timeZoneService struct {
+	repo TimeZoneRepository
+}
+
+func NewTimeZoneService(repo TimeZoneRepository) TimeZoneService {
+	return &timeZoneService{repo: repo}
+}
+
+func (s *timeZoneService) GetTimeZone(ctx context.Context, loginName string) (*TimeZoneSetting, error) {
+	loginName = strings.ToUpper(loginName)
+	setting, err := s.repo.SelectTimeZone(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	if setting == nil {
+		return &TimeZoneSetting{LoginName: loginName, TimeZone: "UTC", Source: TimeZoneDefault}, nil
+	}
+
+	setting.Source = TimeZoneOfUser
+	if setting.LoginName == "" {
+		setting.LoginName, setting.Source = loginName, TimeZoneOfOrganization
+	}
+	return setting, nil
+}
+
+func (s *timeZoneService) SetTimeZone(ctx context.Context, editor *auth.Principal, loginName string, setting *TimeZoneSetting) (*TimeZoneSetting, error) {
+	loginName = strings.ToUpper(loginName)
+	if err := checkTimeZoneEditor(editor, loginName); err != nil {
+		return nil, err
+	}
+	ve := validate.New()
+	ve.IsRequired("TimeZone", setting.TimeZone)
+	validateTimeZone(ve, "TimeZone", setting.TimeZone)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	setting.LoginName, setting.UpdatedAt = loginName, time.Now()
+	if err := s.repo.UpsertTimeZone(ctx, setting); err != nil {
+		return nil, err
+	}
+	setting.Source = TimeZoneOfUser
+	if loginName == "" {
+		setting.Source = TimeZoneOfOrganization
+	}
+	return setting, nil
+}
+
+func (s *timeZoneService) ClearTimeZone(ctx context.Context, editor *auth.Principal, loginName string) error {
+	loginName = strings.ToUpper(loginName)
+	if err := checkTimeZoneEditor(editor, loginName); err != nil {
+		return err
+	}
+	return s.repo.DeleteTimeZone(ctx, loginName)
+}
+
+func (s *timeZoneService) Location(ctx context.Context, loginName string) (*time.Location, error) {
+	setting, err := s.GetTimeZone(ctx, loginName)
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(setting.TimeZone)
+}
+
+func (s *timeZoneService) ReportLocation(ctx context.Context, loginName, timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return s.Location(ctx, loginName)
+	}
+	ve := validate.New()
+	validateTimeZone(ve, "timeZone", timeZone)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	return time.LoadLocation(timeZone)
+}
+
+func checkTimeZoneEditor(editor *auth.Principal, loginName string) error {
+	if editor.Admin || (loginName != "" && editor.LoginName == loginName) {
+		return nil
+	}
+	return &res.AppError{ResponseCode: res.Forbidden, Cause: errors.Errorf("%s may not change the time zone of %q", editor.LoginName, loginName)}
+}
+
+//localDate is the date of the instant t in loc, as the UTC midnight dates are kept in
+func localDate(t time.Time, loc *time.Location) time.Time {
+	return dayOf(t.In(loc))
+}
@@ -0,0 +1,137 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"timesheet/commons/auth"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type TokenService interface {
+	CreateToken(ctx context.Context, loginName string, req *CreateAPIToken) (*IssuedAPIToken, error)
+
+	ListTokens(ctx context.Context, loginName string) ([]*APIToken, error)
+
+	RevokeToken(ctx context.Context, loginName string, tokenID string) error
+
+	Authenticate(ctx context.Context, secret string) (*APIToken, error)
+}
+
+type tokenService struct {
+	repo TokenRepository
+}
+
+func NewTokenService(repo TokenRepository) TokenService {
+	return &tokenService{repo: repo}
+}
+
+//lastUsedResolution avoids a write on every request made with the same token
+const lastUsedResolution = time.Minute
+
+//IsAPIToken reports whether a bearer credential looks like a personal api token rather than a JWT
+func IsAPIToken(credential string) bool {
+	return strings.HasPrefix(credential, apiTokenPrefix)
+}
+
+func (s *tokenService) CreateToken(ctx context.Context, loginName string, req *CreateAPIToken) (*IssuedAPIToken, error) {
+	ve := validate.New()
+	ve.IsSizeInRange("Name", strings.TrimSpace(req.Name), 1, 100)
+	ve.IsNumberInRange("ExpiresInDays", req.ExpiresInDays, 1, apiTokenMaxExpiryDays)
+	ve.IsNumberInRange("Scopes", len(req.Scopes), 1, len(auth.AllScopes))
+	allowedScopes := make([]string, 0, len(auth.AllScopes))
+	for _, scope := range auth.AllScopes {
+		allowedScopes = append(allowedScopes, string(scope))
+	}
+	for _, scope := range req.Scopes {
+		ve.IsWithin("Scopes", scope, allowedScopes)
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, &res.AppError{ResponseCode: res.InternalServerError, Cause: err}
+	}
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now().UTC()
+	expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+	t := APIToken{
+		ID:        uuid.New(),
+		LoginName: strings.ToUpper(loginName),
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    secret[:len(apiTokenPrefix)+6],
+		Scopes:    req.Scopes,
+		ExpiresAt: &expiresAt,
+		CreatedAt: now,
+	}
+
+	if err := s.repo.InsertAPIToken(ctx, &t, hashAPIToken(secret)); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("loginName", t.LoginName).Str("tokenID", t.ID.String()).Msg("Issued api token")
+	return &IssuedAPIToken{APIToken: t, Secret: secret}, nil
+}
+
+func (s *tokenService) ListTokens(ctx context.Context, loginName string) ([]*APIToken, error) {
+	return s.repo.SelectAPITokensByLoginName(ctx, strings.ToUpper(loginName))
+}
+
+func (s *tokenService) RevokeToken(ctx context.Context, loginName string, tokenID string) error {
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return &res.AppError{ResponseCode: TokenNotFound, Cause: err}
+	}
+
+	revoked, err := s.repo.RevokeAPIToken(ctx, strings.ToUpper(loginName), id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return &res.AppError{ResponseCode: TokenNotFound, Cause: errTokenNotFound}
+	}
+
+	log.Info().Str("loginName", loginName).Str("tokenID", tokenID).Msg("Revoked api token")
+	return nil
+}
+
+func (s *tokenService) Authenticate(ctx context.Context, secret string) (*APIToken, error) {
+	t, err := s.repo.SelectAPITokenByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if t == nil || t.RevokedAt != nil || (t.ExpiresAt != nil && now.After(*t.ExpiresAt)) {
+		return nil, &res.AppError{ResponseCode: TokenInvalid, Cause: errTokenInvalid}
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) > lastUsedResolution {
+		if err = s.repo.UpdateAPITokenLastUsed(ctx, t.ID, now); err != nil {
+			//Not fatal, the token itself is valid.
+			log.Error().Err(err).Str("tokenID", t.ID.String()).Msg("Unable to record api token usage")
+		}
+		t.LastUsedAt = &now
+	}
+
+	return t, nil
+}
+
+//hashAPIToken returns the value stored in place of the secret. Tokens carry 256 bits of
+//entropy so a plain SHA-256 is sufficient, and unlike bcrypt it can be looked up directly.
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
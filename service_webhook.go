@@ -0,0 +1,250 @@
+package timesheets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"timesheet/commons/events"
+	"timesheet/commons/res"
+	"timesheet/commons/validate"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+//WebhookService manages the subscriptions and sends the deliveries. As an EventPublisher it queues a delivery
+//for every subscription of the event, the deliveries are sent by DeliverDue.
+type WebhookService interface {
+	EventPublisher
+
+	CreateSubscription(ctx context.Context, sub *WebhookSubscription) (*WebhookSubscription, error)
+
+	ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+
+	DeleteSubscription(ctx context.Context, subscriptionID string) error
+
+	ListDeliveries(ctx context.Context, subscriptionID string) ([]*WebhookDelivery, error)
+
+	//Redeliver queues a delivery again, whatever the outcome of its earlier attempts
+	Redeliver(ctx context.Context, deliveryID string) (*WebhookDelivery, error)
+
+	//DeliverDue sends the deliveries of the organization in ctx that are due and returns how many succeeded
+	DeliverDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type webhookService struct {
+	repo   WebhookRepository
+	client *http.Client
+	cfg    WebhookConfig
+}
+
+func NewWebhookService(repo WebhookRepository, cfg WebhookConfig) WebhookService {
+	return &webhookService{repo: repo, client: &http.Client{Timeout: cfg.Timeout}, cfg: cfg}
+}
+
+//deliveryBatch is how many deliveries one DeliverDue claims
+const deliveryBatch = 50
+
+func (s *webhookService) CreateSubscription(ctx context.Context, sub *WebhookSubscription) (*WebhookSubscription, error) {
+	ve := validate.New()
+	ve.IsSizeInRange("URL", sub.URL, 1, 2000)
+	if u, err := url.Parse(sub.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ve.Errors = append(ve.Errors, validate.FieldError{Field: "URL", Constraint: validate.Like,
+			Message: "Must be an absolute http or https URL", Args: nil})
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	for _, t := range sub.EventTypes {
+		ve.IsWithin("EventTypes", t, eventTypes)
+	}
+	ve.IsSizeInRange("Secret", sub.Secret, 0, 200)
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+
+	sub.ID = uuid.New()
+	sub.CreatedAt = time.Now()
+	if err := s.repo.InsertSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error) {
+	return s.repo.SelectSubscriptions(ctx)
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	id, err := uuid.Parse(subscriptionID)
+	if err != nil {
+		return &res.AppError{ResponseCode: WebhookSubscriptionNotFound, Cause: err}
+	}
+
+	found, err := s.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &res.AppError{ResponseCode: WebhookSubscriptionNotFound, Cause: errors.New("no such webhook subscription")}
+	}
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, subscriptionID string) ([]*WebhookDelivery, error) {
+	id, err := uuid.Parse(subscriptionID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: WebhookSubscriptionNotFound, Cause: err}
+	}
+	return s.repo.SelectDeliveries(ctx, id)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, deliveryID string) (*WebhookDelivery, error) {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return nil, &res.AppError{ResponseCode: WebhookDeliveryNotFound, Cause: err}
+	}
+
+	d, err := s.repo.SelectDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, &res.AppError{ResponseCode: WebhookDeliveryNotFound, Cause: errors.New("no such webhook delivery")}
+	}
+
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	if err = s.repo.UpdateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *webhookService) Publish(ctx context.Context, e *Event) error {
+	subs, err := s.repo.SelectSubscriptionsFor(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		d := &WebhookDelivery{ID: uuid.New(), SubscriptionID: sub.ID, EventID: e.ID, EventType: e.Type, Payload: payload,
+			Status: DeliveryPending, NextAttemptAt: e.OccurredAt, CreatedAt: time.Now()}
+		if err = s.repo.InsertDelivery(ctx, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *webhookService) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	//A delivery left behind by a crashed instance becomes due again once the lease is over
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, now, now.Add(2*s.cfg.Timeout), deliveryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, d := range deliveries {
+		d.Attempts++
+		status, err := s.send(ctx, d)
+		d.ResponseStatus = status
+		if err == nil {
+			delivered := time.Now()
+			d.Status, d.LastError, d.DeliveredAt = DeliverySucceeded, "", &delivered
+			succeeded++
+		} else {
+			d.LastError = err.Error()
+			if d.Attempts >= s.cfg.MaxAttempts {
+				d.Status = DeliveryFailed
+			} else {
+				d.NextAttemptAt = time.Now().Add(s.cfg.RetryBase * time.Duration(1<<uint(d.Attempts-1)))
+			}
+			log.Warn().Err(err).Str("delivery", d.ID.String()).Int("attempts", d.Attempts).Msg("Webhook delivery failed")
+		}
+		if err = s.repo.UpdateDelivery(ctx, d); err != nil {
+			return succeeded, err
+		}
+	}
+	return succeeded, nil
+}
+
+//send posts the payload signed with the subscription's secret. The signature covers the timestamp as well
+//so a receiver can reject replays: hex(HMAC-SHA256(secret, timestamp + "." + body)).
+func (s *webhookService) send(ctx context.Context, d *WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(d.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(d.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Timesheet-Event", string(d.EventType))
+	req.Header.Set("X-Timesheet-Delivery", d.ID.String())
+	req.Header.Set("X-Timesheet-Timestamp", timestamp)
+	req.Header.Set("X-Timesheet-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+type webhookSink struct {
+	webhooks WebhookService
+}
+
+//NewWebhookSink lets the outbox relay queue webhook deliveries. Deliveries are unique per subscription and
+//event, so an event the relay publishes again is not sent twice.
+func NewWebhookSink(webhooks WebhookService) events.Sink {
+	return &webhookSink{webhooks: webhooks}
+}
+
+func (s *webhookSink) Name() string { return "webhooks" }
+
+func (s *webhookSink) Publish(ctx context.Context, msg *events.Message) error {
+	e := &Event{}
+	if err := json.Unmarshal(msg.Payload, e); err != nil {
+		return err
+	}
+	return s.webhooks.Publish(ctx, e)
+}
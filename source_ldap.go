@@ -0,0 +1,109 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+//DirectorySource lists the users an external directory knows about. ManagerLoginName is already resolved.
+type DirectorySource interface {
+	FetchUsers(ctx context.Context) ([]*DirectoryEntry, error)
+}
+
+type ldapSource struct {
+	cfg LDAPConfig
+}
+
+func NewLDAPSource(cfg LDAPConfig) DirectorySource {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(objectClass=person)"
+	}
+	if cfg.LoginAttr == "" {
+		cfg.LoginAttr = "uid"
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = 500
+	}
+	return &ldapSource{cfg: cfg}
+}
+
+func (s *ldapSource) FetchUsers(ctx context.Context) ([]*DirectoryEntry, error) {
+	conn, err := ldap.DialURL(s.cfg.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to ldap")
+	}
+	defer conn.Close()
+
+	if s.cfg.BindDN != "" {
+		if err = conn.Bind(s.cfg.BindDN, s.cfg.BindPassword); err != nil {
+			return nil, errors.Wrap(err, "ldap bind failed")
+		}
+	}
+
+	attrs := []string{s.cfg.LoginAttr}
+	for _, a := range []string{s.cfg.GivenNameAttr, s.cfg.FamilyNameAttr, s.cfg.EmailAttr,
+		s.cfg.DepartmentAttr, s.cfg.TitleAttr, s.cfg.ManagerAttr} {
+		if a != "" {
+			attrs = append(attrs, a)
+		}
+	}
+
+	result, err := conn.SearchWithPaging(ldap.NewSearchRequest(s.cfg.BaseDN, ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases, 0, 0, false, s.cfg.UserFilter, attrs, nil), s.cfg.PageSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "ldap user search failed")
+	}
+
+	disabled := map[string]bool{}
+	if s.cfg.DisabledFilter != "" {
+		filter := fmt.Sprintf("(&%s%s)", s.cfg.UserFilter, s.cfg.DisabledFilter)
+		locked, err := conn.SearchWithPaging(ldap.NewSearchRequest(s.cfg.BaseDN, ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases, 0, 0, false, filter, []string{"dn"}, nil), s.cfg.PageSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "ldap disabled user search failed")
+		}
+		for _, e := range locked.Entries {
+			disabled[strings.ToLower(e.DN)] = true
+		}
+	}
+
+	//Managers are referenced by DN, so the login name can only be resolved once every entry is known.
+	loginByDN := map[string]string{}
+	entries := make([]*DirectoryEntry, 0, len(result.Entries))
+	managerDNs := make([]string, 0, len(result.Entries))
+	for _, le := range result.Entries {
+		login := strings.ToUpper(le.GetAttributeValue(s.cfg.LoginAttr))
+		if login == "" {
+			continue
+		}
+		loginByDN[strings.ToLower(le.DN)] = login
+		entries = append(entries, &DirectoryEntry{
+			LoginName:  login,
+			ExternalID: le.DN,
+			GivenName:  s.attr(le, s.cfg.GivenNameAttr),
+			FamilyName: s.attr(le, s.cfg.FamilyNameAttr),
+			Email:      s.attr(le, s.cfg.EmailAttr),
+			Department: s.attr(le, s.cfg.DepartmentAttr),
+			JobTitle:   s.attr(le, s.cfg.TitleAttr),
+			Active:     !disabled[strings.ToLower(le.DN)],
+			Source:     DirectorySourceLDAP,
+		})
+		managerDNs = append(managerDNs, strings.ToLower(s.attr(le, s.cfg.ManagerAttr)))
+	}
+	for i, e := range entries {
+		e.ManagerLoginName = loginByDN[managerDNs[i]]
+	}
+
+	return entries, nil
+}
+
+func (s *ldapSource) attr(e *ldap.Entry, name string) string {
+	if name == "" {
+		return ""
+	}
+	return e.GetAttributeValue(name)
+}
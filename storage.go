@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+//ErrNotFound is returned by Get for a key that was never stored or was deleted
+var ErrNotFound = errors.New("object not found")
+
+//Store keeps the content of uploaded files under a key. Features that keep files depend on this interface only,
+//so the local directory used in development can be swapped for an S3-compatible bucket.
+type Store interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+
+	//Get fails with ErrNotFound for an unknown key, the caller closes the content
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	//Delete succeeds for an unknown key
+	Delete(ctx context.Context, key string) error
+}
+
+//Scanner checks uploaded content for malware before it is stored. Scan returns an error wrapping ErrInfected
+//when the content must be refused, other errors mean the scan could not be done.
+type Scanner interface {
+	Scan(ctx context.Context, name string, content []byte) error
+}
+
+var ErrInfected = errors.New("content is infected")
+
+type noScanner struct{}
+
+//NewNoScanner accepts every upload, it is used when no virus scanner is set up
+func NewNoScanner() Scanner {
+	return &noScanner{}
+}
+
+func (s *noScanner) Scan(ctx context.Context, name string, content []byte) error {
+	return nil
+}
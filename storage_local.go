@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type localStore struct {
+	dir string
+}
+
+//NewLocalStore keeps files below dir, the path of a file being its key
+func NewLocalStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, errors.Wrapf(err, "creating storage directory %s", dir)
+	}
+	return &localStore{dir: dir}, nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return errors.Wrapf(err, "creating directory of %s", key)
+	}
+
+	//Written to a temporary file first so that a failed upload never leaves half a file under the key
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return errors.Wrapf(err, "storing %s", key)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "storing %s", key)
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrapf(err, "storing %s", key)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrapf(err, "storing %s", key)
+	}
+	return nil
+}
+
+func (s *localStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", key)
+	}
+	return f, nil
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "deleting %s", key)
+	}
+	return nil
+}
+
+//path maps a key below the directory, keys leading out of it are refused
+func (s *localStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", errors.Errorf("invalid storage key %q", key)
+	}
+	return path, nil
+}
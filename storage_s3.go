@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type S3Config struct {
+	//Endpoint is the base URL of the service, such as https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	//for MinIO. Buckets are addressed path-style below it.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Timeout   time.Duration
+}
+
+type s3Store struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+//NewS3Store keeps files in a bucket of an S3-compatible service. Requests are signed with AWS Signature Version 4,
+//which MinIO and the other S3-compatible servers accept as well.
+func NewS3Store(cfg S3Config) Store {
+	return &s3Store{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return errors.Wrapf(err, "storing %s", key)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrapf(err, "reading %s", key)
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return errors.Wrapf(err, "deleting %s", key)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (s *s3Store) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid storage endpoint %q", s.cfg.Endpoint)
+	}
+	escaped := u.Path + "/" + uriEncode(s.cfg.Bucket)
+	for _, segment := range strings.Split(key, "/") {
+		escaped += "/" + uriEncode(segment)
+	}
+	u.Path, u.RawPath = u.Path+"/"+s.cfg.Bucket+"/"+key, escaped
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+//do signs and sends the request, a status other than 2xx is an error and 404 is ErrNotFound
+func (s *s3Store) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, errors.Errorf("storage responded %s: %s", resp.Status, detail)
+}
+
+//sign adds the headers of AWS Signature Version 4. The payload is not hashed, uploads are streamed.
+func (s *s3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:UNSIGNED-PAYLOAD\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), day)
+	for _, part := range []string{s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+//uriEncode escapes everything but the unreserved characters, as the canonical request of a signature requires
+func uriEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
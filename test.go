@@ -1,9 +1,9 @@
-package main
-
-import (
-	"testing"
-)
-
-func TestCreateUser(t *testing.T) {
-
-}
+package main
+
+import (
+	"testing"
+)
+
+func TestCreateUser(t *testing.T) {
+
+}